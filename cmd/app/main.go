@@ -2,11 +2,13 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/joho/godotenv"
 
 	"trading_bot/config"
 	"trading_bot/internal/app"
+	"trading_bot/internal/common/secrets"
 )
 
 func init() {
@@ -23,6 +25,18 @@ func main() {
 		log.Fatalf("Config error: %s", err)
 	}
 
+	// Secrets, ${secret:name} references of the keys are replaced with the values of the keystore or the mounts
+	var stdin = secrets.NewInput(os.Stdin)
+	provider, err := secrets.NewProvider(cfg.Secrets.Keystore, cfg.Secrets.Dir, func() (string, error) {
+		return secrets.ReadPassphrase(stdin, os.Stderr, "Keystore passphrase: ")
+	})
+	if err != nil {
+		log.Fatalf("Secrets error: %s", err)
+	}
+	if err := cfg.ResolveSecrets(provider); err != nil {
+		log.Fatalf("Secrets error: %s", err)
+	}
+
 	// Run
 	app.Run(cfg)
 }
@@ -0,0 +1,147 @@
+// Command secrets manages the encrypted keystore of the API keys.
+//
+//	secrets [-keystore path] init
+//	secrets [-keystore path] list
+//	secrets [-keystore path] add <name>
+//	secrets [-keystore path] rotate <name>
+//	secrets [-keystore path] remove <name>
+//	secrets [-keystore path] passwd
+//
+// The passphrase is taken from SECRETS_PASSPHRASE or read from stdin, secret values are read from stdin,
+// so they don't get into the shell history. The terminal doesn't echo them.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"trading_bot/internal/common/secrets"
+)
+
+const defaultKeystore = "./config/secrets.keystore"
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "secrets: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, out io.Writer) error {
+	var flags = flag.NewFlagSet("secrets", flag.ContinueOnError)
+	flags.SetOutput(out)
+	var path = flags.String("keystore", keystorePath(), "keystore file (SECRETS_KEYSTORE)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var in = secrets.NewInput(stdin)
+	var command, name = flags.Arg(0), flags.Arg(1)
+
+	switch command {
+	case "init":
+		passphrase, err := secrets.ReadPassphrase(in, out, "New keystore passphrase: ")
+		if err != nil {
+			return err
+		}
+		ks, err := secrets.CreateKeystore(*path, passphrase)
+		if err != nil {
+			return err
+		}
+		if err := ks.Save(); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "keystore %v created\n", *path)
+		return nil
+
+	case "list":
+		ks, err := open(*path, in, out)
+		if err != nil {
+			return err
+		}
+		for _, name := range ks.Names() {
+			fmt.Fprintln(out, name)
+		}
+		return nil
+
+	case "add", "rotate":
+		if len(name) == 0 {
+			return fmt.Errorf("%v : secret name is required", command)
+		}
+		ks, err := open(*path, in, out)
+		if err != nil {
+			return err
+		}
+		if command == "add" && ks.Has(name) {
+			return fmt.Errorf("secret %q already exists, use rotate", name)
+		}
+		if command == "rotate" && !ks.Has(name) {
+			return fmt.Errorf("secret %q : %w", name, secrets.ErrNotFound)
+		}
+
+		fmt.Fprintf(out, "Value of %v: ", name)
+		value, err := in.ReadLine(out)
+		if err != nil {
+			return fmt.Errorf("secret %q : %w", name, err)
+		}
+		if len(value) == 0 {
+			return fmt.Errorf("secret %q : empty value", name)
+		}
+		if err := ks.Set(name, secrets.Secret(value)); err != nil {
+			return err
+		}
+		if err := ks.Save(); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "secret %v saved, reference it as ${secret:%v}\n", name, name)
+		return nil
+
+	case "remove":
+		ks, err := open(*path, in, out)
+		if err != nil {
+			return err
+		}
+		if err := ks.Remove(name); err != nil {
+			return err
+		}
+		return ks.Save()
+
+	case "passwd":
+		ks, err := open(*path, in, out)
+		if err != nil {
+			return err
+		}
+		// the new passphrase is always read from stdin
+		fmt.Fprint(out, "New keystore passphrase: ")
+		passphrase, err := in.ReadLine(out)
+		if err != nil {
+			return err
+		}
+		if err := ks.SetPassphrase(passphrase); err != nil {
+			return err
+		}
+		return ks.Save()
+	}
+
+	flags.Usage()
+	return errors.New("command is required: init, list, add, rotate, remove or passwd")
+}
+
+func open(path string, in *secrets.Input, out io.Writer) (*secrets.Keystore, error) {
+	passphrase, err := secrets.ReadPassphrase(in, out, "Keystore passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+
+	return secrets.OpenKeystore(path, passphrase)
+}
+
+func keystorePath() string {
+	if path := os.Getenv("SECRETS_KEYSTORE"); len(path) > 0 {
+		return path
+	}
+	return defaultKeystore
+}
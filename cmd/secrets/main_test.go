@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"trading_bot/internal/common/secrets"
+)
+
+const testValue = "poloniex-secret-value"
+
+// unsetPassphrase unsets SECRETS_PASSPHRASE until the end of the test, the passphrase is read from stdin
+func unsetPassphrase(t *testing.T) {
+	t.Helper()
+
+	t.Setenv(secrets.PassphraseEnv, "")
+	os.Unsetenv(secrets.PassphraseEnv)
+}
+
+// runCommand runs the command with the lines of stdin, the passphrase is read from stdin
+func runCommand(t *testing.T, path string, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	var out = &bytes.Buffer{}
+	var err = run(append([]string{"-keystore", path}, args...), strings.NewReader(stdin), out)
+
+	return out.String(), err
+}
+
+func TestRun_AddRotateList_Success(t *testing.T) {
+	unsetPassphrase(t)
+
+	var path = filepath.Join(t.TempDir(), "secrets.keystore")
+
+	if _, err := runCommand(t, path, "passphrase\n", "init"); err != nil {
+		t.Fatalf("init : got error %v, wanted nil", err)
+	}
+	if _, err := runCommand(t, path, "passphrase\nfirst-value\n", "add", "poloniex_btc"); err != nil {
+		t.Fatalf("add : got error %v, wanted nil", err)
+	}
+	out, err := runCommand(t, path, "passphrase\n"+testValue+"\n", "rotate", "poloniex_btc")
+	if err != nil {
+		t.Fatalf("rotate : got error %v, wanted nil", err)
+	}
+	if strings.Contains(out, testValue) {
+		t.Errorf("got secret value in the output %q", out)
+	}
+
+	out, err = runCommand(t, path, "passphrase\n", "list")
+	if err != nil || !strings.Contains(out, "poloniex_btc\n") {
+		t.Errorf("list : got %q, %v, wanted poloniex_btc", out, err)
+	}
+
+	ks, err := secrets.OpenKeystore(path, "passphrase")
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if got, _ := ks.Get("poloniex_btc"); got.Reveal() != testValue {
+		t.Errorf("got %v, wanted %v", got.Reveal(), testValue)
+	}
+}
+
+func TestRun_WrongPassphrase_NotSuccess(t *testing.T) {
+	unsetPassphrase(t)
+
+	var path = filepath.Join(t.TempDir(), "secrets.keystore")
+
+	if _, err := runCommand(t, path, "passphrase\n", "init"); err != nil {
+		t.Fatalf("init : got error %v, wanted nil", err)
+	}
+	if _, err := runCommand(t, path, "wrong\n"+testValue+"\n", "add", "poloniex_btc"); err == nil {
+		t.Errorf("got nil error, wanted error")
+	}
+}
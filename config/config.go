@@ -1,8 +1,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"trading_bot/internal/common/secrets"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/shopspring/decimal"
@@ -14,6 +16,11 @@ type (
 		App              `json:"app"`
 		Log              `json:"logger"`
 		PG               `json:"postgres"`
+		Storage          `json:"storage"`
+		Alert            `json:"alert"`
+		Http             `json:"http"`
+		Secrets          `json:"secrets"`
+		Reconcile        `json:"reconcile"`
 		CryptoCurrencies []CryptoCurrency `json:"CryptoCurrencies"`
 	}
 
@@ -34,6 +41,50 @@ type (
 		URL     string `env-required:"true"                 env:"PG_URL"`
 	}
 
+	// Storage -.
+	Storage struct {
+		HedgeJournal string `json:"hedge_journal" env:"HEDGE_JOURNAL"`
+		// nonces of the trading system keys are persisted here, used when TradingSettings.NonceDir is empty
+		NonceDir string `json:"nonce_dir" env:"NONCE_DIR"`
+		// withdrawals of the balance workers, pending transfers are restored on start
+		TransferLedger string `json:"transfer_ledger" env:"TRANSFER_LEDGER"`
+	}
+
+	// Secrets are the sources of ${secret:name} references of the keys, the mounts are asked before the keystore
+	Secrets struct {
+		Keystore string `json:"keystore" env:"SECRETS_KEYSTORE"`
+		Dir      string `json:"dir"      env:"SECRETS_DIR"`
+	}
+
+	// Reconcile of the journaled hedges with the trading system history, zero values mean defaults
+	Reconcile struct {
+		IntervalSeconds int `json:"interval_seconds" env:"RECONCILE_INTERVAL_SECONDS"`
+		LookbackMinutes int `json:"lookback_minutes" env:"RECONCILE_LOOKBACK_MINUTES"`
+	}
+
+	// Alert -.
+	Alert struct {
+		WebhookUrl string `json:"webhook_url" env:"ALERT_WEBHOOK_URL"`
+	}
+
+	// Http client shared by all requests, zero values mean defaults
+	Http struct {
+		DialTimeoutMs           int    `json:"dial_timeout_ms"            env:"HTTP_DIAL_TIMEOUT_MS"`
+		TLSHandshakeTimeoutMs   int    `json:"tls_handshake_timeout_ms"   env:"HTTP_TLS_HANDSHAKE_TIMEOUT_MS"`
+		ResponseHeaderTimeoutMs int    `json:"response_header_timeout_ms" env:"HTTP_RESPONSE_HEADER_TIMEOUT_MS"`
+		RequestTimeoutMs        int    `json:"request_timeout_ms"         env:"HTTP_REQUEST_TIMEOUT_MS"`
+		IdleConnTimeoutMs       int    `json:"idle_conn_timeout_ms"       env:"HTTP_IDLE_CONN_TIMEOUT_MS"`
+		MaxIdleConns            int    `json:"max_idle_conns"             env:"HTTP_MAX_IDLE_CONNS"`
+		MaxIdleConnsPerHost     int    `json:"max_idle_conns_per_host"    env:"HTTP_MAX_IDLE_CONNS_PER_HOST"`
+		MaxConnsPerHost         int    `json:"max_conns_per_host"         env:"HTTP_MAX_CONNS_PER_HOST"`
+		MaxBodyBytes            int64  `json:"max_body_bytes"             env:"HTTP_MAX_BODY_BYTES"`
+		ProxyUrl                string `json:"proxy_url"                  env:"HTTP_PROXY_URL"`
+		TLSMinVersion           string `json:"tls_min_version"            env:"HTTP_TLS_MIN_VERSION"`
+		TLSInsecureSkipVerify   bool   `json:"tls_insecure_skip_verify"   env:"HTTP_TLS_INSECURE_SKIP_VERIFY"`
+		TLSCAFile               string `json:"tls_ca_file"                env:"HTTP_TLS_CA_FILE"`
+		UserAgent               string `json:"user_agent"                 env:"HTTP_USER_AGENT"`
+	}
+
 	// CryptoCurrency
 	CryptoCurrency struct {
 		CurrencyId       int             `json:"CurrencyId"`
@@ -43,29 +94,74 @@ type (
 		SellMultiplier   decimal.Decimal `json:"SellMultiplier"`
 		BuyMultiplier    decimal.Decimal `json:"BuyMultiplier"`
 		TimeoutMinutes   int             `json:"TimeoutMinutes"`
-		InternalSettings `json:"InternalSettings"`
-		TradingSettings  `json:"TradingSettings"`
+		// pause between worker cycles, 10 seconds when zero
+		WorkIntervalMilliseconds int `json:"WorkIntervalMilliseconds"`
+		// refresh of the tick size, lot size and order limits of the pair, 60 minutes when zero
+		MarketInfoRefreshMinutes int `json:"MarketInfoRefreshMinutes"`
+		InternalSettings         `json:"InternalSettings"`
+		TradingSettings          `json:"TradingSettings"`
 	}
 
 	InternalSettings struct {
-		Url            string          `json:"Url"`
-		Key            string          `json:"Key"`
-		Secret         string          `json:"Secret"`
-		Pair           string          `json:"Pair"`
-		Currency       string          `json:"Currency"`
-		CryptoAddress  string          `json:"CryptoAddress"`
-		UsdcUsageLimit decimal.Decimal `json:"UsdcUsageLimit"`
+		Url            string            `json:"Url"`
+		Key            secrets.Secret    `json:"Key"`
+		Secret         secrets.Secret    `json:"Secret"`
+		Pair           string            `json:"Pair"`
+		Currency       string            `json:"Currency"`
+		CryptoAddress  string            `json:"CryptoAddress"`
+		UsdcUsageLimit decimal.Decimal   `json:"UsdcUsageLimit"`
+		RateLimit      RateLimitSettings `json:"RateLimit"`
+		Breaker        BreakerSettings   `json:"Breaker"`
+		Signer         string            `json:"Signer"`
 	}
 	TradingSettings struct {
-		Url               string          `json:"Url"`
-		Key               string          `json:"Key"`
-		Secret            string          `json:"Secret"`
-		Pair              string          `json:"Pair"`
-		Currency          string          `json:"Currency"`
-		CryptoAddress     string          `json:"CryptoAddress"`
-		DestinationTag    string          `json:"DestinationTag"`
-		WithdrawalNetwork string          `json:"WithdrawalNetwork"`
-		UsdcUsageLimit    decimal.Decimal `json:"UsdcUsageLimit"`
+		Type              string            `json:"Type"`
+		Url               string            `json:"Url"`
+		WebSocketUrl      string            `json:"WebSocketUrl"`
+		Key               secrets.Secret    `json:"Key"`
+		Secret            secrets.Secret    `json:"Secret"`
+		Pair              string            `json:"Pair"`
+		Currency          string            `json:"Currency"`
+		CryptoAddress     string            `json:"CryptoAddress"`
+		DestinationTag    string            `json:"DestinationTag"`
+		WithdrawalNetwork string            `json:"WithdrawalNetwork"`
+		TimeInForce       string            `json:"TimeInForce"`
+		UsdcUsageLimit    decimal.Decimal   `json:"UsdcUsageLimit"`
+		Retry             RetrySettings     `json:"Retry"`
+		RateLimit         RateLimitSettings `json:"RateLimit"`
+		NonceDir          string            `json:"NonceDir"`
+		Signer            string            `json:"Signer"`
+	}
+
+	// RateLimitSettings per endpoint class, shared by all workers using the same key.
+	// Zero values mean venue defaults, Weights are tokens taken by the endpoint (1 by default).
+	RateLimitSettings struct {
+		Public  RateLimit      `json:"Public"`
+		Private RateLimit      `json:"Private"`
+		Order   RateLimit      `json:"Order"`
+		Weights map[string]int `json:"Weights"`
+	}
+
+	// BreakerSettings of the backend circuit breaker, zero values mean defaults
+	BreakerSettings struct {
+		FailureThreshold int `json:"FailureThreshold"`
+		OpenSeconds      int `json:"OpenSeconds"`
+	}
+
+	RateLimit struct {
+		RequestsPerSecond float64 `json:"RequestsPerSecond"`
+		Burst             int     `json:"Burst"`
+	}
+
+	// RetrySettings of the hedge orders, zero values mean defaults
+	RetrySettings struct {
+		MaxAttempts                int             `json:"MaxAttempts"`
+		MaxElapsedSeconds          int             `json:"MaxElapsedSeconds"`
+		InitialBackoffMilliseconds int             `json:"InitialBackoffMilliseconds"`
+		MaxBackoffMilliseconds     int             `json:"MaxBackoffMilliseconds"`
+		BackoffMultiplier          float64         `json:"BackoffMultiplier"`
+		Jitter                     float64         `json:"Jitter"`
+		PriceStep                  decimal.Decimal `json:"PriceStep"`
 	}
 )
 
@@ -86,3 +182,26 @@ func NewConfig() (*Config, error) {
 
 	return cfg, nil
 }
+
+// ResolveSecrets replaces ${secret:name} references of the keys and secrets with the values of the provider
+func (cfg *Config) ResolveSecrets(p secrets.Provider) error {
+	var errs []error
+	var resolve = func(value *secrets.Secret, currency string, field string) {
+		resolved, err := secrets.Resolve(*value, p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v %v : %w", currency, field, err))
+			return
+		}
+		*value = resolved
+	}
+
+	for i := range cfg.CryptoCurrencies {
+		var item = &cfg.CryptoCurrencies[i]
+		resolve(&item.InternalSettings.Key, item.InternalSettings.Currency, "InternalSettings.Key")
+		resolve(&item.InternalSettings.Secret, item.InternalSettings.Currency, "InternalSettings.Secret")
+		resolve(&item.TradingSettings.Key, item.TradingSettings.Currency, "TradingSettings.Key")
+		resolve(&item.TradingSettings.Secret, item.TradingSettings.Currency, "TradingSettings.Secret")
+	}
+
+	return errors.Join(errs...)
+}
@@ -4,11 +4,13 @@ go 1.24.1
 
 require (
 	github.com/gofrs/uuid v4.4.0+incompatible
+	github.com/gorilla/websocket v1.5.3
 	github.com/ilyakaznacheev/cleanenv v1.5.0
 	github.com/joho/godotenv v1.5.1
 	github.com/rs/zerolog v1.34.0
 	github.com/shopspring/decimal v1.4.0
 	github.com/stretchr/testify v1.7.1
+	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211
 	gopkg.in/guregu/null.v4 v4.0.0
 )
 
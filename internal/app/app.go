@@ -3,19 +3,31 @@ package app
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"trading_bot/config"
+	"trading_bot/internal/common"
+	"trading_bot/internal/common/breaker"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/journal"
+	"trading_bot/internal/common/transfer"
+	"trading_bot/pkg/alert"
 	balanceManager "trading_bot/pkg/balance/manager"
 	"trading_bot/pkg/logger"
 	tradingManager "trading_bot/pkg/trading/manager"
+	"trading_bot/pkg/trading/reconciler"
 
 	"github.com/shopspring/decimal"
 )
 
+// request timings of the shared client are logged every interval
+const requestStatsInterval = 10 * time.Minute
+
 // Run creates objects via constructors.
 func Run(cfg *config.Config) {
 	l := logger.New(cfg.Log.Level)
@@ -25,18 +37,61 @@ func Run(cfg *config.Config) {
 	ctx, cancel := context.WithCancel(context.Background())
 	var wg sync.WaitGroup
 
-	balManager, err := balanceManager.New(ctx, &wg, cfg.CryptoCurrencies, l)
+	// hedge journal is optional, hedges are logged anyway
+	var hedgeJournal *journal.Journal
+	if len(cfg.Storage.HedgeJournal) > 0 {
+		var err error
+		hedgeJournal, err = journal.New(cfg.Storage.HedgeJournal)
+		if err != nil {
+			l.Fatal("app - Run - journal.New: %w", err)
+		}
+	}
+
+	// transfer ledger is optional, transfers are tracked in memory without it
+	var transferLedger *transfer.Ledger
+	if len(cfg.Storage.TransferLedger) > 0 {
+		var err error
+		transferLedger, err = transfer.NewLedger(cfg.Storage.TransferLedger)
+		if err != nil {
+			l.Fatal("app - Run - transfer.NewLedger: %w", err)
+		}
+	}
+
+	// nonces of the trading system keys survive restarts when the directory is set
+	for i := range cfg.CryptoCurrencies {
+		if len(cfg.CryptoCurrencies[i].TradingSettings.NonceDir) == 0 {
+			cfg.CryptoCurrencies[i].TradingSettings.NonceDir = cfg.Storage.NonceDir
+		}
+	}
+
+	alerter := alert.New(l, cfg.Alert.WebhookUrl, cfg.App.Name)
+	breakers := breaker.NewRegistry(breakerListener(l, alerter))
+
+	// single client keeps connections to the venues alive between worker cycles
+	httpClient, err := helpermethods.NewClient(cfg.Http)
+	if err != nil {
+		l.Fatal("app - Run - helpermethods.NewClient: %w", err)
+	}
+	defer httpClient.CloseIdleConnections()
+	logRequestStats(ctx, &wg, l, httpClient)
+
+	balManager, err := balanceManager.New(ctx, &wg, cfg.CryptoCurrencies, l, transferLedger, breakers, httpClient)
 	if err != nil {
 		l.Fatal("app - Run - BalanceManager.New: %w", err)
 	}
 	balManager.Start()
 
-	tradeManager, err1 := tradingManager.New(ctx, &wg, cfg.CryptoCurrencies, l)
+	tradeManager, err1 := tradingManager.New(ctx, &wg, cfg.CryptoCurrencies, l, hedgeJournal, breakers, httpClient)
 	if err1 != nil {
 		l.Fatal("app - Run - TradingManager.New: %w", err1)
 	}
 	tradeManager.Start()
 
+	// journaled hedges are reconciled with the trading system history
+	if hedgeJournal != nil {
+		startReconcilers(ctx, &wg, cfg, l, hedgeJournal, alerter, httpClient)
+	}
+
 	// Waiting signal
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
@@ -53,3 +108,64 @@ func Run(cfg *config.Config) {
 	cancel()
 	wg.Wait()
 }
+
+// startReconcilers starts hedge reconcilers of the currencies whose trading system provides the trade history
+func startReconcilers(ctx context.Context, wg *sync.WaitGroup, cfg *config.Config, l logger.ILogger, hedgeJournal *journal.Journal, alerter alert.IAlerter, httpClient *helpermethods.Client) {
+	for _, item := range cfg.CryptoCurrencies {
+		// skip empty currencies
+		if len(item.InternalSettings.Currency) == 0 {
+			continue
+		}
+
+		hr, err := reconciler.New(ctx, wg, item, cfg.Reconcile, l, hedgeJournal, alerter, httpClient)
+		if errors.Is(err, common.ErrNotSupported) {
+			// the hedge journal is set, the trading system of the currency can't be reconciled
+			l.Error("app - Run - hedges of %v are not reconciled, the trading system %v has no trade history : %v", item.InternalSettings.Pair, item.TradingSettings.Type, err)
+			continue
+		}
+		if err != nil {
+			l.Fatal("app - Run - HedgeReconciler.New: %w", err)
+		}
+		hr.Start()
+	}
+}
+
+// logRequestStats logs the timings of the requests by endpoint every interval and on shutdown
+func logRequestStats(ctx context.Context, wg *sync.WaitGroup, l logger.ILogger, httpClient *helpermethods.Client) {
+	var logStats = func() {
+		for _, stats := range httpClient.Metrics().Snapshot() {
+			l.Info("app - HTTP - %v", stats)
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		var ticker = time.NewTicker(requestStatsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				logStats()
+			case <-ctx.Done():
+				logStats()
+				return
+			}
+		}
+	}()
+}
+
+// breakerListener reports circuit breaker state changes to the logger and alerting
+func breakerListener(l logger.ILogger, alerter alert.IAlerter) breaker.Listener {
+	return func(name string, from breaker.State, to breaker.State, err error) {
+		switch to {
+		case breaker.StateOpen:
+			alerter.Alert("%v circuit breaker is open (was %v), quoting and hedging are paused : %v", name, from, err)
+		case breaker.StateClosed:
+			alerter.Alert("%v circuit breaker is closed, quoting and hedging are resumed", name)
+		default:
+			l.Warn("%v circuit breaker is %v, probing backend", name, to)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"trading_bot/config"
+	"trading_bot/internal/common"
+)
+
+// ErrOpen is returned instead of calling the backend while the breaker is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Listener is called on every state change of the breaker
+type Listener func(name string, from State, to State, err error)
+
+// Breaker opens after FailureThreshold consecutive backend failures (5xx, timeouts),
+// rejects requests while open and lets a single probe request through after the open timeout.
+type Breaker struct {
+	mu               sync.Mutex
+	name             string
+	failureThreshold int
+	openTimeout      time.Duration
+	listener         Listener
+	state            State
+	failures         int
+	openedAt         time.Time
+	probing          bool
+}
+
+func New(name string, bs config.BreakerSettings, listener Listener) *Breaker {
+	var b = &Breaker{
+		name:             name,
+		failureThreshold: bs.FailureThreshold,
+		openTimeout:      time.Duration(bs.OpenSeconds) * time.Second,
+		listener:         listener,
+		state:            StateClosed,
+	}
+
+	if b.failureThreshold <= 0 {
+		b.failureThreshold = defaultFailureThreshold
+	}
+	if b.openTimeout <= 0 {
+		b.openTimeout = defaultOpenTimeout
+	}
+
+	return b
+}
+
+// Name of the backend
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns current state, open breaker is reported as half-open when the probe is allowed
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.openTimeout {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// Allow checks that the request can be sent, every allowed request must be finished with Done
+func (b *Breaker) Allow(operation string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.openTimeout {
+		b.setState(StateHalfOpen, nil)
+	}
+
+	switch b.state {
+	case StateOpen:
+		return b.openError(operation)
+	case StateHalfOpen:
+		if b.probing {
+			return b.openError(operation)
+		}
+		b.probing = true
+	}
+
+	return nil
+}
+
+// Done records result of the allowed request
+func (b *Breaker) Done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var failed = isFailure(err)
+
+	if b.state == StateHalfOpen {
+		b.probing = false
+		if failed {
+			b.open(err)
+		} else if !errors.Is(err, context.Canceled) {
+			b.failures = 0
+			b.setState(StateClosed, nil)
+		}
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == StateClosed && b.failures >= b.failureThreshold {
+		b.open(err)
+	}
+}
+
+func (b *Breaker) open(err error) {
+	b.openedAt = time.Now()
+	b.setState(StateOpen, err)
+}
+
+func (b *Breaker) setState(state State, err error) {
+	if b.state == state {
+		return
+	}
+
+	var from = b.state
+	b.state = state
+	if b.listener != nil {
+		b.listener(b.name, from, state, err)
+	}
+}
+
+func (b *Breaker) openError(operation string) error {
+	return &common.RequestError{
+		Kind:      common.ErrServiceUnavailable,
+		System:    b.name,
+		Operation: operation,
+		Message:   "request is not sent",
+		Err:       ErrOpen,
+	}
+}
+
+// isFailure reports backend availability errors, request errors (4xx, rejected orders) don't open the breaker
+func isFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return errors.Is(err, common.ErrServiceUnavailable) || errors.Is(err, common.ErrTransientNetwork)
+}
+
+// Registry keeps breakers per backend, workers of all currencies share them
+type Registry struct {
+	mu       sync.Mutex
+	listener Listener
+	breakers map[string]*Breaker
+}
+
+func NewRegistry(listener Listener) *Registry {
+	return &Registry{
+		listener: listener,
+		breakers: make(map[string]*Breaker),
+	}
+}
+
+// Get returns breaker of the backend, settings of the first caller are used
+func (r *Registry) Get(name string, bs config.BreakerSettings) *Breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if b, found := r.breakers[name]; found {
+		return b
+	}
+
+	var b = New(name, bs, r.listener)
+	r.breakers[name] = b
+
+	return b
+}
@@ -0,0 +1,129 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"trading_bot/config"
+	"trading_bot/internal/common"
+)
+
+var errUnavailable = common.NewRequestError(common.ErrServiceUnavailable, "Test", "test", 503, "")
+
+type transition struct {
+	from State
+	to   State
+}
+
+func testBreaker(t *testing.T, openTimeout time.Duration) (*Breaker, *[]transition) {
+	t.Helper()
+
+	var transitions = &[]transition{}
+	var b = New("Test", config.BreakerSettings{FailureThreshold: 3}, func(name string, from State, to State, err error) {
+		*transitions = append(*transitions, transition{from, to})
+	})
+	b.openTimeout = openTimeout
+
+	return b, transitions
+}
+
+func call(b *Breaker, err error) error {
+	if allowErr := b.Allow("test"); allowErr != nil {
+		return allowErr
+	}
+	b.Done(err)
+	return err
+}
+
+func TestBreaker_OpensAfterFailures_Success(t *testing.T) {
+	t.Parallel()
+
+	var b, transitions = testBreaker(t, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		call(b, errUnavailable)
+	}
+
+	got := call(b, nil)
+
+	if !errors.Is(got, ErrOpen) || !common.IsRetryable(got) {
+		t.Errorf("got error %v, wanted retryable %v", got, ErrOpen)
+	}
+	if len(*transitions) != 1 || (*transitions)[0] != (transition{StateClosed, StateOpen}) {
+		t.Errorf("got transitions %v, wanted closed -> open", *transitions)
+	}
+}
+
+func TestBreaker_RequestErrorsIgnored_Success(t *testing.T) {
+	t.Parallel()
+
+	var b, _ = testBreaker(t, time.Minute)
+	var rejected = common.NewRequestError(common.ErrRejected, "Test", "test", 400, "")
+
+	for i := 0; i < 5; i++ {
+		call(b, rejected)
+	}
+
+	if got := b.State(); got != StateClosed {
+		t.Errorf("got %v, wanted %v", got, StateClosed)
+	}
+}
+
+func TestBreaker_HalfOpenProbe_Success(t *testing.T) {
+	t.Parallel()
+
+	var b, transitions = testBreaker(t, 20*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		call(b, errUnavailable)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if got := b.State(); got != StateHalfOpen {
+		t.Fatalf("got %v, wanted %v", got, StateHalfOpen)
+	}
+
+	// single probe at a time
+	if err := b.Allow("probe"); err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if err := b.Allow("second"); !errors.Is(err, ErrOpen) {
+		t.Errorf("got error %v, wanted %v", err, ErrOpen)
+	}
+	b.Done(nil)
+
+	want := []transition{{StateClosed, StateOpen}, {StateOpen, StateHalfOpen}, {StateHalfOpen, StateClosed}}
+	if len(*transitions) != len(want) {
+		t.Fatalf("got transitions %v, wanted %v", *transitions, want)
+	}
+	for i := range want {
+		if (*transitions)[i] != want[i] {
+			t.Errorf("got transitions %v, wanted %v", *transitions, want)
+		}
+	}
+}
+
+func TestBreaker_HalfOpenProbeFailed_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var b, _ = testBreaker(t, 20*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		call(b, errUnavailable)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	call(b, errUnavailable)
+
+	if got := b.State(); got != StateOpen {
+		t.Errorf("got %v, wanted %v", got, StateOpen)
+	}
+}
+
+func TestRegistry_SharedByName_Success(t *testing.T) {
+	t.Parallel()
+
+	var r = NewRegistry(nil)
+
+	if r.Get("JetCrypto a", config.BreakerSettings{}) != r.Get("JetCrypto a", config.BreakerSettings{}) {
+		t.Errorf("got different breakers, wanted shared")
+	}
+}
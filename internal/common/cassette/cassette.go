@@ -0,0 +1,161 @@
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Redacted replaces secrets and volatile values in the cassette
+const Redacted = "REDACTED"
+
+// Interaction is the single request/response pair of the cassette
+type Interaction struct {
+	Method      string            `json:"method"`
+	Url         string            `json:"url"`
+	ContentType string            `json:"contentType,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	StatusCode  int               `json:"statusCode"`
+	Response    string            `json:"response"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// Cassette keeps interactions in the recorded order
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Load reads cassette file
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette : %w", err)
+	}
+
+	var c = &Cassette{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("cassette %v : %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Save writes cassette file, directories are created
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cassette : %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("cassette : %w", err)
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Redactor hides secret headers and volatile parameters (nonce, timestamp, signature).
+// Volatile parameters are also ignored when requests are matched on replay.
+type Redactor struct {
+	Headers []string
+	Params  []string
+}
+
+// DefaultRedactor covers the headers and parameters of all venue adapters
+var DefaultRedactor = Redactor{
+	Headers: []string{"Key", "Sign", "X-MBX-APIKEY", "signature", "signTimestamp", "signatureMethod", "Authorization"},
+	Params:  []string{"nonce", "timestamp", "signature", "signTimestamp", "uuId"},
+}
+
+// RedactHeaders returns copy of the headers with the secret values redacted
+func (r Redactor) RedactHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	var res = make(map[string]string, len(headers))
+	for key, val := range headers {
+		if r.isSecretHeader(key) {
+			val = Redacted
+		}
+		res[key] = val
+	}
+
+	return res
+}
+
+// RedactUrl redacts volatile query parameters, the order of parameters is normalized
+func (r Redactor) RedactUrl(u *url.URL) string {
+	var copied = *u
+	copied.RawQuery = r.redactQuery(u.RawQuery)
+
+	return copied.String()
+}
+
+// RedactBody redacts volatile parameters of the form or the top level of the JSON object
+func (r Redactor) RedactBody(body string) string {
+	if len(body) == 0 {
+		return body
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(body), "{") {
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &obj); err != nil {
+			return body
+		}
+		for key := range obj {
+			if r.isVolatileParam(key) {
+				obj[key] = Redacted
+			}
+		}
+		data, _ := json.Marshal(obj)
+		return string(data)
+	}
+
+	return r.redactQuery(body)
+}
+
+func (r Redactor) redactQuery(rawQuery string) string {
+	if len(rawQuery) == 0 {
+		return rawQuery
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+
+	for key := range values {
+		if r.isVolatileParam(key) {
+			values.Set(key, Redacted)
+		}
+	}
+
+	return values.Encode()
+}
+
+// matchKey identifies request on replay, host is ignored so the adapters can be pointed to any base url
+func (r Redactor) matchKey(method string, u *url.URL, body string) string {
+	return method + " " + u.Path + "?" + r.redactQuery(u.RawQuery) + " " + r.RedactBody(body)
+}
+
+func (r Redactor) isSecretHeader(name string) bool {
+	return containsFold(r.Headers, name)
+}
+
+func (r Redactor) isVolatileParam(name string) bool {
+	return containsFold(r.Params, name)
+}
+
+func containsFold(items []string, name string) bool {
+	for _, item := range items {
+		if strings.EqualFold(item, name) {
+			return true
+		}
+	}
+	return false
+}
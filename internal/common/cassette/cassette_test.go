@@ -0,0 +1,90 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/mocks"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func testLogger(t *testing.T) *mocks.ILogger {
+	t.Helper()
+
+	var l = &mocks.ILogger{}
+	var args = []interface{}{mock.Anything}
+	for i := 0; i < 3; i++ {
+		l.On("Debug", args...).Return()
+		l.On("Error", args...).Return()
+		args = append(args, mock.Anything)
+	}
+
+	return l
+}
+
+func TestRecorder_RedactedReplay_Success(t *testing.T) {
+	t.Parallel()
+
+	var calls = 0
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"call":` + strconv.Itoa(calls) + `}`))
+	}))
+	defer server.Close()
+
+	var recorder = NewRecorder(helpermethods.New(testLogger(t), nil), DefaultRedactor)
+	var u, _ = url.Parse(server.URL + "/tradingApi?command=returnBalances&nonce=1001")
+	var headers = map[string]string{"Key": "secret-key", "Sign": "abcdef"}
+
+	recorder.HttpPost(context.Background(), u, []byte("command=returnBalances&nonce=1001"), "", headers)
+	recorder.HttpPost(context.Background(), u, []byte("command=returnBalances&nonce=1002"), "", headers)
+
+	var path = filepath.Join(t.TempDir(), "cassette.json")
+	if err := recorder.Cassette().Save(path); err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	var item = c.Interactions[0]
+	if strings.Contains(item.Url, "1001") || strings.Contains(item.Body, "1001") || item.Headers["Key"] != Redacted || item.Headers["Sign"] != Redacted {
+		t.Errorf("got %+v, wanted nonce, key and sign redacted", item)
+	}
+
+	// other nonces and host are replayed in the recorded order
+	var player = NewPlayer(c, DefaultRedactor)
+	var replayUrl, _ = url.Parse("https://poloniex.test/tradingApi?command=returnBalances&nonce=2001")
+	for _, want := range []string{`{"call":1}`, `{"call":2}`} {
+		got, status, err := player.HttpPost(context.Background(), replayUrl, []byte("command=returnBalances&nonce=2001"), "", headers)
+		if err != nil || status != 200 || got != want {
+			t.Errorf("got %v %v %v, wanted %v 200 nil", got, status, err, want)
+		}
+	}
+
+	if _, _, got := player.HttpPost(context.Background(), replayUrl, []byte("command=returnBalances&nonce=2002"), "", headers); !errors.Is(got, ErrNoInteraction) {
+		t.Errorf("got error %v, wanted %v", got, ErrNoInteraction)
+	}
+	if got := len(player.Requests()); got != 3 || player.Requests()[0].Headers["Key"] != "secret-key" {
+		t.Errorf("got %v requests, wanted 3 unredacted requests", got)
+	}
+}
+
+func TestRedactor_JsonBody_Success(t *testing.T) {
+	t.Parallel()
+
+	var got = DefaultRedactor.RedactBody(`{"symbol":"BTC_USDT","timestamp":1672531200000}`)
+
+	if want := `{"symbol":"BTC_USDT","timestamp":"REDACTED"}`; got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
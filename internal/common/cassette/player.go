@@ -0,0 +1,256 @@
+package cassette
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"trading_bot/internal/common"
+)
+
+// ErrNoInteraction is returned on replay when the request was not recorded
+var ErrNoInteraction = errors.New("no recorded interaction")
+
+// RecordEnv enables recording of the cassettes against the real venues in Use
+const RecordEnv = "RECORD_CASSETTES"
+
+// Transport is the helper methods of the cassette, sent requests are kept unredacted for the signing checks
+type Transport interface {
+	common.IHelperMethods
+	Requests() []*Interaction
+}
+
+// requests keeps the requests sent by the adapter
+type requests struct {
+	mu   sync.Mutex
+	sent []*Interaction
+}
+
+func (r *requests) add(method string, u *url.URL, body []byte, contentType string, headers map[string]string) {
+	var copied = make(map[string]string, len(headers))
+	for key, val := range headers {
+		copied[key] = val
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sent = append(r.sent, &Interaction{
+		Method:      method,
+		Url:         u.String(),
+		ContentType: contentType,
+		Headers:     copied,
+		Body:        string(body),
+	})
+}
+
+// Requests returns the sent requests
+func (r *requests) Requests() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*Interaction(nil), r.sent...)
+}
+
+// Player replays the cassette, requests are matched by method, path, query and body without volatile parameters.
+// Equal requests get the recorded responses in order.
+type Player struct {
+	requests
+	mu       sync.Mutex
+	cassette *Cassette
+	redactor Redactor
+	used     []bool
+}
+
+var _ Transport = (*Player)(nil)
+
+func NewPlayer(c *Cassette, r Redactor) *Player {
+	return &Player{
+		cassette: c,
+		redactor: r,
+		used:     make([]bool, len(c.Interactions)),
+	}
+}
+
+func (p *Player) HttpGet(ctx context.Context, u *url.URL, contentType string, webHeaders map[string]string) (string, int, error) {
+	return p.replay(ctx, "GET", u, nil, contentType, webHeaders)
+}
+
+func (p *Player) HttpPost(ctx context.Context, u *url.URL, data []byte, contentType string, webHeaders map[string]string) (string, int, error) {
+	return p.replay(ctx, "POST", u, data, contentType, webHeaders)
+}
+
+func (p *Player) HmacSha512(keyBytes []byte, messageBytes []byte) ([]byte, error) {
+	h := hmac.New(sha512.New, keyBytes)
+	h.Write(messageBytes)
+	return h.Sum(nil), nil
+}
+
+func (p *Player) HmacSha256(keyBytes []byte, messageBytes []byte) ([]byte, error) {
+	h := hmac.New(sha256.New, keyBytes)
+	h.Write(messageBytes)
+	return h.Sum(nil), nil
+}
+
+// Unused returns recorded interactions which were not replayed
+func (p *Player) Unused() []*Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var res []*Interaction
+	for i, item := range p.cassette.Interactions {
+		if !p.used[i] {
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
+
+func (p *Player) replay(ctx context.Context, method string, u *url.URL, body []byte, contentType string, webHeaders map[string]string) (string, int, error) {
+	p.add(method, u, body, contentType, webHeaders)
+
+	if err := ctx.Err(); err != nil {
+		return "", 500, err
+	}
+
+	var key = p.redactor.matchKey(method, u, string(body))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, item := range p.cassette.Interactions {
+		if p.used[i] {
+			continue
+		}
+
+		recordedUrl, err := url.Parse(item.Url)
+		if err != nil || p.redactor.matchKey(item.Method, recordedUrl, item.Body) != key {
+			continue
+		}
+
+		p.used[i] = true
+		if len(item.Error) > 0 {
+			return item.Response, item.StatusCode, errors.New(item.Error)
+		}
+		return item.Response, item.StatusCode, nil
+	}
+
+	return "", 500, fmt.Errorf("cassette : %w for %v", ErrNoInteraction, key)
+}
+
+// Recorder sends requests with the live helper methods and records redacted interactions
+type Recorder struct {
+	requests
+	mu       sync.Mutex
+	live     common.IHelperMethods
+	redactor Redactor
+	cassette *Cassette
+}
+
+var _ Transport = (*Recorder)(nil)
+
+func NewRecorder(live common.IHelperMethods, r Redactor) *Recorder {
+	return &Recorder{
+		live:     live,
+		redactor: r,
+		cassette: &Cassette{},
+	}
+}
+
+func (r *Recorder) HttpGet(ctx context.Context, u *url.URL, contentType string, webHeaders map[string]string) (string, int, error) {
+	r.add("GET", u, nil, contentType, webHeaders)
+	var response, statusCode, err = r.live.HttpGet(ctx, u, contentType, webHeaders)
+	r.record("GET", u, nil, contentType, webHeaders, response, statusCode, err)
+
+	return response, statusCode, err
+}
+
+func (r *Recorder) HttpPost(ctx context.Context, u *url.URL, data []byte, contentType string, webHeaders map[string]string) (string, int, error) {
+	r.add("POST", u, data, contentType, webHeaders)
+	var response, statusCode, err = r.live.HttpPost(ctx, u, data, contentType, webHeaders)
+	r.record("POST", u, data, contentType, webHeaders, response, statusCode, err)
+
+	return response, statusCode, err
+}
+
+func (r *Recorder) HmacSha512(keyBytes []byte, messageBytes []byte) ([]byte, error) {
+	return r.live.HmacSha512(keyBytes, messageBytes)
+}
+
+func (r *Recorder) HmacSha256(keyBytes []byte, messageBytes []byte) ([]byte, error) {
+	return r.live.HmacSha256(keyBytes, messageBytes)
+}
+
+// Cassette returns the recorded interactions
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return &Cassette{Interactions: append([]*Interaction(nil), r.cassette.Interactions...)}
+}
+
+func (r *Recorder) record(method string, u *url.URL, body []byte, contentType string, webHeaders map[string]string, response string, statusCode int, err error) {
+	var item = &Interaction{
+		Method:      method,
+		Url:         r.redactor.RedactUrl(u),
+		ContentType: contentType,
+		Headers:     r.redactor.RedactHeaders(webHeaders),
+		Body:        r.redactor.RedactBody(string(body)),
+		StatusCode:  statusCode,
+		Response:    response,
+	}
+	if err != nil {
+		item.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, item)
+}
+
+// Use returns transport of the test: the cassette at path is replayed and all its interactions must be used.
+// When RECORD_CASSETTES is set the requests are sent with live helper methods and the cassette is rewritten after the test.
+func Use(tb testing.TB, path string, live func() common.IHelperMethods) Transport {
+	tb.Helper()
+
+	if len(os.Getenv(RecordEnv)) > 0 {
+		var recorder = NewRecorder(live(), DefaultRedactor)
+		tb.Cleanup(func() {
+			if err := recorder.Cassette().Save(path); err != nil {
+				tb.Errorf("cassette : can't save %v : %v", path, err)
+			}
+		})
+		return recorder
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		tb.Fatalf("cassette : %v", err)
+	}
+
+	var player = NewPlayer(c, DefaultRedactor)
+	tb.Cleanup(func() {
+		for _, item := range player.Unused() {
+			tb.Errorf("cassette %v : interaction is not used : %v %v", path, item.Method, item.Url)
+		}
+	})
+
+	return player
+}
+
+// Setting returns the environment value when cassettes are recorded, otherwise the test value.
+// Tests use it for the keys so the replayed requests are signed with known secrets.
+func Setting(env string, testValue string) string {
+	if len(os.Getenv(RecordEnv)) > 0 {
+		return os.Getenv(env)
+	}
+	return testValue
+}
@@ -0,0 +1,38 @@
+// Package clientid derives deterministic client order ids of the hedge orders, so the hedge can be found
+// in the trading system when the outcome of the order request is unknown.
+package clientid
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/gofrs/uuid"
+)
+
+// Hedge returns the client order id of the hedge of the internal order fills, fillSequence is the number of the fills
+// covered by the hedge. The id fits Binance limit of 36 characters.
+func Hedge(internalOrderId uuid.UUID, fillSequence int) string {
+	var sum = sha256.Sum256([]byte(fmt.Sprintf("%v:%v", internalOrderId, fillSequence)))
+	return "h" + hex.EncodeToString(sum[:10])
+}
+
+// Attempt returns the id of the hedge order placed at the price step (0 based). Fill-or-kill orders killed at the price
+// are placed again at the next price, every price step gets its own id. Empty id stays empty.
+func Attempt(clientOrderId string, priceStep int) string {
+	if len(clientOrderId) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v-%v", clientOrderId, priceStep)
+}
+
+// Numeric returns the positive 63 bit number of the id for the venues accepting only numeric client order ids
+func Numeric(clientOrderId string) string {
+	if len(clientOrderId) == 0 {
+		return ""
+	}
+	var sum = sha256.Sum256([]byte(clientOrderId))
+	return strconv.FormatUint(binary.BigEndian.Uint64(sum[:8])>>1, 10)
+}
@@ -0,0 +1,49 @@
+package clientid
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestHedge_Success(t *testing.T) {
+	t.Parallel()
+
+	var orderId = uuid.Must(uuid.FromString("6ba7b810-9dad-11d1-80b4-00c04fd430c8"))
+	var got = Hedge(orderId, 2)
+
+	if got != Hedge(orderId, 2) {
+		t.Errorf("got different ids of the same fills, wanted %v", got)
+	}
+	if len(got) != 21 || got[0] != 'h' {
+		t.Errorf("got %v, wanted 21 characters starting with h", got)
+	}
+	if other := Hedge(orderId, 3); other == got {
+		t.Errorf("got %v for the next fill, wanted a new id", other)
+	}
+}
+
+func TestAttempt_Success(t *testing.T) {
+	t.Parallel()
+
+	if got := Attempt("h1", 0); got != "h1-0" {
+		t.Errorf("got %v, wanted h1-0", got)
+	}
+	if got := Attempt("", 3); got != "" {
+		t.Errorf("got %v, wanted empty id", got)
+	}
+}
+
+func TestNumeric_Success(t *testing.T) {
+	t.Parallel()
+
+	var got = Numeric("h1-0")
+
+	if n, err := strconv.ParseInt(got, 10, 64); err != nil || n < 0 {
+		t.Errorf("got %v, wanted positive int64", got)
+	}
+	if got != Numeric("h1-0") || got == Numeric("h1-1") {
+		t.Errorf("got %v, wanted the same number for the same id only", got)
+	}
+}
@@ -0,0 +1,123 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Request error kinds, use errors.Is to check the kind of a returned error
+var (
+	ErrRateLimited        = errors.New("rate limited")
+	ErrInsufficientFunds  = errors.New("insufficient funds")
+	ErrMarketFrozen       = errors.New("market frozen")
+	ErrAuthFailure        = errors.New("auth failure")
+	ErrNotFound           = errors.New("not found")
+	ErrTransientNetwork   = errors.New("transient network error")
+	ErrServiceUnavailable = errors.New("service unavailable")
+	ErrOrderNotFilled     = errors.New("order not filled")
+	ErrRejected           = errors.New("request rejected")
+	ErrInvalidResponse    = errors.New("invalid response")
+	ErrNotSupported       = errors.New("not supported")
+)
+
+// RequestError describes failed request to the internal or trading system
+type RequestError struct {
+	Kind       error
+	System     string
+	Operation  string
+	StatusCode int
+	Message    string
+	Err        error
+}
+
+func NewRequestError(kind error, system string, operation string, statusCode int, message string) *RequestError {
+	return &RequestError{
+		Kind:       kind,
+		System:     system,
+		Operation:  operation,
+		StatusCode: statusCode,
+		Message:    message,
+	}
+}
+
+// WrapRequestError classifies transport level error (or http status code when there is no error)
+func WrapRequestError(err error, system string, operation string, statusCode int, message string) *RequestError {
+	var kind = KindFromStatus(statusCode)
+	if err != nil {
+		kind = ErrTransientNetwork
+	}
+
+	return &RequestError{
+		Kind:       kind,
+		System:     system,
+		Operation:  operation,
+		StatusCode: statusCode,
+		Message:    message,
+		Err:        err,
+	}
+}
+
+func (e *RequestError) Error() string {
+	var res = fmt.Sprintf("%v : %v : %v", e.System, e.Operation, e.Kind)
+	if e.StatusCode != 0 {
+		res += fmt.Sprintf(" (status %v)", e.StatusCode)
+	}
+	if len(e.Message) > 0 {
+		res += " : " + e.Message
+	}
+	if e.Err != nil {
+		res += " : " + e.Err.Error()
+	}
+	return res
+}
+
+func (e *RequestError) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// KindFromStatus maps http status code to the request error kind
+func KindFromStatus(statusCode int) error {
+	switch {
+	case statusCode == 401 || statusCode == 403:
+		return ErrAuthFailure
+	case statusCode == 404:
+		return ErrNotFound
+	case statusCode == 418 || statusCode == 429:
+		return ErrRateLimited
+	case statusCode == 408:
+		return ErrTransientNetwork
+	case statusCode >= 500:
+		return ErrServiceUnavailable
+	case statusCode >= 400:
+		return ErrRejected
+	}
+	return ErrInvalidResponse
+}
+
+// IsRetryable reports errors that may disappear on the next attempt
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return errors.Is(err, ErrRateLimited) ||
+		errors.Is(err, ErrTransientNetwork) ||
+		errors.Is(err, ErrServiceUnavailable) ||
+		errors.Is(err, ErrMarketFrozen)
+}
+
+// IsAmbiguous reports errors after which the request may be executed by the server: the response was lost,
+// the server failed after accepting the request or the response can't be read
+func IsAmbiguous(err error) bool {
+	return errors.Is(err, ErrTransientNetwork) ||
+		errors.Is(err, ErrServiceUnavailable) ||
+		errors.Is(err, ErrInvalidResponse)
+}
+
+// IsFatal reports errors that can't be fixed without operator actions
+func IsFatal(err error) bool {
+	return errors.Is(err, ErrAuthFailure)
+}
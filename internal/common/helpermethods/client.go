@@ -0,0 +1,152 @@
+package helpermethods
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+	"trading_bot/config"
+)
+
+const (
+	defaultDialTimeout           = 10 * time.Second
+	defaultTLSHandshakeTimeout   = 10 * time.Second
+	defaultResponseHeaderTimeout = 15 * time.Second
+	defaultRequestTimeout        = 30 * time.Second
+	defaultIdleConnTimeout       = 90 * time.Second
+	defaultMaxIdleConns          = 100
+	defaultMaxIdleConnsPerHost   = 10
+	defaultMaxBodySize           = 10 << 20
+	defaultUserAgent             = "trading_bot"
+)
+
+// ErrBodyTooLarge is returned when the response body exceeds the configured limit
+var ErrBodyTooLarge = errors.New("response body is too large")
+
+// Client is the http client shared by all requests of the application
+type Client struct {
+	httpClient  *http.Client
+	userAgent   string
+	maxBodySize int64
+	metrics     *Metrics
+}
+
+// NewClient creates client with the keep-alive transport, zero settings are replaced by defaults
+func NewClient(hs config.Http) (*Client, error) {
+	var dialer = &net.Dialer{
+		Timeout:   msOrDefault(hs.DialTimeoutMs, defaultDialTimeout),
+		KeepAlive: 30 * time.Second,
+	}
+
+	var proxy = http.ProxyFromEnvironment
+	if len(hs.ProxyUrl) > 0 {
+		proxyUrl, err := url.Parse(hs.ProxyUrl)
+		if err != nil {
+			return nil, fmt.Errorf("http proxy url : %w", err)
+		}
+		proxy = http.ProxyURL(proxyUrl)
+	}
+
+	tlsConfig, err := newTLSConfig(hs)
+	if err != nil {
+		return nil, err
+	}
+
+	var transport = &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   msOrDefault(hs.TLSHandshakeTimeoutMs, defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: msOrDefault(hs.ResponseHeaderTimeoutMs, defaultResponseHeaderTimeout),
+		IdleConnTimeout:       msOrDefault(hs.IdleConnTimeoutMs, defaultIdleConnTimeout),
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConns:          intOrDefault(hs.MaxIdleConns, defaultMaxIdleConns),
+		MaxIdleConnsPerHost:   intOrDefault(hs.MaxIdleConnsPerHost, defaultMaxIdleConnsPerHost),
+		MaxConnsPerHost:       hs.MaxConnsPerHost,
+		ForceAttemptHTTP2:     true,
+	}
+
+	var maxBodySize = hs.MaxBodyBytes
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+
+	var userAgent = hs.UserAgent
+	if len(userAgent) == 0 {
+		userAgent = defaultUserAgent
+	}
+
+	return &Client{
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   msOrDefault(hs.RequestTimeoutMs, defaultRequestTimeout),
+		},
+		userAgent:   userAgent,
+		maxBodySize: maxBodySize,
+		metrics:     NewMetrics(),
+	}, nil
+}
+
+// DefaultClient returns client with default settings
+func DefaultClient() *Client {
+	var c, _ = NewClient(config.Http{})
+	return c
+}
+
+// Metrics returns timings of the requests sent by the client
+func (c *Client) Metrics() *Metrics {
+	return c.metrics
+}
+
+// CloseIdleConnections closes kept-alive connections of the transport
+func (c *Client) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
+func newTLSConfig(hs config.Http) (*tls.Config, error) {
+	var tlsConfig = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: hs.TLSInsecureSkipVerify,
+	}
+
+	switch hs.TLSMinVersion {
+	case "", "1.2":
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported tls min version : %v", hs.TLSMinVersion)
+	}
+
+	if len(hs.TLSCAFile) > 0 {
+		pem, err := os.ReadFile(hs.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls ca file : %w", err)
+		}
+		var pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls ca file : no certificates in %v", hs.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+func msOrDefault(ms int, def time.Duration) time.Duration {
+	if ms <= 0 {
+		return def
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+func intOrDefault(v int, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
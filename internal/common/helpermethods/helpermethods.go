@@ -4,38 +4,57 @@ import (
 	"bytes"
 	"context"
 	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/sha512"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"trading_bot/pkg/logger"
 )
 
 type HelperMethods struct {
 	logger logger.ILogger
+	client *Client
 }
 
-func New(l logger.ILogger) *HelperMethods {
+// New creates helper methods sending requests with the shared client, nil client means default settings
+func New(l logger.ILogger, c *Client) *HelperMethods {
+	if c == nil {
+		c = DefaultClient()
+	}
+
 	return &HelperMethods{
 		logger: l,
+		client: c,
 	}
 }
 
 func (hm HelperMethods) HttpGet(ctx context.Context, url *url.URL, contentType string, webHeaders map[string]string) (string, int, error) {
+	return hm.do(ctx, http.MethodGet, url, nil, contentType, webHeaders)
+}
+
+func (hm HelperMethods) HttpPost(ctx context.Context, url *url.URL, data []byte, contentType string, webHeaders map[string]string) (string, int, error) {
+	return hm.do(ctx, http.MethodPost, url, bytes.NewBuffer(data), contentType, webHeaders)
+}
+
+func (hm HelperMethods) do(ctx context.Context, method string, url *url.URL, body io.Reader, contentType string, webHeaders map[string]string) (string, int, error) {
 	if len(contentType) == 0 {
 		contentType = "application/x-www-form-urlencoded"
 	}
 
-	client := &http.Client{}
-	req, err := http.NewRequest(http.MethodGet, url.String(), nil)
+	var tr = newTracer(method, url.Host, url.Path, endpointOf(ctx))
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, tr.clientTrace()), method, url.String(), body)
 	if err != nil {
+		hm.record(tr.finish(0, true))
 		hm.logger.Error(err)
 		return "", 500, err
 	}
 
-	req = req.WithContext(ctx)
-
 	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", hm.client.userAgent)
 
 	// appending headers
 	if len(webHeaders) > 0 {
@@ -44,59 +63,35 @@ func (hm HelperMethods) HttpGet(ctx context.Context, url *url.URL, contentType s
 		}
 	}
 
-	resp, err := client.Do(req)
+	resp, err := hm.client.httpClient.Do(req)
 	if err != nil {
+		hm.record(tr.finish(0, true))
 		hm.logger.Error(err)
 		return "", 500, err
 	}
 
 	defer resp.Body.Close()
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		hm.logger.Error(err)
-		return "", 500, err
-	}
 
-	return string(responseBody), resp.StatusCode, nil
-}
-
-func (hm HelperMethods) HttpPost(ctx context.Context, url *url.URL, data []byte, contentType string, webHeaders map[string]string) (string, int, error) {
-	if len(contentType) == 0 {
-		contentType = "application/x-www-form-urlencoded"
+	// one byte over the limit tells that the body is truncated
+	responseBody, err := io.ReadAll(io.LimitReader(resp.Body, hm.client.maxBodySize+1))
+	if err == nil && int64(len(responseBody)) > hm.client.maxBodySize {
+		err = fmt.Errorf("%w : %v %v limit %v bytes", ErrBodyTooLarge, method, url.Path, hm.client.maxBodySize)
 	}
-
-	client := &http.Client{}
-	req, err := http.NewRequest(http.MethodPost, url.String(), bytes.NewBuffer(data))
 	if err != nil {
+		hm.record(tr.finish(resp.StatusCode, true))
 		hm.logger.Error(err)
 		return "", 500, err
 	}
 
-	req = req.WithContext(ctx)
-
-	req.Header.Set("Content-Type", contentType)
+	hm.record(tr.finish(resp.StatusCode, false))
 
-	// appending headers
-	if len(webHeaders) > 0 {
-		for key, val := range webHeaders {
-			req.Header.Set(key, val)
-		}
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		hm.logger.Error(err)
-		return "", 500, err
-	}
+	return string(responseBody), resp.StatusCode, nil
+}
 
-	defer resp.Body.Close()
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		hm.logger.Error(err)
-		return "", 500, err
-	}
+func (hm HelperMethods) record(timing Timing) {
+	hm.client.metrics.Record(timing)
 
-	return string(responseBody), resp.StatusCode, nil
+	hm.logger.Debug("HTTP : %v", timing)
 }
 
 func (hm HelperMethods) HmacSha512(keyBytes []byte, messageBytes []byte) ([]byte, error) {
@@ -108,3 +103,13 @@ func (hm HelperMethods) HmacSha512(keyBytes []byte, messageBytes []byte) ([]byte
 
 	return h.Sum(nil), nil
 }
+
+func (hm HelperMethods) HmacSha256(keyBytes []byte, messageBytes []byte) ([]byte, error) {
+	// Create a new HMAC by defining the hash type and the key (as byte array)
+	h := hmac.New(sha256.New, keyBytes)
+
+	// Write Data to it
+	h.Write(messageBytes)
+
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,133 @@
+package helpermethods
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"trading_bot/config"
+	"trading_bot/mocks"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func testLogger(t *testing.T) *mocks.ILogger {
+	t.Helper()
+
+	var l = &mocks.ILogger{}
+	var args = []interface{}{mock.Anything}
+	for i := 0; i < 3; i++ {
+		l.On("Debug", args...).Return()
+		l.On("Error", args...).Return()
+		args = append(args, mock.Anything)
+	}
+
+	return l
+}
+
+func TestHttpGet_KeepAliveAndUserAgent_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.UserAgent()))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.Http{UserAgent: "bot-test"})
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	var hm = New(testLogger(t), client)
+	var u, _ = url.Parse(server.URL + "/ping")
+
+	for i := 0; i < 3; i++ {
+		body, status, err := hm.HttpGet(context.Background(), u, "", nil)
+		if err != nil || status != http.StatusOK || body != "bot-test" {
+			t.Fatalf("got %v %v %v, wanted bot-test 200 nil", body, status, err)
+		}
+	}
+
+	var stats = client.Metrics().Snapshot()
+	if len(stats) != 1 {
+		t.Fatalf("got %v endpoints, wanted 1", len(stats))
+	}
+	if stats[0].Requests != 3 || stats[0].ReusedConns != 2 {
+		t.Errorf("got %v requests %v reused, wanted 3 requests 2 reused", stats[0].Requests, stats[0].ReusedConns)
+	}
+}
+
+func TestHttpGet_NamedEndpoint_Aggregated(t *testing.T) {
+	t.Parallel()
+
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(config.Http{})
+	var hm = New(testLogger(t), client)
+
+	// ids of the path don't create new stats
+	for _, path := range []string{"/orders/1", "/orders/2", "/orders/cid:3"} {
+		var u, _ = url.Parse(server.URL + path)
+		if _, _, err := hm.HttpGet(WithEndpoint(context.Background(), "/orders/{id}"), u, "", nil); err != nil {
+			t.Fatalf("got error %v, wanted nil", err)
+		}
+	}
+	var u, _ = url.Parse(server.URL + "/markets/BTC_USDC")
+	if _, _, err := hm.HttpGet(WithDefaultEndpoint(WithEndpoint(context.Background(), "/markets/{symbol}"), "/markets/BTC_USDC"), u, "", nil); err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	var stats = client.Metrics().Snapshot()
+	if len(stats) != 2 {
+		t.Fatalf("got stats %+v, wanted 2 endpoints", stats)
+	}
+	if !strings.HasSuffix(stats[0].Endpoint, " /markets/{symbol}") || stats[0].Requests != 1 {
+		t.Errorf("got %v, wanted 1 request of /markets/{symbol}", stats[0])
+	}
+	if !strings.HasSuffix(stats[1].Endpoint, " /orders/{id}") || stats[1].Requests != 3 {
+		t.Errorf("got %v, wanted 3 requests of /orders/{id}", stats[1])
+	}
+}
+
+func TestHttpPost_BodyTooLarge_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(config.Http{MaxBodyBytes: 10})
+	var hm = New(testLogger(t), client)
+	var u, _ = url.Parse(server.URL + "/big")
+
+	_, _, got := hm.HttpPost(context.Background(), u, []byte("a=1"), "", nil)
+
+	if !errors.Is(got, ErrBodyTooLarge) {
+		t.Errorf("got error %v, wanted %v", got, ErrBodyTooLarge)
+	}
+	if stats := client.Metrics().Snapshot(); len(stats) != 1 || stats[0].Failures != 1 {
+		t.Errorf("got stats %+v, wanted 1 failure", stats)
+	}
+}
+
+func TestNewClient_InvalidSettings_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var tests = []config.Http{
+		{ProxyUrl: "://proxy"},
+		{TLSMinVersion: "1.0"},
+		{TLSCAFile: "/nonexistent/ca.pem"},
+	}
+
+	for _, hs := range tests {
+		if _, err := NewClient(hs); err == nil {
+			t.Errorf("%+v : got nil error, wanted error", hs)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package helpermethods
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"time"
+)
+
+type endpointKey struct{}
+
+// WithEndpoint names the endpoint of the requests sent with the context by its route, e.g. "/orders/{id}".
+// Timings are aggregated by the name, so ids and pairs of the path don't create new stats.
+func WithEndpoint(ctx context.Context, endpoint string) context.Context {
+	return context.WithValue(ctx, endpointKey{}, endpoint)
+}
+
+// WithDefaultEndpoint names the endpoint unless the caller named it already
+func WithDefaultEndpoint(ctx context.Context, endpoint string) context.Context {
+	if _, found := ctx.Value(endpointKey{}).(string); found {
+		return ctx
+	}
+	return WithEndpoint(ctx, endpoint)
+}
+
+// endpointOf returns the endpoint name of the context, empty when it's not named
+func endpointOf(ctx context.Context) string {
+	var endpoint, _ = ctx.Value(endpointKey{}).(string)
+	return endpoint
+}
+
+// Timing of the single request phases
+type Timing struct {
+	Method       string
+	Host         string
+	Path         string
+	Endpoint     string
+	StatusCode   int
+	Failed       bool
+	ReusedConn   bool
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	FirstByte    time.Duration
+	Total        time.Duration
+}
+
+func (t Timing) String() string {
+	return fmt.Sprintf("%v %v%v %v in %v (dns %v, connect %v, tls %v, first byte %v, reused %v)",
+		t.Method, t.Host, t.Path, t.StatusCode, t.Total, t.DNS, t.Connect, t.TLSHandshake, t.FirstByte, t.ReusedConn)
+}
+
+// tracer fills the phase timings, hooks of the dial may be called from the transport goroutines
+type tracer struct {
+	mu           sync.Mutex
+	started      time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timing       Timing
+}
+
+func newTracer(method string, host string, path string, endpoint string) *tracer {
+	return &tracer{
+		started: time.Now(),
+		timing: Timing{
+			Method:   method,
+			Host:     host,
+			Path:     path,
+			Endpoint: endpoint,
+		},
+	}
+}
+
+func (tr *tracer) update(f func(t *tracer)) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	f(tr)
+}
+
+func (tr *tracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			tr.update(func(t *tracer) { t.timing.ReusedConn = info.Reused })
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			tr.update(func(t *tracer) { t.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			tr.update(func(t *tracer) { t.timing.DNS = time.Since(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			tr.update(func(t *tracer) { t.connectStart = time.Now() })
+		},
+		ConnectDone: func(string, string, error) {
+			tr.update(func(t *tracer) { t.timing.Connect = time.Since(t.connectStart) })
+		},
+		TLSHandshakeStart: func() {
+			tr.update(func(t *tracer) { t.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tr.update(func(t *tracer) { t.timing.TLSHandshake = time.Since(t.tlsStart) })
+		},
+		GotFirstResponseByte: func() {
+			tr.update(func(t *tracer) { t.timing.FirstByte = time.Since(t.started) })
+		},
+	}
+}
+
+// finish returns timing of the request
+func (tr *tracer) finish(statusCode int, failed bool) Timing {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.timing.StatusCode = statusCode
+	tr.timing.Failed = failed
+	tr.timing.Total = time.Since(tr.started)
+
+	return tr.timing
+}
+
+// EndpointStats aggregates timings of the endpoint
+type EndpointStats struct {
+	Endpoint      string
+	Requests      int64
+	Failures      int64
+	ReusedConns   int64
+	TLSHandshakes int64
+	TotalTime     time.Duration
+	MaxTime       time.Duration
+}
+
+// AverageTime of the endpoint requests
+func (s EndpointStats) AverageTime() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.TotalTime / time.Duration(s.Requests)
+}
+
+func (s EndpointStats) String() string {
+	return fmt.Sprintf("%v : %v requests, %v failed, %v reused connections, %v tls handshakes, average %v, max %v",
+		s.Endpoint, s.Requests, s.Failures, s.ReusedConns, s.TLSHandshakes, s.AverageTime(), s.MaxTime)
+}
+
+// Metrics collects request timings by endpoint (METHOD host endpoint), requests without the endpoint name
+// are aggregated by the method and host
+type Metrics struct {
+	mu    sync.Mutex
+	stats map[string]*EndpointStats
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		stats: make(map[string]*EndpointStats),
+	}
+}
+
+// Record adds timing of the request
+func (m *Metrics) Record(t Timing) {
+	var endpoint = t.Method + " " + t.Host
+	if len(t.Endpoint) > 0 {
+		endpoint += " " + t.Endpoint
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var s, found = m.stats[endpoint]
+	if !found {
+		s = &EndpointStats{Endpoint: endpoint}
+		m.stats[endpoint] = s
+	}
+
+	s.Requests++
+	if t.Failed {
+		s.Failures++
+	}
+	if t.ReusedConn {
+		s.ReusedConns++
+	}
+	if t.TLSHandshake > 0 {
+		s.TLSHandshakes++
+	}
+	s.TotalTime += t.Total
+	if t.Total > s.MaxTime {
+		s.MaxTime = t.Total
+	}
+}
+
+// Snapshot returns copy of the stats sorted by endpoint
+func (m *Metrics) Snapshot() []EndpointStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var res = make([]EndpointStats, 0, len(m.stats))
+	for _, s := range m.stats {
+		res = append(res, *s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Endpoint < res[j].Endpoint })
+
+	return res
+}
@@ -3,6 +3,7 @@ package common
 import (
 	"context"
 	"net/url"
+	"time"
 	"trading_bot/internal/entity"
 
 	"github.com/gofrs/uuid"
@@ -15,27 +16,52 @@ type (
 		HttpGet(ctx context.Context, url *url.URL, contentType string, webHeaders map[string]string) (string, int, error)
 		HttpPost(ctx context.Context, url *url.URL, data []byte, contentType string, webHeaders map[string]string) (string, int, error)
 		HmacSha512(keyBytes []byte, messageBytes []byte) ([]byte, error)
+		HmacSha256(keyBytes []byte, messageBytes []byte) ([]byte, error)
 	}
 
 	ITradingSystemRequest interface {
-		GetTradingBalances(ctx context.Context) map[string]*entity.BalanceObject
-		GetPublicTradingOrders(ctx context.Context, tradingSystemPair string, usdcTradingLimit decimal.Decimal, cryptoTradingLimit decimal.Decimal, internalCryptoBalance decimal.Decimal, internalUsdcBalance decimal.Decimal, pairMinAmount decimal.Decimal) []*entity.TradingOrder
-		Buy(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string) bool
-		Sell(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string) bool
-		Withdraw(ctx context.Context, addr string, withdrawalAmount decimal.Decimal, currency string, tradingSystemWithdrawalNetwork string) bool
-		GetCryptoAddress(ctx context.Context, currency string, tradingSystemWithdrawalNetwork string) string
+		GetTradingBalances(ctx context.Context) (map[string]*entity.BalanceObject, error)
+		GetPublicTradingOrders(ctx context.Context, tradingSystemPair string, usdcTradingLimit decimal.Decimal, cryptoTradingLimit decimal.Decimal, internalCryptoBalance decimal.Decimal, internalUsdcBalance decimal.Decimal, pairMinAmount decimal.Decimal) ([]*entity.TradingOrder, error)
+		Buy(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string, clientOrderId string) (*entity.ExecutionReport, error)
+		Sell(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string, clientOrderId string) (*entity.ExecutionReport, error)
+		Withdraw(ctx context.Context, addr string, withdrawalAmount decimal.Decimal, currency string, tradingSystemWithdrawalNetwork string) (string, error)
+		GetCryptoAddress(ctx context.Context, currency string, tradingSystemWithdrawalNetwork string) (string, error)
+		GetFees(ctx context.Context, tradingSystemPair string) (*entity.FeeSchedule, error)
+		GetMarketInfo(ctx context.Context, tradingSystemPair string) (*entity.MarketInfo, error)
+	}
+
+	// ITradeHistoryRequest is implemented by the trading systems whose hedges can be reconciled with the account history
+	ITradeHistoryRequest interface {
+		GetOpenOrders(ctx context.Context, tradingSystemPair string) ([]*entity.VenueOrder, error)
+		GetTradeHistory(ctx context.Context, tradingSystemPair string, start time.Time, end time.Time) ([]*entity.VenueTrade, error)
+		GetOrderTrades(ctx context.Context, orderNumber string) ([]*entity.VenueTrade, error)
+	}
+
+	// IBalanceCacheRequest is implemented by the trading systems caching the balances
+	IBalanceCacheRequest interface {
+		InvalidateBalances()
+	}
+
+	// ITransferHistoryRequest is implemented by the systems reporting deposits and withdrawals of the account
+	ITransferHistoryRequest interface {
+		GetTransfers(ctx context.Context, currency string, start time.Time, end time.Time) ([]*entity.VenueTransfer, error)
 	}
 
 	IInternalRequest interface {
-		GetOrders(ctx context.Context, jetCryptoPair string) map[uuid.UUID]*entity.InternalOrder
-		GetOrder(ctx context.Context, orderId uuid.UUID, jetCryptoPair string) *entity.InternalOrder
-		IsPaymentCompleted(ctx context.Context, orderId uuid.UUID) bool
-		RemoveOrder(ctx context.Context, orderId uuid.UUID, currencyFrom string, currencyTo string) bool
-		AddOrder(ctx context.Context, currencyFrom string, currencyTo string, amount decimal.Decimal, price decimal.Decimal, isSellOrder bool) (bool, uuid.UUID)
-		GetCompleteOrder(ctx context.Context, orderId uuid.UUID, jetCryptoPair string) []*entity.InternalOrder
-		GetBalances(ctx context.Context) map[string]*entity.BalanceObject
-		GetTradingPairInfo(ctx context.Context, jetCryptoPair string) decimal.Decimal
-		Withdraw(ctx context.Context, addr string, destinationTag string, withdrawalAmount decimal.Decimal, currentCurrencyId string) null.Int
-		GetCryptoAddress(ctx context.Context, currency string) string
+		GetOrders(ctx context.Context, jetCryptoPair string) (map[uuid.UUID]*entity.InternalOrder, error)
+		GetOrder(ctx context.Context, orderId uuid.UUID, jetCryptoPair string) (*entity.InternalOrder, error)
+		IsPaymentCompleted(ctx context.Context, orderId uuid.UUID) (bool, error)
+		RemoveOrder(ctx context.Context, orderId uuid.UUID, currencyFrom string, currencyTo string) error
+		AddOrder(ctx context.Context, currencyFrom string, currencyTo string, amount decimal.Decimal, price decimal.Decimal, isSellOrder bool) (uuid.UUID, error)
+		ModifyOrder(ctx context.Context, orderId uuid.UUID, currencyFrom string, currencyTo string, amount decimal.Decimal, price decimal.Decimal) error
+		AddOrders(ctx context.Context, orders []*entity.NewInternalOrder) ([]*entity.OrderResult, error)
+		RemoveOrders(ctx context.Context, orderIds []uuid.UUID, currencyFrom string, currencyTo string) ([]*entity.OrderResult, error)
+		RemoveAllOrders(ctx context.Context, jetCryptoPair string) ([]*entity.OrderResult, error)
+		GetCompleteOrder(ctx context.Context, orderId uuid.UUID, jetCryptoPair string) ([]*entity.InternalOrder, error)
+		GetBalances(ctx context.Context) (map[string]*entity.BalanceObject, error)
+		GetBalance(ctx context.Context, currency string) (*entity.BalanceObject, error)
+		GetTradingPairInfo(ctx context.Context, jetCryptoPair string) (*entity.MarketInfo, error)
+		Withdraw(ctx context.Context, addr string, destinationTag string, withdrawalAmount decimal.Decimal, currentCurrencyId string, orderId uuid.UUID) (null.Int, error)
+		GetCryptoAddress(ctx context.Context, currency string) (string, error)
 	}
 )
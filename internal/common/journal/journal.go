@@ -0,0 +1,143 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"trading_bot/internal/entity"
+
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// HedgeRecord links the spent internal order with its hedge order in the trading system
+type HedgeRecord struct {
+	Time                time.Time               `json:"time"`
+	Currency            string                  `json:"currency"`
+	InternalPair        string                  `json:"internalPair"`
+	InternalOrderId     uuid.UUID               `json:"internalOrderId"`
+	InternalPrice       decimal.Decimal         `json:"internalPrice"`
+	InternalAmount      decimal.Decimal         `json:"internalAmount"`
+	InternalIsSellOrder bool                    `json:"internalIsSellOrder"`
+	ClientOrderId       string                  `json:"clientOrderId,omitempty"`
+	Report              *entity.ExecutionReport `json:"report"`
+	RealizedSpread      decimal.Decimal         `json:"realizedSpread"`
+	Error               string                  `json:"error,omitempty"`
+}
+
+// NewHedgeRecord creates record and calculates realized spread of the hedge in the quote currency.
+// currency is the crypto (base) currency, fee paid in it is converted with the average hedge price.
+func NewHedgeRecord(currency string, internalPair string, internalOrderId uuid.UUID, internalPrice decimal.Decimal, internalAmount decimal.Decimal, internalIsSellOrder bool, report *entity.ExecutionReport, hedgeErr error) *HedgeRecord {
+	var record = &HedgeRecord{
+		Time:                time.Now().UTC(),
+		Currency:            currency,
+		InternalPair:        internalPair,
+		InternalOrderId:     internalOrderId,
+		InternalPrice:       internalPrice,
+		InternalAmount:      internalAmount,
+		InternalIsSellOrder: internalIsSellOrder,
+		Report:              report,
+	}
+	if hedgeErr != nil {
+		record.Error = hedgeErr.Error()
+	}
+
+	if report == nil || !report.FilledAmount.IsPositive() {
+		return record
+	}
+
+	// internal sell is hedged by buy in the trading system and vice versa
+	var spread = internalPrice.Sub(report.AveragePrice)
+	if !internalIsSellOrder {
+		spread = spread.Neg()
+	}
+
+	var fee = report.Fee
+	if report.FeeCurrency == currency {
+		fee = fee.Mul(report.AveragePrice)
+	}
+
+	record.RealizedSpread = spread.Mul(report.FilledAmount).Sub(fee).RoundDown(8)
+
+	return record
+}
+
+// Journal appends hedge records to the JSON lines file
+type Journal struct {
+	mu   sync.Mutex
+	path string
+}
+
+func New(path string) (*Journal, error) {
+	if len(path) == 0 {
+		return nil, errors.New("journal path is empty")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	// check that file is writable on start instead of the first hedge
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Journal{path: path}, file.Close()
+}
+
+// Append writes the record as a single line
+func (j *Journal) Append(record *HedgeRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Records reads all records of the journal
+func (j *Journal) Records() ([]*HedgeRecord, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, err := os.Open(j.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records = make([]*HedgeRecord, 0)
+	var scanner = bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record = &HedgeRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
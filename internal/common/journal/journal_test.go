@@ -0,0 +1,87 @@
+package journal
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"trading_bot/internal/entity"
+
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func filledReport(isSellOrder bool, price float64, amount float64, fee float64, feeCurrency string) *entity.ExecutionReport {
+	var report = entity.NewExecutionReport("Test", "BTC_USDC", isSellOrder, decimal.NewFromFloat(amount))
+	report.AddTrade(&entity.ExecutionTrade{
+		TradeId:     "1",
+		Price:       decimal.NewFromFloat(price),
+		Amount:      decimal.NewFromFloat(amount),
+		Total:       decimal.NewFromFloat(price * amount),
+		Fee:         decimal.NewFromFloat(fee),
+		FeeCurrency: feeCurrency,
+	})
+	report.Status = entity.ExecutionStatusFilled
+	return report
+}
+
+func TestNewHedgeRecord_InternalSell_Success(t *testing.T) {
+	t.Parallel()
+
+	// internal sell at 101 hedged by buy at 100, fee in BTC
+	var report = filledReport(false, 100, 2, 0.01, "BTC")
+
+	got := NewHedgeRecord("BTC", "BTC,USDC", uuid.Must(uuid.NewV4()), decimal.NewFromInt(101), decimal.NewFromInt(2), true, report, nil).RealizedSpread
+	want := decimal.NewFromInt(1)
+
+	if !got.Equal(want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
+
+func TestNewHedgeRecord_InternalBuy_Success(t *testing.T) {
+	t.Parallel()
+
+	// internal buy at 99 hedged by sell at 100, fee in USDC
+	var report = filledReport(true, 100, 2, 0.5, "USDC")
+
+	got := NewHedgeRecord("BTC", "BTC,USDC", uuid.Must(uuid.NewV4()), decimal.NewFromInt(99), decimal.NewFromInt(2), false, report, nil).RealizedSpread
+	want := decimal.NewFromFloat(1.5)
+
+	if !got.Equal(want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
+
+func TestJournal_AppendRecords_Success(t *testing.T) {
+	t.Parallel()
+
+	j, err := New(filepath.Join(t.TempDir(), "data", "hedges.jsonl"))
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	var orderId = uuid.Must(uuid.NewV4())
+	var records = []*HedgeRecord{
+		NewHedgeRecord("BTC", "BTC,USDC", orderId, decimal.NewFromInt(101), decimal.NewFromInt(2), true, filledReport(false, 100, 2, 0.01, "BTC"), nil),
+		NewHedgeRecord("BTC", "BTC,USDC", orderId, decimal.NewFromInt(101), decimal.NewFromInt(2), true, nil, errors.New("rate limited")),
+	}
+	for _, record := range records {
+		if err := j.Append(record); err != nil {
+			t.Fatalf("got error %v, wanted nil", err)
+		}
+	}
+
+	got, err := j.Records()
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %v records, wanted 2", len(got))
+	}
+	if got[0].InternalOrderId != orderId || got[0].Report == nil || !got[0].Report.AveragePrice.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("got first record %v, wanted hedge of %v at 100", got[0], orderId)
+	}
+	if got[1].Report != nil || got[1].Error != "rate limited" {
+		t.Errorf("got second record %v, wanted failed hedge", got[1])
+	}
+}
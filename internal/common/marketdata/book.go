@@ -0,0 +1,135 @@
+package marketdata
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"trading_bot/internal/common/orderbook"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrSequenceGap is returned when an incremental update does not follow the last applied one
+var ErrSequenceGap = errors.New("order book sequence gap")
+
+// Book is a local L2 order book built from a snapshot and incremental updates
+type Book struct {
+	mu      sync.RWMutex
+	asks    map[string]orderbook.Level
+	bids    map[string]orderbook.Level
+	id      int64
+	synced  bool
+	updated time.Time
+}
+
+func NewBook() *Book {
+	return &Book{
+		asks: make(map[string]orderbook.Level),
+		bids: make(map[string]orderbook.Level),
+	}
+}
+
+// ApplySnapshot replaces the whole book
+func (b *Book) ApplySnapshot(asks []orderbook.Level, bids []orderbook.Level, id int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.asks = make(map[string]orderbook.Level, len(asks))
+	b.bids = make(map[string]orderbook.Level, len(bids))
+	applyLevels(b.asks, asks)
+	applyLevels(b.bids, bids)
+
+	b.id = id
+	b.synced = true
+	b.updated = time.Now().UTC()
+}
+
+// ApplyUpdate applies incremental changes, zero volume removes the price level.
+// lastId must be equal to the id of the previously applied snapshot or update.
+func (b *Book) ApplyUpdate(asks []orderbook.Level, bids []orderbook.Level, lastId int64, id int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.synced {
+		return fmt.Errorf("%w : book is not synced", ErrSequenceGap)
+	}
+
+	if lastId != b.id {
+		b.synced = false
+		return fmt.Errorf("%w : expected %v, got %v", ErrSequenceGap, b.id, lastId)
+	}
+
+	applyLevels(b.asks, asks)
+	applyLevels(b.bids, bids)
+
+	b.id = id
+	b.updated = time.Now().UTC()
+
+	return nil
+}
+
+// Invalidate marks the book as out of sync until the next snapshot
+func (b *Book) Invalidate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.synced = false
+}
+
+// Synced reports whether the book can be used
+func (b *Book) Synced() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.synced
+}
+
+// Updated returns time of the last applied snapshot or update
+func (b *Book) Updated() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.updated
+}
+
+// Levels returns up to depth best levels: asks ascending, bids descending by price
+func (b *Book) Levels(depth int) ([]orderbook.Level, []orderbook.Level) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var asks = sortedLevels(b.asks, func(a, c decimal.Decimal) bool { return a.LessThan(c) })
+	var bids = sortedLevels(b.bids, func(a, c decimal.Decimal) bool { return a.GreaterThan(c) })
+
+	if depth > 0 && len(asks) > depth {
+		asks = asks[:depth]
+	}
+	if depth > 0 && len(bids) > depth {
+		bids = bids[:depth]
+	}
+
+	return asks, bids
+}
+
+func applyLevels(side map[string]orderbook.Level, levels []orderbook.Level) {
+	for _, level := range levels {
+		// normalized key, "1.10" and "1.1" are the same price
+		var key = level.Price.String()
+		if level.Volume.IsZero() {
+			delete(side, key)
+			continue
+		}
+		side[key] = level
+	}
+}
+
+func sortedLevels(side map[string]orderbook.Level, less func(a, c decimal.Decimal) bool) []orderbook.Level {
+	var res = make([]orderbook.Level, 0, len(side))
+	for _, level := range side {
+		res = append(res, level)
+	}
+	sort.Slice(res, func(i, j int) bool { return less(res[i].Price, res[j].Price) })
+
+	return res
+}
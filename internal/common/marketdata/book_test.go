@@ -0,0 +1,48 @@
+package marketdata
+
+import (
+	"errors"
+	"testing"
+	"trading_bot/internal/common/orderbook"
+
+	"github.com/shopspring/decimal"
+)
+
+func level(price string, volume string) orderbook.Level {
+	return orderbook.Level{Price: decimal.RequireFromString(price), Volume: decimal.RequireFromString(volume)}
+}
+
+func TestApplyUpdate_Levels_Success(t *testing.T) {
+	t.Parallel()
+
+	var book = NewBook()
+	book.ApplySnapshot([]orderbook.Level{level("101", "1"), level("102", "2")}, []orderbook.Level{level("99", "1"), level("98", "2")}, 10)
+
+	err := book.ApplyUpdate([]orderbook.Level{level("100.5", "3"), level("102.0", "0")}, []orderbook.Level{level("99", "5")}, 10, 11)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	var asks, bids = book.Levels(20)
+	if len(asks) != 2 || !asks[0].Price.Equal(decimal.RequireFromString("100.5")) || !asks[1].Price.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("got asks %v, wanted 100.5, 101", asks)
+	}
+	if len(bids) != 2 || !bids[0].Volume.Equal(decimal.NewFromInt(5)) || !bids[1].Price.Equal(decimal.NewFromInt(98)) {
+		t.Errorf("got bids %v, wanted 99x5, 98x2", bids)
+	}
+}
+
+func TestApplyUpdate_SequenceGap_Error(t *testing.T) {
+	t.Parallel()
+
+	var book = NewBook()
+	book.ApplySnapshot(nil, nil, 10)
+
+	err := book.ApplyUpdate([]orderbook.Level{level("101", "1")}, nil, 11, 12)
+	if !errors.Is(err, ErrSequenceGap) {
+		t.Errorf("got %v, wanted %v", err, ErrSequenceGap)
+	}
+	if book.Synced() {
+		t.Errorf("got synced book after sequence gap")
+	}
+}
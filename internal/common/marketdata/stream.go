@@ -0,0 +1,248 @@
+package marketdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+	"trading_bot/internal/common/orderbook"
+	"trading_bot/pkg/logger"
+
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	bookChannel = "book_lv2"
+
+	// Poloniex drops connections without a ping for 30 seconds
+	pingInterval   = 20 * time.Second
+	reconnectDelay = 5 * time.Second
+	writeTimeout   = 5 * time.Second
+	// connection is closed when nothing is received for two pings, pongs are received at least
+	readTimeout = 2 * pingInterval
+)
+
+// Stream keeps local L2 books of the Poloniex spot public WebSocket (wss://ws.poloniex.com/ws/public)
+// and notifies subscribers about book changes.
+type Stream struct {
+	logger   logger.ILogger
+	url      string
+	pairs    []string
+	books    map[string]*Book
+	updates  chan string
+	dialer   *websocket.Dialer
+	writeMu  sync.Mutex
+	interval time.Duration
+	ping     time.Duration
+	timeout  time.Duration
+}
+
+func New(l logger.ILogger, wsUrl string, pairs []string) *Stream {
+	var books = make(map[string]*Book, len(pairs))
+	for _, pair := range pairs {
+		books[pair] = NewBook()
+	}
+
+	return &Stream{
+		logger:   l,
+		url:      wsUrl,
+		pairs:    pairs,
+		books:    books,
+		updates:  make(chan string, len(pairs)),
+		dialer:   websocket.DefaultDialer,
+		interval: reconnectDelay,
+		ping:     pingInterval,
+		timeout:  readTimeout,
+	}
+}
+
+// Book returns the local book of the pair
+func (s *Stream) Book(pair string) (*Book, bool) {
+	book, found := s.books[pair]
+	return book, found
+}
+
+// Updates delivers the pair name after its book was changed.
+// Notifications are coalesced, a slow reader gets only the latest state.
+func (s *Stream) Updates() <-chan string {
+	return s.updates
+}
+
+// Run keeps the connection open until the context is cancelled
+func (s *Stream) Run(ctx context.Context) {
+	for {
+		err := s.runConnection(ctx)
+		for _, book := range s.books {
+			book.Invalidate()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Error("MarketData : connection to %v closed : %v, reconnecting", s.url, err)
+
+		select {
+		case <-time.After(s.interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Stream) runConnection(ctx context.Context) error {
+	conn, _, err := s.dialer.DialContext(ctx, s.url, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// half-open connection is detected by the read deadline, every message, ping and pong extends it
+	conn.SetReadDeadline(time.Now().Add(s.timeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(s.timeout))
+	})
+	conn.SetPingHandler(func(appData string) error {
+		conn.SetReadDeadline(time.Now().Add(s.timeout))
+		return conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(writeTimeout))
+	})
+
+	// close connection on cancel to unblock the reader
+	var done = make(chan struct{})
+	defer close(done)
+	go func() {
+		var ticker = time.NewTicker(s.ping)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.send(conn, subscription{Event: "ping"}); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	if err := s.send(conn, subscription{Event: "subscribe", Channel: []string{bookChannel}, Symbols: s.pairs}); err != nil {
+		return err
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		conn.SetReadDeadline(time.Now().Add(s.timeout))
+
+		if err := s.handleMessage(conn, data); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Stream) handleMessage(conn *websocket.Conn, data []byte) error {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		s.logger.Error("MarketData : can't parse message : %s", data)
+		return nil
+	}
+
+	if msg.Event == "error" {
+		return errors.New(msg.Message)
+	}
+	if msg.Channel != bookChannel || len(msg.Data) == 0 {
+		return nil
+	}
+
+	for _, item := range msg.Data {
+		book, found := s.books[item.Symbol]
+		if !found {
+			continue
+		}
+
+		switch msg.Action {
+		case "snapshot":
+			book.ApplySnapshot(toLevels(item.Asks), toLevels(item.Bids), item.Id)
+		case "update":
+			if !book.Synced() {
+				// waiting for the snapshot requested on (re)subscribe
+				continue
+			}
+			if err := book.ApplyUpdate(toLevels(item.Asks), toLevels(item.Bids), item.LastId, item.Id); err != nil {
+				s.logger.Error("MarketData : %v : %v, resubscribing", item.Symbol, err)
+				if err := s.resync(conn, item.Symbol); err != nil {
+					return err
+				}
+				continue
+			}
+		default:
+			continue
+		}
+
+		s.notify(item.Symbol)
+	}
+
+	return nil
+}
+
+// resync requests a fresh snapshot of the pair
+func (s *Stream) resync(conn *websocket.Conn, pair string) error {
+	if err := s.send(conn, subscription{Event: "unsubscribe", Channel: []string{bookChannel}, Symbols: []string{pair}}); err != nil {
+		return err
+	}
+	return s.send(conn, subscription{Event: "subscribe", Channel: []string{bookChannel}, Symbols: []string{pair}})
+}
+
+func (s *Stream) notify(pair string) {
+	select {
+	case s.updates <- pair:
+	default:
+		// pending notification already covers this change
+	}
+}
+
+func (s *Stream) send(conn *websocket.Conn, v interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return conn.WriteJSON(v)
+}
+
+type subscription struct {
+	Event   string   `json:"event"`
+	Channel []string `json:"channel,omitempty"`
+	Symbols []string `json:"symbols,omitempty"`
+}
+
+type message struct {
+	Event   string `json:"event"`
+	Message string `json:"message"`
+	Channel string `json:"channel"`
+	Action  string `json:"action"`
+	Data    []struct {
+		Symbol string              `json:"symbol"`
+		Asks   [][]decimal.Decimal `json:"asks"`
+		Bids   [][]decimal.Decimal `json:"bids"`
+		LastId int64               `json:"lastId"`
+		Id     int64               `json:"id"`
+	} `json:"data"`
+}
+
+func toLevels(items [][]decimal.Decimal) []orderbook.Level {
+	var levels = make([]orderbook.Level, 0, len(items))
+	for _, item := range items {
+		if len(item) < 2 {
+			continue
+		}
+		levels = append(levels, orderbook.Level{Price: item[0], Volume: item[1]})
+	}
+	return levels
+}
@@ -0,0 +1,147 @@
+package marketdata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"trading_bot/mocks"
+
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+)
+
+func testLogger(t *testing.T) *mocks.ILogger {
+	t.Helper()
+
+	var l = &mocks.ILogger{}
+	var args = []interface{}{mock.Anything}
+	for i := 0; i < 5; i++ {
+		l.On("Debug", args...).Return()
+		l.On("Info", args...).Return()
+		l.On("Error", args...).Return()
+		args = append(args, mock.Anything)
+	}
+
+	return l
+}
+
+// webSocketStandIn emulates Poloniex book_lv2 channel: snapshot, valid update, update with a gap
+// and a fresh snapshot after resubscribe
+func webSocketStandIn(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var upgrader = websocket.Upgrader{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var subscriptions = 0
+		for {
+			var msg subscription
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg.Event != "subscribe" {
+				continue
+			}
+			subscriptions++
+
+			if subscriptions == 1 {
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"channel":"book_lv2","action":"snapshot","data":[{"symbol":"BTC_USDC","asks":[["101","1"]],"bids":[["99","1"]],"lastId":0,"id":1}]}`))
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"channel":"book_lv2","action":"update","data":[{"symbol":"BTC_USDC","asks":[["100.5","2"]],"bids":[],"lastId":1,"id":2}]}`))
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"channel":"book_lv2","action":"update","data":[{"symbol":"BTC_USDC","asks":[["100","2"]],"bids":[],"lastId":5,"id":6}]}`))
+				continue
+			}
+
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"channel":"book_lv2","action":"snapshot","data":[{"symbol":"BTC_USDC","asks":[["105","3"]],"bids":[["95","3"]],"lastId":0,"id":20}]}`))
+		}
+	}))
+}
+
+func TestRun_GapResync_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = webSocketStandIn(t)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var stream = New(testLogger(t), "ws"+strings.TrimPrefix(server.URL, "http"), []string{"BTC_USDC"})
+	go stream.Run(ctx)
+
+	var book, _ = stream.Book("BTC_USDC")
+	for {
+		select {
+		case <-stream.Updates():
+		case <-ctx.Done():
+			t.Fatalf("book was not resynced")
+		}
+
+		var asks, _ = book.Levels(20)
+		if book.Synced() && len(asks) == 1 && asks[0].Price.Equal(decimal.NewFromInt(105)) {
+			return
+		}
+	}
+}
+
+func TestRun_SilentConnection_Reconnected(t *testing.T) {
+	t.Parallel()
+
+	var upgrader = websocket.Upgrader{}
+	var connections atomic.Int32
+
+	// first connection sends the snapshot and goes silent without closing, the second one sends a fresh snapshot
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var snapshot = `{"channel":"book_lv2","action":"snapshot","data":[{"symbol":"BTC_USDC","asks":[["101","1"]],"bids":[["99","1"]],"lastId":0,"id":1}]}`
+		if connections.Add(1) > 1 {
+			snapshot = `{"channel":"book_lv2","action":"snapshot","data":[{"symbol":"BTC_USDC","asks":[["105","3"]],"bids":[["95","3"]],"lastId":0,"id":20}]}`
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(snapshot))
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var stream = New(testLogger(t), "ws"+strings.TrimPrefix(server.URL, "http"), []string{"BTC_USDC"})
+	stream.interval = 10 * time.Millisecond
+	stream.ping = time.Hour
+	stream.timeout = 100 * time.Millisecond
+	go stream.Run(ctx)
+
+	var book, _ = stream.Book("BTC_USDC")
+	for {
+		select {
+		case <-stream.Updates():
+		case <-ctx.Done():
+			t.Fatalf("silent connection was not reconnected")
+		}
+
+		var asks, _ = book.Levels(20)
+		if book.Synced() && len(asks) == 1 && asks[0].Price.Equal(decimal.NewFromInt(105)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Package marketinfo keeps the trading rules (tick size, lot size, min and max amount, min notional) of the pair
+// in the internal and the trading system, the rules are refreshed periodically.
+package marketinfo
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"trading_bot/internal/common"
+	"trading_bot/internal/entity"
+	"trading_bot/pkg/logger"
+)
+
+// rules are requested again after the hour when the refresh interval is not set
+const defaultRefresh = 60 * time.Minute
+
+// Rules of the pair in both systems
+type Rules struct {
+	Internal      *entity.MarketInfo
+	TradingSystem *entity.MarketInfo
+}
+
+// Quote returns the rules of the internal quotes: the internal rules merged with the trading system ones,
+// so the fill of the quote can be hedged
+func (r *Rules) Quote() *entity.MarketInfo {
+	return r.Internal.Merge(r.TradingSystem)
+}
+
+func (r *Rules) String() string {
+	return fmt.Sprintf("internal %v, trading system %v", describe(r.Internal), describe(r.TradingSystem))
+}
+
+func describe(info *entity.MarketInfo) string {
+	return fmt.Sprintf("tick %v, lot %v, amount %v - %v, min notional %v", info.PriceStep, info.AmountStep, info.MinAmount, info.MaxAmount, info.MinNotional)
+}
+
+// Service requests the rules of the pair and keeps them until the refresh interval passes
+type Service struct {
+	logger            logger.ILogger
+	internal          common.IInternalRequest
+	tradingSystem     common.ITradingSystemRequest
+	internalPair      string
+	tradingSystemPair string
+	refresh           time.Duration
+	rules             *Rules
+	updated           time.Time
+}
+
+// New creates the service, defaultRefresh is used when refresh is not positive
+func New(l logger.ILogger, internal common.IInternalRequest, tradingSystem common.ITradingSystemRequest, internalPair string, tradingSystemPair string, refresh time.Duration) *Service {
+	if refresh <= 0 {
+		refresh = defaultRefresh
+	}
+
+	return &Service{
+		logger:            l,
+		internal:          internal,
+		tradingSystem:     tradingSystem,
+		internalPair:      internalPair,
+		tradingSystemPair: tradingSystemPair,
+		refresh:           refresh,
+	}
+}
+
+// Get returns the rules of the pair, the rules are requested when they are older than the refresh interval.
+// The last rules are returned when they can't be refreshed.
+func (s *Service) Get(ctx context.Context) (*Rules, error) {
+	if s.rules != nil && time.Since(s.updated) < s.refresh {
+		return s.rules, nil
+	}
+
+	var rules, err = s.request(ctx)
+	if err != nil {
+		if s.rules != nil {
+			s.logger.Error("MarketInfo %v : can't refresh market info, last rules are used : %v", s.internalPair, err)
+			return s.rules, nil
+		}
+		return nil, err
+	}
+
+	if s.rules == nil || s.rules.String() != rules.String() {
+		s.logger.Info("MarketInfo %v : %v", s.internalPair, rules)
+	}
+	s.rules = rules
+	s.updated = time.Now()
+
+	return s.rules, nil
+}
+
+// Invalidate makes the next Get request the rules, it's called when the venue rejects the order
+func (s *Service) Invalidate() {
+	s.updated = time.Time{}
+}
+
+func (s *Service) request(ctx context.Context) (*Rules, error) {
+	var internalInfo, err = s.internal.GetTradingPairInfo(ctx, s.internalPair)
+	if err != nil {
+		return nil, err
+	}
+	tradingSystemInfo, err := s.tradingSystem.GetMarketInfo(ctx, s.tradingSystemPair)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rules{Internal: internalInfo, TradingSystem: tradingSystemInfo}, nil
+}
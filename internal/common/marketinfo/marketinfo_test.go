@@ -0,0 +1,107 @@
+package marketinfo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"trading_bot/internal/common"
+	"trading_bot/internal/entity"
+	"trading_bot/mocks"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+)
+
+func testLogger(t *testing.T) *mocks.ILogger {
+	t.Helper()
+
+	var l = &mocks.ILogger{}
+	var args = []interface{}{mock.Anything}
+	for i := 0; i < 4; i++ {
+		l.On("Info", args...).Return()
+		l.On("Error", args...).Return()
+		args = append(args, mock.Anything)
+	}
+
+	return l
+}
+
+func TestService_Get_Success(t *testing.T) {
+	t.Parallel()
+
+	var internal = &mocks.IInternalRequest{}
+	internal.On("GetTradingPairInfo", mock.Anything, "BTC,USDC").Return(&entity.MarketInfo{
+		Pair:       "BTC,USDC",
+		PriceStep:  decimal.NewFromFloat(0.01),
+		AmountStep: decimal.NewFromFloat(0.0001),
+		MinAmount:  decimal.NewFromFloat(0.001),
+	}, nil).Once()
+	var tradingSystem = &mocks.ITradingSystemRequest{}
+	tradingSystem.On("GetMarketInfo", mock.Anything, "BTC_USDC").Return(&entity.MarketInfo{
+		Pair:        "BTC_USDC",
+		PriceStep:   decimal.NewFromFloat(0.1),
+		AmountStep:  decimal.NewFromFloat(0.000001),
+		MinAmount:   decimal.NewFromFloat(0.0001),
+		MaxAmount:   decimal.NewFromInt(100),
+		MinNotional: decimal.NewFromInt(5),
+	}, nil).Once()
+	var s = New(testLogger(t), internal, tradingSystem, "BTC,USDC", "BTC_USDC", time.Hour)
+
+	got, err := s.Get(context.Background())
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	// the rules are kept until the refresh interval passes
+	if cached, _ := s.Get(context.Background()); cached != got {
+		t.Errorf("got %v, wanted cached %v", cached, got)
+	}
+
+	var quote = got.Quote()
+	if !quote.PriceStep.Equal(decimal.NewFromFloat(0.1)) || !quote.AmountStep.Equal(decimal.NewFromFloat(0.0001)) {
+		t.Errorf("got tick %v and lot %v, wanted 0.1 and 0.0001", quote.PriceStep, quote.AmountStep)
+	}
+	if !quote.MinAmount.Equal(decimal.NewFromFloat(0.001)) || !quote.MaxAmount.Equal(decimal.NewFromInt(100)) || !quote.MinNotional.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("got amount %v - %v and min notional %v, wanted 0.001 - 100 and 5", quote.MinAmount, quote.MaxAmount, quote.MinNotional)
+	}
+	internal.AssertExpectations(t)
+	tradingSystem.AssertExpectations(t)
+}
+
+func TestService_Get_RefreshFailed_Success(t *testing.T) {
+	t.Parallel()
+
+	var internal = &mocks.IInternalRequest{}
+	internal.On("GetTradingPairInfo", mock.Anything, "BTC,USDC").Return(&entity.MarketInfo{Pair: "BTC,USDC"}, nil)
+	var tradingSystem = &mocks.ITradingSystemRequest{}
+	tradingSystem.On("GetMarketInfo", mock.Anything, "BTC_USDC").Return(&entity.MarketInfo{Pair: "BTC_USDC"}, nil).Once()
+	tradingSystem.On("GetMarketInfo", mock.Anything, "BTC_USDC").Return(nil, common.ErrServiceUnavailable)
+	var s = New(testLogger(t), internal, tradingSystem, "BTC,USDC", "BTC_USDC", time.Hour)
+
+	first, err := s.Get(context.Background())
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	// the rejected order invalidates the rules, the last rules are used while they can't be requested
+	s.Invalidate()
+	got, err := s.Get(context.Background())
+
+	if err != nil || got != first {
+		t.Errorf("got %v (error %v), wanted last rules %v", got, err, first)
+	}
+}
+
+func TestService_Get_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var internal = &mocks.IInternalRequest{}
+	internal.On("GetTradingPairInfo", mock.Anything, "BTC,USDC").Return(nil, common.ErrServiceUnavailable)
+	var s = New(testLogger(t), internal, &mocks.ITradingSystemRequest{}, "BTC,USDC", "BTC_USDC", 0)
+
+	_, err := s.Get(context.Background())
+
+	if !errors.Is(err, common.ErrServiceUnavailable) {
+		t.Errorf("got error %v, wanted %v", err, common.ErrServiceUnavailable)
+	}
+}
@@ -0,0 +1,164 @@
+// Package nonce issues strictly increasing nonces of the venue keys.
+package nonce
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider issues nonces of the single key. The nonce is the current time in nanoseconds, but never lower or equal to
+// the last issued one, so clock skew doesn't produce lower nonces. When the path is set, every nonce is persisted
+// before it is used and the next process continues from it.
+type Provider struct {
+	// held by Do while the request is signed and sent
+	mu     sync.Mutex
+	path   string
+	last   int64
+	loaded bool
+	now    func() int64
+}
+
+func New(path string) *Provider {
+	return &Provider{
+		path: path,
+		now:  func() int64 { return time.Now().UnixNano() },
+	}
+}
+
+// Do calls fn with the next nonce. Calls of the provider are serialized, so the request has to be sent by fn:
+// the request signed with the lower nonce can't reach the venue after the higher one.
+func (p *Provider) Do(fn func(nonce int64) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.load(); err != nil {
+		return err
+	}
+
+	var next = p.now()
+	if next <= p.last {
+		next = p.last + 1
+	}
+	if err := p.save(next); err != nil {
+		return err
+	}
+	p.last = next
+
+	return fn(next)
+}
+
+// Raise makes next nonces greater than min, it's used when the venue rejects the nonce as too small
+func (p *Provider) Raise(min int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.load(); err != nil {
+		return err
+	}
+	if min <= p.last {
+		return nil
+	}
+	if err := p.save(min); err != nil {
+		return err
+	}
+	p.last = min
+
+	return nil
+}
+
+// Last returns the last issued nonce
+func (p *Provider) Last() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.last
+}
+
+func (p *Provider) load() error {
+	if p.loaded || len(p.path) == 0 {
+		p.loaded = true
+		return nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("nonce : %w", err)
+	}
+	if err == nil {
+		var last, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return fmt.Errorf("nonce %v : %w", p.path, err)
+		}
+		p.last = last
+	}
+	p.loaded = true
+
+	return nil
+}
+
+// save writes the nonce to the temporary file and renames it, so the file always has the complete value
+func (p *Provider) save(nonce int64) error {
+	if len(p.path) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return fmt.Errorf("nonce : %w", err)
+	}
+
+	var tmp = p.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("nonce : %w", err)
+	}
+	_, err = f.WriteString(strconv.FormatInt(nonce, 10) + "\n")
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("nonce : %w", err)
+	}
+
+	if err := os.Rename(tmp, p.path); err != nil {
+		return fmt.Errorf("nonce : %w", err)
+	}
+
+	return nil
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   = map[string]*Provider{}
+)
+
+// Shared returns provider of the venue key, all requests using the same key share it.
+// Path of the first caller is used.
+func Shared(venue string, key string, path string) *Provider {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	var id = venue + "|" + key
+	if provider, found := shared[id]; found {
+		return provider
+	}
+
+	var provider = New(path)
+	shared[id] = provider
+
+	return provider
+}
+
+// FileName returns name of the nonce file of the venue key, the key itself is not exposed
+func FileName(venue string, key string) string {
+	var hash = sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%v-%x.nonce", strings.ToLower(venue), hash[:8])
+}
@@ -0,0 +1,97 @@
+package nonce
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func fixedClock(p *Provider, now int64) *Provider {
+	p.now = func() int64 { return now }
+	return p
+}
+
+func TestProvider_Restart_Success(t *testing.T) {
+	t.Parallel()
+
+	var path = filepath.Join(t.TempDir(), "nonces", FileName("Poloniex", "key"))
+
+	var first = fixedClock(New(path), 1000)
+	for i := 0; i < 3; i++ {
+		if err := first.Do(func(int64) error { return nil }); err != nil {
+			t.Fatalf("got error %v, wanted nil", err)
+		}
+	}
+
+	// restarted process with the clock behind continues after the persisted nonce
+	var got int64
+	var second = fixedClock(New(path), 10)
+	if err := second.Do(func(n int64) error { got = n; return nil }); err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	if want := int64(1003); got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
+
+func TestProvider_Raise_Success(t *testing.T) {
+	t.Parallel()
+
+	var p = fixedClock(New(""), 100)
+	if err := p.Raise(500); err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	// lower values don't move the nonce back
+	if err := p.Raise(200); err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	var got int64
+	p.Do(func(n int64) error { got = n; return nil })
+
+	if want := int64(501); got != want {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
+
+func TestProvider_Concurrent_Success(t *testing.T) {
+	t.Parallel()
+
+	var p = fixedClock(New(""), 1)
+	var sent []int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// fn is serialized, nonces are sent in the issued order
+			p.Do(func(n int64) error {
+				sent = append(sent, n)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	if len(sent) != 20 {
+		t.Fatalf("got %v nonces, wanted %v", len(sent), 20)
+	}
+	for i := 1; i < len(sent); i++ {
+		if sent[i] <= sent[i-1] {
+			t.Errorf("got nonce %v after %v, wanted increasing", sent[i], sent[i-1])
+		}
+	}
+}
+
+func TestShared_SameKey_Success(t *testing.T) {
+	t.Parallel()
+
+	if Shared("Test", "key", "") != Shared("Test", "key", "other") {
+		t.Errorf("got different providers, wanted shared provider of the key")
+	}
+	if Shared("Test", "key", "") == Shared("Test", "other-key", "") {
+		t.Errorf("got shared provider, wanted provider per key")
+	}
+}
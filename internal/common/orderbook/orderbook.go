@@ -0,0 +1,104 @@
+package orderbook
+
+import (
+	"trading_bot/internal/entity"
+
+	"github.com/shopspring/decimal"
+)
+
+// Level is a single price level of a trading system order book.
+type Level struct {
+	Price  decimal.Decimal
+	Volume decimal.Decimal
+}
+
+// SelectTradingOrders picks trading system book levels that can be mirrored to the internal system
+// within the given trading system limits and internal balances.
+func SelectTradingOrders(asks []Level, bids []Level, usdcTradingLimit decimal.Decimal, cryptoTradingLimit decimal.Decimal, internalCryptoBalance decimal.Decimal, internalUsdcBalance decimal.Decimal, pairMinAmount decimal.Decimal) []*entity.TradingOrder {
+	var res []*entity.TradingOrder = []*entity.TradingOrder{}
+
+	var amountFound = usdcTradingLimit
+	var breakProcess = false
+	if amountFound.GreaterThan(decimal.Decimal{}) {
+		for _, ask := range asks {
+			if breakProcess {
+				break
+			}
+
+			var order = &entity.TradingOrder{
+				Rate:   ask.Price,
+				Amount: ask.Volume,
+			}
+
+			if ask.Price.GreaterThan(internalCryptoBalance) {
+				order.Amount = internalCryptoBalance
+				breakProcess = true
+			} else {
+				internalCryptoBalance = internalCryptoBalance.Sub(order.Amount)
+			}
+
+			amountFound = amountFound.Sub(order.Amount.Mul(order.Rate))
+
+			if amountFound.LessThan(decimal.Decimal{}) {
+				order.Amount = order.Amount.Add(amountFound.Div(order.Rate))
+				breakProcess = true
+			}
+
+			// ignore orders that lower than pair minimum amount
+			if order.Amount.LessThanOrEqual(pairMinAmount) {
+				continue
+			}
+
+			order.IsSellOrder = true
+
+			res = append(res, order)
+		}
+	}
+
+	amountFound = cryptoTradingLimit
+	breakProcess = false
+	if amountFound.GreaterThan(decimal.Decimal{}) {
+		for _, ask := range bids {
+			if breakProcess {
+				break
+			}
+
+			var order = &entity.TradingOrder{
+				Rate:   ask.Price,
+				Amount: ask.Volume,
+			}
+
+			if (order.Amount.Mul(order.Rate)).GreaterThan(internalCryptoBalance) {
+				order.Amount = internalCryptoBalance
+				breakProcess = true
+			} else {
+				internalCryptoBalance = internalCryptoBalance.Sub(order.Amount)
+			}
+
+			if order.Amount.Mul(order.Rate).GreaterThan(internalUsdcBalance) {
+				order.Amount = internalUsdcBalance.Div(order.Rate)
+				breakProcess = true
+			} else {
+				internalUsdcBalance = internalUsdcBalance.Sub(order.Amount.Mul(order.Rate))
+			}
+
+			amountFound = amountFound.Sub(order.Amount)
+
+			if amountFound.LessThan(decimal.Decimal{}) {
+				order.Amount = order.Amount.Add(amountFound)
+				breakProcess = true
+			}
+
+			// ignore orders that lower than pair minimum amount
+			if order.Amount.LessThanOrEqual(pairMinAmount) {
+				continue
+			}
+
+			order.IsSellOrder = false
+
+			res = append(res, order)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,97 @@
+// Package orderdiff compares live internal orders with the desired quotes, so only changed quotes are touched.
+package orderdiff
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// Quote is the live order or the desired quote of the internal system
+type Quote struct {
+	IsSellOrder bool
+	Price       decimal.Decimal
+	Amount      decimal.Decimal
+}
+
+// Match pairs the live order index with the desired quote index
+type Match struct {
+	Live    int
+	Desired int
+}
+
+// Plan of the changes, indexes refer to the slices passed to Diff
+type Plan struct {
+	// live orders equal to the desired quotes
+	Keep []Match
+	// live orders changed to the desired price and amount
+	Amend []Match
+	// live orders without desired quotes
+	Cancel []int
+	// desired quotes without live orders
+	Add []int
+}
+
+// IsEmpty reports whether no request is needed
+func (p Plan) IsEmpty() bool {
+	return len(p.Amend) == 0 && len(p.Cancel) == 0 && len(p.Add) == 0
+}
+
+// Diff plans changes turning the live orders into the desired quotes. Orders equal by side, price and amount are kept,
+// the rest of the orders of the side are amended in the price order (best first), orders left over are cancelled
+// and quotes left over are added.
+func Diff(live []Quote, desired []Quote) Plan {
+	var plan Plan
+	for _, isSell := range []bool{true, false} {
+		var liveLeft = side(live, isSell)
+		var desiredLeft = side(desired, isSell)
+
+		// equal orders are kept
+		var unmatched = desiredLeft[:0:0]
+		for _, d := range desiredLeft {
+			var found = -1
+			for i, l := range liveLeft {
+				if live[l].Price.Equal(desired[d].Price) && live[l].Amount.Equal(desired[d].Amount) {
+					found = i
+					break
+				}
+			}
+			if found < 0 {
+				unmatched = append(unmatched, d)
+				continue
+			}
+			plan.Keep = append(plan.Keep, Match{Live: liveLeft[found], Desired: d})
+			liveLeft = append(liveLeft[:found], liveLeft[found+1:]...)
+		}
+		desiredLeft = unmatched
+
+		var i = 0
+		for ; i < len(liveLeft) && i < len(desiredLeft); i++ {
+			plan.Amend = append(plan.Amend, Match{Live: liveLeft[i], Desired: desiredLeft[i]})
+		}
+		plan.Cancel = append(plan.Cancel, liveLeft[i:]...)
+		plan.Add = append(plan.Add, desiredLeft[i:]...)
+	}
+
+	return plan
+}
+
+// side returns indexes of the quotes of the side sorted by price, the best price first
+func side(quotes []Quote, isSell bool) []int {
+	var res []int
+	for i, quote := range quotes {
+		if quote.IsSellOrder == isSell {
+			res = append(res, i)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		var a, b = quotes[res[i]].Price, quotes[res[j]].Price
+		if isSell {
+			return a.LessThan(b)
+		}
+		return a.GreaterThan(b)
+	})
+
+	return res
+}
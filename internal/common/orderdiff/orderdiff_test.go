@@ -0,0 +1,73 @@
+package orderdiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func quote(isSell bool, price int64, amount float64) Quote {
+	return Quote{IsSellOrder: isSell, Price: decimal.NewFromInt(price), Amount: decimal.NewFromFloat(amount)}
+}
+
+func TestDiff_Success(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name    string
+		live    []Quote
+		desired []Quote
+		want    Plan
+	}{
+		{
+			name:    "unchanged",
+			live:    []Quote{quote(true, 20100, 0.1), quote(false, 19900, 0.5)},
+			desired: []Quote{quote(false, 19900, 0.5), quote(true, 20100, 0.1)},
+			want:    Plan{Keep: []Match{{Live: 0, Desired: 1}, {Live: 1, Desired: 0}}},
+		},
+		{
+			name:    "price moved",
+			live:    []Quote{quote(true, 20100, 0.1), quote(true, 20150, 1)},
+			desired: []Quote{quote(true, 20120, 0.1), quote(true, 20150, 1)},
+			want:    Plan{Keep: []Match{{Live: 1, Desired: 1}}, Amend: []Match{{Live: 0, Desired: 0}}},
+		},
+		{
+			name:    "level added and removed",
+			live:    []Quote{quote(true, 20100, 0.1), quote(false, 19900, 0.5), quote(false, 19800, 1)},
+			desired: []Quote{quote(true, 20100, 0.1), quote(true, 20150, 1), quote(false, 19850, 0.5)},
+			want: Plan{
+				Keep:   []Match{{Live: 0, Desired: 0}},
+				Amend:  []Match{{Live: 1, Desired: 2}},
+				Cancel: []int{2},
+				Add:    []int{1},
+			},
+		},
+		{
+			name: "no desired quotes",
+			live: []Quote{quote(true, 20100, 0.1), quote(false, 19900, 0.5)},
+			want: Plan{Cancel: []int{0, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		var got = Diff(tt.live, tt.desired)
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v : got %+v, wanted %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlan_IsEmpty_Success(t *testing.T) {
+	t.Parallel()
+
+	var live = []Quote{quote(true, 20100, 0.1)}
+
+	if got := Diff(live, live).IsEmpty(); !got {
+		t.Errorf("got %v, wanted true", got)
+	}
+	if got := Diff(live, nil).IsEmpty(); got {
+		t.Errorf("got %v, wanted false", got)
+	}
+}
@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+	"trading_bot/config"
+)
+
+// Endpoint classes limited separately
+const (
+	ClassPublic  = "public"
+	ClassPrivate = "private"
+	ClassOrder   = "order"
+)
+
+// Bucket is a token bucket refilled with rate tokens per second up to burst tokens
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewBucket(rate float64, burst int) *Bucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes weight tokens, it blocks until the tokens are available or context is cancelled
+func (b *Bucket) Wait(ctx context.Context, weight int) error {
+	if b.rate <= 0 {
+		// unlimited
+		return nil
+	}
+
+	var need = float64(weight)
+	if need > b.burst {
+		need = b.burst
+	}
+
+	for {
+		var delay = b.reserve(need)
+		if delay == 0 {
+			return nil
+		}
+
+		var timer = time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve takes tokens when available, otherwise returns time to wait for them
+func (b *Bucket) reserve(need float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var now = time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens >= need {
+		b.tokens -= need
+		return 0
+	}
+
+	return time.Duration((need - b.tokens) / b.rate * float64(time.Second))
+}
+
+// Limiter limits requests of the single venue account by endpoint class
+type Limiter struct {
+	buckets map[string]*Bucket
+	weights map[string]int
+}
+
+// New creates limiter, zero settings of the class are taken from defaults
+func New(rs config.RateLimitSettings, defaults config.RateLimitSettings) *Limiter {
+	var weights = make(map[string]int, len(defaults.Weights)+len(rs.Weights))
+	for endpoint, weight := range defaults.Weights {
+		weights[endpoint] = weight
+	}
+	for endpoint, weight := range rs.Weights {
+		weights[endpoint] = weight
+	}
+
+	return &Limiter{
+		buckets: map[string]*Bucket{
+			ClassPublic:  newClassBucket(rs.Public, defaults.Public),
+			ClassPrivate: newClassBucket(rs.Private, defaults.Private),
+			ClassOrder:   newClassBucket(rs.Order, defaults.Order),
+		},
+		weights: weights,
+	}
+}
+
+func newClassBucket(rl config.RateLimit, def config.RateLimit) *Bucket {
+	if rl.RequestsPerSecond <= 0 {
+		rl = def
+	}
+	if rl.Burst <= 0 {
+		rl.Burst = int(rl.RequestsPerSecond)
+	}
+	return NewBucket(rl.RequestsPerSecond, rl.Burst)
+}
+
+// Wait blocks until the endpoint of the class can be called
+func (l *Limiter) Wait(ctx context.Context, class string, endpoint string) error {
+	bucket, found := l.buckets[class]
+	if !found {
+		return nil
+	}
+
+	return bucket.Wait(ctx, l.Weight(endpoint))
+}
+
+// Weight returns configured weight of the endpoint, 1 by default
+func (l *Limiter) Weight(endpoint string) int {
+	if weight, found := l.weights[endpoint]; found && weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   = map[string]*Limiter{}
+)
+
+// Shared returns limiter of the venue account, all workers using the same key share it.
+// Settings of the first caller are used.
+func Shared(venue string, key string, rs config.RateLimitSettings, defaults config.RateLimitSettings) *Limiter {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	var id = venue + "|" + key
+	if limiter, found := shared[id]; found {
+		return limiter
+	}
+
+	var limiter = New(rs, defaults)
+	shared[id] = limiter
+
+	return limiter
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"trading_bot/config"
+)
+
+func TestBucket_BurstThenRate_Success(t *testing.T) {
+	t.Parallel()
+
+	var b = NewBucket(20, 5)
+	var started = time.Now()
+
+	// burst is free, 5 more tokens take 250ms at 20 per second
+	for i := 0; i < 10; i++ {
+		if err := b.Wait(context.Background(), 1); err != nil {
+			t.Fatalf("got error %v, wanted nil", err)
+		}
+	}
+
+	if elapsed := time.Since(started); elapsed < 200*time.Millisecond || elapsed > time.Second {
+		t.Errorf("got elapsed %v, wanted about 250ms", elapsed)
+	}
+}
+
+func TestBucket_Cancelled_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var b = NewBucket(0.1, 1)
+	if err := b.Wait(context.Background(), 1); err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	got := b.Wait(ctx, 1)
+
+	if !errors.Is(got, context.DeadlineExceeded) {
+		t.Errorf("got error %v, wanted %v", got, context.DeadlineExceeded)
+	}
+}
+
+func TestLimiter_WeightsAndDefaults_Success(t *testing.T) {
+	t.Parallel()
+
+	var defaults = config.RateLimitSettings{
+		Public:  config.RateLimit{RequestsPerSecond: 10},
+		Weights: map[string]int{"/depth": 5, "/account": 20},
+	}
+	var l = New(config.RateLimitSettings{Weights: map[string]int{"/account": 10}}, defaults)
+
+	var tests = []struct {
+		endpoint string
+		want     int
+	}{
+		{"/depth", 5},
+		{"/account", 10},
+		{"/order", 1},
+	}
+
+	for _, tt := range tests {
+		if got := l.Weight(tt.endpoint); got != tt.want {
+			t.Errorf("%v : got %v, wanted %v", tt.endpoint, got, tt.want)
+		}
+	}
+
+	if got := l.buckets[ClassPublic].burst; got != 10 {
+		t.Errorf("got public burst %v, wanted 10", got)
+	}
+}
+
+func TestShared_SameKey_Success(t *testing.T) {
+	t.Parallel()
+
+	var settings = config.RateLimitSettings{}
+
+	if Shared("Venue", "key-1", settings, settings) != Shared("Venue", "key-1", settings, settings) {
+		t.Errorf("got different limiters, wanted shared for the same venue and key")
+	}
+	if Shared("Venue", "key-1", settings, settings) == Shared("Venue", "key-2", settings, settings) {
+		t.Errorf("got shared limiter, wanted different for different keys")
+	}
+}
@@ -0,0 +1,133 @@
+// Package reconcile matches the journaled hedges with the orders and trades of the trading system account,
+// so hedges lost or repeated after network errors are found.
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"trading_bot/internal/common/clientid"
+	"trading_bot/internal/common/journal"
+	"trading_bot/internal/entity"
+
+	"github.com/shopspring/decimal"
+)
+
+// Kinds of the discrepancies
+const (
+	// the journal has fills of the hedge the trading system doesn't have
+	KindMissing = "missing"
+	// the hedge is executed by several orders
+	KindDuplicated = "duplicated"
+	// the trading system filled more than the journal has
+	KindOverFilled = "over_filled"
+	// the fill-or-kill hedge is open in the trading system
+	KindOpen = "open"
+)
+
+// Discrepancy between the journaled hedge and the trading system
+type Discrepancy struct {
+	Kind  string
+	Hedge *journal.HedgeRecord
+	// orders of the trading system matched to the hedge
+	OrderNumbers  []string
+	JournalAmount decimal.Decimal
+	VenueAmount   decimal.Decimal
+}
+
+// Key identifies the discrepancy, it's the same on every reconciliation
+func (d *Discrepancy) Key() string {
+	return fmt.Sprintf("%v:%v:%v:%v", d.Kind, d.Hedge.InternalOrderId, d.Hedge.ClientOrderId, strings.Join(d.OrderNumbers, ","))
+}
+
+func (d *Discrepancy) String() string {
+	return fmt.Sprintf("%v hedge of internal order %v (client order id %v, time %v) : journal filled %v, trading system filled %v, orders %v",
+		d.Kind, d.Hedge.InternalOrderId, d.Hedge.ClientOrderId, d.Hedge.Time.Format("2006-01-02 15:04:05"), d.JournalAmount, d.VenueAmount, d.OrderNumbers)
+}
+
+// Reconcile matches the hedges with the trades and open orders of the trading system. Orders are matched by the order
+// number of the execution report or by the client order id of any of priceSteps attempts of the hedge.
+// Trades of the hedges have to be in trades, orders not matched to any hedge are ignored.
+func Reconcile(hedges []*journal.HedgeRecord, trades []*entity.VenueTrade, openOrders []*entity.VenueOrder, priceSteps int) []*Discrepancy {
+	var filled = make(map[string]decimal.Decimal)
+	var clientOrderIds = make(map[string]string)
+	for _, trade := range trades {
+		filled[trade.OrderNumber] = filled[trade.OrderNumber].Add(trade.Amount)
+		if len(trade.ClientOrderId) > 0 && trade.ClientOrderId != "0" {
+			clientOrderIds[trade.ClientOrderId] = trade.OrderNumber
+		}
+	}
+	var open = make(map[string]bool)
+	for _, order := range openOrders {
+		open[order.OrderNumber] = true
+		if len(order.ClientOrderId) > 0 && order.ClientOrderId != "0" {
+			clientOrderIds[order.ClientOrderId] = order.OrderNumber
+		}
+	}
+
+	var res []*Discrepancy
+	for _, hedge := range hedges {
+		var orderNumbers = matchOrders(hedge, clientOrderIds, priceSteps)
+
+		var discrepancy = &Discrepancy{Hedge: hedge, OrderNumbers: orderNumbers, JournalAmount: decimal.Zero, VenueAmount: decimal.Zero}
+		if hedge.Report != nil {
+			discrepancy.JournalAmount = hedge.Report.FilledAmount
+		}
+
+		var isOpen = false
+		var filledOrders = 0
+		for _, orderNumber := range orderNumbers {
+			if open[orderNumber] {
+				isOpen = true
+			}
+			if amount, found := filled[orderNumber]; found && amount.IsPositive() {
+				discrepancy.VenueAmount = discrepancy.VenueAmount.Add(amount)
+				filledOrders++
+			}
+		}
+
+		switch {
+		case isOpen:
+			discrepancy.Kind = KindOpen
+		case filledOrders > 1:
+			discrepancy.Kind = KindDuplicated
+		case discrepancy.VenueAmount.GreaterThan(discrepancy.JournalAmount):
+			discrepancy.Kind = KindOverFilled
+		case discrepancy.VenueAmount.LessThan(discrepancy.JournalAmount):
+			discrepancy.Kind = KindMissing
+		default:
+			continue
+		}
+		res = append(res, discrepancy)
+	}
+
+	return res
+}
+
+// matchOrders returns sorted order numbers of the hedge
+func matchOrders(hedge *journal.HedgeRecord, clientOrderIds map[string]string, priceSteps int) []string {
+	var orderNumbers = make(map[string]bool)
+	if hedge.Report != nil && len(hedge.Report.OrderNumber) > 0 {
+		orderNumbers[hedge.Report.OrderNumber] = true
+	}
+
+	// venues keep the attempt id or its number
+	if len(hedge.ClientOrderId) > 0 {
+		for step := 0; step < priceSteps; step++ {
+			var attempt = clientid.Attempt(hedge.ClientOrderId, step)
+			for _, id := range []string{attempt, clientid.Numeric(attempt)} {
+				if orderNumber, found := clientOrderIds[id]; found {
+					orderNumbers[orderNumber] = true
+				}
+			}
+		}
+	}
+
+	var res = make([]string, 0, len(orderNumbers))
+	for orderNumber := range orderNumbers {
+		res = append(res, orderNumber)
+	}
+	sort.Strings(res)
+
+	return res
+}
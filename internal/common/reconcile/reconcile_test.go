@@ -0,0 +1,95 @@
+package reconcile
+
+import (
+	"testing"
+	"trading_bot/internal/common/clientid"
+	"trading_bot/internal/common/journal"
+	"trading_bot/internal/entity"
+
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func hedge(orderNumber string, clientOrderId string, filled float64) *journal.HedgeRecord {
+	var record = &journal.HedgeRecord{InternalOrderId: uuid.Must(uuid.NewV4()), ClientOrderId: clientOrderId}
+	if len(orderNumber) > 0 {
+		record.Report = entity.NewExecutionReport("Poloniex", "USDC_BTC", false, decimal.NewFromFloat(filled))
+		record.Report.OrderNumber = orderNumber
+		record.Report.FilledAmount = decimal.NewFromFloat(filled)
+	}
+	return record
+}
+
+func trade(orderNumber string, clientOrderId string, amount float64) *entity.VenueTrade {
+	return &entity.VenueTrade{OrderNumber: orderNumber, ClientOrderId: clientOrderId, Amount: decimal.NewFromFloat(amount)}
+}
+
+func TestReconcile_Success(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name   string
+		hedge  *journal.HedgeRecord
+		trades []*entity.VenueTrade
+		open   []*entity.VenueOrder
+		want   string
+	}{
+		{
+			name:   "matched",
+			hedge:  hedge("101", "h1", 0.1),
+			trades: []*entity.VenueTrade{trade("101", clientid.Numeric("h1-0"), 0.04), trade("101", clientid.Numeric("h1-0"), 0.06)},
+		},
+		{
+			name:  "missing",
+			hedge: hedge("101", "h1", 0.1),
+			want:  KindMissing,
+		},
+		{
+			name:   "duplicated",
+			hedge:  hedge("101", "h1", 0.1),
+			trades: []*entity.VenueTrade{trade("101", clientid.Numeric("h1-0"), 0.1), trade("102", clientid.Numeric("h1-1"), 0.1)},
+			want:   KindDuplicated,
+		},
+		{
+			name:   "over-filled failed hedge",
+			hedge:  hedge("", "h1", 0),
+			trades: []*entity.VenueTrade{trade("102", "h1-2", 0.1)},
+			want:   KindOverFilled,
+		},
+		{
+			name:  "open",
+			hedge: hedge("", "h1", 0),
+			open:  []*entity.VenueOrder{{OrderNumber: "103", ClientOrderId: clientid.Numeric("h1-0")}},
+			want:  KindOpen,
+		},
+		{
+			name:   "other orders",
+			hedge:  hedge("", "h1", 0),
+			trades: []*entity.VenueTrade{trade("104", clientid.Numeric("h2-0"), 0.1), trade("105", "", 0.1)},
+		},
+	}
+
+	for _, tt := range tests {
+		var got = Reconcile([]*journal.HedgeRecord{tt.hedge}, tt.trades, tt.open, 20)
+
+		if len(tt.want) == 0 && len(got) != 0 {
+			t.Errorf("%v : got %v, wanted no discrepancies", tt.name, got[0])
+		}
+		if len(tt.want) > 0 && (len(got) != 1 || got[0].Kind != tt.want) {
+			t.Errorf("%v : got %v discrepancies, wanted %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiscrepancy_Key_Success(t *testing.T) {
+	t.Parallel()
+
+	var record = hedge("101", "h1", 0.1)
+
+	var first = Reconcile([]*journal.HedgeRecord{record}, nil, nil, 1)
+	var second = Reconcile([]*journal.HedgeRecord{record}, nil, nil, 1)
+
+	if len(first) != 1 || len(second) != 1 || first[0].Key() != second[0].Key() {
+		t.Errorf("got %v and %v, wanted the same discrepancy", first, second)
+	}
+}
@@ -0,0 +1,872 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"trading_bot/config"
+	"trading_bot/internal/common"
+	"trading_bot/internal/common/clientid"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/orderbook"
+	"trading_bot/internal/common/ratelimit"
+	"trading_bot/internal/common/retry"
+	"trading_bot/internal/common/signer"
+	"trading_bot/internal/entity"
+	"trading_bot/pkg/logger"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// order statuses
+	statusFilled          = "FILLED"
+	statusPartiallyFilled = "PARTIALLY_FILLED"
+	statusExpired         = "EXPIRED"
+
+	// deposit statuses: credited, credited but can't be withdrawn yet, rejected, wrong deposit
+	depositSuccess        = 1
+	depositCreditedLocked = 6
+	depositRejected       = 2
+	depositWrong          = 7
+	// withdrawal statuses: cancelled, rejected, failure, completed
+	withdrawalCancelled = 1
+	withdrawalRejected  = 3
+	withdrawalFailure   = 5
+	withdrawalCompleted = 6
+	// applyTime of the withdrawal history, UTC
+	withdrawalTimeLayout = "2006-01-02 15:04:05"
+
+	systemName = "Binance"
+
+	// fees are requested again after the hour
+	feeCacheTime = 1 * time.Hour
+	// trading rules of the hedge orders are requested again after the hour
+	marketCacheTime = 1 * time.Hour
+)
+
+// fees of the hedges when the fees of the account are unknown, regular user without BNB discount
+var defaultFees = entity.FeeSchedule{MakerFee: decimal.NewFromFloat(0.001), TakerFee: decimal.NewFromFloat(0.001)}
+
+// request weight limit is 6000 per minute shared by market data and account endpoints,
+// orders are limited to 100 per 10 seconds
+var defaultRateLimits = config.RateLimitSettings{
+	Public:  config.RateLimit{RequestsPerSecond: 40, Burst: 100},
+	Private: config.RateLimit{RequestsPerSecond: 40, Burst: 100},
+	Order:   config.RateLimit{RequestsPerSecond: 10, Burst: 10},
+	Weights: map[string]int{
+		"/api/v3/depth":                    5,
+		"/api/v3/account":                  20,
+		"/sapi/v1/capital/deposit/address": 10,
+		"/sapi/v1/asset/tradeFee":          1,
+		"/api/v3/exchangeInfo":             20,
+	},
+}
+
+type BinanceRequests struct {
+	logger        logger.ILogger
+	helperMethods common.IHelperMethods
+	cacheUpdate   time.Time
+	balanceCache  map[string]*entity.BalanceObject
+	feeCache      *entity.FeeSchedule
+	marketCache   *entity.MarketInfo
+	baseUrl       string
+	publicKey     string
+	signer        signer.Signer
+	timeInForce   string
+	retryPolicy   retry.Policy
+	rateLimiter   *ratelimit.Limiter
+}
+
+func New(l logger.ILogger, hm common.IHelperMethods, cs config.TradingSettings) *BinanceRequests {
+	var timeInForce = strings.ToUpper(cs.TimeInForce)
+	if len(timeInForce) == 0 {
+		timeInForce = "FOK"
+	}
+
+	return &BinanceRequests{
+		logger:        l,
+		helperMethods: hm,
+		cacheUpdate:   time.Time{},
+		balanceCache:  make(map[string]*entity.BalanceObject),
+		baseUrl:       cs.Url,
+		publicKey:     cs.Key.Reveal(),
+		signer:        signer.Resolve(cs.Signer, signer.TypeHmacSha256Query, cs.Secret.Reveal()),
+		timeInForce:   timeInForce,
+		retryPolicy:   retry.NewPolicy(cs.Retry),
+		rateLimiter:   ratelimit.Shared(systemName, cs.Key.Reveal(), cs.RateLimit, defaultRateLimits),
+	}
+}
+
+func (br *BinanceRequests) GetTradingBalances(ctx context.Context) (map[string]*entity.BalanceObject, error) {
+	// 1 minute cache
+	if br.cacheUpdate.Add(1 * time.Minute).After(time.Now().UTC()) {
+		return br.balanceCache, nil
+	}
+
+	var requestData map[string]string = make(map[string]string)
+	requestData["omitZeroBalances"] = "true"
+
+	var balancesStr, err = br.queryPrivate(ctx, "get", "/api/v3/account", requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	account := struct {
+		Balances []struct {
+			Asset  string          `json:"asset"`
+			Free   decimal.Decimal `json:"free"`
+			Locked decimal.Decimal `json:"locked"`
+		} `json:"balances"`
+	}{}
+
+	err = json.Unmarshal([]byte(balancesStr), &account)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/api/v3/account", 200, balancesStr)
+	}
+
+	var res = make(map[string]*entity.BalanceObject)
+
+	for _, val := range account.Balances {
+		res[val.Asset] = &entity.BalanceObject{
+			Currency: val.Asset,
+			Balance:  val.Free,
+			Reserved: val.Locked,
+		}
+	}
+
+	br.balanceCache = res
+	br.cacheUpdate = time.Now().UTC()
+
+	return res, nil
+}
+
+func (ord *orderItem) UnmarshalJSON(data []byte) error {
+	var v []string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v) < 2 {
+		return fmt.Errorf("binance : unexpected order book level %s", data)
+	}
+	ord.Price, _ = decimal.NewFromString(v[0])
+	ord.Volume, _ = decimal.NewFromString(v[1])
+
+	return nil
+}
+
+// InvalidateBalances drops the cached balances, the next GetTradingBalances requests them
+func (br *BinanceRequests) InvalidateBalances() {
+	br.cacheUpdate = time.Time{}
+}
+
+func (br *BinanceRequests) GetPublicTradingOrders(ctx context.Context, tradingSystemPair string, usdcTradingLimit decimal.Decimal, cryptoTradingLimit decimal.Decimal, internalCryptoBalance decimal.Decimal, internalUsdcBalance decimal.Decimal, pairMinAmount decimal.Decimal) ([]*entity.TradingOrder, error) {
+	var requestData map[string]string = make(map[string]string)
+	requestData["symbol"] = tradingSystemPair
+	requestData["limit"] = "20"
+
+	var tradingOrders, err = br.queryPublic(ctx, "/api/v3/depth", requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := struct {
+		Asks []orderItem `json:"asks"`
+		Bids []orderItem `json:"bids"`
+	}{}
+
+	err = json.Unmarshal([]byte(tradingOrders), &orders)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/api/v3/depth", 200, tradingOrders)
+	}
+
+	return orderbook.SelectTradingOrders(toLevels(orders.Asks), toLevels(orders.Bids), usdcTradingLimit, cryptoTradingLimit, internalCryptoBalance, internalUsdcBalance, pairMinAmount), nil
+}
+
+func (br *BinanceRequests) Buy(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string, clientOrderId string) (*entity.ExecutionReport, error) {
+	var rules = br.orderRules(ctx, tradingSystemPair)
+	// taker fee of the account fees (GetFees) is taken from the bought currency, the amount is rounded up to the lot size
+	var requiredAmount = rules.RoundAmount(br.hedgeFees(ctx, tradingSystemPair).GrossAmount(amount), true)
+	var orderPrice = rules.RoundPrice(tradingSystemPrice, true)
+	var report = entity.NewExecutionReport(systemName, tradingSystemPair, false, requiredAmount)
+	var attempts = br.retryPolicy.Start(ctx)
+	var priceStep = 0
+
+	for {
+		var orderId = clientid.Attempt(clientOrderId, priceStep)
+		if err := rules.Validate(orderPrice, requiredAmount); err != nil {
+			return nil, common.NewRequestError(common.ErrRejected, systemName, "buy", 0, err.Error())
+		}
+		var order, err = br.placeOrder(ctx, "BUY", tradingSystemPair, orderPrice, requiredAmount, orderId)
+		if common.IsAmbiguous(err) && len(orderId) > 0 {
+			// the order may be executed, it's looked up before it's placed again
+			var found, lookupErr = br.lookupOrder(ctx, attempts, tradingSystemPair, orderId, err)
+			if lookupErr != nil {
+				return nil, lookupErr
+			}
+			if found != nil {
+				order, err = found, nil
+			}
+		}
+		if common.IsRetryable(err) {
+			if waitErr := attempts.Wait(err); waitErr != nil {
+				return nil, waitErr
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if order.Status == statusExpired {
+			var killedErr = common.NewRequestError(common.ErrOrderNotFilled, systemName, "buy", 0, "order expired at price "+orderPrice.String())
+			// rising price
+			orderPrice = rules.RoundPrice(br.retryPolicy.StepUp(orderPrice), true)
+			priceStep++
+			if orderPrice.GreaterThan(internalPrice) {
+				return report, common.NewRequestError(common.ErrOrderNotFilled, systemName, "buy", 0, fmt.Sprintf("max price reached : %v, amount : %v, pair : %v", internalPrice, requiredAmount, internalPair))
+			}
+			if nextErr := attempts.Next(killedErr); nextErr != nil {
+				return report, nextErr
+			}
+			continue
+		}
+
+		order.fillReport(report)
+		br.logger.Info("Binance : buy order %v filled %v at average price %v, fee %v %v", report.OrderNumber, report.FilledAmount, report.AveragePrice, report.Fee, report.FeeCurrency)
+
+		var resultedAmount = order.ExecutedQty.RoundDown(8)
+		//check if executed amount different from requiredAmount
+		if resultedAmount.LessThan(requiredAmount) {
+			report.Status = entity.ExecutionStatusPartiallyFilled
+			return report, common.NewRequestError(common.ErrOrderNotFilled, systemName, "buy", 0, fmt.Sprintf("resulted amount : %v, is lower than Required Amount : %v", resultedAmount, requiredAmount))
+		}
+		report.Status = entity.ExecutionStatusFilled
+		return report, nil
+	}
+}
+
+func (br *BinanceRequests) Sell(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string, clientOrderId string) (*entity.ExecutionReport, error) {
+	var rules = br.orderRules(ctx, tradingSystemPair)
+	// taker fee is taken from the received quote currency, it's covered by the internal buy price
+	var requiredAmount = rules.RoundAmount(amount, false)
+	var orderPrice = rules.RoundPrice(tradingSystemPrice, false)
+	var report = entity.NewExecutionReport(systemName, tradingSystemPair, true, requiredAmount)
+	var attempts = br.retryPolicy.Start(ctx)
+	var priceStep = 0
+
+	for {
+		var orderId = clientid.Attempt(clientOrderId, priceStep)
+		if err := rules.Validate(orderPrice, requiredAmount); err != nil {
+			return nil, common.NewRequestError(common.ErrRejected, systemName, "sell", 0, err.Error())
+		}
+		var order, err = br.placeOrder(ctx, "SELL", tradingSystemPair, orderPrice, requiredAmount, orderId)
+		if common.IsAmbiguous(err) && len(orderId) > 0 {
+			// the order may be executed, it's looked up before it's placed again
+			var found, lookupErr = br.lookupOrder(ctx, attempts, tradingSystemPair, orderId, err)
+			if lookupErr != nil {
+				return nil, lookupErr
+			}
+			if found != nil {
+				order, err = found, nil
+			}
+		}
+		if common.IsRetryable(err) {
+			if waitErr := attempts.Wait(err); waitErr != nil {
+				return nil, waitErr
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if order.Status == statusExpired {
+			var killedErr = common.NewRequestError(common.ErrOrderNotFilled, systemName, "sell", 0, "order expired at price "+orderPrice.String())
+			// lowering price
+			orderPrice = rules.RoundPrice(br.retryPolicy.StepDown(orderPrice), false)
+			priceStep++
+			if orderPrice.LessThan(internalPrice) {
+				return report, common.NewRequestError(common.ErrOrderNotFilled, systemName, "sell", 0, fmt.Sprintf("max price reached : %v, amount : %v, pair : %v", internalPrice, requiredAmount, internalPair))
+			}
+			if nextErr := attempts.Next(killedErr); nextErr != nil {
+				return report, nextErr
+			}
+			continue
+		}
+
+		order.fillReport(report)
+		br.logger.Info("Binance : sell order %v filled %v at average price %v, fee %v %v", report.OrderNumber, report.FilledAmount, report.AveragePrice, report.Fee, report.FeeCurrency)
+
+		var resultedAmount = order.ExecutedQty.RoundDown(8)
+		//check if executed amount different from requiredAmount
+		if resultedAmount.LessThan(requiredAmount) {
+			report.Status = entity.ExecutionStatusPartiallyFilled
+			return report, common.NewRequestError(common.ErrOrderNotFilled, systemName, "sell", 0, fmt.Sprintf("resulted amount : %v, is lower than Required Amount : %v", resultedAmount, requiredAmount))
+		}
+		report.Status = entity.ExecutionStatusFilled
+		return report, nil
+	}
+}
+
+// GetFees returns maker and taker fees of the account for the symbol, fees are cached for an hour.
+// Cached fees are returned when they can't be refreshed.
+func (br *BinanceRequests) GetFees(ctx context.Context, tradingSystemPair string) (*entity.FeeSchedule, error) {
+	if br.feeCache != nil && br.feeCache.Updated.Add(feeCacheTime).After(time.Now().UTC()) {
+		return br.feeCache, nil
+	}
+
+	var requestData map[string]string = make(map[string]string)
+	requestData["symbol"] = tradingSystemPair
+
+	var method = "/sapi/v1/asset/tradeFee"
+	var feeResponse, err = br.queryPrivate(ctx, "get", method, requestData)
+	if err != nil {
+		if br.feeCache != nil {
+			br.logger.Error("Binance : can't refresh fees, cached fees are used : %v", err)
+			return br.feeCache, nil
+		}
+		return nil, err
+	}
+
+	var fees []struct {
+		Symbol          string          `json:"symbol"`
+		MakerCommission decimal.Decimal `json:"makerCommission"`
+		TakerCommission decimal.Decimal `json:"takerCommission"`
+	}
+	err = json.Unmarshal([]byte(feeResponse), &fees)
+	if err != nil || len(fees) != 1 || fees[0].TakerCommission.IsNegative() || fees[0].TakerCommission.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, method, 200, feeResponse)
+	}
+
+	if br.feeCache == nil || !br.feeCache.TakerFee.Equal(fees[0].TakerCommission) || !br.feeCache.MakerFee.Equal(fees[0].MakerCommission) {
+		br.logger.Info("Binance : fees of %v are maker %v, taker %v", fees[0].Symbol, fees[0].MakerCommission, fees[0].TakerCommission)
+	}
+	br.feeCache = &entity.FeeSchedule{MakerFee: fees[0].MakerCommission, TakerFee: fees[0].TakerCommission, Updated: time.Now().UTC()}
+
+	return br.feeCache, nil
+}
+
+// GetMarketInfo requests the filters of the symbol, the rules are kept for the hedge orders
+func (br *BinanceRequests) GetMarketInfo(ctx context.Context, tradingSystemPair string) (*entity.MarketInfo, error) {
+	var requestData map[string]string = make(map[string]string)
+	requestData["symbol"] = tradingSystemPair
+
+	var method = "/api/v3/exchangeInfo"
+	var infoResponse, err = br.queryPublic(ctx, method, requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	var exchangeInfo struct {
+		Symbols []struct {
+			Symbol  string `json:"symbol"`
+			Filters []struct {
+				FilterType  string          `json:"filterType"`
+				TickSize    decimal.Decimal `json:"tickSize"`
+				StepSize    decimal.Decimal `json:"stepSize"`
+				MinQty      decimal.Decimal `json:"minQty"`
+				MaxQty      decimal.Decimal `json:"maxQty"`
+				MinNotional decimal.Decimal `json:"minNotional"`
+			} `json:"filters"`
+		} `json:"symbols"`
+	}
+	err = json.Unmarshal([]byte(infoResponse), &exchangeInfo)
+	if err != nil || len(exchangeInfo.Symbols) != 1 {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, method, 200, infoResponse)
+	}
+
+	var info = &entity.MarketInfo{Pair: tradingSystemPair, Updated: time.Now().UTC()}
+	for _, filter := range exchangeInfo.Symbols[0].Filters {
+		switch filter.FilterType {
+		case "PRICE_FILTER":
+			info.PriceStep = filter.TickSize
+		case "LOT_SIZE":
+			info.AmountStep = filter.StepSize
+			info.MinAmount = filter.MinQty
+			info.MaxAmount = filter.MaxQty
+		case "NOTIONAL", "MIN_NOTIONAL":
+			info.MinNotional = filter.MinNotional
+		}
+	}
+	br.marketCache = info
+
+	return info, nil
+}
+
+// orderRules returns the trading rules of the hedge order, the order is checked by the trading system only
+// when the rules are unknown
+func (br *BinanceRequests) orderRules(ctx context.Context, tradingSystemPair string) *entity.MarketInfo {
+	var cached = br.marketCache
+	if cached != nil && cached.Pair == tradingSystemPair && cached.Updated.Add(marketCacheTime).After(time.Now().UTC()) {
+		return cached
+	}
+
+	var rules, err = br.GetMarketInfo(ctx, tradingSystemPair)
+	if err != nil {
+		if cached != nil && cached.Pair == tradingSystemPair {
+			br.logger.Error("Binance : can't refresh market info, cached rules are used : %v", err)
+			return cached
+		}
+		br.logger.Error("Binance : can't get market info, the order is not normalised : %v", err)
+		return &entity.MarketInfo{Pair: tradingSystemPair}
+	}
+	return rules
+}
+
+// hedgeFees returns fees of the hedge order, the default fees are used when the fees of the account are unknown
+func (br *BinanceRequests) hedgeFees(ctx context.Context, tradingSystemPair string) *entity.FeeSchedule {
+	var fees, err = br.GetFees(ctx, tradingSystemPair)
+	if err != nil {
+		br.logger.Error("Binance : can't get fees, default taker fee %v is used : %v", defaultFees.TakerFee, err)
+		return &defaultFees
+	}
+	return fees
+}
+
+// Withdraw returns id of the withdrawal
+func (br *BinanceRequests) Withdraw(ctx context.Context, addr string, withdrawalAmount decimal.Decimal, currency string, tradingSystemWithdrawalNetwork string) (string, error) {
+	// make request object
+	var requestData map[string]string = make(map[string]string)
+	requestData["coin"] = currency
+	requestData["address"] = addr
+	requestData["amount"] = withdrawalAmount.String()
+	if len(tradingSystemWithdrawalNetwork) > 0 {
+		requestData["network"] = tradingSystemWithdrawalNetwork
+	}
+
+	br.logger.Info("Binance : withdraw request is : %v", requestData)
+	var paymentResponse, err = br.queryPrivate(ctx, "post", "/sapi/v1/capital/withdraw/apply", requestData)
+	if err != nil {
+		return "", err
+	}
+
+	result := struct {
+		Id string `json:"id"`
+	}{}
+	err = json.Unmarshal([]byte(paymentResponse), &result)
+	if err != nil || len(result.Id) == 0 {
+		return "", common.NewRequestError(common.ErrInvalidResponse, systemName, "withdraw", 200, paymentResponse)
+	}
+
+	return result.Id, nil
+}
+
+// GetTransfers returns deposits and withdrawals of the coin between start and end
+func (br *BinanceRequests) GetTransfers(ctx context.Context, currency string, start time.Time, end time.Time) ([]*entity.VenueTransfer, error) {
+	var requestData map[string]string = make(map[string]string)
+	requestData["coin"] = currency
+	requestData["startTime"] = strconv.FormatInt(start.UnixMilli(), 10)
+	requestData["endTime"] = strconv.FormatInt(end.UnixMilli(), 10)
+
+	var depositResponse, err = br.queryPrivate(ctx, "get", "/sapi/v1/capital/deposit/hisrec", requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	var deposits []struct {
+		Id           string          `json:"id"`
+		Amount       decimal.Decimal `json:"amount"`
+		Coin         string          `json:"coin"`
+		Status       int             `json:"status"`
+		Address      string          `json:"address"`
+		TxId         string          `json:"txId"`
+		InsertTime   int64           `json:"insertTime"`
+		ConfirmTimes string          `json:"confirmTimes"`
+	}
+	err = json.Unmarshal([]byte(depositResponse), &deposits)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/sapi/v1/capital/deposit/hisrec", 200, depositResponse)
+	}
+
+	withdrawalResponse, err := br.queryPrivate(ctx, "get", "/sapi/v1/capital/withdraw/history", requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	var withdrawals []struct {
+		Id             string          `json:"id"`
+		Amount         decimal.Decimal `json:"amount"`
+		TransactionFee decimal.Decimal `json:"transactionFee"`
+		Coin           string          `json:"coin"`
+		Status         int             `json:"status"`
+		Address        string          `json:"address"`
+		TxId           string          `json:"txId"`
+		ApplyTime      string          `json:"applyTime"`
+		ConfirmNo      int             `json:"confirmNo"`
+	}
+	err = json.Unmarshal([]byte(withdrawalResponse), &withdrawals)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/sapi/v1/capital/withdraw/history", 200, withdrawalResponse)
+	}
+
+	var res = make([]*entity.VenueTransfer, 0, len(deposits)+len(withdrawals))
+	for _, item := range deposits {
+		var status = entity.TransferStatusPending
+		switch item.Status {
+		case depositSuccess, depositCreditedLocked:
+			status = entity.TransferStatusComplete
+		case depositRejected, depositWrong:
+			status = entity.TransferStatusFailed
+		}
+		// confirmTimes is "confirmations/required"
+		var confirmations, _ = strconv.Atoi(strings.SplitN(item.ConfirmTimes, "/", 2)[0])
+
+		res = append(res, &entity.VenueTransfer{
+			Id:            item.Id,
+			Currency:      item.Coin,
+			Address:       item.Address,
+			Amount:        item.Amount,
+			TxId:          item.TxId,
+			Confirmations: confirmations,
+			Status:        status,
+			IsDeposit:     true,
+			Date:          time.UnixMilli(item.InsertTime).UTC(),
+		})
+	}
+	for _, item := range withdrawals {
+		var status = entity.TransferStatusPending
+		switch item.Status {
+		case withdrawalCompleted:
+			status = entity.TransferStatusComplete
+		case withdrawalCancelled, withdrawalRejected, withdrawalFailure:
+			status = entity.TransferStatusFailed
+		}
+		var date, _ = time.Parse(withdrawalTimeLayout, item.ApplyTime)
+
+		res = append(res, &entity.VenueTransfer{
+			Id:            item.Id,
+			Currency:      item.Coin,
+			Address:       item.Address,
+			Amount:        item.Amount,
+			Fee:           item.TransactionFee,
+			TxId:          item.TxId,
+			Confirmations: item.ConfirmNo,
+			Status:        status,
+			Date:          date,
+		})
+	}
+
+	return res, nil
+}
+
+func (br *BinanceRequests) GetCryptoAddress(ctx context.Context, currency string, tradingSystemWithdrawalNetwork string) (string, error) {
+	var requestData map[string]string = make(map[string]string)
+	requestData["coin"] = currency
+	if len(tradingSystemWithdrawalNetwork) > 0 {
+		requestData["network"] = tradingSystemWithdrawalNetwork
+	}
+
+	var addressResponse, err = br.queryPrivate(ctx, "get", "/sapi/v1/capital/deposit/address", requestData)
+	if err != nil {
+		return "", err
+	}
+
+	result := struct {
+		Address string `json:"address"`
+	}{}
+	err = json.Unmarshal([]byte(addressResponse), &result)
+	if err != nil {
+		return "", common.NewRequestError(common.ErrInvalidResponse, systemName, "/sapi/v1/capital/deposit/address", 200, addressResponse)
+	}
+	if len(result.Address) == 0 {
+		return "", common.NewRequestError(common.ErrNotFound, systemName, "/sapi/v1/capital/deposit/address", 200, "no deposit address for "+currency)
+	}
+
+	return result.Address, nil
+}
+
+// placeOrder sends a signed limit order and returns the order result
+func (br *BinanceRequests) placeOrder(ctx context.Context, side string, tradingSystemPair string, price decimal.Decimal, quantity decimal.Decimal, clientOrderId string) (*orderResult, error) {
+	// make request object
+	var requestData map[string]string = make(map[string]string)
+	requestData["symbol"] = tradingSystemPair
+	requestData["side"] = side
+	requestData["type"] = "LIMIT"
+	requestData["timeInForce"] = br.timeInForce
+	requestData["price"] = price.String()
+	requestData["quantity"] = quantity.String()
+	requestData["newOrderRespType"] = "FULL"
+	if len(clientOrderId) > 0 {
+		requestData["newClientOrderId"] = clientOrderId
+	}
+
+	var orderResponse, err = br.queryPrivate(ctx, "post", "/api/v3/order", requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	br.logger.Info("Binance : %v response is : %v", strings.ToLower(side), orderResponse)
+
+	var order = &orderResult{}
+	err = json.Unmarshal([]byte(orderResponse), order)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/api/v3/order", 200, orderResponse)
+	}
+
+	if order.Status != statusFilled && order.Status != statusPartiallyFilled && order.Status != statusExpired {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/api/v3/order", 200, "unexpected order status : "+order.Status)
+	}
+
+	return order, nil
+}
+
+// lookupOrder looks the order up by the client order id after the ambiguous placeErr, lookups are retried with
+// the attempts of the order. Nil order is returned when the order was not placed.
+func (br *BinanceRequests) lookupOrder(ctx context.Context, attempts *retry.Attempts, tradingSystemPair string, clientOrderId string, placeErr error) (*orderResult, error) {
+	br.logger.Warn("Binance : order %v outcome is unknown, looking it up : %v", clientOrderId, placeErr)
+
+	var lastErr = placeErr
+	for {
+		if waitErr := attempts.Wait(lastErr); waitErr != nil {
+			return nil, waitErr
+		}
+
+		var order, err = br.findOrder(ctx, tradingSystemPair, clientOrderId)
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, nil
+		}
+		if err == nil {
+			br.logger.Info("Binance : order %v is found : %v, status : %v", clientOrderId, order.OrderId, order.Status)
+			return order, nil
+		}
+		if !common.IsRetryable(err) && !common.IsAmbiguous(err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+}
+
+// findOrder returns the final order found by the client order id with its fills taken from the account trades
+func (br *BinanceRequests) findOrder(ctx context.Context, tradingSystemPair string, clientOrderId string) (*orderResult, error) {
+	// make request object
+	var requestData map[string]string = make(map[string]string)
+	requestData["symbol"] = tradingSystemPair
+	requestData["origClientOrderId"] = clientOrderId
+
+	var orderResponse, err = br.queryPrivate(ctx, "get", "/api/v3/order", requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	var order = &orderResult{}
+	err = json.Unmarshal([]byte(orderResponse), order)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/api/v3/order", 200, orderResponse)
+	}
+	if order.Status != statusFilled && order.Status != statusPartiallyFilled && order.Status != statusExpired {
+		return nil, common.NewRequestError(common.ErrTransientNetwork, systemName, "/api/v3/order", 200, "order "+clientOrderId+" is not final, status : "+order.Status)
+	}
+	if order.ExecutedQty.IsZero() {
+		return order, nil
+	}
+
+	requestData = make(map[string]string)
+	requestData["symbol"] = tradingSystemPair
+	requestData["orderId"] = strconv.FormatInt(order.OrderId, 10)
+
+	var tradesResponse string
+	tradesResponse, err = br.queryPrivate(ctx, "get", "/api/v3/myTrades", requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	var trades []struct {
+		Id              int64           `json:"id"`
+		Price           decimal.Decimal `json:"price"`
+		Qty             decimal.Decimal `json:"qty"`
+		Commission      decimal.Decimal `json:"commission"`
+		CommissionAsset string          `json:"commissionAsset"`
+	}
+	err = json.Unmarshal([]byte(tradesResponse), &trades)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/api/v3/myTrades", 200, tradesResponse)
+	}
+	for _, trade := range trades {
+		order.Fills = append(order.Fills, orderFill{
+			Price:           trade.Price,
+			Qty:             trade.Qty,
+			Commission:      trade.Commission,
+			CommissionAsset: trade.CommissionAsset,
+			TradeId:         trade.Id,
+		})
+	}
+
+	return order, nil
+}
+
+func (br *BinanceRequests) queryPublic(ctx context.Context, method string, requestData map[string]string) (string, error) {
+	ctx = helpermethods.WithDefaultEndpoint(ctx, method)
+
+	u, err := url.Parse(br.baseUrl + method)
+	if err != nil {
+		return "", common.WrapRequestError(err, systemName, method, 0, "")
+	}
+
+	q := u.Query()
+	if len(requestData) > 0 {
+		for key, val := range requestData {
+			q.Set(key, val)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	if err := br.rateLimiter.Wait(ctx, ratelimit.ClassPublic, method); err != nil {
+		return "", common.WrapRequestError(err, systemName, method, 0, "")
+	}
+
+	var resText, statusCode, err1 = br.helperMethods.HttpGet(ctx, u, "", map[string]string{})
+
+	if err1 != nil || statusCode != 200 {
+		br.logger.Error("Binance : response status : %v, %v : %v", statusCode, err1, resText)
+		return "", newRequestError(err1, method, statusCode, resText)
+	}
+
+	return resText, nil
+}
+
+func (br *BinanceRequests) queryPrivate(ctx context.Context, requestType string, method string, requestData map[string]string) (string, error) {
+	ctx = helpermethods.WithDefaultEndpoint(ctx, method)
+
+	u, err := url.Parse(br.baseUrl + method)
+	if err != nil {
+		return "", common.WrapRequestError(err, systemName, method, 0, "")
+	}
+
+	q := u.Query()
+	if len(requestData) > 0 {
+		for key, val := range requestData {
+			q.Set(key, val)
+		}
+	}
+	// wait before signing, timestamp must be within recvWindow
+	var class = ratelimit.ClassPrivate
+	if method == "/api/v3/order" {
+		class = ratelimit.ClassOrder
+	}
+	if err := br.rateLimiter.Wait(ctx, class, method); err != nil {
+		return "", common.WrapRequestError(err, systemName, method, 0, "")
+	}
+
+	q.Set("recvWindow", "5000")
+	q.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+
+	u.RawQuery = q.Encode()
+
+	var req = &signer.Request{
+		Method:  strings.ToUpper(requestType),
+		Url:     u,
+		Headers: map[string]string{"X-MBX-APIKEY": br.publicKey},
+	}
+	if err := br.signer.Sign(req); err != nil {
+		return "", common.NewRequestError(common.ErrAuthFailure, systemName, method, 0, err.Error())
+	}
+	var webHeaders = req.Headers
+
+	var statusCode = 500
+	var resText = ""
+	var err1 error
+	if requestType == "get" {
+		resText, statusCode, err1 = br.helperMethods.HttpGet(ctx, u, "", webHeaders)
+	} else {
+		resText, statusCode, err1 = br.helperMethods.HttpPost(ctx, u, nil, "application/x-www-form-urlencoded", webHeaders)
+	}
+
+	if err1 != nil || statusCode != 200 {
+		br.logger.Error("Binance : response status : %v, %v : %v", statusCode, err1, resText)
+		return "", newRequestError(err1, method, statusCode, resText)
+	}
+
+	return resText, nil
+}
+
+// newRequestError classifies Binance error response {"code":-2010,"msg":"..."}
+func newRequestError(err error, method string, statusCode int, response string) *common.RequestError {
+	var requestError = common.WrapRequestError(err, systemName, method, statusCode, response)
+	if err != nil {
+		return requestError
+	}
+
+	var result apiError
+	if json.Unmarshal([]byte(response), &result) != nil {
+		return requestError
+	}
+	requestError.Message = fmt.Sprintf("%v %v", result.Code, result.Msg)
+
+	var msg = strings.ToLower(result.Msg)
+	switch {
+	case result.Code == -1003 || result.Code == -1015:
+		requestError.Kind = common.ErrRateLimited
+	case result.Code == -1001 || result.Code == -1007 || result.Code == -1021:
+		requestError.Kind = common.ErrTransientNetwork
+	case result.Code == -1022 || result.Code == -2014 || result.Code == -2015:
+		requestError.Kind = common.ErrAuthFailure
+	case result.Code == -2013:
+		requestError.Kind = common.ErrNotFound
+	case strings.Contains(msg, "insufficient balance"):
+		requestError.Kind = common.ErrInsufficientFunds
+	case strings.Contains(msg, "market is closed") || strings.Contains(msg, "trading is disabled"):
+		requestError.Kind = common.ErrMarketFrozen
+	}
+
+	return requestError
+}
+
+type apiError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+type orderResult struct {
+	Symbol        string          `json:"symbol"`
+	OrderId       int64           `json:"orderId"`
+	ClientOrderId string          `json:"clientOrderId"`
+	Price         decimal.Decimal `json:"price"`
+	OrigQty       decimal.Decimal `json:"origQty"`
+	ExecutedQty   decimal.Decimal `json:"executedQty"`
+	Status        string          `json:"status"`
+	Fills         []orderFill     `json:"fills"`
+}
+
+type orderFill struct {
+	Price           decimal.Decimal `json:"price"`
+	Qty             decimal.Decimal `json:"qty"`
+	Commission      decimal.Decimal `json:"commission"`
+	CommissionAsset string          `json:"commissionAsset"`
+	TradeId         int64           `json:"tradeId"`
+}
+
+// fillReport copies order ids and fills (FULL response type) to the report
+func (o *orderResult) fillReport(report *entity.ExecutionReport) {
+	report.OrderNumber = strconv.FormatInt(o.OrderId, 10)
+	report.ClientOrderId = o.ClientOrderId
+
+	for _, fill := range o.Fills {
+		report.AddTrade(&entity.ExecutionTrade{
+			TradeId:     strconv.FormatInt(fill.TradeId, 10),
+			Price:       fill.Price,
+			Amount:      fill.Qty,
+			Total:       fill.Price.Mul(fill.Qty),
+			Fee:         fill.Commission,
+			FeeCurrency: fill.CommissionAsset,
+		})
+	}
+}
+
+type orderItem struct {
+	Volume decimal.Decimal
+	Price  decimal.Decimal
+}
+
+func toLevels(items []orderItem) []orderbook.Level {
+	var levels = make([]orderbook.Level, 0, len(items))
+	for _, item := range items {
+		levels = append(levels, orderbook.Level{Price: item.Price, Volume: item.Volume})
+	}
+	return levels
+}
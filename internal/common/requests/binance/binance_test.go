@@ -0,0 +1,468 @@
+package binance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"trading_bot/config"
+	"trading_bot/internal/common"
+	"trading_bot/internal/common/cassette"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/retry"
+	"trading_bot/internal/common/secrets"
+	"trading_bot/internal/common/signer"
+	"trading_bot/internal/entity"
+	"trading_bot/mocks"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	testKey    = "test-key"
+	testSecret = "test-secret"
+)
+
+func testLogger(t *testing.T) *mocks.ILogger {
+	t.Helper()
+
+	var l = &mocks.ILogger{}
+	var args = []interface{}{mock.Anything}
+	for i := 0; i < 8; i++ {
+		l.On("Debug", args...).Return()
+		l.On("Info", args...).Return()
+		l.On("Warn", args...).Return()
+		l.On("Error", args...).Return()
+		args = append(args, mock.Anything)
+	}
+
+	return l
+}
+
+// statusLost is filled order whose response is lost
+const statusLost = "LOST"
+
+// binanceStandIn emulates the Binance spot REST API for the endpoints used by BinanceRequests
+func binanceStandIn(t *testing.T, orderStatuses ...string) *httptest.Server {
+	t.Helper()
+
+	var orderCalls = 0
+	var clientOrderIds = map[string]int{}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v3/depth", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"lastUpdateId":1027024,"bids":[["99.00","2.0"],["98.00","3.0"]],"asks":[["101.00","1.0"],["102.00","4.0"]]}`)
+	})
+
+	mux.HandleFunc("/api/v3/exchangeInfo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"timezone":"UTC","symbols":[{"symbol":"%v","status":"TRADING","filters":[`+
+			`{"filterType":"PRICE_FILTER","minPrice":"0.01000000","maxPrice":"1000000.00000000","tickSize":"0.01000000"},`+
+			`{"filterType":"LOT_SIZE","minQty":"0.00001000","maxQty":"9000.00000000","stepSize":"0.00000001"},`+
+			`{"filterType":"NOTIONAL","minNotional":"5.00000000","applyMinToMarket":true}]}]}`, r.URL.Query().Get("symbol"))
+	})
+
+	signed := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var query = r.URL.RawQuery
+			var idx = strings.LastIndex(query, "&signature=")
+			if r.Header.Get("X-MBX-APIKEY") != testKey || idx < 0 {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"code":-2015,"msg":"Invalid API-key, IP, or permissions for action."}`)
+				return
+			}
+
+			h := hmac.New(sha256.New, []byte(testSecret))
+			h.Write([]byte(query[:idx]))
+			if fmt.Sprintf("%x", h.Sum(nil)) != query[idx+len("&signature="):] {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"code":-1022,"msg":"Signature for this request is not valid."}`)
+				return
+			}
+
+			handler(w, r)
+		}
+	}
+
+	mux.HandleFunc("/api/v3/account", signed(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"balances":[{"asset":"BTC","free":"1.5","locked":"0.5"},{"asset":"USDC","free":"1000","locked":"0"}]}`)
+	}))
+
+	mux.HandleFunc("/api/v3/order", signed(func(w http.ResponseWriter, r *http.Request) {
+		var q = r.URL.Query()
+		if r.Method == http.MethodGet {
+			var id, found = clientOrderIds[q.Get("origClientOrderId")]
+			if !found {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"code":-2013,"msg":"Order does not exist."}`)
+				return
+			}
+			fmt.Fprintf(w, `{"symbol":"%v","orderId":%v,"clientOrderId":"%v","status":"FILLED","price":"100","origQty":"0.50050051","executedQty":"0.50050051"}`,
+				q.Get("symbol"), id, q.Get("origClientOrderId"))
+			return
+		}
+
+		var status = statusFilled
+		if orderCalls < len(orderStatuses) {
+			status = orderStatuses[orderCalls]
+		}
+		orderCalls++
+		if len(q.Get("newClientOrderId")) > 0 {
+			clientOrderIds[q.Get("newClientOrderId")] = orderCalls
+		}
+		if status == statusLost {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var executed = q.Get("quantity")
+		var fills = fmt.Sprintf(`[{"price":"%v","qty":"%v","commission":"0.0005","commissionAsset":"BTC","tradeId":%v}]`, q.Get("price"), executed, 100+orderCalls)
+		if status == statusExpired {
+			executed = "0"
+			fills = "[]"
+		}
+		fmt.Fprintf(w, `{"symbol":"%v","orderId":%v,"clientOrderId":"client-%v","status":"%v","price":"%v","origQty":"%v","executedQty":"%v","fills":%v}`,
+			q.Get("symbol"), orderCalls, orderCalls, status, q.Get("price"), q.Get("quantity"), executed, fills)
+	}))
+
+	mux.HandleFunc("/api/v3/myTrades", signed(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"id":%v,"orderId":%v,"price":"100","qty":"0.50050051","commission":"0.0005","commissionAsset":"BTC"}]`, "1"+r.URL.Query().Get("orderId"), r.URL.Query().Get("orderId"))
+	}))
+
+	mux.HandleFunc("/sapi/v1/asset/tradeFee", signed(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"symbol":"%v","makerCommission":"0.001","takerCommission":"0.001"}]`, r.URL.Query().Get("symbol"))
+	}))
+
+	mux.HandleFunc("/sapi/v1/capital/withdraw/apply", signed(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"7213fea8e94b4a5593d507237e5a555b"}`)
+	}))
+
+	mux.HandleFunc("/sapi/v1/capital/deposit/address", signed(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"address":"%v-address","coin":"%v","tag":"","url":""}`, r.URL.Query().Get("network"), r.URL.Query().Get("coin"))
+	}))
+
+	return httptest.NewServer(mux)
+}
+
+func binanceRequests(t *testing.T, server *httptest.Server) *BinanceRequests {
+	t.Helper()
+
+	var l = testLogger(t)
+
+	return New(l, helpermethods.New(l, nil), config.TradingSettings{
+		Url:    server.URL,
+		Key:    testKey,
+		Secret: testSecret,
+		Pair:   "BTCUSDC",
+	})
+}
+
+// newTestRequests replays testdata/<name>.json, RECORD_CASSETTES=1 records it against BINANCE_URL with BINANCE_KEY and BINANCE_SECRET
+func newTestRequests(t *testing.T, name string) *BinanceRequests {
+	t.Helper()
+
+	var l = testLogger(t)
+	var transport = cassette.Use(t, filepath.Join("testdata", name+".json"), func() common.IHelperMethods {
+		return helpermethods.New(l, nil)
+	})
+
+	return New(l, transport, config.TradingSettings{
+		Url:    cassette.Setting("BINANCE_URL", "https://api.binance.com"),
+		Key:    secrets.Secret(cassette.Setting("BINANCE_KEY", testKey)),
+		Secret: secrets.Secret(cassette.Setting("BINANCE_SECRET", testSecret)),
+	})
+}
+
+func TestGetTradingBalances_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t)
+	defer server.Close()
+
+	got, err := binanceRequests(t, server).GetTradingBalances(context.Background())
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %v balances, wanted 2", len(got))
+	}
+	if !got["BTC"].Balance.Equal(decimal.NewFromFloat(1.5)) || !got["BTC"].Reserved.Equal(decimal.NewFromFloat(0.5)) {
+		t.Errorf("got BTC balance %v/%v, wanted 1.5/0.5", got["BTC"].Balance, got["BTC"].Reserved)
+	}
+}
+
+func TestGetPublicTradingOrders_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t)
+	defer server.Close()
+
+	got, err := binanceRequests(t, server).GetPublicTradingOrders(context.Background(), "BTCUSDC",
+		decimal.NewFromInt(1000), decimal.NewFromInt(10), decimal.NewFromInt(1000), decimal.NewFromInt(1000), decimal.NewFromFloat(0.001))
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("got %v orders, wanted 4", len(got))
+	}
+	if !got[0].IsSellOrder || !got[0].Rate.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("got first order %v, wanted sell at 101", got[0])
+	}
+	if got[2].IsSellOrder || !got[2].Rate.Equal(decimal.NewFromInt(99)) {
+		t.Errorf("got third order %v, wanted buy at 99", got[2])
+	}
+}
+
+func TestGetFees_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t)
+	var requests = binanceRequests(t, server)
+
+	got, err := requests.GetFees(context.Background(), "BTCUSDC")
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if !got.MakerFee.Equal(decimal.NewFromFloat(0.001)) || !got.TakerFee.Equal(decimal.NewFromFloat(0.001)) {
+		t.Errorf("got maker %v, taker %v, wanted 0.001, 0.001", got.MakerFee, got.TakerFee)
+	}
+
+	// fees are cached, the trading system is not requested again
+	server.Close()
+	cached, err := requests.GetFees(context.Background(), "BTCUSDC")
+	if err != nil || cached != got {
+		t.Errorf("got %v (error %v), wanted cached %v", cached, err, got)
+	}
+}
+
+func TestGetMarketInfo_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t)
+	defer server.Close()
+
+	got, err := binanceRequests(t, server).GetMarketInfo(context.Background(), "BTCUSDC")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if !got.PriceStep.Equal(decimal.NewFromFloat(0.01)) || !got.AmountStep.Equal(decimal.New(1, -8)) {
+		t.Errorf("got tick %v and lot %v, wanted 0.01 and 0.00000001", got.PriceStep, got.AmountStep)
+	}
+	if !got.MinAmount.Equal(decimal.NewFromFloat(0.00001)) || !got.MaxAmount.Equal(decimal.NewFromInt(9000)) || !got.MinNotional.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("got amount %v - %v and min notional %v, wanted 0.00001 - 9000 and 5", got.MinAmount, got.MaxAmount, got.MinNotional)
+	}
+}
+
+func TestBuy_MinNotional_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t)
+	defer server.Close()
+
+	// 0.01 BTC at 100 is lower than the min notional, the order is not sent
+	_, err := binanceRequests(t, server).Buy(context.Background(), "BTCUSDC", decimal.NewFromInt(100), decimal.NewFromInt(101), decimal.NewFromFloat(0.01), "BTC,USDC", "")
+
+	if !errors.Is(err, common.ErrRejected) {
+		t.Errorf("got error %v, wanted %v", err, common.ErrRejected)
+	}
+}
+
+func TestBuy_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t)
+	defer server.Close()
+
+	got, err := binanceRequests(t, server).Buy(context.Background(), "BTCUSDC", decimal.NewFromInt(100), decimal.NewFromInt(101), decimal.NewFromFloat(0.5), "BTC,USDC", "")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if got.Status != entity.ExecutionStatusFilled || got.OrderNumber != "1" || got.ClientOrderId != "client-1" {
+		t.Errorf("got report %v/%v/%v, wanted filled order 1", got.Status, got.OrderNumber, got.ClientOrderId)
+	}
+	if !got.FilledAmount.Equal(decimal.NewFromFloat(0.50050051)) || !got.AveragePrice.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("got filled %v at %v, wanted 0.50050051 at 100", got.FilledAmount, got.AveragePrice)
+	}
+	if !got.Fee.Equal(decimal.NewFromFloat(0.0005)) || got.FeeCurrency != "BTC" {
+		t.Errorf("got fee %v %v, wanted 0.0005 BTC", got.Fee, got.FeeCurrency)
+	}
+}
+
+func TestBuy_ExpiredThenFilled_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t, statusExpired, statusExpired)
+	defer server.Close()
+
+	_, err := binanceRequests(t, server).Buy(context.Background(), "BTCUSDC", decimal.NewFromInt(100), decimal.NewFromInt(101), decimal.NewFromFloat(0.5), "BTC,USDC", "")
+
+	if err != nil {
+		t.Errorf("got error %v, wanted nil", err)
+	}
+}
+
+func TestBuy_LostResponse_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t, statusLost)
+	defer server.Close()
+
+	// the order is found by the client order id, it isn't placed again
+	got, err := binanceRequests(t, server).Buy(context.Background(), "BTCUSDC", decimal.NewFromInt(100), decimal.NewFromInt(101), decimal.NewFromFloat(0.5), "BTC,USDC", "h1")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if got.Status != entity.ExecutionStatusFilled || got.OrderNumber != "1" || got.ClientOrderId != "h1-0" {
+		t.Errorf("got report %v/%v/%v, wanted filled order 1 h1-0", got.Status, got.OrderNumber, got.ClientOrderId)
+	}
+	if !got.FilledAmount.Equal(decimal.NewFromFloat(0.50050051)) || !got.Fee.Equal(decimal.NewFromFloat(0.0005)) {
+		t.Errorf("got filled %v with fee %v, wanted 0.50050051 with fee 0.0005", got.FilledAmount, got.Fee)
+	}
+}
+
+func TestSell_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t)
+	defer server.Close()
+
+	// quantity of the sell order is the base currency amount
+	var quantities = make(chan string, 1)
+	var handler = server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/api/v3/order" {
+			quantities <- r.URL.Query().Get("quantity")
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	got, err := binanceRequests(t, server).Sell(context.Background(), "BTCUSDC", decimal.NewFromInt(100), decimal.NewFromInt(99), decimal.NewFromFloat(0.5), "BTC,USDC", "")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if quantity := <-quantities; quantity != "0.5" {
+		t.Errorf("got quantity %v, wanted 0.5", quantity)
+	}
+	if got.Status != entity.ExecutionStatusFilled || !got.FilledAmount.Equal(decimal.NewFromFloat(0.5)) {
+		t.Errorf("got report %v filled %v, wanted filled 0.5", got.Status, got.FilledAmount)
+	}
+}
+
+func TestSell_MaxPriceReached_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t, statusExpired, statusExpired, statusExpired)
+	defer server.Close()
+
+	got, err := binanceRequests(t, server).Sell(context.Background(), "BTCUSDC", decimal.NewFromInt(100), decimal.NewFromFloat(99.85), decimal.NewFromFloat(0.5), "BTC,USDC", "")
+
+	if !errors.Is(err, common.ErrOrderNotFilled) {
+		t.Errorf("got error %v, wanted %v", err, common.ErrOrderNotFilled)
+	}
+	if got == nil || got.Status != entity.ExecutionStatusNotFilled {
+		t.Errorf("got report %v, wanted not filled", got)
+	}
+}
+
+func TestBuy_AttemptsExhausted_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t, statusExpired, statusExpired, statusExpired)
+	defer server.Close()
+
+	var br = binanceRequests(t, server)
+	br.retryPolicy = retry.NewPolicy(config.RetrySettings{MaxAttempts: 2})
+
+	got, err := br.Buy(context.Background(), "BTCUSDC", decimal.NewFromInt(100), decimal.NewFromInt(110), decimal.NewFromFloat(0.5), "BTC,USDC", "")
+
+	if !errors.Is(err, retry.ErrExhausted) || !errors.Is(err, common.ErrOrderNotFilled) {
+		t.Errorf("got error %v, wanted %v wrapping %v", err, retry.ErrExhausted, common.ErrOrderNotFilled)
+	}
+	if got == nil || got.Status != entity.ExecutionStatusNotFilled {
+		t.Errorf("got report %v, wanted not filled", got)
+	}
+}
+
+func TestWithdraw_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t)
+	defer server.Close()
+
+	got, err := binanceRequests(t, server).Withdraw(context.Background(), "address", decimal.NewFromFloat(0.1), "BTC", "BTC")
+
+	if err != nil || got != "7213fea8e94b4a5593d507237e5a555b" {
+		t.Errorf("got %v %v, wanted 7213fea8e94b4a5593d507237e5a555b nil", got, err)
+	}
+}
+
+func TestGetTransfers_Success(t *testing.T) {
+	t.Parallel()
+
+	var br = newTestRequests(t, "transfers")
+
+	got, err := br.GetTransfers(context.Background(), "BTC", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC))
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %v transfers, wanted deposit and 2 withdrawals", len(got))
+	}
+	var deposit, completed, processing = got[0], got[1], got[2]
+	if !deposit.IsDeposit || deposit.Status != entity.TransferStatusComplete || deposit.TxId != "9f2b1c" || deposit.Confirmations != 2 {
+		t.Errorf("got deposit %+v, wanted complete with tx 9f2b1c and 2 confirmations", deposit)
+	}
+	if completed.IsDeposit || completed.Id != "7213fea8e94b4a5593d507237e5a555b" || completed.Status != entity.TransferStatusComplete || completed.TxId != "36e483ef" {
+		t.Errorf("got withdrawal %+v, wanted complete 7213fea8e94b4a5593d507237e5a555b with tx 36e483ef", completed)
+	}
+	if !completed.Date.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("got withdrawal date %v, wanted 2024-05-01 10:00:00 UTC", completed.Date)
+	}
+	if processing.Status != entity.TransferStatusPending || len(processing.TxId) != 0 {
+		t.Errorf("got withdrawal %+v, wanted pending without tx", processing)
+	}
+}
+
+func TestGetCryptoAddress_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t)
+	defer server.Close()
+
+	got, err := binanceRequests(t, server).GetCryptoAddress(context.Background(), "USDC", "ETH")
+	want := "ETH-address"
+
+	if err != nil || got != want {
+		t.Errorf("got %v (error %v), wanted %v", got, err, want)
+	}
+}
+
+func TestGetTradingBalances_InvalidSignature_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var server = binanceStandIn(t)
+	defer server.Close()
+
+	var br = binanceRequests(t, server)
+	br.signer = signer.Resolve("", signer.TypeHmacSha256Query, "wrong-secret")
+
+	_, err := br.GetTradingBalances(context.Background())
+
+	if !errors.Is(err, common.ErrAuthFailure) {
+		t.Errorf("got error %v, wanted %v", err, common.ErrAuthFailure)
+	}
+}
@@ -3,12 +3,18 @@ package jetcrypto
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 	"trading_bot/config"
 	"trading_bot/internal/common"
+	"trading_bot/internal/common/breaker"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/ratelimit"
+	"trading_bot/internal/common/signer"
 	"trading_bot/internal/entity"
 	"trading_bot/pkg/logger"
 
@@ -17,6 +23,30 @@ import (
 	"gopkg.in/guregu/null.v4"
 )
 
+const systemName = "JetCrypto"
+
+const (
+	balancesEndpoint = "api/Device/UserAccount"
+	balanceEndpoint  = "api/Device/UserAccount/Balance"
+	modifyEndpoint   = "api/Trading/ModifyOrder"
+	// batch endpoints, orders are placed and removed one by one when they are missing
+	addBatchEndpoint    = "api/Trading/TradeBatch"
+	removeBatchEndpoint = "api/Trading/RemoveOrders"
+	removeAllEndpoint   = "api/Trading/RemoveAllOrders"
+	maxBatchSize        = 50
+	balancesPageSize    = 1000
+	// guard against endless paging
+	maxBalancePages = 100
+	// deposits of the account, the deposits are not tracked when it's missing
+	depositsEndpoint = "api/Trovemat/UserAccount/Deposits"
+)
+
+var defaultRateLimits = config.RateLimitSettings{
+	Public:  config.RateLimit{RequestsPerSecond: 10, Burst: 10},
+	Private: config.RateLimit{RequestsPerSecond: 10, Burst: 10},
+	Order:   config.RateLimit{RequestsPerSecond: 10, Burst: 10},
+}
+
 type JetCryptoRequests struct {
 	logger        logger.ILogger
 	helperMethods common.IHelperMethods
@@ -24,42 +54,44 @@ type JetCryptoRequests struct {
 	balancesCache []*entity.BalanceObject
 	baseUrl       string
 	publicKey     string
-	secretKey     string
+	signer        signer.Signer
+	rateLimiter   *ratelimit.Limiter
+	breaker       *breaker.Breaker
+	// optional endpoints responded 404 (per currency balance, modify order, batches), the fallbacks are used instead
+	missingEndpoints map[string]bool
 }
 
-func New(l logger.ILogger, hm common.IHelperMethods, cs config.InternalSettings) *JetCryptoRequests {
+// New creates JetCrypto requests, requests are not sent while the circuit breaker cb is open (cb is optional)
+func New(l logger.ILogger, hm common.IHelperMethods, cs config.InternalSettings, cb *breaker.Breaker) *JetCryptoRequests {
 	return &JetCryptoRequests{
-		logger:        l,
-		helperMethods: hm,
-		cacheUpdate:   time.Time{},
-		balancesCache: []*entity.BalanceObject{},
-		baseUrl:       cs.Url,
-		publicKey:     cs.Key,
-		secretKey:     cs.Secret,
+		logger:           l,
+		helperMethods:    hm,
+		cacheUpdate:      time.Time{},
+		balancesCache:    []*entity.BalanceObject{},
+		baseUrl:          cs.Url,
+		publicKey:        cs.Key.Reveal(),
+		signer:           signer.Resolve(cs.Signer, signer.TypeHmacSha512Url, cs.Secret.Reveal()),
+		rateLimiter:      ratelimit.Shared(systemName, cs.Key.Reveal(), cs.RateLimit, defaultRateLimits),
+		breaker:          cb,
+		missingEndpoints: make(map[string]bool),
 	}
 }
 
-func (jc *JetCryptoRequests) GetOrders(ctx context.Context, jetCryptoPair string) map[uuid.UUID]*entity.InternalOrder {
+func (jc *JetCryptoRequests) GetOrders(ctx context.Context, jetCryptoPair string) (map[uuid.UUID]*entity.InternalOrder, error) {
 	// make request object
 	var requestData map[string]string = make(map[string]string)
 	requestData["tradingPair"] = jetCryptoPair
 
 	// get JetCrypto orders
-	var jetCryptoOrders, statusCode = jc.query(ctx, "api/Trading/ActiveOrders", "get", requestData)
-
-	if statusCode == 503 {
-		jc.logger.Error("JetCrypto : error on GetOrders - ServiceUnavailable!")
-		return nil
-	}
-	if len(jetCryptoOrders) == 0 {
-		return nil
+	var jetCryptoOrders, err = jc.query(ctx, "api/Trading/ActiveOrders", "get", requestData)
+	if err != nil {
+		return nil, err
 	}
 
 	var orders []*entity.InternalOrder
-	err := json.Unmarshal([]byte(jetCryptoOrders), &orders)
+	err = json.Unmarshal([]byte(jetCryptoOrders), &orders)
 	if err != nil {
-		jc.logger.Error("JetCrypto : error on GetOrders - empty response for tradingPair: %v!", jetCryptoPair)
-		return nil
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "api/Trading/ActiveOrders", 200, "tradingPair : "+jetCryptoPair)
 	}
 
 	result := map[uuid.UUID]*entity.InternalOrder{}
@@ -67,180 +99,353 @@ func (jc *JetCryptoRequests) GetOrders(ctx context.Context, jetCryptoPair string
 		result[val.Id] = val
 	}
 
-	return result
+	return result, nil
 }
 
-func (jc *JetCryptoRequests) GetCompleteOrder(ctx context.Context, orderId uuid.UUID, jetCryptoPair string) []*entity.InternalOrder {
+func (jc *JetCryptoRequests) GetCompleteOrder(ctx context.Context, orderId uuid.UUID, jetCryptoPair string) ([]*entity.InternalOrder, error) {
 	// make request object
 	var requestData map[string]string = make(map[string]string)
 	requestData["tradingPair"] = jetCryptoPair
 	requestData["orderId"] = orderId.String()
 
 	// get JetCrypto orders
-	var jetCryptoOrders, statusCode = jc.query(ctx, "api/Trading/CompletedOrderInfo", "get", requestData)
-
-	if statusCode == 503 {
-		jc.logger.Error("JetCrypto : error on GetCompleteOrder - ServiceUnavailable!")
-		return nil
-	}
-	if len(jetCryptoOrders) == 0 {
-		return nil
+	var jetCryptoOrders, err = jc.query(ctx, "api/Trading/CompletedOrderInfo", "get", requestData)
+	if err != nil {
+		return nil, err
 	}
 
 	var orders []*entity.InternalOrder
-	err := json.Unmarshal([]byte(jetCryptoOrders), &orders)
+	err = json.Unmarshal([]byte(jetCryptoOrders), &orders)
 	if err != nil {
-		jc.logger.Error("JetCrypto : error on GetCompleteOrder - empty response for tradingPair: %v!", jetCryptoPair)
-		return nil
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "api/Trading/CompletedOrderInfo", 200, "tradingPair : "+jetCryptoPair)
 	}
 
-	return orders
+	return orders, nil
 }
 
-func (jc *JetCryptoRequests) GetBalances(ctx context.Context) map[string]*entity.BalanceObject {
-	// make request object
-	var requestData map[string]string = make(map[string]string)
-	requestData["page"] = "1"
-	requestData["itemsPerPage"] = "1000"
+// GetBalances requests all pages of the account balances. Paging metadata of the response is used when the server
+// sends it, otherwise pages are requested until a short or empty page.
+func (jc *JetCryptoRequests) GetBalances(ctx context.Context) (map[string]*entity.BalanceObject, error) {
+	result := map[string]*entity.BalanceObject{}
+	var totalCount = 0
+	var largestPage = 0
 
-	// get JetCrypto orders
-	var jetCryptoOrders, statusCode = jc.query(ctx, "api/Device/UserAccount", "get", requestData)
+	for page := 1; ; page++ {
+		if page > maxBalancePages {
+			return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, balancesEndpoint, 200, fmt.Sprintf("more than %v pages of balances", maxBalancePages))
+		}
+
+		var balances, err = jc.getBalancesPage(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+
+		var added = 0
+		for _, val := range balances.Items {
+			if _, found := result[val.Currency]; found {
+				continue
+			}
+			result[val.Currency] = val
+			added++
+		}
+
+		// server ignoring the page parameter returns the same rows again
+		if len(balances.Items) == 0 || added == 0 {
+			break
+		}
+
+		if balances.hasMetadata() {
+			totalCount = balances.TotalCount
+			if balances.isLastPage(page, len(result)) {
+				break
+			}
+			continue
+		}
+
+		// plain list, the page shorter than the previous ones is the last
+		if len(balances.Items) < largestPage {
+			break
+		}
+		largestPage = len(balances.Items)
+	}
 
-	if statusCode == 503 {
-		jc.logger.Error("JetCrypto : error on GetBalances - ServiceUnavailable!")
-		return nil
+	if totalCount > len(result) {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, balancesEndpoint, 200, fmt.Sprintf("got %v of %v balances", len(result), totalCount))
 	}
-	if len(jetCryptoOrders) == 0 {
-		return nil
+
+	return result, nil
+}
+
+// GetBalance requests balance of the single currency, all balances are requested when the endpoint is not available
+func (jc *JetCryptoRequests) GetBalance(ctx context.Context, currency string) (*entity.BalanceObject, error) {
+	if !jc.missingEndpoints[balanceEndpoint] {
+		// make request object
+		var requestData map[string]string = make(map[string]string)
+		requestData["currencyIsoCode"] = currency
+
+		var balanceStr, err = jc.query(ctx, balanceEndpoint, "get", requestData)
+		if err == nil {
+			var balance *entity.BalanceObject
+			err = json.Unmarshal([]byte(balanceStr), &balance)
+			if err != nil {
+				return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, balanceEndpoint, 200, "currencyIsoCode : "+currency)
+			}
+			if balance == nil || balance.Currency != currency {
+				return nil, common.NewRequestError(common.ErrNotFound, systemName, balanceEndpoint, 200, "currencyIsoCode : "+currency)
+			}
+			return balance, nil
+		}
+
+		if !jc.endpointMissing(balanceEndpoint, "all balances are requested", err) {
+			return nil, err
+		}
 	}
 
-	var balances []*entity.BalanceObject
-	err := json.Unmarshal([]byte(jetCryptoOrders), &balances)
+	var balances, err = jc.GetBalances(ctx)
 	if err != nil {
-		jc.logger.Error("JetCrypto : error on GetBalances - empty response!")
-		return nil
+		return nil, err
 	}
 
-	result := map[string]*entity.BalanceObject{}
-	for _, val := range balances {
-		result[val.Currency] = val
+	balance, found := balances[currency]
+	if !found {
+		return nil, common.NewRequestError(common.ErrNotFound, systemName, balancesEndpoint, 200, "currencyIsoCode : "+currency)
 	}
 
-	return result
+	return balance, nil
+}
+
+// balancesPage is the page of balances, metadata is empty when the server returns plain list
+type balancesPage struct {
+	Items        []*entity.BalanceObject `json:"items"`
+	Page         int                     `json:"page"`
+	ItemsPerPage int                     `json:"itemsPerPage"`
+	TotalCount   int                     `json:"totalCount"`
+	TotalPages   int                     `json:"totalPages"`
+}
+
+func (p *balancesPage) hasMetadata() bool {
+	return p.TotalCount > 0 || p.TotalPages > 0 || p.ItemsPerPage > 0
+}
+
+func (p *balancesPage) isLastPage(page int, received int) bool {
+	switch {
+	case p.TotalPages > 0:
+		return page >= p.TotalPages
+	case p.TotalCount > 0:
+		return received >= p.TotalCount
+	}
+	return len(p.Items) < p.ItemsPerPage
 }
 
-func (jc *JetCryptoRequests) GetOrder(ctx context.Context, orderId uuid.UUID, jetCryptoPair string) *entity.InternalOrder {
+func (jc *JetCryptoRequests) getBalancesPage(ctx context.Context, page int) (*balancesPage, error) {
+	// make request object
+	var requestData map[string]string = make(map[string]string)
+	requestData["page"] = strconv.Itoa(page)
+	requestData["itemsPerPage"] = strconv.Itoa(balancesPageSize)
+
+	var balancesStr, err = jc.query(ctx, balancesEndpoint, "get", requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	var res = &balancesPage{}
+	if strings.HasPrefix(strings.TrimSpace(balancesStr), "[") {
+		err = json.Unmarshal([]byte(balancesStr), &res.Items)
+	} else {
+		err = json.Unmarshal([]byte(balancesStr), res)
+	}
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, balancesEndpoint, 200, fmt.Sprintf("page : %v", page))
+	}
+
+	return res, nil
+}
+
+func (jc *JetCryptoRequests) GetOrder(ctx context.Context, orderId uuid.UUID, jetCryptoPair string) (*entity.InternalOrder, error) {
 	// make request object
 	var requestData map[string]string = make(map[string]string)
 	requestData["tradingPair"] = jetCryptoPair
 	requestData["orderId"] = orderId.String()
 
 	// get JetCrypto order
-	var jetCryptoOrder, statusCode = jc.query(ctx, "api/Trading/OrderInfo", "get", requestData)
-
-	if statusCode == 503 {
-		jc.logger.Error("JetCrypto : error on GetOrder - ServiceUnavailable!")
-		return nil
-	}
-	if len(jetCryptoOrder) == 0 {
-		return nil
+	var jetCryptoOrder, err = jc.query(ctx, "api/Trading/OrderInfo", "get", requestData)
+	if err != nil {
+		return nil, err
 	}
 
 	var order *entity.InternalOrder
-	err := json.Unmarshal([]byte(jetCryptoOrder), &order)
+	err = json.Unmarshal([]byte(jetCryptoOrder), &order)
 	if err != nil {
-		jc.logger.Error("JetCrypto : error on GetOrder - empty response for tradingPair: %v, orderId:%v !", jetCryptoPair, orderId)
-		return nil
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "api/Trading/OrderInfo", 200, fmt.Sprintf("tradingPair : %v, orderId : %v", jetCryptoPair, orderId))
+	}
+	if order == nil {
+		return nil, common.NewRequestError(common.ErrNotFound, systemName, "api/Trading/OrderInfo", 200, fmt.Sprintf("tradingPair : %v, orderId : %v", jetCryptoPair, orderId))
 	}
 
-	return order
+	return order, nil
 }
 
-func (jc *JetCryptoRequests) IsPaymentCompleted(ctx context.Context, orderId uuid.UUID) bool {
+func (jc *JetCryptoRequests) IsPaymentCompleted(ctx context.Context, orderId uuid.UUID) (bool, error) {
 	// make request object
 	var requestData map[string]string = make(map[string]string)
 	requestData["orderId"] = orderId.String()
 
 	// get JetCrypto order
-	var jetCryptoOrders, statusCode = jc.query(ctx, "api/Trovemat/Payment", "get", requestData)
-
-	if statusCode == 503 {
-		jc.logger.Error("JetCrypto : error on IsPaymentCompleted - ServiceUnavailable!")
-		return false
-	}
-	if len(jetCryptoOrders) == 0 {
-		return false
+	var jetCryptoOrders, err = jc.query(ctx, "api/Trovemat/Payment", "get", requestData)
+	if err != nil {
+		return false, err
 	}
 
 	order := struct {
 		StatusId int `json:"statusId"`
 	}{}
-	err := json.Unmarshal([]byte(jetCryptoOrders), &order)
+	err = json.Unmarshal([]byte(jetCryptoOrders), &order)
 	if err != nil {
-		jc.logger.Error("JetCrypto : error on IsPaymentCompleted - empty response for orderId:%v !", orderId)
-		return false
+		return false, common.NewRequestError(common.ErrInvalidResponse, systemName, "api/Trovemat/Payment", 200, "orderId : "+orderId.String())
 	}
 
-	return order.StatusId >= 2
+	return order.StatusId >= 2, nil
+}
+
+// deposit statuses of the internal account
+const (
+	depositCredited = 2
+	depositRejected = 3
+)
+
+type deposit struct {
+	Id            int64           `json:"id"`
+	Currency      string          `json:"currencyName"`
+	Address       string          `json:"address"`
+	Amount        decimal.Decimal `json:"amount"`
+	TxId          string          `json:"txId"`
+	Confirmations int             `json:"confirmations"`
+	StatusId      int             `json:"statusId"`
+	Created       time.Time       `json:"created"`
 }
 
-func (jc *JetCryptoRequests) GetCryptoAddress(ctx context.Context, currency string) string {
+// GetTransfers returns the deposits of the currency created between start and end, the internal account has no withdrawal history,
+// the payments are checked by IsPaymentCompleted. ErrNotSupported is returned when the backend has no deposits endpoint.
+func (jc *JetCryptoRequests) GetTransfers(ctx context.Context, currency string, start time.Time, end time.Time) ([]*entity.VenueTransfer, error) {
+	if jc.missingEndpoints[depositsEndpoint] {
+		return nil, common.NewRequestError(common.ErrNotSupported, systemName, depositsEndpoint, 404, "")
+	}
+
 	// make request object
 	var requestData map[string]string = make(map[string]string)
 	requestData["currencyName"] = currency
+	requestData["dateFrom"] = start.UTC().Format(time.RFC3339)
+	requestData["dateTo"] = end.UTC().Format(time.RFC3339)
 
-	// get JetCrypto order
-	var jetCryptoOrders, statusCode = jc.query(ctx, "api/Trovemat/UserAccount/getCryptoAddress", "get", requestData)
+	var depositsResponse, err = jc.query(ctx, depositsEndpoint, "get", requestData)
+	if err != nil {
+		if jc.endpointMissing(depositsEndpoint, "deposits are not tracked", err) {
+			return nil, common.NewRequestError(common.ErrNotSupported, systemName, depositsEndpoint, 404, "")
+		}
+		return nil, err
+	}
 
-	if statusCode == 503 {
-		jc.logger.Error("JetCrypto : error on GetCryptoAddress - ServiceUnavailable!")
-		return ""
+	var deposits []*deposit
+	err = json.Unmarshal([]byte(depositsResponse), &deposits)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, depositsEndpoint, 200, depositsResponse)
 	}
-	if len(jetCryptoOrders) == 0 {
-		return ""
+
+	var res = make([]*entity.VenueTransfer, 0, len(deposits))
+	for _, item := range deposits {
+		var status = entity.TransferStatusPending
+		switch item.StatusId {
+		case depositCredited:
+			status = entity.TransferStatusComplete
+		case depositRejected:
+			status = entity.TransferStatusFailed
+		}
+
+		res = append(res, &entity.VenueTransfer{
+			Id:            strconv.FormatInt(item.Id, 10),
+			Currency:      item.Currency,
+			Address:       item.Address,
+			Amount:        item.Amount,
+			TxId:          item.TxId,
+			Confirmations: item.Confirmations,
+			Status:        status,
+			IsDeposit:     true,
+			Date:          item.Created,
+		})
+	}
+
+	return res, nil
+}
+
+func (jc *JetCryptoRequests) GetCryptoAddress(ctx context.Context, currency string) (string, error) {
+	// make request object
+	var requestData map[string]string = make(map[string]string)
+	requestData["currencyName"] = currency
+
+	// get JetCrypto order
+	var jetCryptoOrders, err = jc.query(ctx, "api/Trovemat/UserAccount/getCryptoAddress", "get", requestData)
+	if err != nil {
+		return "", err
 	}
 
 	result := struct {
 		CryptoAddress string `json:"cryptoAddress"`
 	}{}
-	err := json.Unmarshal([]byte(jetCryptoOrders), &result)
+	err = json.Unmarshal([]byte(jetCryptoOrders), &result)
 	if err != nil {
-		jc.logger.Error("JetCrypto : error on GetCryptoAddress - empty response for currencyName:%v !", currency)
-		return ""
+		return "", common.NewRequestError(common.ErrInvalidResponse, systemName, "api/Trovemat/UserAccount/getCryptoAddress", 200, "currencyName : "+currency)
+	}
+	if len(result.CryptoAddress) == 0 {
+		return "", common.NewRequestError(common.ErrNotFound, systemName, "api/Trovemat/UserAccount/getCryptoAddress", 200, "currencyName : "+currency)
 	}
 
-	return result.CryptoAddress
+	return result.CryptoAddress, nil
 }
 
-func (jc *JetCryptoRequests) GetTradingPairInfo(ctx context.Context, jetCryptoPair string) decimal.Decimal {
+// GetTradingPairInfo returns the trading rules of the pair, precisions are numbers of decimal places
+func (jc *JetCryptoRequests) GetTradingPairInfo(ctx context.Context, jetCryptoPair string) (*entity.MarketInfo, error) {
 	// make request object
 	var requestData map[string]string = make(map[string]string)
 	requestData["tradingPair"] = jetCryptoPair
 
-	// get JetCrypto order
-	var jetCryptoOrders, statusCode = jc.query(ctx, "api/Trading/Info", "get", requestData)
-
-	if statusCode == 503 {
-		jc.logger.Error("JetCrypto : error on GetTradingPairInfo - ServiceUnavailable!")
-		return decimal.Decimal{}
-	}
-	if len(jetCryptoOrders) == 0 {
-		return decimal.Decimal{}
+	// get JetCrypto pair info
+	var pairInfo, err = jc.query(ctx, "api/Trading/Info", "get", requestData)
+	if err != nil {
+		return nil, err
 	}
 
-	order := struct {
-		MinAmount decimal.Decimal `json:"minAmount"`
+	info := struct {
+		MinAmount       decimal.Decimal `json:"minAmount"`
+		MaxAmount       decimal.Decimal `json:"maxAmount"`
+		MinTotal        decimal.Decimal `json:"minTotal"`
+		PricePrecision  *int32          `json:"pricePrecision"`
+		AmountPrecision *int32          `json:"amountPrecision"`
 	}{}
-	err := json.Unmarshal([]byte(jetCryptoOrders), &order)
+	err = json.Unmarshal([]byte(pairInfo), &info)
 	if err != nil {
-		jc.logger.Error("JetCrypto : error on GetTradingPairInfo - empty response!")
-		return decimal.Decimal{}
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "api/Trading/Info", 200, "tradingPair : "+jetCryptoPair)
+	}
+
+	var res = &entity.MarketInfo{
+		Pair:        jetCryptoPair,
+		MinAmount:   info.MinAmount,
+		MaxAmount:   info.MaxAmount,
+		MinNotional: info.MinTotal,
+		Updated:     time.Now().UTC(),
+	}
+	// older backends return the min amount only
+	if info.PricePrecision != nil {
+		res.PriceStep = entity.StepFromPrecision(*info.PricePrecision)
+	}
+	if info.AmountPrecision != nil {
+		res.AmountStep = entity.StepFromPrecision(*info.AmountPrecision)
 	}
 
-	return order.MinAmount
+	return res, nil
 }
 
-func (jc *JetCryptoRequests) Withdraw(ctx context.Context, addr string, destinationTag string, withdrawalAmount decimal.Decimal, currentCurrencyId string) null.Int {
+// Withdraw creates the payment identified by orderId, the status of the payment is requested by IsPaymentCompleted
+func (jc *JetCryptoRequests) Withdraw(ctx context.Context, addr string, destinationTag string, withdrawalAmount decimal.Decimal, currentCurrencyId string, orderId uuid.UUID) (null.Int, error) {
 
 	params := struct {
 		Address        string `json:"address"`
@@ -259,8 +464,7 @@ func (jc *JetCryptoRequests) Withdraw(ctx context.Context, addr string, destinat
 	requestData["withdrawalAmount"] = withdrawalAmount.String()
 	requestData["withdrawalCurrencyId"] = currentCurrencyId
 	requestData["trovematFee"] = "0"
-	var uuid, _ = uuid.NewV4()
-	requestData["uuId"] = uuid.String()
+	requestData["uuId"] = orderId.String()
 	requestData["moneySource"] = "0"
 	requestData["moneySourceId"] = "0"
 
@@ -268,29 +472,23 @@ func (jc *JetCryptoRequests) Withdraw(ctx context.Context, addr string, destinat
 		requestData["destinationTag"] = destinationTag
 	}
 	// get JetCrypto order
-	var jetCryptoOrders, statusCode = jc.query(ctx, "api/Trovemat/Payment", "post", requestData)
-
-	if statusCode == 503 {
-		jc.logger.Error("JetCrypto : error on Withdraw - ServiceUnavailable!")
-		return null.Int{}
-	}
-	if len(jetCryptoOrders) == 0 {
-		return null.Int{}
+	var jetCryptoOrders, err = jc.query(ctx, "api/Trovemat/Payment", "post", requestData)
+	if err != nil {
+		return null.Int{}, err
 	}
 
 	order := struct {
 		Id null.Int `json:"id"`
 	}{}
-	err := json.Unmarshal([]byte(jetCryptoOrders), &order)
-	if err != nil {
-		jc.logger.Error("JetCrypto : error on Withdraw - empty response!")
-		return null.Int{}
+	err = json.Unmarshal([]byte(jetCryptoOrders), &order)
+	if err != nil || !order.Id.Valid {
+		return null.Int{}, common.NewRequestError(common.ErrInvalidResponse, systemName, "api/Trovemat/Payment", 200, jetCryptoOrders)
 	}
 
-	return order.Id
+	return order.Id, nil
 }
 
-func (jc *JetCryptoRequests) RemoveOrder(ctx context.Context, orderId uuid.UUID, currencyFrom string, currencyTo string) bool {
+func (jc *JetCryptoRequests) RemoveOrder(ctx context.Context, orderId uuid.UUID, currencyFrom string, currencyTo string) error {
 	// make request object
 	var requestData map[string]string = make(map[string]string)
 	requestData["id"] = orderId.String()
@@ -298,34 +496,27 @@ func (jc *JetCryptoRequests) RemoveOrder(ctx context.Context, orderId uuid.UUID,
 	requestData["currencyTo"] = currencyTo
 
 	// remove JetCrypto order
-	var deleteResult, statusCode = jc.query(ctx, "api/Trading/RemoveOrder", "post", requestData)
-
-	if statusCode == 503 {
-		jc.logger.Error("JetCrypto : error on RemoveOrder - ServiceUnavailable!")
-		return false
-	}
-	if len(deleteResult) == 0 {
-		jc.logger.Error("JetCrypto : error on RemoveOrder - empty response!")
-		return false
+	var deleteResult, err = jc.query(ctx, "api/Trading/RemoveOrder", "post", requestData)
+	if err != nil {
+		return err
 	}
 
 	result := struct {
 		ErrorCode int `json:"errorCode"`
 	}{}
-	err := json.Unmarshal([]byte(deleteResult), &result)
+	err = json.Unmarshal([]byte(deleteResult), &result)
 	if err != nil {
-		jc.logger.Error("JetCrypto : error on RemoveOrder - empty response !")
-		return false
+		return common.NewRequestError(common.ErrInvalidResponse, systemName, "api/Trading/RemoveOrder", 200, deleteResult)
 	}
 
 	if result.ErrorCode != 0 {
-		return false
+		return common.NewRequestError(common.ErrRejected, systemName, "api/Trading/RemoveOrder", 200, fmt.Sprintf("errorCode : %v", result.ErrorCode))
 	}
 
-	return true
+	return nil
 }
 
-func (jc *JetCryptoRequests) AddOrder(ctx context.Context, currencyFrom string, currencyTo string, amount decimal.Decimal, price decimal.Decimal, isSellOrder bool) (bool, uuid.UUID) {
+func (jc *JetCryptoRequests) AddOrder(ctx context.Context, currencyFrom string, currencyTo string, amount decimal.Decimal, price decimal.Decimal, isSellOrder bool) (uuid.UUID, error) {
 	// make request object
 	var requestData map[string]string = make(map[string]string)
 	requestData["currencyFrom"] = currencyFrom
@@ -335,39 +526,253 @@ func (jc *JetCryptoRequests) AddOrder(ctx context.Context, currencyFrom string,
 	requestData["isSellOrder"] = strconv.FormatBool(isSellOrder)
 
 	// add new JetCrypto order
-	var addResult, statusCode = jc.query(ctx, "api/Trading/Trade", "post", requestData)
-
-	if statusCode == 503 {
-		jc.logger.Error("JetCrypto : error on AddOrder - ServiceUnavailable!")
-		return false, uuid.Nil
-	}
-	if len(addResult) == 0 {
-		jc.logger.Error("JetCrypto : error on AddOrder - empty response!")
-		return false, uuid.Nil
+	var addResult, err = jc.query(ctx, "api/Trading/Trade", "post", requestData)
+	if err != nil {
+		return uuid.Nil, err
 	}
 
 	result := struct {
 		Id        string `json:"id"`
 		ErrorCode int    `json:"errorCode"`
 	}{}
-	err := json.Unmarshal([]byte(addResult), &result)
+	err = json.Unmarshal([]byte(addResult), &result)
 	if err != nil {
-		jc.logger.Error("JetCrypto : error on AddOrder - empty response !")
-		return false, uuid.Nil
+		return uuid.Nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "api/Trading/Trade", 200, addResult)
 	}
 
 	if result.ErrorCode != 0 {
-		return false, uuid.Nil
+		return uuid.Nil, common.NewRequestError(common.ErrRejected, systemName, "api/Trading/Trade", 200, fmt.Sprintf("errorCode : %v", result.ErrorCode))
+	}
+
+	return uuid.FromStringOrNil(result.Id), nil
+}
+
+// ModifyOrder changes price and amount left of the active order in place. ErrNotSupported is returned when the backend
+// has no modify endpoint, the order has to be removed and added again then.
+func (jc *JetCryptoRequests) ModifyOrder(ctx context.Context, orderId uuid.UUID, currencyFrom string, currencyTo string, amount decimal.Decimal, price decimal.Decimal) error {
+	if jc.missingEndpoints[modifyEndpoint] {
+		return common.NewRequestError(common.ErrNotSupported, systemName, modifyEndpoint, 404, "")
+	}
+
+	// make request object
+	var requestData map[string]string = make(map[string]string)
+	requestData["id"] = orderId.String()
+	requestData["currencyFrom"] = currencyFrom
+	requestData["currencyTo"] = currencyTo
+	requestData["amount"] = amount.String()
+	requestData["price"] = price.String()
+
+	// modify JetCrypto order
+	var modifyResult, err = jc.query(ctx, modifyEndpoint, "post", requestData)
+	if err != nil {
+		if jc.endpointMissing(modifyEndpoint, "orders are replaced", err) {
+			return common.NewRequestError(common.ErrNotSupported, systemName, modifyEndpoint, 404, "")
+		}
+		return err
+	}
+
+	result := struct {
+		ErrorCode int `json:"errorCode"`
+	}{}
+	err = json.Unmarshal([]byte(modifyResult), &result)
+	if err != nil {
+		return common.NewRequestError(common.ErrInvalidResponse, systemName, modifyEndpoint, 200, modifyResult)
+	}
+
+	if result.ErrorCode != 0 {
+		return common.NewRequestError(common.ErrRejected, systemName, modifyEndpoint, 200, fmt.Sprintf("errorCode : %v", result.ErrorCode))
+	}
+
+	return nil
+}
+
+// AddOrders places the orders by batches of maxBatchSize, results are in the order of the orders. Orders are placed
+// one by one when the backend has no batch endpoint. On error results of the sent batches are returned with it.
+func (jc *JetCryptoRequests) AddOrders(ctx context.Context, orders []*entity.NewInternalOrder) ([]*entity.OrderResult, error) {
+	var res = make([]*entity.OrderResult, 0, len(orders))
+	for start := 0; start < len(orders); start += maxBatchSize {
+		var batch = orders[start:min(start+maxBatchSize, len(orders))]
+		if jc.missingEndpoints[addBatchEndpoint] {
+			res = append(res, jc.addOrdersOneByOne(ctx, batch)...)
+			continue
+		}
+
+		data, err := json.Marshal(batch)
+		if err != nil {
+			return res, common.NewRequestError(common.ErrRejected, systemName, addBatchEndpoint, 0, err.Error())
+		}
+		// make request object
+		var requestData map[string]string = make(map[string]string)
+		requestData["orders"] = string(data)
+
+		// add new JetCrypto orders
+		addResult, err := jc.query(ctx, addBatchEndpoint, "post", requestData)
+		if jc.endpointMissing(addBatchEndpoint, "orders are placed one by one", err) {
+			res = append(res, jc.addOrdersOneByOne(ctx, batch)...)
+			continue
+		}
+		if err != nil {
+			return res, err
+		}
+
+		results, err := batchResults(addBatchEndpoint, addResult, len(batch))
+		if err != nil {
+			return res, err
+		}
+		res = append(res, results...)
+	}
+
+	return res, nil
+}
+
+func (jc *JetCryptoRequests) addOrdersOneByOne(ctx context.Context, orders []*entity.NewInternalOrder) []*entity.OrderResult {
+	var res = make([]*entity.OrderResult, 0, len(orders))
+	for _, order := range orders {
+		var id, err = jc.AddOrder(ctx, order.CurrencyFrom, order.CurrencyTo, order.Amount, order.Price, order.IsSellOrder)
+		res = append(res, &entity.OrderResult{Id: id, Err: err})
+	}
+	return res
+}
+
+// RemoveOrders removes the orders by batches of maxBatchSize, results are in the order of the ids. Orders are removed
+// one by one when the backend has no batch endpoint. On error results of the sent batches are returned with it.
+func (jc *JetCryptoRequests) RemoveOrders(ctx context.Context, orderIds []uuid.UUID, currencyFrom string, currencyTo string) ([]*entity.OrderResult, error) {
+	var res = make([]*entity.OrderResult, 0, len(orderIds))
+	for start := 0; start < len(orderIds); start += maxBatchSize {
+		var batch = orderIds[start:min(start+maxBatchSize, len(orderIds))]
+		if jc.missingEndpoints[removeBatchEndpoint] {
+			res = append(res, jc.removeOrdersOneByOne(ctx, batch, currencyFrom, currencyTo)...)
+			continue
+		}
+
+		var ids = make([]string, 0, len(batch))
+		for _, id := range batch {
+			ids = append(ids, id.String())
+		}
+		// make request object
+		var requestData map[string]string = make(map[string]string)
+		requestData["ids"] = strings.Join(ids, ",")
+		requestData["currencyFrom"] = currencyFrom
+		requestData["currencyTo"] = currencyTo
+
+		// remove JetCrypto orders
+		removeResult, err := jc.query(ctx, removeBatchEndpoint, "post", requestData)
+		if jc.endpointMissing(removeBatchEndpoint, "orders are removed one by one", err) {
+			res = append(res, jc.removeOrdersOneByOne(ctx, batch, currencyFrom, currencyTo)...)
+			continue
+		}
+		if err != nil {
+			return res, err
+		}
+
+		results, err := batchResults(removeBatchEndpoint, removeResult, len(batch))
+		if err != nil {
+			return res, err
+		}
+		for i, result := range results {
+			result.Id = batch[i]
+		}
+		res = append(res, results...)
+	}
+
+	return res, nil
+}
+
+func (jc *JetCryptoRequests) removeOrdersOneByOne(ctx context.Context, orderIds []uuid.UUID, currencyFrom string, currencyTo string) []*entity.OrderResult {
+	var res = make([]*entity.OrderResult, 0, len(orderIds))
+	for _, id := range orderIds {
+		res = append(res, &entity.OrderResult{Id: id, Err: jc.RemoveOrder(ctx, id, currencyFrom, currencyTo)})
 	}
+	return res
+}
 
-	return true, uuid.FromStringOrNil(result.Id)
+// RemoveAllOrders removes all active orders of the pair ("BTC,USDC") and returns results of the removed orders.
+// Active orders are requested and removed by RemoveOrders when the backend has no endpoint for it.
+func (jc *JetCryptoRequests) RemoveAllOrders(ctx context.Context, jetCryptoPair string) ([]*entity.OrderResult, error) {
+	if !jc.missingEndpoints[removeAllEndpoint] {
+		// make request object
+		var requestData map[string]string = make(map[string]string)
+		requestData["tradingPair"] = jetCryptoPair
+
+		var removeResult, err = jc.query(ctx, removeAllEndpoint, "post", requestData)
+		if err == nil {
+			return batchResults(removeAllEndpoint, removeResult, -1)
+		}
+		if !jc.endpointMissing(removeAllEndpoint, "active orders are removed by ids", err) {
+			return nil, err
+		}
+	}
+
+	var currencies = strings.Split(jetCryptoPair, ",")
+	if len(currencies) != 2 {
+		return nil, common.NewRequestError(common.ErrRejected, systemName, removeAllEndpoint, 0, "tradingPair : "+jetCryptoPair)
+	}
+	var orders, err = jc.GetOrders(ctx, jetCryptoPair)
+	if err != nil {
+		return nil, err
+	}
+	var ids = make([]uuid.UUID, 0, len(orders))
+	for id := range orders {
+		ids = append(ids, id)
+	}
+
+	return jc.RemoveOrders(ctx, ids, currencies[0], currencies[1])
 }
 
-func (jc *JetCryptoRequests) query(ctx context.Context, method string, requestType string, requestData map[string]string) (string, int) {
+// batchResults parses results of the batch items, count is the expected number of the items (-1 for any)
+func batchResults(method string, text string, count int) ([]*entity.OrderResult, error) {
+	var items []struct {
+		Id        string `json:"id"`
+		ErrorCode int    `json:"errorCode"`
+	}
+	if err := json.Unmarshal([]byte(text), &items); err != nil || (count >= 0 && len(items) != count) {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, method, 200, text)
+	}
+
+	var res = make([]*entity.OrderResult, 0, len(items))
+	for _, item := range items {
+		var result = &entity.OrderResult{Id: uuid.FromStringOrNil(item.Id)}
+		if item.ErrorCode != 0 {
+			result.Err = common.NewRequestError(common.ErrRejected, systemName, method, 200, fmt.Sprintf("errorCode : %v", item.ErrorCode))
+		}
+		res = append(res, result)
+	}
+
+	return res, nil
+}
+
+// endpointMissing reports that the optional endpoint responded 404 and remembers it, the fallback is used from then on
+func (jc *JetCryptoRequests) endpointMissing(method string, fallback string, err error) bool {
+	var requestErr *common.RequestError
+	if !errors.As(err, &requestErr) || requestErr.StatusCode != 404 {
+		return false
+	}
+
+	jc.logger.Info("JetCrypto : %v is not available, %v", method, fallback)
+	jc.missingEndpoints[method] = true
+	return true
+}
+
+func (jc *JetCryptoRequests) query(ctx context.Context, method string, requestType string, requestData map[string]string) (string, error) {
+	ctx = helpermethods.WithDefaultEndpoint(ctx, "/"+method)
+	if jc.breaker == nil {
+		return jc.doQuery(ctx, method, requestType, requestData)
+	}
+
+	if err := jc.breaker.Allow(method); err != nil {
+		return "", err
+	}
+
+	var resText, err = jc.doQuery(ctx, method, requestType, requestData)
+	jc.breaker.Done(err)
+
+	return resText, err
+}
+
+func (jc *JetCryptoRequests) doQuery(ctx context.Context, method string, requestType string, requestData map[string]string) (string, error) {
 	u, err := url.Parse(jc.baseUrl + "/" + method)
 	if err != nil {
-		jc.logger.Error(err)
-		return "", 500
+		return "", common.WrapRequestError(err, systemName, method, 0, "")
 	}
 
 	q := u.Query()
@@ -378,16 +783,24 @@ func (jc *JetCryptoRequests) query(ctx context.Context, method string, requestTy
 	}
 	u.RawQuery = q.Encode()
 
-	var keyBytes = []byte(jc.secretKey)
-	var dataBytes = []byte(u.String())
-
-	var hash512Bytes, _ = jc.helperMethods.HmacSha512(keyBytes, dataBytes)
-	var signature = fmt.Sprintf("%x", hash512Bytes)
+	var class = ratelimit.ClassPrivate
+	switch method {
+	case "api/Trading/Trade", "api/Trading/RemoveOrder", modifyEndpoint, addBatchEndpoint, removeBatchEndpoint, removeAllEndpoint:
+		class = ratelimit.ClassOrder
+	}
+	if err := jc.rateLimiter.Wait(ctx, class, method); err != nil {
+		return "", common.WrapRequestError(err, systemName, method, 0, "")
+	}
 
-	var webHeaders = map[string]string{
-		"Key":  jc.publicKey,
-		"Sign": signature,
+	var req = &signer.Request{
+		Method:  strings.ToUpper(requestType),
+		Url:     u,
+		Headers: map[string]string{"Key": jc.publicKey},
+	}
+	if err := jc.signer.Sign(req); err != nil {
+		return "", common.NewRequestError(common.ErrAuthFailure, systemName, method, 0, err.Error())
 	}
+	var webHeaders = req.Headers
 
 	var statusCode = 500
 	var resText = ""
@@ -398,9 +811,13 @@ func (jc *JetCryptoRequests) query(ctx context.Context, method string, requestTy
 		resText, statusCode, err1 = jc.helperMethods.HttpPost(ctx, u, []byte(u.RawQuery), "application/json", webHeaders)
 	}
 
-	if statusCode != 200 {
+	if err1 != nil || statusCode != 200 {
 		jc.logger.Error("JetCrypto : response status : %v, %v : %v", statusCode, err1, resText)
+		return "", common.WrapRequestError(err1, systemName, method, statusCode, resText)
+	}
+	if len(resText) == 0 {
+		return "", common.NewRequestError(common.ErrInvalidResponse, systemName, method, statusCode, "empty response")
 	}
 
-	return resText, statusCode
+	return resText, nil
 }
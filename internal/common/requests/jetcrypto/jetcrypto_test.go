@@ -0,0 +1,525 @@
+package jetcrypto
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+	"trading_bot/config"
+	"trading_bot/internal/common"
+	"trading_bot/internal/common/cassette"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/requests/jetcrypto/jetcryptotest"
+	"trading_bot/internal/common/secrets"
+	"trading_bot/internal/entity"
+	"trading_bot/mocks"
+
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	testKey    = "test-key"
+	testSecret = "test-secret"
+)
+
+func testLogger(t *testing.T) *mocks.ILogger {
+	t.Helper()
+
+	var l = &mocks.ILogger{}
+	var args = []interface{}{mock.Anything}
+	for i := 0; i < 8; i++ {
+		l.On("Debug", args...).Return()
+		l.On("Info", args...).Return()
+		l.On("Error", args...).Return()
+		args = append(args, mock.Anything)
+	}
+
+	return l
+}
+
+// newTestRequests replays testdata/<name>.json, RECORD_CASSETTES=1 records it against JETCRYPTO_URL with JETCRYPTO_KEY and JETCRYPTO_SECRET
+func newTestRequests(t *testing.T, name string) (*JetCryptoRequests, cassette.Transport) {
+	t.Helper()
+
+	var l = testLogger(t)
+	var transport = cassette.Use(t, filepath.Join("testdata", name+".json"), func() common.IHelperMethods {
+		return helpermethods.New(l, nil)
+	})
+
+	return New(l, transport, config.InternalSettings{
+		Url:    cassette.Setting("JETCRYPTO_URL", "https://jetcrypto.test"),
+		Key:    secrets.Secret(cassette.Setting("JETCRYPTO_KEY", testKey)),
+		Secret: secrets.Secret(cassette.Setting("JETCRYPTO_SECRET", testSecret)),
+	}, nil), transport
+}
+
+// checkSigned verifies that the full request urls are signed with the test secret
+func checkSigned(t *testing.T, transport cassette.Transport) {
+	t.Helper()
+
+	for _, req := range transport.Requests() {
+		var h = hmac.New(sha512.New, []byte(testSecret))
+		h.Write([]byte(req.Url))
+
+		if got, want := req.Headers["Sign"], fmt.Sprintf("%x", h.Sum(nil)); got != want {
+			t.Errorf("got sign %v, wanted %v", got, want)
+		}
+		if got := req.Headers["Key"]; got != testKey {
+			t.Errorf("got key %v, wanted %v", got, testKey)
+		}
+	}
+}
+
+func TestGetOrders_Success(t *testing.T) {
+	t.Parallel()
+
+	var jc, transport = newTestRequests(t, "orders")
+
+	got, err := jc.GetOrders(context.Background(), "BTC,USDC")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	var order, found = got[uuid.FromStringOrNil("0f8fad5b-d9cb-469f-a165-70867728950e")]
+	if len(got) != 2 || !found {
+		t.Fatalf("got %v orders, wanted 2 with 0f8fad5b-d9cb-469f-a165-70867728950e", len(got))
+	}
+	if !order.IsSellOrder || !order.AmountLeft.Equal(decimal.NewFromFloat(0.2)) || !order.Price.Equal(decimal.NewFromFloat(20100.5)) {
+		t.Errorf("got order %+v, wanted sell 0.2 left at 20100.5", order)
+	}
+	checkSigned(t, transport)
+}
+
+func TestGetBalances_Success(t *testing.T) {
+	t.Parallel()
+
+	var jc, _ = newTestRequests(t, "balances")
+
+	got, err := jc.GetBalances(context.Background())
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 2 || !got["BTC"].Reserved.Equal(decimal.NewFromFloat(0.2)) {
+		t.Errorf("got %v, wanted 2 balances with BTC reserved 0.2", got)
+	}
+}
+
+func TestAddOrder_RemoveRejected_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var jc, transport = newTestRequests(t, "add_remove_order")
+
+	id, err := jc.AddOrder(context.Background(), "BTC", "USDC", decimal.NewFromFloat(0.5), decimal.NewFromFloat(20100.5), true)
+	if err != nil || id.String() != "0f8fad5b-d9cb-469f-a165-70867728950e" {
+		t.Fatalf("got %v %v, wanted 0f8fad5b-d9cb-469f-a165-70867728950e nil", id, err)
+	}
+
+	got := jc.RemoveOrder(context.Background(), id, "BTC", "USDC")
+
+	if !errors.Is(got, common.ErrRejected) {
+		t.Errorf("got error %v, wanted %v", got, common.ErrRejected)
+	}
+	checkSigned(t, transport)
+}
+
+func TestGetOrder_NotFound_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var jc, _ = newTestRequests(t, "order_not_found")
+
+	_, got := jc.GetOrder(context.Background(), uuid.FromStringOrNil("7c9e6679-7425-40de-944b-e07fc1f90ae7"), "BTC,USDC")
+
+	if !errors.Is(got, common.ErrNotFound) {
+		t.Errorf("got error %v, wanted %v", got, common.ErrNotFound)
+	}
+}
+
+func TestWithdraw_Success(t *testing.T) {
+	t.Parallel()
+
+	var jc, _ = newTestRequests(t, "withdraw")
+
+	got, err := jc.Withdraw(context.Background(), "bc1qtest", "", decimal.NewFromFloat(0.3), "2001", uuid.Must(uuid.NewV4()))
+
+	if err != nil || got.Int64 != 4521 {
+		t.Errorf("got %v %v, wanted 4521 nil", got, err)
+	}
+}
+
+func TestGetTradingPairInfo_Unavailable_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var jc, _ = newTestRequests(t, "unavailable")
+
+	_, got := jc.GetTradingPairInfo(context.Background(), "BTC,USDC")
+
+	if !errors.Is(got, common.ErrServiceUnavailable) || !common.IsRetryable(got) {
+		t.Errorf("got error %v, wanted retryable %v", got, common.ErrServiceUnavailable)
+	}
+}
+
+func TestGetTradingPairInfo_Simulator_Success(t *testing.T) {
+	t.Parallel()
+
+	var sim = jetcryptotest.NewServer(testKey, testSecret)
+	defer sim.Close()
+	sim.SetMinAmount("BTC,USDC", decimal.NewFromFloat(0.001))
+	sim.SetPrecision("BTC,USDC", 2, 4)
+
+	var l = testLogger(t)
+	var jc = New(l, helpermethods.New(l, nil), config.InternalSettings{Url: sim.URL(), Key: testKey, Secret: testSecret}, nil)
+
+	got, err := jc.GetTradingPairInfo(context.Background(), "BTC,USDC")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if !got.MinAmount.Equal(decimal.NewFromFloat(0.001)) || !got.PriceStep.Equal(decimal.NewFromFloat(0.01)) || !got.AmountStep.Equal(decimal.NewFromFloat(0.0001)) {
+		t.Errorf("got min amount %v, tick %v, lot %v, wanted 0.001, 0.01, 0.0001", got.MinAmount, got.PriceStep, got.AmountStep)
+	}
+}
+
+func TestGetTransfers_Simulator_Success(t *testing.T) {
+	t.Parallel()
+
+	var sim = jetcryptotest.NewServer(testKey, testSecret)
+	defer sim.Close()
+	var start = time.Now().Add(-time.Minute)
+	sim.CreditDeposit(sim.AddDeposit("BTC", "bc1qinternal", decimal.NewFromFloat(0.5), "36e483ef"))
+	sim.AddDeposit("BTC", "bc1qinternal", decimal.NewFromFloat(0.2), "")
+
+	var l = testLogger(t)
+	var jc = New(l, helpermethods.New(l, nil), config.InternalSettings{Url: sim.URL(), Key: testKey, Secret: testSecret}, nil)
+
+	got, err := jc.GetTransfers(context.Background(), "BTC", start, time.Now())
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 2 || got[0].Status != entity.TransferStatusComplete || got[0].TxId != "36e483ef" || got[1].Status != entity.TransferStatusPending {
+		t.Errorf("got %+v, wanted credited and pending deposits", got)
+	}
+	if balance, _ := sim.Balance("BTC"); !balance.Equal(decimal.NewFromFloat(0.5)) {
+		t.Errorf("got balance %v, wanted 0.5", balance)
+	}
+}
+
+func TestGetTransfers_MissingEndpoint_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var sim = jetcryptotest.NewServer(testKey, testSecret)
+	defer sim.Close()
+	sim.SetDepositEndpoint(false)
+
+	var l = testLogger(t)
+	var jc = New(l, helpermethods.New(l, nil), config.InternalSettings{Url: sim.URL(), Key: testKey, Secret: testSecret}, nil)
+
+	_, err := jc.GetTransfers(context.Background(), "BTC", time.Now().Add(-time.Hour), time.Now())
+	_, again := jc.GetTransfers(context.Background(), "BTC", time.Now().Add(-time.Hour), time.Now())
+
+	if !errors.Is(err, common.ErrNotSupported) || !errors.Is(again, common.ErrNotSupported) {
+		t.Errorf("got errors %v, %v, wanted %v", err, again, common.ErrNotSupported)
+	}
+	if got := sim.Requests("api/Trovemat/UserAccount/Deposits"); got != 1 {
+		t.Errorf("got %v requests, wanted 1", got)
+	}
+}
+
+func TestPartialFill_Simulator_Success(t *testing.T) {
+	t.Parallel()
+
+	var sim = jetcryptotest.NewServer(testKey, testSecret)
+	defer sim.Close()
+	sim.SetBalance("BTC", decimal.NewFromInt(1))
+
+	var l = testLogger(t)
+	var jc = New(l, helpermethods.New(l, nil), config.InternalSettings{Url: sim.URL(), Key: testKey, Secret: testSecret}, nil)
+	var ctx = context.Background()
+
+	id, err := jc.AddOrder(ctx, "BTC", "USDC", decimal.NewFromFloat(0.5), decimal.NewFromInt(20000), true)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	sim.CustomerBuy("BTC,USDC", decimal.NewFromInt(20000), decimal.NewFromFloat(0.2))
+
+	if err := jc.RemoveOrder(ctx, id, "BTC", "USDC"); err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	fills, err := jc.GetCompleteOrder(ctx, id, "BTC,USDC")
+	if err != nil || len(fills) != 1 || !fills[0].Amount.Equal(decimal.NewFromFloat(0.2)) {
+		t.Fatalf("got %v %v, wanted single fill of 0.2", fills, err)
+	}
+
+	balances, err := jc.GetBalances(ctx)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if got := balances["BTC"]; !got.Balance.Equal(decimal.NewFromFloat(0.8)) || !got.Reserved.IsZero() {
+		t.Errorf("got BTC %v reserved %v, wanted 0.8 reserved 0", got.Balance, got.Reserved)
+	}
+	if got := balances["USDC"]; !got.Balance.Equal(decimal.NewFromInt(4000)) {
+		t.Errorf("got USDC %v, wanted 4000", got.Balance)
+	}
+}
+
+func simulatorRequests(t *testing.T, balances int) (*JetCryptoRequests, *jetcryptotest.Server) {
+	t.Helper()
+
+	// rate limiter is shared per key, own key keeps the unlimited settings of the test
+	var key = testKey + "-" + t.Name()
+	var sim = jetcryptotest.NewServer(key, testSecret)
+	t.Cleanup(sim.Close)
+	for i := 0; i < balances; i++ {
+		sim.SetBalance(fmt.Sprintf("C%03d", i), decimal.NewFromInt(int64(i)))
+	}
+	// the rows the workers depend on are the last ones
+	sim.SetBalance("USDC", decimal.NewFromInt(5000))
+	sim.SetBalance("BTC", decimal.NewFromFloat(1.5))
+
+	var l = testLogger(t)
+	var unlimited = config.RateLimit{RequestsPerSecond: 1000}
+	return New(l, helpermethods.New(l, nil), config.InternalSettings{
+		Url:       sim.URL(),
+		Key:       secrets.Secret(key),
+		Secret:    testSecret,
+		RateLimit: config.RateLimitSettings{Public: unlimited, Private: unlimited, Order: unlimited},
+	}, nil), sim
+}
+
+func TestGetBalances_Paging_Success(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name     string
+		metadata bool
+	}{
+		{name: "plain list", metadata: false},
+		{name: "metadata", metadata: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var jc, sim = simulatorRequests(t, 120)
+			sim.SetBalancesPaging(50, tt.metadata)
+
+			got, err := jc.GetBalances(context.Background())
+
+			if err != nil {
+				t.Fatalf("got error %v, wanted nil", err)
+			}
+			if len(got) != 122 {
+				t.Errorf("got %v balances, wanted %v", len(got), 122)
+			}
+			if usdc, found := got["USDC"]; !found || !usdc.Balance.Equal(decimal.NewFromInt(5000)) {
+				t.Errorf("got USDC %v, wanted 5000", usdc)
+			}
+		})
+	}
+}
+
+func TestGetBalance_Success(t *testing.T) {
+	t.Parallel()
+
+	var jc, _ = simulatorRequests(t, 10)
+
+	got, err := jc.GetBalance(context.Background(), "BTC")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if !got.Balance.Equal(decimal.NewFromFloat(1.5)) {
+		t.Errorf("got %v, wanted %v", got.Balance, 1.5)
+	}
+}
+
+func TestGetBalance_EndpointMissing_Success(t *testing.T) {
+	t.Parallel()
+
+	var jc, sim = simulatorRequests(t, 60)
+	sim.SetBalanceEndpoint(false)
+	sim.SetBalancesPaging(25, false)
+
+	for i := 0; i < 2; i++ {
+		got, err := jc.GetBalance(context.Background(), "USDC")
+		if err != nil {
+			t.Fatalf("got error %v, wanted nil", err)
+		}
+		if !got.Balance.Equal(decimal.NewFromInt(5000)) {
+			t.Errorf("got %v, wanted %v", got.Balance, 5000)
+		}
+	}
+	if !jc.missingEndpoints[balanceEndpoint] {
+		t.Errorf("got %v missing %t, wanted %t", balanceEndpoint, jc.missingEndpoints[balanceEndpoint], true)
+	}
+}
+
+func TestGetBalance_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	for _, enabled := range []bool{true, false} {
+		var jc, sim = simulatorRequests(t, 3)
+		sim.SetBalanceEndpoint(enabled)
+
+		_, err := jc.GetBalance(context.Background(), "ETH")
+
+		if !errors.Is(err, common.ErrNotFound) {
+			t.Errorf("got error %v, wanted %v", err, common.ErrNotFound)
+		}
+	}
+}
+
+func TestModifyOrder_Success(t *testing.T) {
+	t.Parallel()
+
+	var jc, sim = simulatorRequests(t, 0)
+	id, err := jc.AddOrder(context.Background(), "BTC", "USDC", decimal.NewFromFloat(0.5), decimal.NewFromInt(20100), true)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	err = jc.ModifyOrder(context.Background(), id, "BTC", "USDC", decimal.NewFromFloat(0.8), decimal.NewFromInt(20120))
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	var order = sim.Orders()[0]
+	if order.Id != id || !order.AmountLeft.Equal(decimal.NewFromFloat(0.8)) || !order.Price.Equal(decimal.NewFromInt(20120)) {
+		t.Errorf("got %v %v %v, wanted %v 0.8 20120", order.Id, order.AmountLeft, order.Price, id)
+	}
+	if _, reserved := sim.Balance("BTC"); !reserved.Equal(decimal.NewFromFloat(0.8)) {
+		t.Errorf("got reserved %v, wanted 0.8", reserved)
+	}
+}
+
+func TestModifyOrder_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var jc, sim = simulatorRequests(t, 0)
+	id, _ := jc.AddOrder(context.Background(), "BTC", "USDC", decimal.NewFromFloat(0.5), decimal.NewFromInt(20100), true)
+
+	// the amount is more than the balance
+	var err = jc.ModifyOrder(context.Background(), id, "BTC", "USDC", decimal.NewFromInt(2), decimal.NewFromInt(20100))
+	if !errors.Is(err, common.ErrRejected) {
+		t.Errorf("got error %v, wanted %v", err, common.ErrRejected)
+	}
+
+	sim.SetModifyEndpoint(false)
+	for i := 0; i < 2; i++ {
+		err = jc.ModifyOrder(context.Background(), id, "BTC", "USDC", decimal.NewFromFloat(0.4), decimal.NewFromInt(20100))
+		if !errors.Is(err, common.ErrNotSupported) {
+			t.Errorf("got error %v, wanted %v", err, common.ErrNotSupported)
+		}
+	}
+	if !jc.missingEndpoints[modifyEndpoint] {
+		t.Errorf("got %v missing %t, wanted %t", modifyEndpoint, jc.missingEndpoints[modifyEndpoint], true)
+	}
+}
+
+func TestAddOrders_Success(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		batch    bool
+		requests map[string]int
+	}{
+		{batch: true, requests: map[string]int{addBatchEndpoint: 1, "api/Trading/Trade": 0}},
+		{batch: false, requests: map[string]int{addBatchEndpoint: 1, "api/Trading/Trade": 3}},
+	} {
+		t.Run(fmt.Sprintf("batch %t", tt.batch), func(t *testing.T) {
+			t.Parallel()
+
+			var jc, sim = simulatorRequests(t, 0)
+			sim.SetMinAmount("BTC,USDC", decimal.NewFromFloat(0.001))
+			sim.SetBatchEndpoints(tt.batch)
+			var orders = []*entity.NewInternalOrder{
+				{CurrencyFrom: "BTC", CurrencyTo: "USDC", Amount: decimal.NewFromFloat(0.5), Price: decimal.NewFromInt(20100), IsSellOrder: true},
+				// less than the pair minimum
+				{CurrencyFrom: "BTC", CurrencyTo: "USDC", Amount: decimal.NewFromFloat(0.0001), Price: decimal.NewFromInt(20150), IsSellOrder: true},
+				{CurrencyFrom: "BTC", CurrencyTo: "USDC", Amount: decimal.NewFromFloat(0.1), Price: decimal.NewFromInt(19900)},
+			}
+
+			got, err := jc.AddOrders(context.Background(), orders)
+
+			if err != nil {
+				t.Fatalf("got error %v, wanted nil", err)
+			}
+			if len(got) != 3 || got[0].Err != nil || !errors.Is(got[1].Err, common.ErrRejected) || got[2].Err != nil {
+				t.Fatalf("got %+v, wanted 3 results with the second rejected", got)
+			}
+			var posted = sim.Orders()
+			if len(posted) != 2 || posted[0].Id != got[0].Id || posted[1].Id != got[2].Id {
+				t.Errorf("got posted %v, wanted ids of the results", posted)
+			}
+			for endpoint, want := range tt.requests {
+				if got := sim.Requests(endpoint); got != want {
+					t.Errorf("got %v requests of %v, wanted %v", got, endpoint, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveOrders_Success(t *testing.T) {
+	t.Parallel()
+
+	for _, batch := range []bool{true, false} {
+		t.Run(fmt.Sprintf("batch %t", batch), func(t *testing.T) {
+			t.Parallel()
+
+			var jc, sim = simulatorRequests(t, 0)
+			sim.SetBatchEndpoints(batch)
+			id, _ := jc.AddOrder(context.Background(), "BTC", "USDC", decimal.NewFromFloat(0.5), decimal.NewFromInt(20100), true)
+			var unknown = uuid.Must(uuid.NewV4())
+
+			got, err := jc.RemoveOrders(context.Background(), []uuid.UUID{id, unknown}, "BTC", "USDC")
+
+			if err != nil {
+				t.Fatalf("got error %v, wanted nil", err)
+			}
+			if len(got) != 2 || got[0].Id != id || got[0].Err != nil || got[1].Id != unknown || !errors.Is(got[1].Err, common.ErrRejected) {
+				t.Errorf("got %+v, wanted removed %v and rejected %v", got, id, unknown)
+			}
+			if sim.Orders()[0].Active {
+				t.Errorf("got active order %v, wanted removed", id)
+			}
+		})
+	}
+}
+
+func TestRemoveAllOrders_Success(t *testing.T) {
+	t.Parallel()
+
+	for _, batch := range []bool{true, false} {
+		t.Run(fmt.Sprintf("batch %t", batch), func(t *testing.T) {
+			t.Parallel()
+
+			var jc, sim = simulatorRequests(t, 0)
+			sim.SetBatchEndpoints(batch)
+			for _, price := range []int64{20100, 20150} {
+				jc.AddOrder(context.Background(), "BTC", "USDC", decimal.NewFromFloat(0.5), decimal.NewFromInt(price), true)
+			}
+
+			got, err := jc.RemoveAllOrders(context.Background(), "BTC,USDC")
+
+			if err != nil {
+				t.Fatalf("got error %v, wanted nil", err)
+			}
+			if len(got) != 2 {
+				t.Errorf("got %v results, wanted 2", len(got))
+			}
+			if balance, reserved := sim.Balance("BTC"); !balance.Equal(decimal.NewFromFloat(1.5)) || !reserved.IsZero() {
+				t.Errorf("got balance %v reserved %v, wanted 1.5 and 0", balance, reserved)
+			}
+		})
+	}
+}
@@ -0,0 +1,903 @@
+// Package jetcryptotest provides in-process stand-in of the JetCrypto backend for tests.
+package jetcryptotest
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ErrorMode is the failure returned instead of processing the request
+type ErrorMode int
+
+const (
+	// ModeUnavailable responds with 503 status
+	ModeUnavailable ErrorMode = iota
+	// ModeServerError responds with 500 status
+	ModeServerError
+	// ModeEmpty responds with 200 status and empty body
+	ModeEmpty
+)
+
+// Error codes of the trading endpoints
+const (
+	ErrorCodeNone              = 0
+	ErrorCodeInsufficientFunds = 3
+	ErrorCodeInvalidOrder      = 4
+	ErrorCodeOrderNotActive    = 5
+)
+
+// Payment statuses, statuses from PaymentCompleted are completed
+const (
+	PaymentCreated   = 1
+	PaymentCompleted = 2
+)
+
+// Deposit statuses
+const (
+	DepositPending  = 1
+	DepositCredited = 2
+	DepositRejected = 3
+)
+
+// Order posted by the bot
+type Order struct {
+	Id           uuid.UUID
+	Pair         string
+	IsSellOrder  bool
+	Price        decimal.Decimal
+	Amount       decimal.Decimal
+	AmountLeft   decimal.Decimal
+	Active       bool
+	Fills        []Fill
+	CurrencyFrom string
+	CurrencyTo   string
+	seq          int64
+}
+
+// Fill of the posted order by the customer
+type Fill struct {
+	OrderId uuid.UUID
+	Price   decimal.Decimal
+	Amount  decimal.Decimal
+}
+
+// Payment is the withdrawal from the internal account
+type Payment struct {
+	Id             int64
+	UuId           string
+	CurrencyId     string
+	Address        string
+	DestinationTag string
+	Amount         decimal.Decimal
+	StatusId       int
+}
+
+// Deposit to the internal account, the balance is increased when it's credited
+type Deposit struct {
+	Id            int64
+	Currency      string
+	Address       string
+	Amount        decimal.Decimal
+	TxId          string
+	Confirmations int
+	StatusId      int
+	Created       time.Time
+}
+
+type balance struct {
+	balance  decimal.Decimal
+	reserved decimal.Decimal
+}
+
+type failure struct {
+	mode  ErrorMode
+	times int
+}
+
+// Server emulates the JetCrypto endpoints used by JetCryptoRequests. Posted orders reserve funds and rest in the book
+// until they are filled by CustomerBuy and CustomerSell (price-time priority) or removed.
+// Requests must be signed with HMAC-SHA512 of the full url.
+type Server struct {
+	server      *httptest.Server
+	mu          sync.Mutex
+	key         string
+	secret      string
+	minAmounts  map[string]decimal.Decimal
+	precisions  map[string][2]int32
+	balances    map[string]*balance
+	currencies  []string
+	addresses   map[string]string
+	currencyIds map[string]string
+	orders      map[uuid.UUID]*Order
+	payments    []*Payment
+	deposits    []*Deposit
+	failures    map[string][]*failure
+	seq         int64
+	// paging of the balances, maxItemsPerPage 0 accepts any page size
+	maxItemsPerPage int
+	pagingMetadata  bool
+	balanceEndpoint bool
+	modifyEndpoint  bool
+	batchEndpoints  bool
+	depositEndpoint bool
+	// served requests by path
+	requests map[string]int
+}
+
+// NewServer starts the stand-in accepting requests signed with the key and secret
+func NewServer(key string, secret string) *Server {
+	var s = &Server{
+		key:             key,
+		secret:          secret,
+		minAmounts:      make(map[string]decimal.Decimal),
+		precisions:      make(map[string][2]int32),
+		balances:        make(map[string]*balance),
+		addresses:       make(map[string]string),
+		currencyIds:     make(map[string]string),
+		orders:          make(map[uuid.UUID]*Order),
+		failures:        make(map[string][]*failure),
+		balanceEndpoint: true,
+		modifyEndpoint:  true,
+		batchEndpoints:  true,
+		depositEndpoint: true,
+		requests:        make(map[string]int),
+	}
+
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/api/Trading/ActiveOrders", s.signed(s.activeOrders))
+	mux.HandleFunc("/api/Trading/Trade", s.signed(s.trade))
+	mux.HandleFunc("/api/Trading/RemoveOrder", s.signed(s.removeOrder))
+	mux.HandleFunc("/api/Trading/ModifyOrder", s.signed(s.modifyOrder))
+	mux.HandleFunc("/api/Trading/TradeBatch", s.signed(s.tradeBatch))
+	mux.HandleFunc("/api/Trading/RemoveOrders", s.signed(s.removeOrders))
+	mux.HandleFunc("/api/Trading/RemoveAllOrders", s.signed(s.removeAllOrders))
+	mux.HandleFunc("/api/Trading/CompletedOrderInfo", s.signed(s.completedOrderInfo))
+	mux.HandleFunc("/api/Trading/OrderInfo", s.signed(s.orderInfo))
+	mux.HandleFunc("/api/Trading/Info", s.signed(s.info))
+	mux.HandleFunc("/api/Device/UserAccount", s.signed(s.userAccount))
+	mux.HandleFunc("/api/Device/UserAccount/Balance", s.signed(s.userAccountBalance))
+	mux.HandleFunc("/api/Trovemat/Payment", s.signed(s.payment))
+	mux.HandleFunc("/api/Trovemat/UserAccount/getCryptoAddress", s.signed(s.cryptoAddress))
+	mux.HandleFunc("/api/Trovemat/UserAccount/Deposits", s.signed(s.depositList))
+	s.server = httptest.NewServer(mux)
+
+	return s
+}
+
+// URL of the stand-in, it's used as InternalSettings.Url
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// SetMinAmount of the pair ("BTC,USDC")
+func (s *Server) SetMinAmount(pair string, amount decimal.Decimal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.minAmounts[pair] = amount
+}
+
+// SetPrecision of the pair ("BTC,USDC"), orders with more decimal places of the price or the amount are rejected
+func (s *Server) SetPrecision(pair string, pricePrecision int32, amountPrecision int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.precisions[pair] = [2]int32{pricePrecision, amountPrecision}
+}
+
+// SetBalance sets available balance of the currency, reserved funds are kept
+func (s *Server) SetBalance(currency string, amount decimal.Decimal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.account(currency).balance = amount
+}
+
+// Balance returns available and reserved funds of the currency
+func (s *Server) Balance(currency string) (decimal.Decimal, decimal.Decimal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b = s.account(currency)
+	return b.balance, b.reserved
+}
+
+func (s *Server) SetCryptoAddress(currency string, address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addresses[currency] = address
+}
+
+// SetCurrencyId maps CurrencyId of the withdrawals to the currency
+func (s *Server) SetCurrencyId(currencyId int, currency string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.currencyIds[strconv.Itoa(currencyId)] = currency
+}
+
+// SetBalancesPaging caps the page size of the balances, with metadata the page is wrapped into the object
+// with items, page, itemsPerPage, totalCount and totalPages instead of the plain list
+func (s *Server) SetBalancesPaging(maxItemsPerPage int, metadata bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxItemsPerPage = maxItemsPerPage
+	s.pagingMetadata = metadata
+}
+
+// SetBalanceEndpoint enables the per currency balance endpoint, disabled endpoint responds with 404 status
+func (s *Server) SetBalanceEndpoint(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.balanceEndpoint = enabled
+}
+
+// SetModifyEndpoint enables the modify order endpoint, disabled endpoint responds with 404 status
+func (s *Server) SetModifyEndpoint(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.modifyEndpoint = enabled
+}
+
+// SetBatchEndpoints enables the batch placement and removal endpoints, disabled endpoints respond with 404 status
+func (s *Server) SetBatchEndpoints(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batchEndpoints = enabled
+}
+
+// Requests returns the number of signed requests of the endpoint ("api/Trading/Trade")
+func (s *Server) Requests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests["/"+strings.TrimPrefix(endpoint, "/")]
+}
+
+// FailNext makes the next times requests of the endpoint ("api/Trading/Trade") fail with the mode
+func (s *Server) FailNext(endpoint string, mode ErrorMode, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var path = "/" + strings.TrimPrefix(endpoint, "/")
+	s.failures[path] = append(s.failures[path], &failure{mode: mode, times: times})
+}
+
+// Orders returns copies of all posted orders in posting order
+func (s *Server) Orders() []Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res = make([]Order, 0, len(s.orders))
+	for _, order := range s.orders {
+		var copied = *order
+		copied.Fills = append([]Fill(nil), order.Fills...)
+		res = append(res, copied)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].seq < res[j].seq })
+
+	return res
+}
+
+// Payments returns copies of the withdrawals
+func (s *Server) Payments() []Payment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res = make([]Payment, 0, len(s.payments))
+	for _, payment := range s.payments {
+		res = append(res, *payment)
+	}
+
+	return res
+}
+
+// CompletePayment marks the withdrawal as completed
+func (s *Server) CompletePayment(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, payment := range s.payments {
+		if payment.Id == id {
+			payment.StatusId = PaymentCompleted
+		}
+	}
+}
+
+// AddDeposit adds the pending deposit of the currency to the address, returns the id of the deposit
+func (s *Server) AddDeposit(currency string, address string, amount decimal.Decimal, txId string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	s.deposits = append(s.deposits, &Deposit{
+		Id:       s.seq,
+		Currency: currency,
+		Address:  address,
+		Amount:   amount,
+		TxId:     txId,
+		StatusId: DepositPending,
+		Created:  time.Now().UTC(),
+	})
+
+	return s.seq
+}
+
+// CreditDeposit confirms the deposit and adds its amount to the balance
+func (s *Server) CreditDeposit(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, deposit := range s.deposits {
+		if deposit.Id == id && deposit.StatusId != DepositCredited {
+			deposit.StatusId = DepositCredited
+			deposit.Confirmations = 6
+			var account = s.account(deposit.Currency)
+			account.balance = account.balance.Add(deposit.Amount)
+		}
+	}
+}
+
+// SetDepositEndpoint enables the deposits endpoint, disabled endpoint responds with 404 status
+func (s *Server) SetDepositEndpoint(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.depositEndpoint = enabled
+}
+
+// CustomerBuy fills our sell orders of the pair with price not higher than the price, returns the filled amount
+func (s *Server) CustomerBuy(pair string, price decimal.Decimal, amount decimal.Decimal) decimal.Decimal {
+	return s.match(pair, true, price, amount)
+}
+
+// CustomerSell fills our buy orders of the pair with price not lower than the price, returns the filled amount
+func (s *Server) CustomerSell(pair string, price decimal.Decimal, amount decimal.Decimal) decimal.Decimal {
+	return s.match(pair, false, price, amount)
+}
+
+func (s *Server) match(pair string, customerBuys bool, price decimal.Decimal, amount decimal.Decimal) decimal.Decimal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var candidates []*Order
+	for _, order := range s.orders {
+		if !order.Active || order.Pair != pair || order.IsSellOrder != customerBuys {
+			continue
+		}
+		if (customerBuys && order.Price.GreaterThan(price)) || (!customerBuys && order.Price.LessThan(price)) {
+			continue
+		}
+		candidates = append(candidates, order)
+	}
+
+	// best price first, then the oldest order
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].Price.Equal(candidates[j].Price) {
+			return candidates[i].Price.LessThan(candidates[j].Price) == customerBuys
+		}
+		return candidates[i].seq < candidates[j].seq
+	})
+
+	var filled = decimal.Zero
+	for _, order := range candidates {
+		var left = amount.Sub(filled)
+		if !left.IsPositive() {
+			break
+		}
+
+		var volume = decimal.Min(order.AmountLeft, left)
+		s.fill(order, volume)
+		filled = filled.Add(volume)
+	}
+
+	return filled
+}
+
+// fill moves the reserved funds of the order to the counter currency
+func (s *Server) fill(order *Order, volume decimal.Decimal) {
+	var total = volume.Mul(order.Price).RoundDown(8)
+	var from = s.account(order.CurrencyFrom)
+	var to = s.account(order.CurrencyTo)
+
+	if order.IsSellOrder {
+		from.reserved = from.reserved.Sub(volume)
+		to.balance = to.balance.Add(total)
+	} else {
+		to.reserved = to.reserved.Sub(total)
+		from.balance = from.balance.Add(volume)
+	}
+
+	order.AmountLeft = order.AmountLeft.Sub(volume)
+	order.Fills = append(order.Fills, Fill{OrderId: order.Id, Price: order.Price, Amount: volume})
+	if !order.AmountLeft.IsPositive() {
+		order.Active = false
+	}
+}
+
+// signed checks the key and the signature of the full url and applies the queued failures
+func (s *Server) signed(handler func(w http.ResponseWriter, params url.Values)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var fullUrl = "http://" + r.Host + r.URL.RequestURI()
+		var mac = hmac.New(sha512.New, []byte(s.secret))
+		mac.Write([]byte(fullUrl))
+		if r.Header.Get("Key") != s.key || r.Header.Get("Sign") != fmt.Sprintf("%x", mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests[r.URL.Path]++
+		if s.fail(w, r.URL.Path) {
+			return
+		}
+
+		// POST requests repeat the query in the body
+		var params = r.URL.Query()
+		if r.Method == http.MethodPost {
+			params.Set("method", "post")
+		}
+		handler(w, params)
+	}
+}
+
+func (s *Server) fail(w http.ResponseWriter, path string) bool {
+	var queue = s.failures[path]
+	if len(queue) == 0 {
+		return false
+	}
+
+	var next = queue[0]
+	next.times--
+	if next.times <= 0 {
+		s.failures[path] = queue[1:]
+	}
+
+	switch next.mode {
+	case ModeUnavailable:
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "<html>503 Service Temporarily Unavailable</html>")
+	case ModeServerError:
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"message":"An error has occurred."}`)
+	case ModeEmpty:
+	}
+
+	return true
+}
+
+func (s *Server) activeOrders(w http.ResponseWriter, params url.Values) {
+	var pair = params.Get("tradingPair")
+	var res = make([]interface{}, 0)
+	for _, order := range s.sortedOrders() {
+		if order.Active && order.Pair == pair {
+			res = append(res, orderJson(order))
+		}
+	}
+
+	writeJson(w, res)
+}
+
+func (s *Server) trade(w http.ResponseWriter, params url.Values) {
+	var id, code = s.placeOrder(params.Get("currencyFrom"), params.Get("currencyTo"), params.Get("amount"), params.Get("price"), params.Get("isSellOrder"))
+
+	writeJson(w, map[string]interface{}{"id": id, "errorCode": code})
+}
+
+// tradeBatch places the orders of the json list, results are in the order of the orders
+func (s *Server) tradeBatch(w http.ResponseWriter, params url.Values) {
+	if !s.batchEndpoints {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var orders []struct {
+		CurrencyFrom string          `json:"currencyFrom"`
+		CurrencyTo   string          `json:"currencyTo"`
+		Amount       decimal.Decimal `json:"amount"`
+		Price        decimal.Decimal `json:"price"`
+		IsSellOrder  bool            `json:"isSellOrder"`
+	}
+	if err := json.Unmarshal([]byte(params.Get("orders")), &orders); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var res = make([]interface{}, 0, len(orders))
+	for _, order := range orders {
+		var id, code = s.placeOrder(order.CurrencyFrom, order.CurrencyTo, order.Amount.String(), order.Price.String(), strconv.FormatBool(order.IsSellOrder))
+		res = append(res, map[string]interface{}{"id": id, "errorCode": code})
+	}
+
+	writeJson(w, res)
+}
+
+// placeOrder reserves the funds and adds the order to the book, returns id of the order and the error code
+func (s *Server) placeOrder(from string, to string, amountStr string, priceStr string, isSellStr string) (string, int) {
+	var pair = from + "," + to
+	amount, err1 := decimal.NewFromString(amountStr)
+	price, err2 := decimal.NewFromString(priceStr)
+	isSell, err3 := strconv.ParseBool(isSellStr)
+	if err1 != nil || err2 != nil || err3 != nil || !price.IsPositive() || amount.LessThan(s.minAmounts[pair]) || !amount.IsPositive() || !s.precise(pair, price, amount) {
+		return "", ErrorCodeInvalidOrder
+	}
+
+	// funds are reserved until the order is filled or removed
+	var account, reserve = s.account(to), amount.Mul(price).RoundDown(8)
+	if isSell {
+		account, reserve = s.account(from), amount
+	}
+	if account.balance.LessThan(reserve) {
+		return "", ErrorCodeInsufficientFunds
+	}
+	account.balance = account.balance.Sub(reserve)
+	account.reserved = account.reserved.Add(reserve)
+
+	s.seq++
+	var id, _ = uuid.NewV4()
+	s.orders[id] = &Order{
+		Id:           id,
+		Pair:         pair,
+		IsSellOrder:  isSell,
+		Price:        price,
+		Amount:       amount,
+		AmountLeft:   amount,
+		Active:       true,
+		CurrencyFrom: from,
+		CurrencyTo:   to,
+		seq:          s.seq,
+	}
+
+	return id.String(), ErrorCodeNone
+}
+
+func (s *Server) removeOrder(w http.ResponseWriter, params url.Values) {
+	writeJson(w, map[string]interface{}{"errorCode": s.cancelOrder(uuid.FromStringOrNil(params.Get("id")))})
+}
+
+// removeOrders removes the orders of the comma separated ids, results are in the order of the ids
+func (s *Server) removeOrders(w http.ResponseWriter, params url.Values) {
+	if !s.batchEndpoints {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var res = make([]interface{}, 0)
+	for _, id := range strings.Split(params.Get("ids"), ",") {
+		res = append(res, map[string]interface{}{"id": id, "errorCode": s.cancelOrder(uuid.FromStringOrNil(id))})
+	}
+
+	writeJson(w, res)
+}
+
+// removeAllOrders removes the active orders of the pair and returns them
+func (s *Server) removeAllOrders(w http.ResponseWriter, params url.Values) {
+	if !s.batchEndpoints {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var res = make([]interface{}, 0)
+	for _, order := range s.sortedOrders() {
+		if order.Active && order.Pair == params.Get("tradingPair") {
+			res = append(res, map[string]interface{}{"id": order.Id.String(), "errorCode": s.cancelOrder(order.Id)})
+		}
+	}
+
+	writeJson(w, res)
+}
+
+// cancelOrder releases the rest of the reserved funds and deactivates the order, returns the error code
+func (s *Server) cancelOrder(id uuid.UUID) int {
+	var order, found = s.orders[id]
+	if !found || !order.Active {
+		return ErrorCodeOrderNotActive
+	}
+
+	// release the rest of the reserved funds
+	if order.IsSellOrder {
+		var account = s.account(order.CurrencyFrom)
+		account.reserved = account.reserved.Sub(order.AmountLeft)
+		account.balance = account.balance.Add(order.AmountLeft)
+	} else {
+		var account = s.account(order.CurrencyTo)
+		var rest = order.AmountLeft.Mul(order.Price).RoundDown(8)
+		account.reserved = account.reserved.Sub(rest)
+		account.balance = account.balance.Add(rest)
+	}
+	order.Active = false
+
+	return ErrorCodeNone
+}
+
+// modifyOrder changes price and amount left of the active order, the reservation follows the new amount left.
+// The order keeps its time priority unless the price is changed.
+func (s *Server) modifyOrder(w http.ResponseWriter, params url.Values) {
+	if !s.modifyEndpoint {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var order, found = s.orders[uuid.FromStringOrNil(params.Get("id"))]
+	if !found || !order.Active {
+		writeJson(w, map[string]interface{}{"errorCode": ErrorCodeOrderNotActive})
+		return
+	}
+	amount, err1 := decimal.NewFromString(params.Get("amount"))
+	price, err2 := decimal.NewFromString(params.Get("price"))
+	if err1 != nil || err2 != nil || !price.IsPositive() || amount.LessThan(s.minAmounts[order.Pair]) || !amount.IsPositive() || !s.precise(order.Pair, price, amount) {
+		writeJson(w, map[string]interface{}{"errorCode": ErrorCodeInvalidOrder})
+		return
+	}
+
+	var account = s.account(order.CurrencyTo)
+	var reserved, reserve = order.AmountLeft.Mul(order.Price).RoundDown(8), amount.Mul(price).RoundDown(8)
+	if order.IsSellOrder {
+		account, reserved, reserve = s.account(order.CurrencyFrom), order.AmountLeft, amount
+	}
+	if account.balance.Add(reserved).LessThan(reserve) {
+		writeJson(w, map[string]interface{}{"errorCode": ErrorCodeInsufficientFunds})
+		return
+	}
+	account.balance = account.balance.Add(reserved).Sub(reserve)
+	account.reserved = account.reserved.Sub(reserved).Add(reserve)
+
+	if !order.Price.Equal(price) {
+		s.seq++
+		order.seq = s.seq
+	}
+	order.Amount = order.Amount.Sub(order.AmountLeft).Add(amount)
+	order.AmountLeft = amount
+	order.Price = price
+
+	writeJson(w, map[string]interface{}{"errorCode": ErrorCodeNone})
+}
+
+// completedOrderInfo returns fills of the order, amount of the fill is in initialAmount
+func (s *Server) completedOrderInfo(w http.ResponseWriter, params url.Values) {
+	var res = make([]interface{}, 0)
+	if order, found := s.orders[uuid.FromStringOrNil(params.Get("orderId"))]; found {
+		for _, item := range order.Fills {
+			res = append(res, map[string]interface{}{
+				"id":            order.Id.String(),
+				"initialAmount": item.Amount,
+				"amountLeft":    order.AmountLeft,
+				"price":         item.Price,
+				"isSellOrder":   order.IsSellOrder,
+			})
+		}
+	}
+
+	writeJson(w, res)
+}
+
+func (s *Server) orderInfo(w http.ResponseWriter, params url.Values) {
+	var order, found = s.orders[uuid.FromStringOrNil(params.Get("orderId"))]
+	if !found {
+		io.WriteString(w, "null")
+		return
+	}
+
+	writeJson(w, orderJson(order))
+}
+
+func (s *Server) info(w http.ResponseWriter, params url.Values) {
+	var pair = params.Get("tradingPair")
+	var res = map[string]interface{}{"minAmount": s.minAmounts[pair]}
+	if precision, found := s.precisions[pair]; found {
+		res["pricePrecision"] = precision[0]
+		res["amountPrecision"] = precision[1]
+	}
+
+	writeJson(w, res)
+}
+
+// precise reports whether the price and the amount fit the precision of the pair
+func (s *Server) precise(pair string, price decimal.Decimal, amount decimal.Decimal) bool {
+	var precision, found = s.precisions[pair]
+	return !found || (price.Equal(price.Truncate(precision[0])) && amount.Equal(amount.Truncate(precision[1])))
+}
+
+// userAccount returns the page of the balances in the order the currencies were added
+func (s *Server) userAccount(w http.ResponseWriter, params url.Values) {
+	var page, _ = strconv.Atoi(params.Get("page"))
+	var itemsPerPage, _ = strconv.Atoi(params.Get("itemsPerPage"))
+	if page < 1 {
+		page = 1
+	}
+	if itemsPerPage < 1 {
+		itemsPerPage = 10
+	}
+
+	if s.maxItemsPerPage > 0 && itemsPerPage > s.maxItemsPerPage {
+		itemsPerPage = s.maxItemsPerPage
+	}
+
+	var res = make([]interface{}, 0)
+	for i := (page - 1) * itemsPerPage; i < len(s.currencies) && i < page*itemsPerPage; i++ {
+		res = append(res, s.balanceJson(s.currencies[i]))
+	}
+
+	if !s.pagingMetadata {
+		writeJson(w, res)
+		return
+	}
+
+	writeJson(w, map[string]interface{}{
+		"items":        res,
+		"page":         page,
+		"itemsPerPage": itemsPerPage,
+		"totalCount":   len(s.currencies),
+		"totalPages":   (len(s.currencies) + itemsPerPage - 1) / itemsPerPage,
+	})
+}
+
+// userAccountBalance returns the balance of the single currency, null for the unknown currency
+func (s *Server) userAccountBalance(w http.ResponseWriter, params url.Values) {
+	if !s.balanceEndpoint {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var currency = params.Get("currencyIsoCode")
+	if _, found := s.balances[currency]; !found {
+		writeJson(w, nil)
+		return
+	}
+
+	writeJson(w, s.balanceJson(currency))
+}
+
+func (s *Server) balanceJson(currency string) map[string]interface{} {
+	var b = s.balances[currency]
+	return map[string]interface{}{
+		"currencyIsoCode": currency,
+		"balance":         b.balance,
+		"reserved":        b.reserved,
+	}
+}
+
+// payment returns status of the payment on GET and creates the withdrawal on POST
+func (s *Server) payment(w http.ResponseWriter, params url.Values) {
+	if params.Get("method") != "post" {
+		var id = params.Get("orderId")
+		for _, payment := range s.payments {
+			if payment.UuId == id || strconv.FormatInt(payment.Id, 10) == id {
+				writeJson(w, map[string]interface{}{"statusId": payment.StatusId})
+				return
+			}
+		}
+		writeJson(w, map[string]interface{}{"statusId": 0})
+		return
+	}
+
+	var destination = struct {
+		Address        string `json:"address"`
+		DestinationTag string `json:"destinationTag"`
+	}{}
+	json.Unmarshal([]byte(params.Get("params")), &destination)
+
+	amount, err := decimal.NewFromString(params.Get("amount"))
+	if err != nil || !amount.IsPositive() || len(destination.Address) == 0 {
+		writeJson(w, map[string]interface{}{"id": nil, "message": "invalid payment"})
+		return
+	}
+
+	// currency is identified by the CurrencyId of the bot settings
+	var account = s.account(s.currencyIds[params.Get("currencyId")])
+	if account.balance.LessThan(amount) {
+		writeJson(w, map[string]interface{}{"id": nil, "message": "insufficient funds"})
+		return
+	}
+	account.balance = account.balance.Sub(amount)
+
+	s.seq++
+	s.payments = append(s.payments, &Payment{
+		Id:             s.seq,
+		UuId:           params.Get("uuId"),
+		CurrencyId:     params.Get("currencyId"),
+		Address:        destination.Address,
+		DestinationTag: destination.DestinationTag,
+		Amount:         amount,
+		StatusId:       PaymentCreated,
+	})
+
+	writeJson(w, map[string]interface{}{"id": s.seq})
+}
+
+// depositList returns the deposits of the currency created between dateFrom and dateTo
+func (s *Server) depositList(w http.ResponseWriter, params url.Values) {
+	if !s.depositEndpoint {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var from, _ = time.Parse(time.RFC3339, params.Get("dateFrom"))
+	var to, err = time.Parse(time.RFC3339, params.Get("dateTo"))
+	if err != nil {
+		to = time.Now()
+	}
+
+	var res = make([]map[string]interface{}, 0)
+	for _, deposit := range s.deposits {
+		if deposit.Currency != params.Get("currencyName") || deposit.Created.Before(from) || deposit.Created.After(to.Add(time.Second)) {
+			continue
+		}
+		res = append(res, map[string]interface{}{
+			"id":            deposit.Id,
+			"currencyName":  deposit.Currency,
+			"address":       deposit.Address,
+			"amount":        deposit.Amount,
+			"txId":          deposit.TxId,
+			"confirmations": deposit.Confirmations,
+			"statusId":      deposit.StatusId,
+			"created":       deposit.Created.Format(time.RFC3339Nano),
+		})
+	}
+
+	writeJson(w, res)
+}
+
+func (s *Server) cryptoAddress(w http.ResponseWriter, params url.Values) {
+	writeJson(w, map[string]interface{}{"cryptoAddress": s.addresses[params.Get("currencyName")]})
+}
+
+func (s *Server) account(currency string) *balance {
+	var b, found = s.balances[currency]
+	if !found {
+		b = &balance{}
+		s.balances[currency] = b
+		s.currencies = append(s.currencies, currency)
+	}
+	return b
+}
+
+func (s *Server) sortedOrders() []*Order {
+	var res = make([]*Order, 0, len(s.orders))
+	for _, order := range s.orders {
+		res = append(res, order)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].seq < res[j].seq })
+
+	return res
+}
+
+func orderJson(order *Order) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            order.Id.String(),
+		"initialAmount": order.Amount,
+		"amountLeft":    order.AmountLeft,
+		"price":         order.Price,
+		"isSellOrder":   order.IsSellOrder,
+	}
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -3,27 +3,69 @@ package poloniex
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 	"trading_bot/config"
 	"trading_bot/internal/common"
+	"trading_bot/internal/common/clientid"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/nonce"
+	"trading_bot/internal/common/orderbook"
+	"trading_bot/internal/common/ratelimit"
+	"trading_bot/internal/common/retry"
+	"trading_bot/internal/common/signer"
 	"trading_bot/internal/entity"
 	"trading_bot/pkg/logger"
 
 	"github.com/shopspring/decimal"
 )
 
+const (
+	systemName = "Poloniex"
+
+	// dates of the trades and orders are UTC
+	dateLayout = "2006-01-02 15:04:05"
+	// max trades of returnTradeHistory
+	tradeHistoryLimit = 10000
+)
+
+// fees are requested again after the hour
+const feeCacheTime = 1 * time.Hour
+
+// fees of the hedges when the fees of the account are unknown, trade volume < 50k USD @ 30 days
+var defaultFees = entity.FeeSchedule{MakerFee: decimal.NewFromFloat(0.0015), TakerFee: decimal.NewFromFloat(0.0025)}
+
+// min totals of the orders by the quote currency, the legacy API has no market info endpoint
+var minTotals = map[string]decimal.Decimal{
+	"BTC":  decimal.NewFromFloat(0.0001),
+	"USDT": decimal.NewFromInt(1),
+	"USDC": decimal.NewFromInt(1),
+}
+
+// legacy API allows 6 calls per second to the trading API
+var defaultRateLimits = config.RateLimitSettings{
+	Public:  config.RateLimit{RequestsPerSecond: 6, Burst: 6},
+	Private: config.RateLimit{RequestsPerSecond: 4, Burst: 4},
+	Order:   config.RateLimit{RequestsPerSecond: 2, Burst: 2},
+}
+
 type PoloniexRequests struct {
 	logger        logger.ILogger
 	helperMethods common.IHelperMethods
 	cacheUpdate   time.Time
 	balanceCache  map[string]*entity.BalanceObject
+	feeCache      *entity.FeeSchedule
 	baseUrl       string
 	publicKey     string
-	secretKey     string
+	signer        signer.Signer
+	retryPolicy   retry.Policy
+	rateLimiter   *ratelimit.Limiter
+	nonces        *nonce.Provider
 }
 
 func New(l logger.ILogger, hm common.IHelperMethods, cs config.TradingSettings) *PoloniexRequests {
@@ -33,29 +75,43 @@ func New(l logger.ILogger, hm common.IHelperMethods, cs config.TradingSettings)
 		cacheUpdate:   time.Time{},
 		balanceCache:  make(map[string]*entity.BalanceObject),
 		baseUrl:       cs.Url,
-		publicKey:     cs.Key,
-		secretKey:     cs.Secret,
+		publicKey:     cs.Key.Reveal(),
+		signer:        signer.Resolve(cs.Signer, signer.TypeHmacSha512Query, cs.Secret.Reveal()),
+		retryPolicy:   retry.NewPolicy(cs.Retry),
+		rateLimiter:   ratelimit.Shared(systemName, cs.Key.Reveal(), cs.RateLimit, defaultRateLimits),
+		nonces:        nonce.Shared(systemName, cs.Key.Reveal(), noncePath(cs)),
 	}
 }
 
-func (pr *PoloniexRequests) GetTradingBalances(ctx context.Context) map[string]*entity.BalanceObject {
+// noncePath returns file of the persisted nonce of the key, nonces are kept in memory when NonceDir is empty
+func noncePath(cs config.TradingSettings) string {
+	if len(cs.NonceDir) == 0 {
+		return ""
+	}
+	return filepath.Join(cs.NonceDir, nonce.FileName(systemName, cs.Key.Reveal()))
+}
+
+func (pr *PoloniexRequests) GetTradingBalances(ctx context.Context) (map[string]*entity.BalanceObject, error) {
 	// 1 minute cache
 	if pr.cacheUpdate.Add(1 * time.Minute).After(time.Now().UTC()) {
-		return pr.balanceCache
+		return pr.balanceCache, nil
 	}
 
-	var balancesStr = pr.queryPrivate(ctx, "returnBalances", map[string]string{})
-	if len(balancesStr) == 0 {
-		return nil
+	var balancesStr, err = pr.queryPrivate(ctx, "returnBalances", map[string]string{})
+	if err != nil {
+		return nil, err
 	}
 
-	var balances map[string]interface{}
-	json.Unmarshal([]byte(balancesStr), &balances)
+	var balances map[string]string
+	err = json.Unmarshal([]byte(balancesStr), &balances)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "returnBalances", 200, balancesStr)
+	}
 
 	var res = make(map[string]*entity.BalanceObject)
 
 	for key, val := range balances {
-		var bal, _ = decimal.NewFromString(val.(string))
+		var bal, _ = decimal.NewFromString(val)
 		res[key] = &entity.BalanceObject{
 			Currency: key,
 			Balance:  bal,
@@ -65,7 +121,69 @@ func (pr *PoloniexRequests) GetTradingBalances(ctx context.Context) map[string]*
 	pr.balanceCache = res
 	pr.cacheUpdate = time.Now().UTC()
 
-	return res
+	return res, nil
+}
+
+// GetFees returns maker and taker fees of the account, fees are cached for an hour.
+// Cached fees are returned when they can't be refreshed.
+func (pr *PoloniexRequests) GetFees(ctx context.Context, tradingSystemPair string) (*entity.FeeSchedule, error) {
+	if pr.feeCache != nil && pr.feeCache.Updated.Add(feeCacheTime).After(time.Now().UTC()) {
+		return pr.feeCache, nil
+	}
+
+	var feeResponse, err = pr.queryPrivate(ctx, "returnFeeInfo", map[string]string{})
+	if err != nil {
+		if pr.feeCache != nil {
+			pr.logger.Error("Poloniex : can't refresh fees, cached fees are used : %v", err)
+			return pr.feeCache, nil
+		}
+		return nil, err
+	}
+
+	fees := struct {
+		MakerFee decimal.Decimal `json:"makerFee"`
+		TakerFee decimal.Decimal `json:"takerFee"`
+	}{}
+	err = json.Unmarshal([]byte(feeResponse), &fees)
+	if err != nil || fees.TakerFee.IsNegative() || fees.TakerFee.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "returnFeeInfo", 200, feeResponse)
+	}
+
+	if pr.feeCache == nil || !pr.feeCache.TakerFee.Equal(fees.TakerFee) || !pr.feeCache.MakerFee.Equal(fees.MakerFee) {
+		pr.logger.Info("Poloniex : fees are maker %v, taker %v", fees.MakerFee, fees.TakerFee)
+	}
+	pr.feeCache = &entity.FeeSchedule{MakerFee: fees.MakerFee, TakerFee: fees.TakerFee, Updated: time.Now().UTC()}
+
+	return pr.feeCache, nil
+}
+
+// GetMarketInfo returns the trading rules of the pair. The legacy API has no market info endpoint,
+// prices and amounts have 8 decimal places and totals are limited by the quote currency.
+func (pr *PoloniexRequests) GetMarketInfo(ctx context.Context, tradingSystemPair string) (*entity.MarketInfo, error) {
+	var quote = strings.Split(tradingSystemPair, "_")[0]
+	return &entity.MarketInfo{
+		Pair:        tradingSystemPair,
+		PriceStep:   entity.StepFromPrecision(8),
+		AmountStep:  entity.StepFromPrecision(8),
+		MinNotional: minTotals[quote],
+		Updated:     time.Now().UTC(),
+	}, nil
+}
+
+// orderRules returns the trading rules of the hedge order
+func (pr *PoloniexRequests) orderRules(ctx context.Context, tradingSystemPair string) *entity.MarketInfo {
+	var rules, _ = pr.GetMarketInfo(ctx, tradingSystemPair)
+	return rules
+}
+
+// hedgeFees returns fees of the hedge order, the default fees are used when the fees of the account are unknown
+func (pr *PoloniexRequests) hedgeFees(ctx context.Context, tradingSystemPair string) *entity.FeeSchedule {
+	var fees, err = pr.GetFees(ctx, tradingSystemPair)
+	if err != nil {
+		pr.logger.Error("Poloniex : can't get fees, default taker fee %v is used : %v", defaultFees.TakerFee, err)
+		return &defaultFees
+	}
+	return fees
 }
 
 func (ord *orderItem) UnmarshalJSON(data []byte) error {
@@ -73,354 +191,457 @@ func (ord *orderItem) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &v); err != nil {
 		return err
 	}
-	ord.Price, _ = decimal.NewFromString(v[0].(string))
-	ord.Volume = decimal.NewFromFloat(v[1].(float64))
+	if len(v) < 2 {
+		return fmt.Errorf("poloniex : unexpected order book level %s", data)
+	}
+	ord.Price = toDecimal(v[0])
+	ord.Volume = toDecimal(v[1])
 
 	return nil
 }
 
-func (pr *PoloniexRequests) GetPublicTradingOrders(ctx context.Context, tradingSystemPair string, usdcTradingLimit decimal.Decimal, cryptoTradingLimit decimal.Decimal, internalCryptoBalance decimal.Decimal, internalUsdcBalance decimal.Decimal, pairMinAmount decimal.Decimal) []*entity.TradingOrder {
+// InvalidateBalances drops the cached balances, the next GetTradingBalances requests them
+func (pr *PoloniexRequests) InvalidateBalances() {
+	pr.cacheUpdate = time.Time{}
+}
+
+func (pr *PoloniexRequests) GetPublicTradingOrders(ctx context.Context, tradingSystemPair string, usdcTradingLimit decimal.Decimal, cryptoTradingLimit decimal.Decimal, internalCryptoBalance decimal.Decimal, internalUsdcBalance decimal.Decimal, pairMinAmount decimal.Decimal) ([]*entity.TradingOrder, error) {
 	var requestData map[string]string = make(map[string]string)
 	requestData["currencyPair"] = tradingSystemPair
 	requestData["depth"] = "20"
 
-	var tradingOrders = pr.queryPublic(ctx, "returnOrderBook", requestData)
-	if len(tradingOrders) == 0 {
-		return nil
+	var tradingOrders, err = pr.queryPublic(ctx, "returnOrderBook", requestData)
+	if err != nil {
+		return nil, err
 	}
 
-	var res []*entity.TradingOrder = []*entity.TradingOrder{}
-
 	orders := struct {
 		Asks []orderItem `json:"asks"`
 		Bids []orderItem `json:"bids"`
 	}{}
 
-	err := json.Unmarshal([]byte(tradingOrders), &orders)
+	err = json.Unmarshal([]byte(tradingOrders), &orders)
 	if err != nil {
-		return nil
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "returnOrderBook", 200, tradingOrders)
 	}
 
-	var amountFound = usdcTradingLimit
-	var breakProcess = false
-	if amountFound.GreaterThan(decimal.Decimal{}) {
-		for _, ask := range orders.Asks {
-			if breakProcess {
-				break
-			}
+	return orderbook.SelectTradingOrders(toLevels(orders.Asks), toLevels(orders.Bids), usdcTradingLimit, cryptoTradingLimit, internalCryptoBalance, internalUsdcBalance, pairMinAmount), nil
+}
 
-			var order = &entity.TradingOrder{
-				Rate:   ask.Price,
-				Amount: ask.Volume,
-			}
+func (pr *PoloniexRequests) Buy(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string, clientOrderId string) (*entity.ExecutionReport, error) {
+	var rules = pr.orderRules(ctx, tradingSystemPair)
+	// taker fee of the account fees (GetFees) is taken from the bought currency, the amount is rounded up to the lot size
+	var requiredAmount = rules.RoundAmount(pr.hedgeFees(ctx, tradingSystemPair).GrossAmount(amount), true)
+	var orderPrice = rules.RoundPrice(tradingSystemPrice, true)
+	var report = entity.NewExecutionReport(systemName, tradingSystemPair, false, requiredAmount)
+	var attempts = pr.retryPolicy.Start(ctx)
+	var priceStep = 0
 
-			if ask.Price.GreaterThan(internalCryptoBalance) {
-				order.Amount = internalCryptoBalance
-				breakProcess = true
-			} else {
-				internalCryptoBalance = internalCryptoBalance.Sub(order.Amount)
+	for {
+		var orderId = clientid.Attempt(clientOrderId, priceStep)
+		if err := rules.Validate(orderPrice, requiredAmount); err != nil {
+			return nil, common.NewRequestError(common.ErrRejected, systemName, "buy", 0, err.Error())
+		}
+		var trade, err = pr.placeOrder(ctx, "buy", tradingSystemPair, orderPrice, requiredAmount, orderId)
+		if common.IsAmbiguous(err) && len(orderId) > 0 {
+			// the order may be executed, it's looked up before it's placed again
+			var found, lookupErr = pr.lookupOrder(ctx, attempts, tradingSystemPair, orderId, err)
+			if lookupErr != nil {
+				return nil, lookupErr
 			}
-
-			amountFound = amountFound.Sub(order.Amount.Mul(order.Rate))
-
-			if amountFound.LessThan(decimal.Decimal{}) {
-				order.Amount = order.Amount.Add(amountFound.Div(order.Rate))
-				breakProcess = true
+			if found != nil {
+				trade, err = found, nil
 			}
-
-			// ignore orders that lower than pair minimum amount
-			if order.Amount.LessThanOrEqual(pairMinAmount) {
+		}
+		if err != nil {
+			if common.IsRetryable(err) {
+				if waitErr := attempts.Wait(err); waitErr != nil {
+					return nil, waitErr
+				}
+				continue
+			}
+			if errors.Is(err, common.ErrOrderNotFilled) {
+				// rising price
+				orderPrice = rules.RoundPrice(pr.retryPolicy.StepUp(orderPrice), true)
+				priceStep++
+				if orderPrice.GreaterThan(internalPrice) {
+					return report, common.NewRequestError(common.ErrOrderNotFilled, systemName, "buy", 0, fmt.Sprintf("max price reached : %v, amount : %v, pair : %v", internalPrice, requiredAmount, internalPair))
+				}
+				if nextErr := attempts.Next(err); nextErr != nil {
+					return report, nextErr
+				}
 				continue
 			}
 
-			order.IsSellOrder = true
+			return nil, err
+		}
+
+		trade.fillReport(report)
+		pr.logger.Info("Poloniex : buy order %v filled %v at average price %v, fee %v %v", report.OrderNumber, report.FilledAmount, report.AveragePrice, report.Fee, report.FeeCurrency)
 
-			res = append(res, order)
+		var resultedAmount = trade.resultedAmount()
+		//check if received amount is lower than amount, received amounts of the trades are rounded down
+		if resultedAmount.Add(decimal.New(int64(len(trade.ResultingTrades)), -8)).LessThan(amount) {
+			report.Status = entity.ExecutionStatusPartiallyFilled
+			return report, common.NewRequestError(common.ErrOrderNotFilled, systemName, "buy", 0, fmt.Sprintf("resulted amount : %v, is lower than Amount : %v", resultedAmount, amount))
 		}
+		report.Status = entity.ExecutionStatusFilled
+		return report, nil
 	}
+}
 
-	amountFound = cryptoTradingLimit
-	breakProcess = false
-	if amountFound.GreaterThan(decimal.Decimal{}) {
-		for _, ask := range orders.Bids {
-			if breakProcess {
-				break
-			}
-
-			var order = &entity.TradingOrder{
-				Rate:   ask.Price,
-				Amount: ask.Volume,
-			}
+func (pr *PoloniexRequests) Sell(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string, clientOrderId string) (*entity.ExecutionReport, error) {
+	var rules = pr.orderRules(ctx, tradingSystemPair)
+	// taker fee is taken from the received quote currency, it's covered by the internal buy price
+	var requiredAmount = rules.RoundAmount(amount, false)
+	var orderPrice = rules.RoundPrice(tradingSystemPrice, false)
+	var report = entity.NewExecutionReport(systemName, tradingSystemPair, true, requiredAmount)
+	var attempts = pr.retryPolicy.Start(ctx)
+	var priceStep = 0
 
-			if (order.Amount.Mul(order.Rate)).GreaterThan(internalCryptoBalance) {
-				order.Amount = internalCryptoBalance
-				breakProcess = true
-			} else {
-				internalCryptoBalance = internalCryptoBalance.Sub(order.Amount)
+	for {
+		var orderId = clientid.Attempt(clientOrderId, priceStep)
+		if err := rules.Validate(orderPrice, requiredAmount); err != nil {
+			return nil, common.NewRequestError(common.ErrRejected, systemName, "sell", 0, err.Error())
+		}
+		var trade, err = pr.placeOrder(ctx, "sell", tradingSystemPair, orderPrice, requiredAmount, orderId)
+		if common.IsAmbiguous(err) && len(orderId) > 0 {
+			// the order may be executed, it's looked up before it's placed again
+			var found, lookupErr = pr.lookupOrder(ctx, attempts, tradingSystemPair, orderId, err)
+			if lookupErr != nil {
+				return nil, lookupErr
 			}
-
-			if order.Amount.Mul(order.Rate).GreaterThan(internalUsdcBalance) {
-				order.Amount = internalUsdcBalance.Div(order.Rate)
-				breakProcess = true
-			} else {
-				internalUsdcBalance = internalUsdcBalance.Sub(order.Amount.Mul(order.Rate))
+			if found != nil {
+				trade, err = found, nil
 			}
-
-			amountFound = amountFound.Sub(order.Amount)
-
-			if amountFound.LessThan(decimal.Decimal{}) {
-				order.Amount = order.Amount.Add(amountFound)
-				breakProcess = true
+		}
+		if err != nil {
+			if common.IsRetryable(err) {
+				if waitErr := attempts.Wait(err); waitErr != nil {
+					return nil, waitErr
+				}
+				continue
 			}
-
-			// ignore orders that lower than pair minimum amount
-			if order.Amount.LessThanOrEqual(pairMinAmount) {
+			if errors.Is(err, common.ErrOrderNotFilled) {
+				// lowering price
+				orderPrice = rules.RoundPrice(pr.retryPolicy.StepDown(orderPrice), false)
+				priceStep++
+				if orderPrice.LessThan(internalPrice) {
+					return report, common.NewRequestError(common.ErrOrderNotFilled, systemName, "sell", 0, fmt.Sprintf("max price reached : %v, amount : %v, pair : %v", internalPrice, requiredAmount, internalPair))
+				}
+				if nextErr := attempts.Next(err); nextErr != nil {
+					return report, nextErr
+				}
 				continue
 			}
 
-			order.IsSellOrder = false
+			return nil, err
+		}
+
+		trade.fillReport(report)
+		pr.logger.Info("Poloniex : sell order %v filled %v at average price %v, fee %v %v", report.OrderNumber, report.FilledAmount, report.AveragePrice, report.Fee, report.FeeCurrency)
 
-			res = append(res, order)
+		//check if sold amount different from requiredAmount
+		if report.FilledAmount.LessThan(requiredAmount) {
+			report.Status = entity.ExecutionStatusPartiallyFilled
+			return report, common.NewRequestError(common.ErrOrderNotFilled, systemName, "sell", 0, fmt.Sprintf("sold amount : %v, is lower than Required Amount : %v", report.FilledAmount, requiredAmount))
 		}
+		report.Status = entity.ExecutionStatusFilled
+		return report, nil
 	}
-
-	return res
 }
 
-func (pr *PoloniexRequests) Buy(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string) bool {
-	// Poloniex taker fee (trade volume < 26k USD @ 30 days): 0.25%
-	var requiredAmount = amount.Mul(decimal.NewFromFloat(1.003))
-	var orderPrice = tradingSystemPrice
+// Withdraw returns withdrawalNumber of the withdrawal
+func (pr *PoloniexRequests) Withdraw(ctx context.Context, addr string, withdrawalAmount decimal.Decimal, currency string, tradingSystemWithdrawalNetwork string) (string, error) {
+	// make request object
+	var requestData map[string]string = make(map[string]string)
+	requestData["address"] = addr
+	requestData["amount"] = withdrawalAmount.String()
+	requestData["currency"] = currency
+	if len(tradingSystemWithdrawalNetwork) > 0 {
+		requestData["currencyToWithdrawAs"] = tradingSystemWithdrawalNetwork
+	}
 
-	for {
-		// make request object
-		var requestData map[string]string = make(map[string]string)
-		requestData["currencyPair"] = tradingSystemPair
-		requestData["rate"] = orderPrice.String()
-		requestData["fillOrKill"] = "1"
-		requestData["amount"] = requiredAmount.String()
-
-		var paymentResponse = pr.queryPrivate(ctx, "buy", requestData)
-		if len(paymentResponse) == 0 {
-			continue
-		}
+	pr.logger.Info("Poloniex : withdraw request is : %v", requestData)
+	var withdrawResponse, err = pr.queryPrivate(ctx, "withdraw", requestData)
+	if err != nil {
+		return "", err
+	}
 
-		pr.logger.Info("Poloniex : buy response is : %v", paymentResponse)
-
-		orders := struct {
-			OrderNumber     string `json:"orderNumber"`
-			Fee             string `json:"fee"`
-			ClientOrderId   string `json:"clientOrderId"`
-			CurrencyPair    string `json:"currencyPair"`
-			ResultingTrades []struct {
-				Amount          string    `json:"amount"`
-				Date            time.Time `json:"date"`
-				Rate            string    `json:"rate"`
-				Total           string    `json:"total"`
-				TradeID         string    `json:"tradeID"`
-				Type            string    `json:"type"`
-				TakerAdjustment string    `json:"takerAdjustment"`
-			} `json:"resultingTrades"`
-		}{}
-
-		err := json.Unmarshal([]byte(paymentResponse), &orders)
-		if err != nil {
-			// handle error response
-			var result map[string]interface{}
-			json.Unmarshal([]byte(paymentResponse), &result)
+	result := struct {
+		WithdrawalNumber json.Number `json:"withdrawalNumber"`
+		Error            string      `json:"error"`
+	}{}
+	err = json.Unmarshal([]byte(withdrawResponse), &result)
+	if err != nil {
+		return "", common.NewRequestError(common.ErrInvalidResponse, systemName, "withdraw", 200, withdrawResponse)
+	}
+	if len(result.Error) > 0 {
+		return "", common.NewRequestError(common.ErrRejected, systemName, "withdraw", 200, result.Error)
+	}
+	// the withdrawal may be made, it can't be tracked without the number
+	if len(result.WithdrawalNumber) == 0 {
+		return "", common.NewRequestError(common.ErrInvalidResponse, systemName, "withdraw", 200, withdrawResponse)
+	}
 
-			val, found := result["error"]
-			if found {
+	return result.WithdrawalNumber.String(), nil
+}
 
-				if strings.Contains(val.(string), "This market is frozen") {
-					time.Sleep(10 * time.Second)
-					continue
-				}
-				// too many requests...
-				if strings.Contains(val.(string), "This IP has been temporarily throttled.") {
-					time.Sleep(10 * time.Second)
-					continue
-				}
-				if strings.Contains(val.(string), "Unable to fill order") {
-					// rising price
-					orderPrice = orderPrice.Mul(decimal.NewFromFloat(1.001)).RoundDown(8)
-					if orderPrice.GreaterThan(internalPrice) {
-						// send message to owner
-						pr.logger.Error("Poloniex : can't buy order. Max price reached : %v, amount : %v, pair : %v!", internalPrice, requiredAmount, internalPair)
-						break
-					}
-					continue
-				}
-			}
+// GetTransfers returns deposits and withdrawals of the currency between start and end
+func (pr *PoloniexRequests) GetTransfers(ctx context.Context, currency string, start time.Time, end time.Time) ([]*entity.VenueTransfer, error) {
+	// make request object
+	var requestData map[string]string = make(map[string]string)
+	requestData["start"] = strconv.FormatInt(start.Unix(), 10)
+	requestData["end"] = strconv.FormatInt(end.Unix(), 10)
 
-			pr.logger.Error("Poloniex : error on Buy request for pair : %v, amount: %v, response is : %v!", internalPair, requiredAmount, paymentResponse)
-			break
-		}
+	var historyResponse, err = pr.queryPrivate(ctx, "returnDepositsWithdrawals", requestData)
+	if err != nil {
+		return nil, err
+	}
 
-		var resultedAmount decimal.Decimal
+	history := struct {
+		Deposits    []*walletTransfer `json:"deposits"`
+		Withdrawals []*walletTransfer `json:"withdrawals"`
+	}{}
+	err = json.Unmarshal([]byte(historyResponse), &history)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "returnDepositsWithdrawals", 200, historyResponse)
+	}
 
-		for _, trade := range orders.ResultingTrades {
-			var am, _ = decimal.NewFromString(trade.TakerAdjustment)
-			resultedAmount = resultedAmount.Add(am)
+	var res = make([]*entity.VenueTransfer, 0, len(history.Deposits)+len(history.Withdrawals))
+	for _, item := range history.Deposits {
+		if item.Currency == currency {
+			res = append(res, item.toVenueTransfer(true))
 		}
-
-		resultedAmount = resultedAmount.RoundDown(8)
-		//check if withdrewAmount different from requiredAmount
-		if resultedAmount.LessThan(requiredAmount) {
-			pr.logger.Error("Poloniex : resulted amount : %v, is lower than Required Amount : %v", resultedAmount, requiredAmount)
-			return false
+	}
+	for _, item := range history.Withdrawals {
+		if item.Currency == currency {
+			res = append(res, item.toVenueTransfer(false))
 		}
-		return true
 	}
 
-	return false
+	return res, nil
 }
 
-func (pr *PoloniexRequests) Sell(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string) bool {
+func (pr *PoloniexRequests) GetCryptoAddress(ctx context.Context, currency string, tradingSystemWithdrawalNetwork string) (string, error) {
 
-	var requiredAmount = amount.Mul(internalPrice).RoundDown(8)
-	var orderPrice = tradingSystemPrice
+	var addresses, err = pr.queryPrivate(ctx, "returnDepositAddresses", map[string]string{})
+	if err != nil {
+		return "", err
+	}
 
-	for {
-		// make request object
-		var requestData map[string]string = make(map[string]string)
-		requestData["currencyPair"] = tradingSystemPair
-		requestData["rate"] = orderPrice.String()
-		requestData["fillOrKill"] = "1"
-		requestData["amount"] = requiredAmount.String()
-
-		var paymentResponse = pr.queryPrivate(ctx, "sell", requestData)
-		if len(paymentResponse) == 0 {
-			continue
-		}
+	if tradingSystemWithdrawalNetwork != "" {
+		currency = tradingSystemWithdrawalNetwork
+	}
 
-		pr.logger.Info("Poloniex : sell response is : %v", paymentResponse)
-
-		orders := struct {
-			OrderNumber     string `json:"orderNumber"`
-			Fee             string `json:"fee"`
-			ClientOrderId   string `json:"clientOrderId"`
-			CurrencyPair    string `json:"currencyPair"`
-			ResultingTrades []struct {
-				Amount          string    `json:"amount"`
-				Date            time.Time `json:"date"`
-				Rate            string    `json:"rate"`
-				Total           string    `json:"total"`
-				TradeID         string    `json:"tradeID"`
-				Type            string    `json:"type"`
-				TakerAdjustment string    `json:"takerAdjustment"`
-			} `json:"resultingTrades"`
-		}{}
-
-		err := json.Unmarshal([]byte(paymentResponse), &orders)
-		if err != nil {
-			// handle error response
-			var result map[string]interface{}
-			json.Unmarshal([]byte(paymentResponse), &result)
+	var result map[string]string
+	json.Unmarshal([]byte(addresses), &result)
+	val, found := result[currency]
+	if !found {
+		return "", common.NewRequestError(common.ErrNotFound, systemName, "returnDepositAddresses", 200, "no deposit address for "+currency)
+	}
+	return val, nil
+}
 
-			val, found := result["error"]
-			if found {
+// placeOrder creates fill-or-kill order, "Unable to fill order" is returned as ErrOrderNotFilled.
+// Legacy API accepts only numeric client order ids, the number of the id is sent.
+func (pr *PoloniexRequests) placeOrder(ctx context.Context, command string, tradingSystemPair string, price decimal.Decimal, amount decimal.Decimal, clientOrderId string) (*tradeResult, error) {
+	// make request object
+	var requestData map[string]string = make(map[string]string)
+	requestData["currencyPair"] = tradingSystemPair
+	requestData["rate"] = price.String()
+	requestData["fillOrKill"] = "1"
+	requestData["amount"] = amount.String()
+	if len(clientOrderId) > 0 {
+		requestData["clientOrderId"] = clientid.Numeric(clientOrderId)
+	}
 
-				if strings.Contains(val.(string), "This market is frozen") {
-					time.Sleep(10 * time.Second)
-					continue
-				}
-				// too many requests...
-				if strings.Contains(val.(string), "This IP has been temporarily throttled.") {
-					time.Sleep(10 * time.Second)
-					continue
-				}
-				if strings.Contains(val.(string), "Unable to fill order") {
-					// rising price
-					orderPrice = orderPrice.Mul(decimal.NewFromFloat(0.999)).RoundDown(8)
-					if orderPrice.LessThan(internalPrice) {
-						// send message to owner
-						pr.logger.Error("Poloniex : can't sell order. Max price reached : %v, amount : %v, pair : %v!", internalPrice, requiredAmount, internalPair)
-						break
-					}
-					continue
-				}
-			}
+	var paymentResponse, err = pr.queryPrivate(ctx, command, requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	pr.logger.Info("Poloniex : %v response is : %v", command, paymentResponse)
+
+	var trade = &tradeResult{}
+	err = json.Unmarshal([]byte(paymentResponse), trade)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, command, 200, paymentResponse)
+	}
+
+	return trade, nil
+}
+
+// lookupOrder looks the order up after the ambiguous placeErr, lookups are retried with the attempts of the order.
+// Nil trade is returned when the order was not executed.
+func (pr *PoloniexRequests) lookupOrder(ctx context.Context, attempts *retry.Attempts, tradingSystemPair string, clientOrderId string, placeErr error) (*tradeResult, error) {
+	pr.logger.Warn("Poloniex : order %v outcome is unknown, looking it up : %v", clientOrderId, placeErr)
+
+	var lastErr = placeErr
+	for {
+		// the order is given time to reach the trade history
+		if waitErr := attempts.Wait(lastErr); waitErr != nil {
+			return nil, waitErr
+		}
 
-			pr.logger.Error("Poloniex : error on Buy request for pair : %v, amount: %v, response is : %v!", internalPair, requiredAmount, paymentResponse)
-			break
+		var trade, err = pr.findOrder(ctx, tradingSystemPair, clientOrderId)
+		if err == nil {
+			return trade, nil
+		}
+		if !common.IsRetryable(err) && !common.IsAmbiguous(err) {
+			return nil, err
 		}
+		lastErr = err
+	}
+}
 
-		var resultedAmount decimal.Decimal
+// findOrder returns trades of the fill-or-kill order found by the client order id in the trade history of the last hour,
+// nil is returned when the order has no trades
+func (pr *PoloniexRequests) findOrder(ctx context.Context, tradingSystemPair string, clientOrderId string) (*tradeResult, error) {
+	var history, err = pr.GetTradeHistory(ctx, tradingSystemPair, time.Now().Add(-time.Hour), time.Now().Add(time.Minute))
+	if err != nil {
+		return nil, err
+	}
 
-		for _, trade := range orders.ResultingTrades {
-			var am, _ = decimal.NewFromString(trade.TakerAdjustment)
-			resultedAmount = resultedAmount.Add(am)
+	var numericId = clientid.Numeric(clientOrderId)
+	var trade = &tradeResult{ClientOrderId: numericId, CurrencyPair: tradingSystemPair}
+	for _, item := range history {
+		if item.ClientOrderId != numericId {
+			continue
 		}
 
-		resultedAmount = resultedAmount.RoundDown(8)
-		//check if withdrewAmount different from requiredAmount
-		if resultedAmount.LessThan(requiredAmount) {
-			// send message to owner
-			pr.logger.Error("Poloniex : resulted amount : %v, is lower than Required Amount : %v", resultedAmount, requiredAmount)
-			return false
+		// fee is taken from the received currency
+		var received, side = item.Amount, "buy"
+		if item.IsSellOrder {
+			received, side = item.Total, "sell"
 		}
-		return true
+		received = received.Mul(decimal.NewFromInt(1).Sub(item.FeeRate)).RoundDown(8)
+
+		trade.OrderNumber = item.OrderNumber
+		trade.Fee = item.FeeRate.String()
+		trade.ResultingTrades = append(trade.ResultingTrades, resultingTrade{
+			Amount:          item.Amount.String(),
+			Date:            item.Date.Format(dateLayout),
+			Rate:            item.Price.String(),
+			Total:           item.Total.String(),
+			TradeID:         item.TradeId,
+			Type:            side,
+			TakerAdjustment: received.String(),
+		})
+	}
+	if len(trade.ResultingTrades) == 0 {
+		return nil, nil
 	}
 
-	return false
+	pr.logger.Info("Poloniex : order %v is found in the trade history : %v", clientOrderId, trade.OrderNumber)
+	return trade, nil
 }
 
-func (pr *PoloniexRequests) Withdraw(ctx context.Context, addr string, withdrawalAmount decimal.Decimal, currency string, tradingSystemWithdrawalNetwork string) bool {
+// GetOpenOrders returns open orders of the pair
+func (pr *PoloniexRequests) GetOpenOrders(ctx context.Context, tradingSystemPair string) ([]*entity.VenueOrder, error) {
 	// make request object
 	var requestData map[string]string = make(map[string]string)
-	requestData["address"] = addr
-	requestData["amount"] = withdrawalAmount.String()
-	requestData["currency"] = currency
-	if len(tradingSystemWithdrawalNetwork) > 0 {
-		requestData["currencyToWithdrawAs"] = tradingSystemWithdrawalNetwork
+	requestData["currencyPair"] = tradingSystemPair
+
+	var ordersResponse, err = pr.queryPrivate(ctx, "returnOpenOrders", requestData)
+	if err != nil {
+		return nil, err
 	}
 
-	pr.logger.Info("Poloniex : withdraw request is : %v", requestData)
-	var paymentResponse = pr.queryPrivate(ctx, "withdraw", requestData)
-	if len(paymentResponse) == 0 {
-		return false
+	var orders []struct {
+		OrderNumber    json.Number     `json:"orderNumber"`
+		ClientOrderId  json.Number     `json:"clientOrderId"`
+		Type           string          `json:"type"`
+		Rate           decimal.Decimal `json:"rate"`
+		StartingAmount decimal.Decimal `json:"startingAmount"`
+		Amount         decimal.Decimal `json:"amount"`
+		Date           string          `json:"date"`
+	}
+	err = json.Unmarshal([]byte(ordersResponse), &orders)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "returnOpenOrders", 200, ordersResponse)
 	}
 
-	// handle error response
-	var result map[string]interface{}
-	json.Unmarshal([]byte(paymentResponse), &result)
-	val, found := result["error"]
-	if found {
-		pr.logger.Error("Poloniex : error on 'withdraw' response is : %v", val)
-		return false
+	var res = make([]*entity.VenueOrder, 0, len(orders))
+	for _, item := range orders {
+		var date, _ = time.Parse(dateLayout, item.Date)
+		res = append(res, &entity.VenueOrder{
+			OrderNumber:    item.OrderNumber.String(),
+			ClientOrderId:  item.ClientOrderId.String(),
+			Pair:           tradingSystemPair,
+			IsSellOrder:    item.Type == "sell",
+			Price:          item.Rate,
+			StartingAmount: item.StartingAmount,
+			Amount:         item.Amount,
+			Date:           date,
+		})
 	}
 
-	return true
+	return res, nil
 }
 
-func (pr *PoloniexRequests) GetCryptoAddress(ctx context.Context, currency string, tradingSystemWithdrawalNetwork string) string {
+// GetTradeHistory returns trades of the pair made from start till end, the newest first
+func (pr *PoloniexRequests) GetTradeHistory(ctx context.Context, tradingSystemPair string, start time.Time, end time.Time) ([]*entity.VenueTrade, error) {
+	// make request object
+	var requestData map[string]string = make(map[string]string)
+	requestData["currencyPair"] = tradingSystemPair
+	requestData["start"] = strconv.FormatInt(start.Unix(), 10)
+	requestData["end"] = strconv.FormatInt(end.Unix(), 10)
+	requestData["limit"] = strconv.Itoa(tradeHistoryLimit)
 
-	var addresses = pr.queryPrivate(ctx, "returnDepositAddresses", map[string]string{})
-	if len(addresses) == 0 {
-		return ""
+	var historyResponse, err = pr.queryPrivate(ctx, "returnTradeHistory", requestData)
+	if err != nil {
+		return nil, err
 	}
 
-	if tradingSystemWithdrawalNetwork != "" {
-		currency = tradingSystemWithdrawalNetwork
+	var history []*historyTrade
+	err = json.Unmarshal([]byte(historyResponse), &history)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "returnTradeHistory", 200, historyResponse)
 	}
 
-	var result map[string]interface{}
-	json.Unmarshal([]byte(addresses), &result)
-	val, found := result[currency]
-	if found {
-		return val.(string)
+	var res = make([]*entity.VenueTrade, 0, len(history))
+	for _, item := range history {
+		res = append(res, item.toVenueTrade(tradingSystemPair))
+	}
+
+	return res, nil
+}
+
+// GetOrderTrades returns trades of the order, ErrNotFound is returned for the order without trades
+func (pr *PoloniexRequests) GetOrderTrades(ctx context.Context, orderNumber string) ([]*entity.VenueTrade, error) {
+	// make request object
+	var requestData map[string]string = make(map[string]string)
+	requestData["orderNumber"] = orderNumber
+
+	var tradesResponse, err = pr.queryPrivate(ctx, "returnOrderTrades", requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	var trades []*historyTrade
+	err = json.Unmarshal([]byte(tradesResponse), &trades)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "returnOrderTrades", 200, tradesResponse)
 	}
-	return ""
+
+	var res = make([]*entity.VenueTrade, 0, len(trades))
+	for _, item := range trades {
+		var trade = item.toVenueTrade(item.CurrencyPair)
+		trade.OrderNumber = orderNumber
+		res = append(res, trade)
+	}
+
+	return res, nil
 }
 
-func (pr *PoloniexRequests) queryPublic(ctx context.Context, method string, requestData map[string]string) string {
+func (pr *PoloniexRequests) queryPublic(ctx context.Context, method string, requestData map[string]string) (string, error) {
+	ctx = helpermethods.WithDefaultEndpoint(ctx, "/public "+method)
 
 	requestData["command"] = method
 	u, err := url.Parse(pr.baseUrl + "/public")
 	if err != nil {
-		pr.logger.Error(err)
-		return ""
+		return "", common.WrapRequestError(err, systemName, method, 0, "")
 	}
 
 	q := u.Query()
@@ -431,58 +652,89 @@ func (pr *PoloniexRequests) queryPublic(ctx context.Context, method string, requ
 	}
 	u.RawQuery = q.Encode()
 
+	if err := pr.rateLimiter.Wait(ctx, ratelimit.ClassPublic, method); err != nil {
+		return "", common.WrapRequestError(err, systemName, method, 0, "")
+	}
+
 	var resText, statusCode, err1 = pr.helperMethods.HttpGet(ctx, u, "", map[string]string{})
 
-	if statusCode != 200 {
+	if err1 != nil || statusCode != 200 {
 		pr.logger.Error("Poloniex : response status : %v, %v : %v", statusCode, err1, resText)
-		return ""
+		return "", common.WrapRequestError(err1, systemName, method, statusCode, resText)
 	}
 
-	return resText
+	return resText, nil
 }
 
-func (pr *PoloniexRequests) queryPrivate(ctx context.Context, method string, requestData map[string]string) string {
+func (pr *PoloniexRequests) queryPrivate(ctx context.Context, method string, requestData map[string]string) (string, error) {
+	ctx = helpermethods.WithDefaultEndpoint(ctx, "/tradingApi "+method)
 
 	requestData["command"] = method
-	var rawResponse = ""
-	var statusCode = 500
+	var err error
+
+	var class = ratelimit.ClassPrivate
+	if method == "buy" || method == "sell" {
+		class = ratelimit.ClassOrder
+	}
 
 	// 10 chances to process
 	for i := 0; i < 10; i++ {
-		// generate a 64 bit nonce using a timestamp at tick resolution
-		var nonce = time.Now().UnixNano()
-
-		rawResponse, statusCode = pr.doRequest(ctx, requestData, nonce)
+		if err := pr.rateLimiter.Wait(ctx, class, method); err != nil {
+			return "", common.WrapRequestError(err, systemName, method, 0, "")
+		}
 
-		if statusCode == 200 {
-			break
+		// the nonce is taken and the request is sent while other requests of the key wait
+		var rawResponse, statusCode = "", 0
+		var err1 error
+		if err := pr.nonces.Do(func(n int64) error {
+			rawResponse, statusCode, err1 = pr.doRequest(ctx, requestData, n)
+			return nil
+		}); err != nil {
+			return "", fmt.Errorf("%v : %v : %w", systemName, method, err)
+		}
+		if errors.Is(err1, common.ErrAuthFailure) {
+			return "", err1
+		}
+		if err1 != nil {
+			return "", common.WrapRequestError(err1, systemName, method, statusCode, "")
 		}
 
-		// handle nonce error
+		// handle error response
 		var result map[string]interface{}
 		json.Unmarshal([]byte(rawResponse), &result)
 
 		val, found := result["error"]
 		if found {
-			rawResponse = ""
-			if strings.Contains(val.(string), "Nonce must be greater than") {
+			var message = fmt.Sprint(val)
+			err = common.NewRequestError(classifyError(message, statusCode), systemName, method, statusCode, message)
+			if strings.Contains(message, "Nonce must be greater than") {
+				// the key was used by another process or the clock is behind, continue from the nonce of the venue
+				if min, found := parseNonce(message); found {
+					if err := pr.nonces.Raise(min); err != nil {
+						return "", fmt.Errorf("%v : %v : %w", systemName, method, err)
+					}
+				}
 				continue
 			}
+			return "", err
 		}
 
-		break
+		if statusCode != 200 {
+			return "", common.WrapRequestError(nil, systemName, method, statusCode, rawResponse)
+		}
+
+		return rawResponse, nil
 	}
 
-	return rawResponse
+	return "", err
 }
 
-func (pr *PoloniexRequests) doRequest(ctx context.Context, requestData map[string]string, nonce int64) (string, int) {
+func (pr *PoloniexRequests) doRequest(ctx context.Context, requestData map[string]string, nonce int64) (string, int, error) {
 
 	requestData["nonce"] = strconv.FormatInt(nonce, 10)
 	u, err := url.Parse(pr.baseUrl + "/tradingApi")
 	if err != nil {
-		pr.logger.Error(err)
-		return "", 500
+		return "", 0, err
 	}
 
 	q := u.Query()
@@ -493,28 +745,222 @@ func (pr *PoloniexRequests) doRequest(ctx context.Context, requestData map[strin
 	}
 	u.RawQuery = q.Encode()
 
-	var keyBytes = []byte(pr.secretKey)
 	var dataBytes = []byte(u.RawQuery)
 
-	var hash512Bytes, _ = pr.helperMethods.HmacSha512(keyBytes, dataBytes)
-
-	var signature = fmt.Sprintf("%x", hash512Bytes)
-
-	var webHeaders = map[string]string{
-		"Key":  pr.publicKey,
-		"Sign": signature,
+	var req = &signer.Request{
+		Method:  "POST",
+		Url:     u,
+		Body:    dataBytes,
+		Headers: map[string]string{"Key": pr.publicKey},
+	}
+	if err := pr.signer.Sign(req); err != nil {
+		return "", 0, common.NewRequestError(common.ErrAuthFailure, systemName, requestData["command"], 0, err.Error())
 	}
 
-	var resText, statusCode, err1 = pr.helperMethods.HttpPost(ctx, u, dataBytes, "application/x-www-form-urlencoded", webHeaders)
+	var resText, statusCode, err1 = pr.helperMethods.HttpPost(ctx, req.Url, dataBytes, "application/x-www-form-urlencoded", req.Headers)
 
 	if statusCode != 200 {
 		pr.logger.Error("Poloniex : response status : %v, %v : %v", statusCode, err1, resText)
 	}
 
-	return resText, statusCode
+	return resText, statusCode, err1
+}
+
+// parseNonce returns the last accepted nonce of "Nonce must be greater than N. You provided M." message
+func parseNonce(message string) (int64, bool) {
+	var fields = strings.Fields(strings.SplitN(message, "Nonce must be greater than", 2)[1])
+	if len(fields) == 0 {
+		return 0, false
+	}
+
+	var res, err = strconv.ParseInt(strings.TrimSuffix(fields[0], "."), 10, 64)
+	return res, err == nil
+}
+
+// classifyError maps Poloniex error message to the request error kind
+func classifyError(message string, statusCode int) error {
+	switch {
+	case strings.Contains(message, "This market is frozen"):
+		return common.ErrMarketFrozen
+	case strings.Contains(message, "temporarily throttled"):
+		return common.ErrRateLimited
+	case strings.Contains(message, "Unable to fill order"):
+		return common.ErrOrderNotFilled
+	case strings.Contains(message, "Not enough"), strings.Contains(message, "Insufficient"):
+		return common.ErrInsufficientFunds
+	case strings.Contains(message, "Invalid API key"):
+		return common.ErrAuthFailure
+	case strings.Contains(message, "Order not found"):
+		return common.ErrNotFound
+	case statusCode >= 400:
+		return common.KindFromStatus(statusCode)
+	}
+	return common.ErrRejected
+}
+
+type tradeResult struct {
+	OrderNumber     string           `json:"orderNumber"`
+	Fee             string           `json:"fee"`
+	ClientOrderId   string           `json:"clientOrderId"`
+	CurrencyPair    string           `json:"currencyPair"`
+	ResultingTrades []resultingTrade `json:"resultingTrades"`
+}
+
+// historyTrade is the item of returnTradeHistory and returnOrderTrades responses
+type historyTrade struct {
+	TradeId       json.Number     `json:"tradeID"`
+	OrderNumber   json.Number     `json:"orderNumber"`
+	ClientOrderId json.Number     `json:"clientOrderId"`
+	CurrencyPair  string          `json:"currencyPair"`
+	Date          string          `json:"date"`
+	Rate          decimal.Decimal `json:"rate"`
+	Amount        decimal.Decimal `json:"amount"`
+	Total         decimal.Decimal `json:"total"`
+	Fee           decimal.Decimal `json:"fee"`
+	Type          string          `json:"type"`
+}
+
+// walletTransfer is the deposit or the withdrawal of returnDepositsWithdrawals response.
+// Withdrawal status is "COMPLETE: txid" when it's broadcast, deposit status is "PENDING" or "COMPLETE".
+type walletTransfer struct {
+	DepositNumber    json.Number     `json:"depositNumber"`
+	WithdrawalNumber json.Number     `json:"withdrawalNumber"`
+	Currency         string          `json:"currency"`
+	Address          string          `json:"address"`
+	Amount           decimal.Decimal `json:"amount"`
+	Fee              decimal.Decimal `json:"fee"`
+	Confirmations    int             `json:"confirmations"`
+	TxId             string          `json:"txid"`
+	Timestamp        int64           `json:"timestamp"`
+	Status           string          `json:"status"`
+}
+
+func (t *walletTransfer) toVenueTransfer(isDeposit bool) *entity.VenueTransfer {
+	var res = &entity.VenueTransfer{
+		Id:            t.WithdrawalNumber.String(),
+		Currency:      t.Currency,
+		Address:       t.Address,
+		Amount:        t.Amount,
+		Fee:           t.Fee,
+		TxId:          t.TxId,
+		Confirmations: t.Confirmations,
+		Status:        entity.TransferStatusPending,
+		IsDeposit:     isDeposit,
+		Date:          time.Unix(t.Timestamp, 0).UTC(),
+	}
+	if isDeposit {
+		res.Id = t.DepositNumber.String()
+	}
+
+	var status, txId, _ = strings.Cut(t.Status, ":")
+	switch strings.TrimSpace(status) {
+	case "COMPLETE":
+		res.Status = entity.TransferStatusComplete
+		if len(strings.TrimSpace(txId)) > 0 {
+			res.TxId = strings.TrimSpace(txId)
+		}
+	case "COMPLETE ERROR", "CANCELED", "FAILED", "REJECTED":
+		res.Status = entity.TransferStatusFailed
+	}
+
+	return res
+}
+
+func (t *historyTrade) toVenueTrade(tradingSystemPair string) *entity.VenueTrade {
+	var date, _ = time.Parse(dateLayout, t.Date)
+	return &entity.VenueTrade{
+		TradeId:       t.TradeId.String(),
+		OrderNumber:   t.OrderNumber.String(),
+		ClientOrderId: t.ClientOrderId.String(),
+		Pair:          tradingSystemPair,
+		IsSellOrder:   t.Type == "sell",
+		Price:         t.Rate,
+		Amount:        t.Amount,
+		Total:         t.Total,
+		FeeRate:       t.Fee,
+		Date:          date,
+	}
+}
+
+type resultingTrade struct {
+	Amount          string `json:"amount"`
+	Date            string `json:"date"`
+	Rate            string `json:"rate"`
+	Total           string `json:"total"`
+	TradeID         string `json:"tradeID"`
+	Type            string `json:"type"`
+	TakerAdjustment string `json:"takerAdjustment"`
+}
+
+// fillReport copies order number and resulting trades to the report.
+// Fee is taken from the received currency: base currency on buy, quote currency on sell ("QUOTE_BASE" pair).
+func (t *tradeResult) fillReport(report *entity.ExecutionReport) {
+	report.OrderNumber = t.OrderNumber
+	report.ClientOrderId = t.ClientOrderId
+
+	var currencies = strings.Split(t.CurrencyPair, "_")
+	var feeCurrency = ""
+	if len(currencies) == 2 {
+		feeCurrency = currencies[1]
+		if report.IsSellOrder {
+			feeCurrency = currencies[0]
+		}
+	}
+
+	for _, item := range t.ResultingTrades {
+		var amount, _ = decimal.NewFromString(item.Amount)
+		var rate, _ = decimal.NewFromString(item.Rate)
+		var total, _ = decimal.NewFromString(item.Total)
+		var adjustment, _ = decimal.NewFromString(item.TakerAdjustment)
+
+		var fee = amount.Sub(adjustment)
+		if report.IsSellOrder {
+			fee = total.Sub(adjustment)
+		}
+
+		report.AddTrade(&entity.ExecutionTrade{
+			TradeId:     item.TradeID,
+			Price:       rate,
+			Amount:      amount,
+			Total:       total,
+			Fee:         fee,
+			FeeCurrency: feeCurrency,
+		})
+	}
+}
+
+func (t *tradeResult) resultedAmount() decimal.Decimal {
+	var resultedAmount decimal.Decimal
+
+	for _, trade := range t.ResultingTrades {
+		var am, _ = decimal.NewFromString(trade.TakerAdjustment)
+		resultedAmount = resultedAmount.Add(am)
+	}
+
+	return resultedAmount.RoundDown(8)
+}
+
+// toDecimal parses price (string) and volume (number) of the order book level
+func toDecimal(v interface{}) decimal.Decimal {
+	switch val := v.(type) {
+	case string:
+		var res, _ = decimal.NewFromString(val)
+		return res
+	case float64:
+		return decimal.NewFromFloat(val)
+	}
+	return decimal.Decimal{}
 }
 
 type orderItem struct {
 	Volume decimal.Decimal
 	Price  decimal.Decimal
 }
+
+func toLevels(items []orderItem) []orderbook.Level {
+	var levels = make([]orderbook.Level, 0, len(items))
+	for _, item := range items {
+		levels = append(levels, orderbook.Level{Price: item.Price, Volume: item.Volume})
+	}
+	return levels
+}
@@ -0,0 +1,403 @@
+package poloniex
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"trading_bot/config"
+	"trading_bot/internal/common"
+	"trading_bot/internal/common/cassette"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/requests/poloniex/poloniextest"
+	"trading_bot/internal/common/secrets"
+	"trading_bot/internal/entity"
+	"trading_bot/mocks"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	testKey    = "test-key"
+	testSecret = "test-secret"
+)
+
+func testLogger(t *testing.T) *mocks.ILogger {
+	t.Helper()
+
+	var l = &mocks.ILogger{}
+	var args = []interface{}{mock.Anything}
+	for i := 0; i < 8; i++ {
+		l.On("Debug", args...).Return()
+		l.On("Info", args...).Return()
+		l.On("Warn", args...).Return()
+		l.On("Error", args...).Return()
+		args = append(args, mock.Anything)
+	}
+
+	return l
+}
+
+// newTestRequests replays testdata/<name>.json, RECORD_CASSETTES=1 records it against POLONIEX_URL with POLONIEX_KEY and POLONIEX_SECRET
+func newTestRequests(t *testing.T, name string) (*PoloniexRequests, cassette.Transport) {
+	t.Helper()
+
+	var l = testLogger(t)
+	var transport = cassette.Use(t, filepath.Join("testdata", name+".json"), func() common.IHelperMethods {
+		return helpermethods.New(l, nil)
+	})
+
+	return New(l, transport, config.TradingSettings{
+		Url:    cassette.Setting("POLONIEX_URL", "https://poloniex.com"),
+		Key:    secrets.Secret(cassette.Setting("POLONIEX_KEY", testKey)),
+		Secret: secrets.Secret(cassette.Setting("POLONIEX_SECRET", testSecret)),
+		// own limiter per test
+		RateLimit: config.RateLimitSettings{Order: config.RateLimit{RequestsPerSecond: 100}},
+		Retry:     config.RetrySettings{PriceStep: decimal.NewFromFloat(0.001)},
+	}), transport
+}
+
+// checkSigned verifies that the private requests are signed with the test secret
+func checkSigned(t *testing.T, transport cassette.Transport) {
+	t.Helper()
+
+	for _, req := range transport.Requests() {
+		var h = hmac.New(sha512.New, []byte(testSecret))
+		h.Write([]byte(req.Body))
+
+		if got, want := req.Headers["Sign"], fmt.Sprintf("%x", h.Sum(nil)); got != want {
+			t.Errorf("got sign %v, wanted %v", got, want)
+		}
+		if got := req.Headers["Key"]; got != testKey {
+			t.Errorf("got key %v, wanted %v", got, testKey)
+		}
+	}
+}
+
+func TestGetTradingBalances_Success(t *testing.T) {
+	t.Parallel()
+
+	var pr, transport = newTestRequests(t, "balances")
+
+	got, err := pr.GetTradingBalances(context.Background())
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 3 || !got["USDC"].Balance.Equal(decimal.RequireFromString("1000.12")) {
+		t.Errorf("got %v, wanted 3 balances with USDC 1000.12", got)
+	}
+	checkSigned(t, transport)
+}
+
+func TestGetPublicTradingOrders_Success(t *testing.T) {
+	t.Parallel()
+
+	var pr, _ = newTestRequests(t, "orderbook")
+	var limit = decimal.NewFromInt(1000000)
+
+	got, err := pr.GetPublicTradingOrders(context.Background(), "USDC_BTC", limit, limit, limit, limit, decimal.Zero)
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("got %v orders, wanted 4", len(got))
+	}
+	if !got[0].IsSellOrder || !got[0].Rate.Equal(decimal.NewFromInt(101)) || !got[0].Amount.Equal(decimal.NewFromFloat(1.5)) {
+		t.Errorf("got first order %+v, wanted sell 1.5 at 101", got[0])
+	}
+}
+
+func TestBuy_RetriedAfterKill_Success(t *testing.T) {
+	t.Parallel()
+
+	var pr, transport = newTestRequests(t, "buy_retried")
+
+	got, err := pr.Buy(context.Background(), "USDC_BTC", decimal.NewFromInt(100), decimal.NewFromInt(101), decimal.NewFromFloat(0.1), "BTC,USDC", "")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if got.Status != entity.ExecutionStatusFilled || got.OrderNumber != "514845991795" || len(got.Trades) != 2 {
+		t.Errorf("got %v order %v with %v trades, wanted filled order 514845991795 with 2 trades", got.Status, got.OrderNumber, len(got.Trades))
+	}
+	if !got.FilledAmount.Equal(decimal.RequireFromString("0.10025063")) {
+		t.Errorf("got filled %v, wanted 0.10025063", got.FilledAmount)
+	}
+	checkSigned(t, transport)
+}
+
+func TestWithdraw_InsufficientFunds_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var pr, _ = newTestRequests(t, "withdraw_insufficient")
+
+	_, got := pr.Withdraw(context.Background(), "bc1qtest", decimal.NewFromFloat(0.5), "BTC", "")
+
+	if !errors.Is(got, common.ErrInsufficientFunds) {
+		t.Errorf("got error %v, wanted %v", got, common.ErrInsufficientFunds)
+	}
+}
+
+func TestWithdraw_WithoutNumber_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var pr, _ = newTestRequests(t, "withdraw_without_number")
+
+	got, err := pr.Withdraw(context.Background(), "bc1qtest", decimal.NewFromFloat(0.5), "BTC", "")
+
+	if !errors.Is(err, common.ErrInvalidResponse) || got != "" {
+		t.Errorf("got %v (error %v), wanted empty id and %v", got, err, common.ErrInvalidResponse)
+	}
+}
+
+func TestGetCryptoAddress_Network_Success(t *testing.T) {
+	t.Parallel()
+
+	var pr, _ = newTestRequests(t, "deposit_addresses")
+
+	got, err := pr.GetCryptoAddress(context.Background(), "USDC", "USDCTRON")
+
+	if err != nil || got != "TXYZtestaddress" {
+		t.Errorf("got %v %v, wanted TXYZtestaddress nil", got, err)
+	}
+}
+
+// newSimRequests creates requests sent to the Poloniex stand-in with short retry backoff
+func newSimRequests(t *testing.T) (*PoloniexRequests, *poloniextest.Server) {
+	t.Helper()
+
+	var sim = poloniextest.NewServer(testKey, testSecret)
+	t.Cleanup(sim.Close)
+
+	sim.SetBalance("BTC", decimal.NewFromInt(2))
+	sim.SetBalance("USDC", decimal.NewFromInt(100000))
+	sim.SetBook("USDC_BTC", []poloniextest.Level{
+		{Price: decimal.NewFromInt(20000), Volume: decimal.NewFromFloat(0.1)},
+		{Price: decimal.NewFromInt(20050), Volume: decimal.NewFromInt(1)},
+	}, []poloniextest.Level{
+		{Price: decimal.NewFromInt(19950), Volume: decimal.NewFromFloat(0.5)},
+		{Price: decimal.NewFromInt(19900), Volume: decimal.NewFromInt(2)},
+	})
+
+	var l = testLogger(t)
+	return New(l, helpermethods.New(l, nil), config.TradingSettings{
+		Url:       sim.URL(),
+		Key:       testKey,
+		Secret:    testSecret,
+		RateLimit: config.RateLimitSettings{Order: config.RateLimit{RequestsPerSecond: 100}},
+		Retry: config.RetrySettings{
+			PriceStep:                  decimal.NewFromFloat(0.001),
+			InitialBackoffMilliseconds: 10,
+			MaxBackoffMilliseconds:     50,
+		},
+	}), sim
+}
+
+func TestBuy_SimulatorWalksBook_Success(t *testing.T) {
+	t.Parallel()
+
+	var pr, sim = newSimRequests(t)
+	sim.FailNext("buy", poloniextest.ModeThrottled, 1)
+	sim.FailNext("buy", poloniextest.ModeNonce, 1)
+	sim.FailNext("buy", poloniextest.ModeFrozen, 1)
+
+	// 0.20050126 BTC (0.2 after the 0.25% fee) needs the second level, the price is stepped up from 19999.5 to 20059.54
+	got, err := pr.Buy(context.Background(), "USDC_BTC", decimal.RequireFromString("19999.5"), decimal.NewFromInt(20100), decimal.NewFromFloat(0.2), "BTC,USDC", "")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if got.Status != entity.ExecutionStatusFilled || !got.FilledAmount.Equal(decimal.RequireFromString("0.20050126")) || len(got.Trades) != 2 {
+		t.Errorf("got %v %v with %v trades, wanted filled 0.20050126 with 2 trades", got.Status, got.FilledAmount, len(got.Trades))
+	}
+	if trades := sim.Trades(); len(trades) != 2 || !trades[1].Rate.Equal(decimal.NewFromInt(20050)) {
+		t.Errorf("got trades %+v, wanted 2 trades ending at 20050", trades)
+	}
+	if asks, _ := sim.Book("USDC_BTC"); !asks[0].Volume.Equal(decimal.RequireFromString("0.89949874")) {
+		t.Errorf("got ask volume %v, wanted 0.89949874 left", asks[0].Volume)
+	}
+}
+
+func TestBuy_SimulatorLostResponse_Success(t *testing.T) {
+	t.Parallel()
+
+	var pr, sim = newSimRequests(t)
+	sim.FailNext("buy", poloniextest.ModeLostResponse, 1)
+	sim.FailNext("returnTradeHistory", poloniextest.ModeUnavailable, 1)
+
+	// the order is executed, it's found in the trade history and isn't placed again
+	got, err := pr.Buy(context.Background(), "USDC_BTC", decimal.NewFromInt(20000), decimal.NewFromInt(20100), decimal.NewFromFloat(0.05), "BTC,USDC", "h1")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	var trades = sim.Trades()
+	if len(trades) != 1 {
+		t.Fatalf("got %v trades, wanted 1", len(trades))
+	}
+	if got.Status != entity.ExecutionStatusFilled || got.OrderNumber != trades[0].OrderNumber || !got.FilledAmount.Equal(trades[0].Amount) {
+		t.Errorf("got %v order %v filled %v, wanted filled order %v", got.Status, got.OrderNumber, got.FilledAmount, trades[0].OrderNumber)
+	}
+}
+
+func TestGetFees_Simulator_Success(t *testing.T) {
+	t.Parallel()
+
+	var pr, sim = newSimRequests(t)
+	sim.SetTakerFee(decimal.NewFromFloat(0.002))
+
+	got, err := pr.GetFees(context.Background(), "USDC_BTC")
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if !got.TakerFee.Equal(decimal.NewFromFloat(0.002)) {
+		t.Errorf("got taker fee %v, wanted 0.002", got.TakerFee)
+	}
+
+	// fees are cached for an hour, the changed fee is not requested
+	sim.SetTakerFee(decimal.NewFromFloat(0.001))
+	cached, err := pr.GetFees(context.Background(), "USDC_BTC")
+	if err != nil || !cached.TakerFee.Equal(decimal.NewFromFloat(0.002)) {
+		t.Errorf("got taker fee %v (error %v), wanted cached 0.002", cached.TakerFee, err)
+	}
+}
+
+func TestSell_SimulatorMaxPrice_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var pr, sim = newSimRequests(t)
+
+	// the bids are lower than the internal price
+	got, err := pr.Sell(context.Background(), "USDC_BTC", decimal.NewFromInt(19990), decimal.NewFromInt(19980), decimal.NewFromFloat(0.0001), "BTC,USDC", "")
+
+	if !errors.Is(err, common.ErrOrderNotFilled) {
+		t.Errorf("got error %v, wanted %v", err, common.ErrOrderNotFilled)
+	}
+	if got == nil || len(got.Trades) != 0 || len(sim.Trades()) != 0 {
+		t.Errorf("got report %+v, wanted report without trades", got)
+	}
+}
+
+func TestWithdraw_Simulator_Success(t *testing.T) {
+	t.Parallel()
+
+	var pr, sim = newSimRequests(t)
+	sim.SetDepositAddress("BTC", "bc1qsim")
+
+	address, err := pr.GetCryptoAddress(context.Background(), "BTC", "")
+	if err != nil || address != "bc1qsim" {
+		t.Fatalf("got %v %v, wanted bc1qsim nil", address, err)
+	}
+
+	withdrawalId, err := pr.Withdraw(context.Background(), "bc1qinternal", decimal.NewFromFloat(0.5), "BTC", "")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if got := sim.Balance("BTC"); !got.Equal(decimal.NewFromFloat(1.5)) {
+		t.Errorf("got balance %v, wanted 1.5", got)
+	}
+	if want := strconv.FormatInt(sim.Withdrawals()[0].Number, 10); withdrawalId != want {
+		t.Errorf("got withdrawal id %v, wanted %v", withdrawalId, want)
+	}
+}
+
+func TestGetTransfers_Simulator_Success(t *testing.T) {
+	t.Parallel()
+
+	var pr, sim = newSimRequests(t)
+	var start = time.Now().Add(-time.Minute)
+
+	withdrawalId, err := pr.Withdraw(context.Background(), "bc1qinternal", decimal.NewFromFloat(0.5), "BTC", "")
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	number, _ := strconv.ParseInt(withdrawalId, 10, 64)
+	sim.SetWithdrawalStatus(number, "COMPLETE: 36e483efa6aff9fd53a235177579d984")
+	sim.CompleteDeposit(sim.AddDeposit("BTC", "bc1qsim", decimal.NewFromFloat(0.3), "9f2b1c"))
+	sim.AddDeposit("USDC", "usdc-address", decimal.NewFromInt(100), "7a1d0e")
+
+	got, err := pr.GetTransfers(context.Background(), "BTC", start, time.Now().Add(time.Minute))
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %v transfers, wanted BTC deposit and withdrawal", len(got))
+	}
+	var deposit, withdrawal = got[0], got[1]
+	if !deposit.IsDeposit || deposit.Status != entity.TransferStatusComplete || deposit.TxId != "9f2b1c" || !deposit.Amount.Equal(decimal.NewFromFloat(0.3)) {
+		t.Errorf("got deposit %+v, wanted complete 0.3 with tx 9f2b1c", deposit)
+	}
+	if withdrawal.IsDeposit || withdrawal.Id != withdrawalId || withdrawal.Status != entity.TransferStatusComplete || withdrawal.TxId != "36e483efa6aff9fd53a235177579d984" {
+		t.Errorf("got withdrawal %+v, wanted complete %v with tx from the status", withdrawal, withdrawalId)
+	}
+}
+
+func TestGetTradingBalances_SimulatorUnavailable_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var pr, sim = newSimRequests(t)
+	sim.FailNext("returnBalances", poloniextest.ModeUnavailable, 1)
+
+	_, got := pr.GetTradingBalances(context.Background())
+
+	if !errors.Is(got, common.ErrServiceUnavailable) {
+		t.Errorf("got error %v, wanted %v", got, common.ErrServiceUnavailable)
+	}
+}
+
+func TestQueryPrivate_SharedKey_Success(t *testing.T) {
+	t.Parallel()
+
+	const key = "shared-nonce-key"
+	var sim = poloniextest.NewServer(key, testSecret)
+	defer sim.Close()
+	sim.SetDepositAddress("BTC", "bc1qsim")
+	// the key was used with nonces ahead of the local clock
+	sim.SetLastNonce(time.Now().Add(time.Hour).UnixNano())
+
+	var l = testLogger(t)
+	var settings = config.TradingSettings{
+		Url:       sim.URL(),
+		Key:       secrets.Secret(key),
+		Secret:    testSecret,
+		NonceDir:  t.TempDir(),
+		RateLimit: config.RateLimitSettings{Private: config.RateLimit{RequestsPerSecond: 1000}},
+	}
+
+	// workers of the key have own requests
+	var wg sync.WaitGroup
+	var errs = make(chan error, 40)
+	for i := 0; i < 4; i++ {
+		var pr = New(l, helpermethods.New(l, nil), settings)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := pr.GetCryptoAddress(context.Background(), "BTC", ""); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("got error %v, wanted nil", err)
+	}
+	// the only rejection is the first request raising the nonce
+	if got := sim.NonceRejections(); got != 1 {
+		t.Errorf("got %v nonce rejections, wanted %v", got, 1)
+	}
+}
@@ -0,0 +1,760 @@
+// Package poloniextest provides in-process stand-in of the legacy Poloniex API for tests.
+package poloniextest
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrorMode is the failure returned instead of processing the command
+type ErrorMode int
+
+const (
+	// ModeUnableToFill kills the fill-or-kill order
+	ModeUnableToFill ErrorMode = iota
+	// ModeFrozen reports that the market is frozen
+	ModeFrozen
+	// ModeThrottled reports that the API key is throttled
+	ModeThrottled
+	// ModeNonce rejects the nonce as too small
+	ModeNonce
+	// ModeUnavailable responds with 503 status
+	ModeUnavailable
+	// ModeLostResponse executes the command and responds with 503 status
+	ModeLostResponse
+)
+
+// Level of the order book
+type Level struct {
+	Price  decimal.Decimal
+	Volume decimal.Decimal
+}
+
+// Trade is the execution of the fill-or-kill order against the book level
+type Trade struct {
+	TradeId       string
+	OrderNumber   string
+	ClientOrderId string
+	Pair          string
+	Type          string
+	Date          time.Time
+	Rate          decimal.Decimal
+	Amount        decimal.Decimal
+	Total         decimal.Decimal
+	Fee           decimal.Decimal
+	Received      decimal.Decimal
+}
+
+// Withdrawal accepted by the stand-in, Status is "PENDING" until it's set by SetWithdrawalStatus
+type Withdrawal struct {
+	Number   int64
+	Currency string
+	Address  string
+	Amount   decimal.Decimal
+	Network  string
+	Status   string
+	Date     time.Time
+}
+
+// Deposit to the account, the balance is increased when it's completed
+type Deposit struct {
+	Number        int64
+	Currency      string
+	Address       string
+	Amount        decimal.Decimal
+	TxId          string
+	Confirmations int
+	Status        string
+	Date          time.Time
+}
+
+type book struct {
+	asks []Level
+	bids []Level
+}
+
+type failure struct {
+	mode  ErrorMode
+	times int
+}
+
+// Server emulates returnOrderBook, returnBalances, buy, sell, returnOpenOrders, returnTradeHistory, returnOrderTrades, withdraw,
+// returnDepositsWithdrawals and returnDepositAddresses commands. Fill-or-kill orders don't rest in the book, there are no open orders.
+// Private commands check the key, the HMAC-SHA512 signature of the body and the increasing nonce.
+// Pairs are "QUOTE_BASE" (USDC_BTC), buy and sell amounts are in the base currency.
+type Server struct {
+	server      *httptest.Server
+	mu          sync.Mutex
+	key         string
+	secret      string
+	takerFee    decimal.Decimal
+	books       map[string]*book
+	frozen      map[string]bool
+	balances    map[string]decimal.Decimal
+	addresses   map[string]string
+	failures    map[string][]*failure
+	lastNonce   int64
+	rejected    int
+	orderSeq    int64
+	tradeSeq    int64
+	trades      []Trade
+	withdrawals []Withdrawal
+	deposits    []Deposit
+	transferSeq int64
+}
+
+// NewServer starts the stand-in accepting requests signed with the key and secret, taker fee is 0.25%
+func NewServer(key string, secret string) *Server {
+	var s = &Server{
+		key:         key,
+		secret:      secret,
+		takerFee:    decimal.NewFromFloat(0.0025),
+		books:       make(map[string]*book),
+		frozen:      make(map[string]bool),
+		balances:    make(map[string]decimal.Decimal),
+		addresses:   make(map[string]string),
+		failures:    make(map[string][]*failure),
+		orderSeq:    100000000,
+		tradeSeq:    200000,
+		transferSeq: 130000,
+	}
+
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/public", s.handlePublic)
+	mux.HandleFunc("/tradingApi", s.handlePrivate)
+	s.server = httptest.NewServer(mux)
+
+	return s
+}
+
+// URL of the stand-in, it's used as TradingSettings.Url
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// SetBook replaces the order book of the pair, asks are sorted ascending and bids descending by the caller
+func (s *Server) SetBook(pair string, asks []Level, bids []Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.books[pair] = &book{
+		asks: append([]Level(nil), asks...),
+		bids: append([]Level(nil), bids...),
+	}
+}
+
+// Book returns the current levels of the pair
+func (s *Server) Book(pair string) ([]Level, []Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b = s.book(pair)
+	return append([]Level(nil), b.asks...), append([]Level(nil), b.bids...)
+}
+
+func (s *Server) SetBalance(currency string, amount decimal.Decimal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.balances[currency] = amount
+}
+
+func (s *Server) Balance(currency string) decimal.Decimal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.balances[currency]
+}
+
+func (s *Server) SetDepositAddress(currency string, address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addresses[currency] = address
+}
+
+func (s *Server) SetTakerFee(fee decimal.Decimal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takerFee = fee
+}
+
+// SetFrozen freezes trading of the pair until it's unfrozen
+func (s *Server) SetFrozen(pair string, frozen bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frozen[pair] = frozen
+}
+
+// FailNext makes the next times calls of the command fail with the mode
+func (s *Server) FailNext(command string, mode ErrorMode, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[command] = append(s.failures[command], &failure{mode: mode, times: times})
+}
+
+// Trades returns executed trades in order
+func (s *Server) Trades() []Trade {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Trade(nil), s.trades...)
+}
+
+// Withdrawals returns accepted withdrawals in order
+func (s *Server) Withdrawals() []Withdrawal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Withdrawal(nil), s.withdrawals...)
+}
+
+// SetWithdrawalStatus sets the status of the withdrawal as the venue reports it, e.g. "COMPLETE: txid"
+func (s *Server) SetWithdrawalStatus(number int64, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.withdrawals {
+		if s.withdrawals[i].Number == number {
+			s.withdrawals[i].Status = status
+		}
+	}
+}
+
+// AddDeposit adds the pending deposit of the currency to the address, returns the number of the deposit
+func (s *Server) AddDeposit(currency string, address string, amount decimal.Decimal, txId string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.transferSeq++
+	s.deposits = append(s.deposits, Deposit{
+		Number:   s.transferSeq,
+		Currency: currency,
+		Address:  address,
+		Amount:   amount,
+		TxId:     txId,
+		Status:   "PENDING",
+		Date:     time.Now(),
+	})
+
+	return s.transferSeq
+}
+
+// CompleteDeposit confirms the deposit and adds its amount to the balance
+func (s *Server) CompleteDeposit(number int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.deposits {
+		if s.deposits[i].Number == number && s.deposits[i].Status != "COMPLETE" {
+			s.deposits[i].Status = "COMPLETE"
+			s.deposits[i].Confirmations = 6
+			s.balances[s.deposits[i].Currency] = s.balances[s.deposits[i].Currency].Add(s.deposits[i].Amount)
+		}
+	}
+}
+
+// SetLastNonce sets the last accepted nonce, e.g. of the key used by another process
+func (s *Server) SetLastNonce(nonce int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastNonce = nonce
+}
+
+// NonceRejections returns the number of requests rejected because of the nonce lower than the last accepted one
+func (s *Server) NonceRejections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rejected
+}
+
+func (s *Server) handlePublic(w http.ResponseWriter, r *http.Request) {
+	var params = r.URL.Query()
+	var command = params.Get("command")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail(w, command, 0) {
+		return
+	}
+
+	switch command {
+	case "returnOrderBook":
+		s.returnOrderBook(w, params)
+	default:
+		writeError(w, "Invalid command.")
+	}
+}
+
+func (s *Server) handlePrivate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var mac = hmac.New(sha512.New, []byte(s.secret))
+	mac.Write(body)
+	if r.Header.Get("Key") != s.key || r.Header.Get("Sign") != fmt.Sprintf("%x", mac.Sum(nil)) {
+		writeError(w, "Invalid API key/secret pair.")
+		return
+	}
+
+	params, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeError(w, "Invalid parameters.")
+		return
+	}
+	var command = params.Get("command")
+	var nonce, _ = strconv.ParseInt(params.Get("nonce"), 10, 64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail(w, command, nonce) {
+		return
+	}
+
+	if nonce <= s.lastNonce {
+		s.rejected++
+		writeError(w, fmt.Sprintf("Nonce must be greater than %v. You provided %v.", s.lastNonce, nonce))
+		return
+	}
+	s.lastNonce = nonce
+
+	if s.lose(command) {
+		s.execute(httptest.NewRecorder(), command, params)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "<html>503 Service Temporarily Unavailable</html>")
+		return
+	}
+	s.execute(w, command, params)
+}
+
+func (s *Server) execute(w http.ResponseWriter, command string, params url.Values) {
+	switch command {
+	case "returnBalances":
+		s.returnBalances(w)
+	case "buy", "sell":
+		s.placeOrder(w, command, params)
+	case "returnOpenOrders":
+		writeJson(w, []interface{}{})
+	case "returnTradeHistory":
+		s.returnTradeHistory(w, params)
+	case "returnOrderTrades":
+		s.returnOrderTrades(w, params)
+	case "withdraw":
+		s.withdraw(w, params)
+	case "returnDepositsWithdrawals":
+		s.returnDepositsWithdrawals(w, params)
+	case "returnDepositAddresses":
+		writeJson(w, s.addresses)
+	case "returnFeeInfo":
+		writeJson(w, map[string]string{
+			"makerFee":        s.takerFee.StringFixed(8),
+			"takerFee":        s.takerFee.StringFixed(8),
+			"thirtyDayVolume": "0.00000000",
+			"nextTier":        "50000.00000000",
+		})
+	default:
+		writeError(w, "Invalid command.")
+	}
+}
+
+// lose reports whether the response of the command is lost, the queued failure is consumed
+func (s *Server) lose(command string) bool {
+	var queue = s.failures[command]
+	if len(queue) == 0 || queue[0].mode != ModeLostResponse {
+		return false
+	}
+
+	queue[0].times--
+	if queue[0].times <= 0 {
+		s.failures[command] = queue[1:]
+	}
+	return true
+}
+
+// fail writes the queued failure of the command, lost responses are written after the command is executed
+func (s *Server) fail(w http.ResponseWriter, command string, nonce int64) bool {
+	var queue = s.failures[command]
+	if len(queue) == 0 || queue[0].mode == ModeLostResponse {
+		return false
+	}
+
+	var next = queue[0]
+	next.times--
+	if next.times <= 0 {
+		s.failures[command] = queue[1:]
+	}
+
+	switch next.mode {
+	case ModeUnableToFill:
+		writeError(w, "Unable to fill order completely.")
+	case ModeFrozen:
+		writeError(w, "This market is frozen.")
+	case ModeThrottled:
+		writeErrorStatus(w, http.StatusTooManyRequests, "Please do not make more than 6 API calls per second, your API key is temporarily throttled.")
+	case ModeNonce:
+		writeError(w, fmt.Sprintf("Nonce must be greater than %v. You provided %v.", nonce, nonce))
+	case ModeUnavailable:
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "<html>503 Service Temporarily Unavailable</html>")
+	}
+
+	return true
+}
+
+func (s *Server) returnOrderBook(w http.ResponseWriter, params url.Values) {
+	var depth, err = strconv.Atoi(params.Get("depth"))
+	if err != nil || depth <= 0 {
+		depth = 50
+	}
+
+	var pair = params.Get("currencyPair")
+	var b = s.book(pair)
+
+	var frozen = "0"
+	if s.frozen[pair] {
+		frozen = "1"
+	}
+
+	writeJson(w, map[string]interface{}{
+		"asks":     levelsJson(b.asks, depth),
+		"bids":     levelsJson(b.bids, depth),
+		"isFrozen": frozen,
+		"seq":      s.orderSeq,
+	})
+}
+
+func (s *Server) returnBalances(w http.ResponseWriter) {
+	var res = make(map[string]string, len(s.balances))
+	for currency, amount := range s.balances {
+		res[currency] = amount.StringFixed(8)
+	}
+
+	writeJson(w, res)
+}
+
+// placeOrder executes the fill-or-kill order against the book, the order is killed when the book can't fill it completely
+func (s *Server) placeOrder(w http.ResponseWriter, command string, params url.Values) {
+	var pair = params.Get("currencyPair")
+	var currencies = strings.Split(pair, "_")
+	rate, err1 := decimal.NewFromString(params.Get("rate"))
+	amount, err2 := decimal.NewFromString(params.Get("amount"))
+	if len(currencies) != 2 || err1 != nil || err2 != nil || !amount.IsPositive() || !rate.IsPositive() {
+		writeError(w, "Invalid parameters.")
+		return
+	}
+	if s.frozen[pair] {
+		writeError(w, "This market is frozen.")
+		return
+	}
+	if params.Get("fillOrKill") != "1" {
+		writeError(w, "Only fill-or-kill orders are supported by the stand-in.")
+		return
+	}
+
+	var quote, base = currencies[0], currencies[1]
+	var isBuy = command == "buy"
+	var b = s.book(pair)
+	var levels = b.bids
+	if isBuy {
+		levels = b.asks
+	}
+
+	// check the book can fill the whole amount within the rate
+	var available, total = decimal.Zero, decimal.Zero
+	for _, level := range levels {
+		if (isBuy && level.Price.GreaterThan(rate)) || (!isBuy && level.Price.LessThan(rate)) || available.GreaterThanOrEqual(amount) {
+			break
+		}
+		var volume = decimal.Min(level.Volume, amount.Sub(available))
+		available = available.Add(volume)
+		total = total.Add(volume.Mul(level.Price))
+	}
+	if available.LessThan(amount) {
+		writeError(w, "Unable to fill order completely.")
+		return
+	}
+
+	if isBuy && s.balances[quote].LessThan(total) {
+		writeError(w, fmt.Sprintf("Not enough %v.", quote))
+		return
+	}
+	if !isBuy && s.balances[base].LessThan(amount) {
+		writeError(w, fmt.Sprintf("Not enough %v.", base))
+		return
+	}
+
+	s.orderSeq++
+	var orderNumber = strconv.FormatInt(s.orderSeq, 10)
+	var feeMultiplier = decimal.NewFromInt(1).Sub(s.takerFee)
+	var resultingTrades = make([]map[string]string, 0)
+	var left = amount
+	var remaining = make([]Level, 0, len(levels))
+
+	for _, level := range levels {
+		if left.IsZero() {
+			remaining = append(remaining, level)
+			continue
+		}
+
+		var volume = decimal.Min(level.Volume, left)
+		left = left.Sub(volume)
+		if level.Volume.GreaterThan(volume) {
+			remaining = append(remaining, Level{Price: level.Price, Volume: level.Volume.Sub(volume)})
+		}
+
+		s.tradeSeq++
+		var trade = Trade{
+			TradeId:       strconv.FormatInt(s.tradeSeq, 10),
+			OrderNumber:   orderNumber,
+			ClientOrderId: params.Get("clientOrderId"),
+			Pair:          pair,
+			Type:          command,
+			Date:          time.Now().UTC(),
+			Rate:          level.Price,
+			Amount:        volume,
+			Total:         volume.Mul(level.Price).RoundDown(8),
+			Fee:           s.takerFee,
+		}
+
+		// fee is taken from the received currency
+		if isBuy {
+			trade.Received = trade.Amount.Mul(feeMultiplier).RoundDown(8)
+			s.balances[quote] = s.balances[quote].Sub(trade.Total)
+			s.balances[base] = s.balances[base].Add(trade.Received)
+		} else {
+			trade.Received = trade.Total.Mul(feeMultiplier).RoundDown(8)
+			s.balances[base] = s.balances[base].Sub(trade.Amount)
+			s.balances[quote] = s.balances[quote].Add(trade.Received)
+		}
+		s.trades = append(s.trades, trade)
+
+		resultingTrades = append(resultingTrades, map[string]string{
+			"amount":          trade.Amount.StringFixed(8),
+			"date":            trade.Date.Format("2006-01-02 15:04:05"),
+			"rate":            trade.Rate.StringFixed(8),
+			"total":           trade.Total.StringFixed(8),
+			"tradeID":         trade.TradeId,
+			"type":            command,
+			"takerAdjustment": trade.Received.StringFixed(8),
+		})
+	}
+
+	if isBuy {
+		b.asks = remaining
+	} else {
+		b.bids = remaining
+	}
+
+	writeJson(w, map[string]interface{}{
+		"orderNumber":     orderNumber,
+		"fee":             s.takerFee.StringFixed(8),
+		"clientOrderId":   params.Get("clientOrderId"),
+		"currencyPair":    pair,
+		"resultingTrades": resultingTrades,
+	})
+}
+
+// returnTradeHistory returns trades of the pair between start and end, the newest first
+func (s *Server) returnTradeHistory(w http.ResponseWriter, params url.Values) {
+	var pair = params.Get("currencyPair")
+	var start, _ = strconv.ParseInt(params.Get("start"), 10, 64)
+	var end, err = strconv.ParseInt(params.Get("end"), 10, 64)
+	if err != nil {
+		end = time.Now().Unix()
+	}
+
+	var res = make([]map[string]interface{}, 0)
+	for i := len(s.trades) - 1; i >= 0; i-- {
+		var trade = s.trades[i]
+		if trade.Pair != pair || trade.Date.Unix() < start || trade.Date.Unix() > end {
+			continue
+		}
+
+		var item = map[string]interface{}{
+			"tradeID":     trade.TradeId,
+			"date":        trade.Date.Format("2006-01-02 15:04:05"),
+			"rate":        trade.Rate.StringFixed(8),
+			"amount":      trade.Amount.StringFixed(8),
+			"total":       trade.Total.StringFixed(8),
+			"fee":         trade.Fee.StringFixed(8),
+			"orderNumber": trade.OrderNumber,
+			"type":        trade.Type,
+			"category":    "exchange",
+		}
+		if len(trade.ClientOrderId) > 0 {
+			item["clientOrderId"] = trade.ClientOrderId
+		}
+		res = append(res, item)
+	}
+
+	writeJson(w, res)
+}
+
+// returnOrderTrades returns trades of the order, the error is returned for the order without trades
+func (s *Server) returnOrderTrades(w http.ResponseWriter, params url.Values) {
+	var res = make([]map[string]interface{}, 0)
+	for _, trade := range s.trades {
+		if trade.OrderNumber != params.Get("orderNumber") {
+			continue
+		}
+		res = append(res, map[string]interface{}{
+			"tradeID":      trade.TradeId,
+			"currencyPair": trade.Pair,
+			"type":         trade.Type,
+			"rate":         trade.Rate.StringFixed(8),
+			"amount":       trade.Amount.StringFixed(8),
+			"total":        trade.Total.StringFixed(8),
+			"fee":          trade.Fee.StringFixed(8),
+			"date":         trade.Date.Format("2006-01-02 15:04:05"),
+		})
+	}
+	if len(res) == 0 {
+		writeError(w, "Order not found, or you are not the person who placed it.")
+		return
+	}
+
+	writeJson(w, res)
+}
+
+func (s *Server) withdraw(w http.ResponseWriter, params url.Values) {
+	var currency = params.Get("currency")
+	amount, err := decimal.NewFromString(params.Get("amount"))
+	if err != nil || !amount.IsPositive() || len(params.Get("address")) == 0 {
+		writeError(w, "Invalid parameters.")
+		return
+	}
+	if s.balances[currency].LessThan(amount) {
+		writeError(w, fmt.Sprintf("Not enough %v.", currency))
+		return
+	}
+
+	s.balances[currency] = s.balances[currency].Sub(amount)
+	s.transferSeq++
+	s.withdrawals = append(s.withdrawals, Withdrawal{
+		Number:   s.transferSeq,
+		Currency: currency,
+		Address:  params.Get("address"),
+		Amount:   amount,
+		Network:  params.Get("currencyToWithdrawAs"),
+		Status:   "PENDING",
+		Date:     time.Now(),
+	})
+
+	writeJson(w, map[string]interface{}{
+		"response":         fmt.Sprintf("Withdrew %v %v.", amount.StringFixed(8), currency),
+		"withdrawalNumber": s.transferSeq,
+	})
+}
+
+// returnDepositsWithdrawals returns deposits and withdrawals between start and end
+func (s *Server) returnDepositsWithdrawals(w http.ResponseWriter, params url.Values) {
+	var start, _ = strconv.ParseInt(params.Get("start"), 10, 64)
+	var end, err = strconv.ParseInt(params.Get("end"), 10, 64)
+	if err != nil {
+		end = time.Now().Unix()
+	}
+
+	var deposits = make([]map[string]interface{}, 0)
+	for _, deposit := range s.deposits {
+		if deposit.Date.Unix() < start || deposit.Date.Unix() > end {
+			continue
+		}
+		deposits = append(deposits, map[string]interface{}{
+			"depositNumber": deposit.Number,
+			"currency":      deposit.Currency,
+			"address":       deposit.Address,
+			"amount":        deposit.Amount.StringFixed(8),
+			"confirmations": deposit.Confirmations,
+			"txid":          deposit.TxId,
+			"timestamp":     deposit.Date.Unix(),
+			"status":        deposit.Status,
+		})
+	}
+
+	var withdrawals = make([]map[string]interface{}, 0)
+	for _, withdrawal := range s.withdrawals {
+		if withdrawal.Date.Unix() < start || withdrawal.Date.Unix() > end {
+			continue
+		}
+		withdrawals = append(withdrawals, map[string]interface{}{
+			"withdrawalNumber": withdrawal.Number,
+			"currency":         withdrawal.Currency,
+			"address":          withdrawal.Address,
+			"amount":           withdrawal.Amount.StringFixed(8),
+			"fee":              "0.00000000",
+			"timestamp":        withdrawal.Date.Unix(),
+			"status":           withdrawal.Status,
+			"ipAddress":        "127.0.0.1",
+		})
+	}
+
+	writeJson(w, map[string]interface{}{"deposits": deposits, "withdrawals": withdrawals})
+}
+
+func (s *Server) book(pair string) *book {
+	var b, found = s.books[pair]
+	if !found {
+		b = &book{}
+		s.books[pair] = b
+	}
+	return b
+}
+
+// levelsJson formats levels as Poloniex does: price string and volume number
+func levelsJson(levels []Level, depth int) [][]interface{} {
+	var res = make([][]interface{}, 0, depth)
+	for i, level := range levels {
+		if i >= depth {
+			break
+		}
+		var volume, _ = level.Volume.Float64()
+		res = append(res, []interface{}{level.Price.StringFixed(8), volume})
+	}
+	return res
+}
+
+func writeError(w http.ResponseWriter, message string) {
+	writeErrorStatus(w, http.StatusOK, message)
+}
+
+func writeErrorStatus(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,957 @@
+package poloniexspot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"trading_bot/config"
+	"trading_bot/internal/common"
+	"trading_bot/internal/common/clientid"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/orderbook"
+	"trading_bot/internal/common/ratelimit"
+	"trading_bot/internal/common/retry"
+	"trading_bot/internal/common/signer"
+	"trading_bot/internal/entity"
+	"trading_bot/pkg/logger"
+
+	"github.com/shopspring/decimal"
+)
+
+// spot API allows 200 calls per second to market data and 50 calls per second to account and orders endpoints
+var defaultRateLimits = config.RateLimitSettings{
+	Public:  config.RateLimit{RequestsPerSecond: 100, Burst: 100},
+	Private: config.RateLimit{RequestsPerSecond: 25, Burst: 25},
+	Order:   config.RateLimit{RequestsPerSecond: 25, Burst: 25},
+}
+
+const ( // order states
+	stateFilled            = "FILLED"
+	statePartiallyCanceled = "PARTIALLY_CANCELED"
+	stateCanceled          = "CANCELED"
+	stateFailed            = "FAILED"
+
+	// how many times order state is requested until it becomes final
+	orderStateChecks = 5
+
+	systemName = "PoloniexSpot"
+
+	// fees are requested again after the hour
+	feeCacheTime = 1 * time.Hour
+	// trading rules of the hedge orders are requested again after the hour
+	marketCacheTime = 1 * time.Hour
+
+	// max trades of the trade history page
+	tradeHistoryLimit = 100
+)
+
+// fees of the hedges when the fees of the account are unknown, trade volume < 50k USD @ 30 days
+var defaultFees = entity.FeeSchedule{MakerFee: decimal.NewFromFloat(0.0015), TakerFee: decimal.NewFromFloat(0.0025)}
+
+// PoloniexSpotRequests speaks the current Poloniex spot REST API (https://api.poloniex.com)
+type PoloniexSpotRequests struct {
+	logger        logger.ILogger
+	helperMethods common.IHelperMethods
+	cacheUpdate   time.Time
+	balanceCache  map[string]*entity.BalanceObject
+	feeCache      *entity.FeeSchedule
+	marketCache   *entity.MarketInfo
+	baseUrl       string
+	publicKey     string
+	signer        signer.Signer
+	retryPolicy   retry.Policy
+	rateLimiter   *ratelimit.Limiter
+
+	// network specific currency of the transfers, e.g. USDTTRON
+	withdrawalNetwork string
+}
+
+func New(l logger.ILogger, hm common.IHelperMethods, cs config.TradingSettings) *PoloniexSpotRequests {
+	return &PoloniexSpotRequests{
+		logger:        l,
+		helperMethods: hm,
+		cacheUpdate:   time.Time{},
+		balanceCache:  make(map[string]*entity.BalanceObject),
+		baseUrl:       strings.TrimSuffix(cs.Url, "/"),
+		publicKey:     cs.Key.Reveal(),
+		signer:        signer.Resolve(cs.Signer, signer.TypeHmacSha256Header, cs.Secret.Reveal()),
+		retryPolicy:   retry.NewPolicy(cs.Retry),
+		rateLimiter:   ratelimit.Shared(systemName, cs.Key.Reveal(), cs.RateLimit, defaultRateLimits),
+
+		withdrawalNetwork: cs.WithdrawalNetwork,
+	}
+}
+
+func (pr *PoloniexSpotRequests) GetTradingBalances(ctx context.Context) (map[string]*entity.BalanceObject, error) {
+	// 1 minute cache
+	if pr.cacheUpdate.Add(1 * time.Minute).After(time.Now().UTC()) {
+		return pr.balanceCache, nil
+	}
+
+	var balancesStr, err = pr.queryPrivate(ctx, "GET", "/accounts/balances", map[string]string{}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []struct {
+		AccountType string `json:"accountType"`
+		Balances    []struct {
+			Currency  string          `json:"currency"`
+			Available decimal.Decimal `json:"available"`
+			Hold      decimal.Decimal `json:"hold"`
+		} `json:"balances"`
+	}
+
+	err = json.Unmarshal([]byte(balancesStr), &accounts)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/accounts/balances", 200, balancesStr)
+	}
+
+	var res = make(map[string]*entity.BalanceObject)
+
+	for _, account := range accounts {
+		if account.AccountType != "SPOT" {
+			continue
+		}
+		for _, val := range account.Balances {
+			res[val.Currency] = &entity.BalanceObject{
+				Currency: val.Currency,
+				Balance:  val.Available,
+				Reserved: val.Hold,
+			}
+		}
+	}
+
+	pr.balanceCache = res
+	pr.cacheUpdate = time.Now().UTC()
+
+	return res, nil
+}
+
+// InvalidateBalances drops the cached balances, the next GetTradingBalances requests them
+func (pr *PoloniexSpotRequests) InvalidateBalances() {
+	pr.cacheUpdate = time.Time{}
+}
+
+func (pr *PoloniexSpotRequests) GetPublicTradingOrders(ctx context.Context, tradingSystemPair string, usdcTradingLimit decimal.Decimal, cryptoTradingLimit decimal.Decimal, internalCryptoBalance decimal.Decimal, internalUsdcBalance decimal.Decimal, pairMinAmount decimal.Decimal) ([]*entity.TradingOrder, error) {
+	var requestData map[string]string = make(map[string]string)
+	requestData["limit"] = "20"
+
+	var method = "/markets/" + url.PathEscape(tradingSystemPair) + "/orderBook"
+	var tradingOrders, err = pr.queryPublic(helpermethods.WithEndpoint(ctx, "/markets/{symbol}/orderBook"), method, requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	// price and quantity levels are flattened: [price, quantity, price, quantity, ...]
+	orders := struct {
+		Asks []decimal.Decimal `json:"asks"`
+		Bids []decimal.Decimal `json:"bids"`
+	}{}
+
+	err = json.Unmarshal([]byte(tradingOrders), &orders)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, method, 200, tradingOrders)
+	}
+
+	return orderbook.SelectTradingOrders(toLevels(orders.Asks), toLevels(orders.Bids), usdcTradingLimit, cryptoTradingLimit, internalCryptoBalance, internalUsdcBalance, pairMinAmount), nil
+}
+
+func (pr *PoloniexSpotRequests) Buy(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string, clientOrderId string) (*entity.ExecutionReport, error) {
+	var rules = pr.orderRules(ctx, tradingSystemPair)
+	// taker fee of the account fees (GetFees) is taken from the bought currency, the amount is rounded up to the lot size
+	var requiredAmount = rules.RoundAmount(pr.hedgeFees(ctx, tradingSystemPair).GrossAmount(amount), true)
+	var orderPrice = rules.RoundPrice(tradingSystemPrice, true)
+	var report = entity.NewExecutionReport(systemName, tradingSystemPair, false, requiredAmount)
+	var attempts = pr.retryPolicy.Start(ctx)
+	var priceStep = 0
+
+	for {
+		var orderId = clientid.Attempt(clientOrderId, priceStep)
+		if err := rules.Validate(orderPrice, requiredAmount); err != nil {
+			return nil, common.NewRequestError(common.ErrRejected, systemName, "buy", 0, err.Error())
+		}
+		var order, err = pr.placeOrder(ctx, "BUY", tradingSystemPair, orderPrice, requiredAmount, orderId)
+		if common.IsAmbiguous(err) && len(orderId) > 0 {
+			// the order may be executed, it's looked up before it's placed again
+			var found, lookupErr = pr.lookupOrder(ctx, attempts, orderId, err)
+			if lookupErr != nil {
+				return nil, lookupErr
+			}
+			if found != nil {
+				order, err = found, nil
+			}
+		}
+		if common.IsRetryable(err) {
+			if waitErr := attempts.Wait(err); waitErr != nil {
+				return nil, waitErr
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if order.isKilled() {
+			var killedErr = common.NewRequestError(common.ErrOrderNotFilled, systemName, "buy", 0, "order killed at price "+orderPrice.String())
+			// rising price
+			orderPrice = rules.RoundPrice(pr.retryPolicy.StepUp(orderPrice), true)
+			priceStep++
+			if orderPrice.GreaterThan(internalPrice) {
+				return report, common.NewRequestError(common.ErrOrderNotFilled, systemName, "buy", 0, fmt.Sprintf("max price reached : %v, amount : %v, pair : %v", internalPrice, requiredAmount, internalPair))
+			}
+			if nextErr := attempts.Next(killedErr); nextErr != nil {
+				return report, nextErr
+			}
+			continue
+		}
+
+		if err := pr.fillReport(ctx, order, report); err != nil {
+			// order is executed already, report is incomplete only
+			pr.logger.Error("PoloniexSpot : can't get trades of order %v : %v", order.Id, err)
+		}
+		pr.logger.Info("PoloniexSpot : buy order %v filled %v at average price %v, fee %v %v", report.OrderNumber, report.FilledAmount, report.AveragePrice, report.Fee, report.FeeCurrency)
+
+		var resultedAmount = order.FilledQuantity.RoundDown(8)
+		//check if filled amount different from requiredAmount
+		if resultedAmount.LessThan(requiredAmount) {
+			report.Status = entity.ExecutionStatusPartiallyFilled
+			return report, common.NewRequestError(common.ErrOrderNotFilled, systemName, "buy", 0, fmt.Sprintf("resulted amount : %v, is lower than Required Amount : %v", resultedAmount, requiredAmount))
+		}
+		report.Status = entity.ExecutionStatusFilled
+		return report, nil
+	}
+}
+
+func (pr *PoloniexSpotRequests) Sell(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string, clientOrderId string) (*entity.ExecutionReport, error) {
+	var rules = pr.orderRules(ctx, tradingSystemPair)
+	// taker fee is taken from the received quote currency, it's covered by the internal buy price
+	var requiredAmount = rules.RoundAmount(amount, false)
+	var orderPrice = rules.RoundPrice(tradingSystemPrice, false)
+	var report = entity.NewExecutionReport(systemName, tradingSystemPair, true, requiredAmount)
+	var attempts = pr.retryPolicy.Start(ctx)
+	var priceStep = 0
+
+	for {
+		var orderId = clientid.Attempt(clientOrderId, priceStep)
+		if err := rules.Validate(orderPrice, requiredAmount); err != nil {
+			return nil, common.NewRequestError(common.ErrRejected, systemName, "sell", 0, err.Error())
+		}
+		var order, err = pr.placeOrder(ctx, "SELL", tradingSystemPair, orderPrice, requiredAmount, orderId)
+		if common.IsAmbiguous(err) && len(orderId) > 0 {
+			// the order may be executed, it's looked up before it's placed again
+			var found, lookupErr = pr.lookupOrder(ctx, attempts, orderId, err)
+			if lookupErr != nil {
+				return nil, lookupErr
+			}
+			if found != nil {
+				order, err = found, nil
+			}
+		}
+		if common.IsRetryable(err) {
+			if waitErr := attempts.Wait(err); waitErr != nil {
+				return nil, waitErr
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if order.isKilled() {
+			var killedErr = common.NewRequestError(common.ErrOrderNotFilled, systemName, "sell", 0, "order killed at price "+orderPrice.String())
+			// lowering price
+			orderPrice = rules.RoundPrice(pr.retryPolicy.StepDown(orderPrice), false)
+			priceStep++
+			if orderPrice.LessThan(internalPrice) {
+				return report, common.NewRequestError(common.ErrOrderNotFilled, systemName, "sell", 0, fmt.Sprintf("max price reached : %v, amount : %v, pair : %v", internalPrice, requiredAmount, internalPair))
+			}
+			if nextErr := attempts.Next(killedErr); nextErr != nil {
+				return report, nextErr
+			}
+			continue
+		}
+
+		if err := pr.fillReport(ctx, order, report); err != nil {
+			// order is executed already, report is incomplete only
+			pr.logger.Error("PoloniexSpot : can't get trades of order %v : %v", order.Id, err)
+		}
+		pr.logger.Info("PoloniexSpot : sell order %v filled %v at average price %v, fee %v %v", report.OrderNumber, report.FilledAmount, report.AveragePrice, report.Fee, report.FeeCurrency)
+
+		var resultedAmount = order.FilledQuantity.RoundDown(8)
+		//check if filled amount different from requiredAmount
+		if resultedAmount.LessThan(requiredAmount) {
+			report.Status = entity.ExecutionStatusPartiallyFilled
+			return report, common.NewRequestError(common.ErrOrderNotFilled, systemName, "sell", 0, fmt.Sprintf("resulted amount : %v, is lower than Required Amount : %v", resultedAmount, requiredAmount))
+		}
+		report.Status = entity.ExecutionStatusFilled
+		return report, nil
+	}
+}
+
+// GetFees returns maker and taker fees of the account, fees are cached for an hour.
+// Cached fees are returned when they can't be refreshed.
+func (pr *PoloniexSpotRequests) GetFees(ctx context.Context, tradingSystemPair string) (*entity.FeeSchedule, error) {
+	if pr.feeCache != nil && pr.feeCache.Updated.Add(feeCacheTime).After(time.Now().UTC()) {
+		return pr.feeCache, nil
+	}
+
+	var method = "/feeinfo"
+	var feeResponse, err = pr.queryPrivate(ctx, "GET", method, map[string]string{}, nil)
+	if err != nil {
+		if pr.feeCache != nil {
+			pr.logger.Error("PoloniexSpot : can't refresh fees, cached fees are used : %v", err)
+			return pr.feeCache, nil
+		}
+		return nil, err
+	}
+
+	fees := struct {
+		MakerRate decimal.Decimal `json:"makerRate"`
+		TakerRate decimal.Decimal `json:"takerRate"`
+	}{}
+	err = json.Unmarshal([]byte(feeResponse), &fees)
+	if err != nil || fees.TakerRate.IsNegative() || fees.TakerRate.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, method, 200, feeResponse)
+	}
+
+	if pr.feeCache == nil || !pr.feeCache.TakerFee.Equal(fees.TakerRate) || !pr.feeCache.MakerFee.Equal(fees.MakerRate) {
+		pr.logger.Info("PoloniexSpot : fees are maker %v, taker %v", fees.MakerRate, fees.TakerRate)
+	}
+	pr.feeCache = &entity.FeeSchedule{MakerFee: fees.MakerRate, TakerFee: fees.TakerRate, Updated: time.Now().UTC()}
+
+	return pr.feeCache, nil
+}
+
+// GetMarketInfo requests the trading rules of the symbol, the rules are kept for the hedge orders
+func (pr *PoloniexSpotRequests) GetMarketInfo(ctx context.Context, tradingSystemPair string) (*entity.MarketInfo, error) {
+	var method = "/markets/" + url.PathEscape(tradingSystemPair)
+	var marketResponse, err = pr.queryPublic(helpermethods.WithEndpoint(ctx, "/markets/{symbol}"), method, map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+
+	var markets []struct {
+		Symbol           string `json:"symbol"`
+		SymbolTradeLimit struct {
+			PriceScale    int32           `json:"priceScale"`
+			QuantityScale int32           `json:"quantityScale"`
+			MinQuantity   decimal.Decimal `json:"minQuantity"`
+			MinAmount     decimal.Decimal `json:"minAmount"`
+		} `json:"symbolTradeLimit"`
+	}
+	err = json.Unmarshal([]byte(marketResponse), &markets)
+	if err != nil || len(markets) != 1 {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, method, 200, marketResponse)
+	}
+
+	var limit = markets[0].SymbolTradeLimit
+	pr.marketCache = &entity.MarketInfo{
+		Pair:        tradingSystemPair,
+		PriceStep:   entity.StepFromPrecision(limit.PriceScale),
+		AmountStep:  entity.StepFromPrecision(limit.QuantityScale),
+		MinAmount:   limit.MinQuantity,
+		MinNotional: limit.MinAmount,
+		Updated:     time.Now().UTC(),
+	}
+
+	return pr.marketCache, nil
+}
+
+// orderRules returns the trading rules of the hedge order, the order is checked by the trading system only
+// when the rules are unknown
+func (pr *PoloniexSpotRequests) orderRules(ctx context.Context, tradingSystemPair string) *entity.MarketInfo {
+	var cached = pr.marketCache
+	if cached != nil && cached.Pair == tradingSystemPair && cached.Updated.Add(marketCacheTime).After(time.Now().UTC()) {
+		return cached
+	}
+
+	var rules, err = pr.GetMarketInfo(ctx, tradingSystemPair)
+	if err != nil {
+		if cached != nil && cached.Pair == tradingSystemPair {
+			pr.logger.Error("PoloniexSpot : can't refresh market info, cached rules are used : %v", err)
+			return cached
+		}
+		pr.logger.Error("PoloniexSpot : can't get market info, the order is not normalised : %v", err)
+		return &entity.MarketInfo{Pair: tradingSystemPair}
+	}
+	return rules
+}
+
+// hedgeFees returns fees of the hedge order, the default fees are used when the fees of the account are unknown
+func (pr *PoloniexSpotRequests) hedgeFees(ctx context.Context, tradingSystemPair string) *entity.FeeSchedule {
+	var fees, err = pr.GetFees(ctx, tradingSystemPair)
+	if err != nil {
+		pr.logger.Error("PoloniexSpot : can't get fees, default taker fee %v is used : %v", defaultFees.TakerFee, err)
+		return &defaultFees
+	}
+	return fees
+}
+
+// Withdraw returns withdrawalRequestsId of the withdrawal
+func (pr *PoloniexSpotRequests) Withdraw(ctx context.Context, addr string, withdrawalAmount decimal.Decimal, currency string, tradingSystemWithdrawalNetwork string) (string, error) {
+	// network specific currency name, e.g. USDTTRON
+	if len(tradingSystemWithdrawalNetwork) > 0 {
+		currency = tradingSystemWithdrawalNetwork
+	}
+
+	params := struct {
+		Currency string `json:"currency"`
+		Amount   string `json:"amount"`
+		Address  string `json:"address"`
+	}{
+		Currency: currency,
+		Amount:   withdrawalAmount.String(),
+		Address:  addr,
+	}
+
+	pr.logger.Info("PoloniexSpot : withdraw request is : %v", params)
+	var withdrawResponse, err = pr.queryPrivate(ctx, "POST", "/wallets/withdraw", map[string]string{}, params)
+	if err != nil {
+		return "", err
+	}
+
+	result := struct {
+		WithdrawalRequestsId json.Number `json:"withdrawalRequestsId"`
+	}{}
+	err = json.Unmarshal([]byte(withdrawResponse), &result)
+	if err != nil || len(result.WithdrawalRequestsId) == 0 {
+		return "", common.NewRequestError(common.ErrInvalidResponse, systemName, "/wallets/withdraw", 200, withdrawResponse)
+	}
+
+	return result.WithdrawalRequestsId.String(), nil
+}
+
+// GetTransfers returns deposits and withdrawals of the currency between start and end. Currencies are matched exactly,
+// the network specific currency of the settings (e.g. USDTTRON for USDT) is included.
+func (pr *PoloniexSpotRequests) GetTransfers(ctx context.Context, currency string, start time.Time, end time.Time) ([]*entity.VenueTransfer, error) {
+	var requestData map[string]string = make(map[string]string)
+	requestData["start"] = strconv.FormatInt(start.Unix(), 10)
+	requestData["end"] = strconv.FormatInt(end.Unix(), 10)
+
+	var activityResponse, err = pr.queryPrivate(ctx, "GET", "/wallets/activity", requestData, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	activity := struct {
+		Deposits    []*walletTransfer `json:"deposits"`
+		Withdrawals []*walletTransfer `json:"withdrawals"`
+	}{}
+	err = json.Unmarshal([]byte(activityResponse), &activity)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/wallets/activity", 200, activityResponse)
+	}
+
+	var res = make([]*entity.VenueTransfer, 0, len(activity.Deposits)+len(activity.Withdrawals))
+	for _, item := range activity.Deposits {
+		if pr.isTransferCurrency(item.Currency, currency) {
+			res = append(res, item.toVenueTransfer(true))
+		}
+	}
+	for _, item := range activity.Withdrawals {
+		if pr.isTransferCurrency(item.Currency, currency) {
+			res = append(res, item.toVenueTransfer(false))
+		}
+	}
+
+	return res, nil
+}
+
+// isTransferCurrency reports whether the transfer currency is the currency or its configured network currency.
+// Prefixes don't match, ETHW is not ETH.
+func (pr *PoloniexSpotRequests) isTransferCurrency(transferCurrency string, currency string) bool {
+	return transferCurrency == currency || (len(pr.withdrawalNetwork) > 0 && transferCurrency == pr.withdrawalNetwork)
+}
+
+func (pr *PoloniexSpotRequests) GetCryptoAddress(ctx context.Context, currency string, tradingSystemWithdrawalNetwork string) (string, error) {
+	if tradingSystemWithdrawalNetwork != "" {
+		currency = tradingSystemWithdrawalNetwork
+	}
+
+	var requestData map[string]string = make(map[string]string)
+	requestData["currency"] = currency
+
+	var addresses, err = pr.queryPrivate(ctx, "GET", "/wallets/addresses", requestData, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var result map[string]string
+	err = json.Unmarshal([]byte(addresses), &result)
+	if err != nil {
+		return "", common.NewRequestError(common.ErrInvalidResponse, systemName, "/wallets/addresses", 200, addresses)
+	}
+	val, found := result[currency]
+	if !found {
+		return "", common.NewRequestError(common.ErrNotFound, systemName, "/wallets/addresses", 200, "no deposit address for "+currency)
+	}
+	return val, nil
+}
+
+// placeOrder creates a limit fill-or-kill order and waits for its final state
+func (pr *PoloniexSpotRequests) placeOrder(ctx context.Context, side string, tradingSystemPair string, price decimal.Decimal, quantity decimal.Decimal, clientOrderId string) (*orderInfo, error) {
+	params := struct {
+		Symbol        string `json:"symbol"`
+		Side          string `json:"side"`
+		Type          string `json:"type"`
+		TimeInForce   string `json:"timeInForce"`
+		Price         string `json:"price"`
+		Quantity      string `json:"quantity"`
+		ClientOrderId string `json:"clientOrderId,omitempty"`
+	}{
+		Symbol:        tradingSystemPair,
+		Side:          side,
+		Type:          "LIMIT",
+		TimeInForce:   "FOK",
+		Price:         price.String(),
+		Quantity:      quantity.String(),
+		ClientOrderId: clientOrderId,
+	}
+
+	var orderResponse, err = pr.queryPrivate(ctx, "POST", "/orders", map[string]string{}, params)
+	if err != nil {
+		return nil, err
+	}
+
+	pr.logger.Info("PoloniexSpot : %v response is : %v", strings.ToLower(side), orderResponse)
+
+	created := struct {
+		Id string `json:"id"`
+	}{}
+	err = json.Unmarshal([]byte(orderResponse), &created)
+	if err != nil || len(created.Id) == 0 {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/orders", 200, orderResponse)
+	}
+
+	return pr.getOrder(ctx, created.Id)
+}
+
+// lookupOrder looks the order up by the client order id after the ambiguous placeErr, lookups are retried with
+// the attempts of the order. Nil order is returned when the order was not placed.
+func (pr *PoloniexSpotRequests) lookupOrder(ctx context.Context, attempts *retry.Attempts, clientOrderId string, placeErr error) (*orderInfo, error) {
+	pr.logger.Warn("PoloniexSpot : order %v outcome is unknown, looking it up : %v", clientOrderId, placeErr)
+
+	var lastErr = placeErr
+	for {
+		if waitErr := attempts.Wait(lastErr); waitErr != nil {
+			return nil, waitErr
+		}
+
+		var order, err = pr.getOrder(ctx, "cid:"+clientOrderId)
+		if errors.Is(err, common.ErrNotFound) {
+			return nil, nil
+		}
+		if err == nil {
+			pr.logger.Info("PoloniexSpot : order %v is found : %v, state : %v", clientOrderId, order.Id, order.State)
+			return order, nil
+		}
+		if !common.IsRetryable(err) && !common.IsAmbiguous(err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+}
+
+func (pr *PoloniexSpotRequests) getOrder(ctx context.Context, orderId string) (*orderInfo, error) {
+	var order = &orderInfo{}
+	var method = "/orders/" + url.PathEscape(orderId)
+
+	for i := 0; i < orderStateChecks; i++ {
+		var orderResponse, err = pr.queryPrivate(helpermethods.WithEndpoint(ctx, "/orders/{id}"), "GET", method, map[string]string{}, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(orderResponse), order)
+		if err != nil {
+			return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, method, 200, orderResponse)
+		}
+
+		if order.isFinal() {
+			return order, nil
+		}
+
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, common.WrapRequestError(ctx.Err(), systemName, method, 0, "")
+		}
+	}
+
+	return nil, common.NewRequestError(common.ErrTransientNetwork, systemName, method, 200, "order "+orderId+" is not final, state : "+order.State)
+}
+
+// fillReport copies order ids and trades of the order to the report
+func (pr *PoloniexSpotRequests) fillReport(ctx context.Context, order *orderInfo, report *entity.ExecutionReport) error {
+	report.OrderNumber = order.Id
+	report.ClientOrderId = order.ClientOrderId
+
+	var trades, err = pr.orderTrades(ctx, order.Id)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range trades {
+		report.AddTrade(&entity.ExecutionTrade{
+			TradeId:     item.Id,
+			Price:       item.Price,
+			Amount:      item.Quantity,
+			Total:       item.Amount,
+			Fee:         item.FeeAmount,
+			FeeCurrency: item.FeeCurrency,
+		})
+	}
+
+	return nil
+}
+
+// orderTrades requests the trades of the order
+func (pr *PoloniexSpotRequests) orderTrades(ctx context.Context, orderId string) ([]*tradeInfo, error) {
+	var method = "/orders/" + url.PathEscape(orderId) + "/trades"
+	var tradesResponse, err = pr.queryPrivate(helpermethods.WithEndpoint(ctx, "/orders/{id}/trades"), "GET", method, map[string]string{}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var trades []*tradeInfo
+	err = json.Unmarshal([]byte(tradesResponse), &trades)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, method, 200, tradesResponse)
+	}
+
+	return trades, nil
+}
+
+// GetOpenOrders returns open orders of the pair
+func (pr *PoloniexSpotRequests) GetOpenOrders(ctx context.Context, tradingSystemPair string) ([]*entity.VenueOrder, error) {
+	var requestData map[string]string = make(map[string]string)
+	requestData["symbol"] = tradingSystemPair
+
+	var ordersResponse, err = pr.queryPrivate(ctx, "GET", "/orders", requestData, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []*orderInfo
+	err = json.Unmarshal([]byte(ordersResponse), &orders)
+	if err != nil {
+		return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/orders", 200, ordersResponse)
+	}
+
+	var res = make([]*entity.VenueOrder, 0, len(orders))
+	for _, item := range orders {
+		res = append(res, &entity.VenueOrder{
+			OrderNumber:    item.Id,
+			ClientOrderId:  item.ClientOrderId,
+			Pair:           item.Symbol,
+			IsSellOrder:    item.Side == "SELL",
+			Price:          item.Price,
+			StartingAmount: item.Quantity,
+			Amount:         item.Quantity.Sub(item.FilledQuantity),
+			Date:           time.UnixMilli(item.CreateTime).UTC(),
+		})
+	}
+
+	return res, nil
+}
+
+// GetTradeHistory returns trades of the pair made from start till end, the newest first.
+// Pages are requested until the trades of the period are exhausted.
+func (pr *PoloniexSpotRequests) GetTradeHistory(ctx context.Context, tradingSystemPair string, start time.Time, end time.Time) ([]*entity.VenueTrade, error) {
+	var res = make([]*entity.VenueTrade, 0)
+	var seen = make(map[string]bool)
+
+	for {
+		var requestData map[string]string = make(map[string]string)
+		requestData["symbols"] = tradingSystemPair
+		requestData["startTime"] = strconv.FormatInt(start.UnixMilli(), 10)
+		requestData["endTime"] = strconv.FormatInt(end.UnixMilli(), 10)
+		requestData["limit"] = strconv.Itoa(tradeHistoryLimit)
+
+		var historyResponse, err = pr.queryPrivate(ctx, "GET", "/trades", requestData, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var trades []*tradeInfo
+		err = json.Unmarshal([]byte(historyResponse), &trades)
+		if err != nil {
+			return nil, common.NewRequestError(common.ErrInvalidResponse, systemName, "/trades", 200, historyResponse)
+		}
+
+		var oldest = end
+		for _, item := range trades {
+			if item.CreateTime < oldest.UnixMilli() {
+				oldest = time.UnixMilli(item.CreateTime)
+			}
+			if seen[item.Id] {
+				continue
+			}
+			seen[item.Id] = true
+			res = append(res, item.toVenueTrade())
+		}
+
+		// trades of the same millisecond may be split by the page, the next page ends with the oldest trade
+		if len(trades) < tradeHistoryLimit || !oldest.Before(end) {
+			return res, nil
+		}
+		end = oldest
+	}
+}
+
+// GetOrderTrades returns trades of the order, ErrNotFound is returned for the unknown order
+func (pr *PoloniexSpotRequests) GetOrderTrades(ctx context.Context, orderNumber string) ([]*entity.VenueTrade, error) {
+	var trades, err = pr.orderTrades(ctx, orderNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var res = make([]*entity.VenueTrade, 0, len(trades))
+	for _, item := range trades {
+		var trade = item.toVenueTrade()
+		trade.OrderNumber = orderNumber
+		res = append(res, trade)
+	}
+
+	return res, nil
+}
+
+func (pr *PoloniexSpotRequests) queryPublic(ctx context.Context, method string, requestData map[string]string) (string, error) {
+	ctx = helpermethods.WithDefaultEndpoint(ctx, method)
+
+	u, err := url.Parse(pr.baseUrl + method)
+	if err != nil {
+		return "", common.WrapRequestError(err, systemName, method, 0, "")
+	}
+
+	q := u.Query()
+	if len(requestData) > 0 {
+		for key, val := range requestData {
+			q.Set(key, val)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	if err := pr.rateLimiter.Wait(ctx, ratelimit.ClassPublic, method); err != nil {
+		return "", common.WrapRequestError(err, systemName, method, 0, "")
+	}
+
+	var resText, statusCode, err1 = pr.helperMethods.HttpGet(ctx, u, "application/json", map[string]string{})
+
+	if err1 != nil || statusCode != 200 {
+		pr.logger.Error("PoloniexSpot : response status : %v, %v : %v", statusCode, err1, resText)
+		return "", newRequestError(err1, method, statusCode, resText)
+	}
+
+	return resText, nil
+}
+
+func (pr *PoloniexSpotRequests) queryPrivate(ctx context.Context, requestType string, method string, requestData map[string]string, body interface{}) (string, error) {
+	ctx = helpermethods.WithDefaultEndpoint(ctx, method)
+
+	u, err := url.Parse(pr.baseUrl + method)
+	if err != nil {
+		return "", common.WrapRequestError(err, systemName, method, 0, "")
+	}
+
+	// wait before signing, timestamp must be fresh
+	var class = ratelimit.ClassPrivate
+	if method == "/orders" && requestType != "GET" {
+		class = ratelimit.ClassOrder
+	}
+	if err := pr.rateLimiter.Wait(ctx, class, method); err != nil {
+		return "", common.WrapRequestError(err, systemName, method, 0, "")
+	}
+
+	q := u.Query()
+	if len(requestData) > 0 {
+		for key, val := range requestData {
+			q.Set(key, val)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	var dataBytes []byte
+	if body != nil {
+		dataBytes, _ = json.Marshal(body)
+	}
+
+	var req = &signer.Request{
+		Method:  requestType,
+		Url:     u,
+		Body:    dataBytes,
+		Headers: map[string]string{"key": pr.publicKey},
+	}
+	if err := pr.signer.Sign(req); err != nil {
+		return "", common.NewRequestError(common.ErrAuthFailure, systemName, method, 0, err.Error())
+	}
+	var webHeaders = req.Headers
+
+	var statusCode = 500
+	var resText = ""
+	var err1 error
+	if requestType == "GET" {
+		resText, statusCode, err1 = pr.helperMethods.HttpGet(ctx, u, "application/json", webHeaders)
+	} else {
+		resText, statusCode, err1 = pr.helperMethods.HttpPost(ctx, u, dataBytes, "application/json", webHeaders)
+	}
+
+	if err1 != nil || statusCode != 200 {
+		pr.logger.Error("PoloniexSpot : response status : %v, %v : %v", statusCode, err1, resText)
+		return "", newRequestError(err1, method, statusCode, resText)
+	}
+
+	return resText, nil
+}
+
+// newRequestError classifies Poloniex error response {"code":21709,"message":"..."}
+func newRequestError(err error, method string, statusCode int, response string) *common.RequestError {
+	var requestError = common.WrapRequestError(err, systemName, method, statusCode, response)
+	if err != nil {
+		return requestError
+	}
+
+	var result apiError
+	if json.Unmarshal([]byte(response), &result) != nil || len(result.Message) == 0 {
+		return requestError
+	}
+	requestError.Message = fmt.Sprintf("%v %v", result.Code, result.Message)
+
+	var message = strings.ToLower(result.Message)
+	switch {
+	case strings.Contains(message, "frozen") || strings.Contains(message, "not trading"):
+		requestError.Kind = common.ErrMarketFrozen
+	case strings.Contains(message, "too many requests"):
+		requestError.Kind = common.ErrRateLimited
+	case strings.Contains(message, "insufficient") || strings.Contains(message, "not enough"):
+		requestError.Kind = common.ErrInsufficientFunds
+	}
+
+	return requestError
+}
+
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type orderInfo struct {
+	Id             string          `json:"id"`
+	ClientOrderId  string          `json:"clientOrderId"`
+	Symbol         string          `json:"symbol"`
+	State          string          `json:"state"`
+	Side           string          `json:"side"`
+	Price          decimal.Decimal `json:"price"`
+	Quantity       decimal.Decimal `json:"quantity"`
+	FilledQuantity decimal.Decimal `json:"filledQuantity"`
+	FilledAmount   decimal.Decimal `json:"filledAmount"`
+	AvgPrice       decimal.Decimal `json:"avgPrice"`
+	CreateTime     int64           `json:"createTime"`
+}
+
+func (o *orderInfo) isFinal() bool {
+	switch o.State {
+	case stateFilled, statePartiallyCanceled, stateCanceled, stateFailed:
+		return true
+	}
+	return false
+}
+
+// isKilled reports fill-or-kill order cancelled without any fill
+func (o *orderInfo) isKilled() bool {
+	return (o.State == stateCanceled || o.State == stateFailed) && o.FilledQuantity.IsZero()
+}
+
+// walletTransfer is the deposit or the withdrawal of /wallets/activity response
+type walletTransfer struct {
+	DepositNumber        json.Number     `json:"depositNumber"`
+	WithdrawalRequestsId json.Number     `json:"withdrawalRequestsId"`
+	Currency             string          `json:"currency"`
+	Address              string          `json:"address"`
+	Amount               decimal.Decimal `json:"amount"`
+	Fee                  decimal.Decimal `json:"fee"`
+	Confirmations        int             `json:"confirmations"`
+	TxId                 string          `json:"txid"`
+	Timestamp            int64           `json:"timestamp"`
+	Status               string          `json:"status"`
+}
+
+func (t *walletTransfer) toVenueTransfer(isDeposit bool) *entity.VenueTransfer {
+	var res = &entity.VenueTransfer{
+		Id:            t.WithdrawalRequestsId.String(),
+		Currency:      t.Currency,
+		Address:       t.Address,
+		Amount:        t.Amount,
+		Fee:           t.Fee,
+		TxId:          t.TxId,
+		Confirmations: t.Confirmations,
+		Status:        entity.TransferStatusPending,
+		IsDeposit:     isDeposit,
+		Date:          time.Unix(t.Timestamp, 0).UTC(),
+	}
+	if isDeposit {
+		res.Id = t.DepositNumber.String()
+	}
+
+	switch strings.ToUpper(t.Status) {
+	case "COMPLETE", "COMPLETED":
+		res.Status = entity.TransferStatusComplete
+	case "FAILED", "CANCELED", "CANCELLED", "REJECTED", "COMPLETE ERROR":
+		res.Status = entity.TransferStatusFailed
+	}
+
+	return res
+}
+
+type tradeInfo struct {
+	Id            string          `json:"id"`
+	Symbol        string          `json:"symbol"`
+	OrderId       string          `json:"orderId"`
+	ClientOrderId string          `json:"clientOrderId"`
+	Side          string          `json:"side"`
+	Price         decimal.Decimal `json:"price"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Amount        decimal.Decimal `json:"amount"`
+	FeeCurrency   string          `json:"feeCurrency"`
+	FeeAmount     decimal.Decimal `json:"feeAmount"`
+	CreateTime    int64           `json:"createTime"`
+}
+
+// toVenueTrade converts the trade, the fee rate is the fee share of the received currency
+func (t *tradeInfo) toVenueTrade() *entity.VenueTrade {
+	var received = t.Quantity
+	if t.Side == "SELL" {
+		received = t.Amount
+	}
+	var feeRate = decimal.Zero
+	if received.IsPositive() {
+		feeRate = t.FeeAmount.Div(received)
+	}
+
+	return &entity.VenueTrade{
+		TradeId:       t.Id,
+		OrderNumber:   t.OrderId,
+		ClientOrderId: t.ClientOrderId,
+		Pair:          t.Symbol,
+		IsSellOrder:   t.Side == "SELL",
+		Price:         t.Price,
+		Amount:        t.Quantity,
+		Total:         t.Amount,
+		FeeRate:       feeRate,
+		Date:          time.UnixMilli(t.CreateTime).UTC(),
+	}
+}
+
+func toLevels(items []decimal.Decimal) []orderbook.Level {
+	var levels = make([]orderbook.Level, 0, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		levels = append(levels, orderbook.Level{Price: items[i], Volume: items[i+1]})
+	}
+	return levels
+}
@@ -0,0 +1,497 @@
+package poloniexspot
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"trading_bot/config"
+	"trading_bot/internal/common"
+	"trading_bot/internal/common/cassette"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/secrets"
+	"trading_bot/internal/common/signer"
+	"trading_bot/internal/entity"
+	"trading_bot/mocks"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	testKey    = "test-key"
+	testSecret = "test-secret"
+)
+
+func testLogger(t *testing.T) *mocks.ILogger {
+	t.Helper()
+
+	var l = &mocks.ILogger{}
+	var args = []interface{}{mock.Anything}
+	for i := 0; i < 8; i++ {
+		l.On("Debug", args...).Return()
+		l.On("Info", args...).Return()
+		l.On("Warn", args...).Return()
+		l.On("Error", args...).Return()
+		args = append(args, mock.Anything)
+	}
+
+	return l
+}
+
+// stateLost is filled order whose create response is lost
+const stateLost = "LOST"
+
+// poloniexStandIn emulates the Poloniex spot REST API, orderStates are returned for the placed orders one by one
+func poloniexStandIn(t *testing.T, orderStates ...string) *httptest.Server {
+	t.Helper()
+
+	var orders = map[string]string{}
+	var clientOrderIds = map[string]string{}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/markets/BTC_USDC/orderBook", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"time":1659695011581,"scale":"0.01","asks":["101.00","1.0","102.00","4.0"],"bids":["99.00","2.0","98.00","3.0"],"ts":1659695011590}`)
+	})
+
+	mux.HandleFunc("/markets/BTC_USDC", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"symbol":"BTC_USDC","baseCurrencyName":"BTC","quoteCurrencyName":"USDC","displayName":"BTC/USDC","state":"NORMAL",`+
+			`"symbolTradeLimit":{"symbol":"BTC_USDC","priceScale":2,"quantityScale":6,"amountScale":2,"minQuantity":"0.000001","minAmount":"1","highestBid":"0","lowestAsk":"0"}}]`)
+	})
+
+	signed := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var timestamp = r.Header.Get("signTimestamp")
+			var params string
+			if r.Method == http.MethodGet {
+				var q = r.URL.Query()
+				q.Set("signTimestamp", timestamp)
+				params = q.Encode()
+			} else {
+				body, _ := io.ReadAll(r.Body)
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				params = "requestBody=" + string(body) + "&signTimestamp=" + timestamp
+			}
+
+			h := hmac.New(sha256.New, []byte(testSecret))
+			h.Write([]byte(r.Method + "\n" + r.URL.EscapedPath() + "\n" + params))
+			if r.Header.Get("key") != testKey || base64.StdEncoding.EncodeToString(h.Sum(nil)) != r.Header.Get("signature") {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"code":401,"message":"Unauthorized"}`)
+				return
+			}
+
+			handler(w, r)
+		}
+	}
+
+	mux.HandleFunc("/accounts/balances", signed(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"accountId":"1","accountType":"SPOT","balances":[{"currencyId":"28","currency":"BTC","available":"1.5","hold":"0.5"},{"currencyId":"2","currency":"USDC","available":"1000","hold":"0"}]}]`)
+	}))
+
+	mux.HandleFunc("/orders", signed(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprintf(w, `[{"id":"21934611","clientOrderId":"h1-0","symbol":"%v","state":"PARTIALLY_FILLED","side":"SELL","type":"LIMIT",`+
+				`"price":"102.00","quantity":"0.5","filledQuantity":"0.2","createTime":1659695011581}]`, r.URL.Query().Get("symbol"))
+			return
+		}
+
+		var state = stateFilled
+		if len(orders) < len(orderStates) {
+			state = orderStates[len(orders)]
+		}
+		var id = fmt.Sprint(len(orders) + 1)
+		orders[id] = state
+
+		var params struct {
+			ClientOrderId string `json:"clientOrderId"`
+		}
+		json.NewDecoder(r.Body).Decode(&params)
+		if len(params.ClientOrderId) > 0 {
+			if _, found := clientOrderIds[params.ClientOrderId]; found {
+				fmt.Fprint(w, `{"code":21352,"message":"Duplicate client order id"}`)
+				return
+			}
+			clientOrderIds[params.ClientOrderId] = id
+		}
+
+		if state == stateLost {
+			orders[id] = stateFilled
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"%v","clientOrderId":"%v"}`, id, params.ClientOrderId)
+	}))
+
+	mux.HandleFunc("/orders/", signed(func(w http.ResponseWriter, r *http.Request) {
+		var id = strings.TrimPrefix(r.URL.Path, "/orders/")
+		if strings.HasPrefix(id, "cid:") {
+			var found bool
+			if id, found = clientOrderIds[strings.TrimPrefix(id, "cid:")]; !found {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"code":21711,"message":"Order not found"}`)
+				return
+			}
+		}
+		if strings.HasSuffix(id, "/trades") {
+			id = strings.TrimSuffix(id, "/trades")
+			fmt.Fprintf(w, `[{"id":"%v1","orderId":"%v","price":"100","quantity":"0.4","amount":"40","feeCurrency":"BTC","feeAmount":"0.001"},`+
+				`{"id":"%v2","orderId":"%v","price":"101","quantity":"0.6","amount":"60.6","feeCurrency":"BTC","feeAmount":"0.0015"}]`, id, id, id, id)
+			return
+		}
+		var filled = "0"
+		if orders[id] == stateFilled {
+			filled = "1"
+		}
+		fmt.Fprintf(w, `{"id":"%v","state":"%v","filledQuantity":"%v"}`, id, orders[id], filled)
+	}))
+
+	mux.HandleFunc("/trades", signed(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"id":"62561","symbol":"%v","orderId":"21934612","clientOrderId":"h2-1","side":"BUY","price":"100","quantity":"0.5",`+
+			`"amount":"50","feeCurrency":"BTC","feeAmount":"0.00125","createTime":1659695012581},`+
+			`{"id":"62560","symbol":"%v","orderId":"21934610","clientOrderId":"","side":"SELL","price":"101","quantity":"0.2",`+
+			`"amount":"20.2","feeCurrency":"USDC","feeAmount":"0.0505","createTime":1659695010581}]`, r.URL.Query().Get("symbols"), r.URL.Query().Get("symbols"))
+	}))
+
+	mux.HandleFunc("/feeinfo", signed(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"trxDiscount":false,"makerRate":"0.001400","takerRate":"0.002400","volume30D":"0.00"}`)
+	}))
+
+	mux.HandleFunc("/wallets/withdraw", signed(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"withdrawalRequestsId":33485231}`)
+	}))
+
+	mux.HandleFunc("/wallets/addresses", signed(func(w http.ResponseWriter, r *http.Request) {
+		var currency = r.URL.Query().Get("currency")
+		fmt.Fprintf(w, `{"%v":"%v-address"}`, currency, currency)
+	}))
+
+	return httptest.NewServer(mux)
+}
+
+func poloniexSpotRequests(t *testing.T, server *httptest.Server) *PoloniexSpotRequests {
+	t.Helper()
+
+	var l = testLogger(t)
+
+	return New(l, helpermethods.New(l, nil), config.TradingSettings{
+		Url:    server.URL,
+		Key:    testKey,
+		Secret: testSecret,
+		Pair:   "BTC_USDC",
+	})
+}
+
+// newTestRequests replays testdata/<name>.json, RECORD_CASSETTES=1 records it against POLONIEX_SPOT_URL with POLONIEX_SPOT_KEY
+// and POLONIEX_SPOT_SECRET
+func newTestRequests(t *testing.T, name string) *PoloniexSpotRequests {
+	t.Helper()
+
+	var l = testLogger(t)
+	var transport = cassette.Use(t, filepath.Join("testdata", name+".json"), func() common.IHelperMethods {
+		return helpermethods.New(l, nil)
+	})
+
+	return New(l, transport, config.TradingSettings{
+		Url:    cassette.Setting("POLONIEX_SPOT_URL", "https://api.poloniex.com"),
+		Key:    secrets.Secret(cassette.Setting("POLONIEX_SPOT_KEY", testKey)),
+		Secret: secrets.Secret(cassette.Setting("POLONIEX_SPOT_SECRET", testSecret)),
+	})
+}
+
+func TestGetTradingBalances_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t)
+	defer server.Close()
+
+	got, err := poloniexSpotRequests(t, server).GetTradingBalances(context.Background())
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %v balances, wanted 2", len(got))
+	}
+	if !got["BTC"].Balance.Equal(decimal.NewFromFloat(1.5)) || !got["BTC"].Reserved.Equal(decimal.NewFromFloat(0.5)) {
+		t.Errorf("got BTC balance %v/%v, wanted 1.5/0.5", got["BTC"].Balance, got["BTC"].Reserved)
+	}
+}
+
+func TestGetPublicTradingOrders_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t)
+	defer server.Close()
+
+	got, err := poloniexSpotRequests(t, server).GetPublicTradingOrders(context.Background(), "BTC_USDC",
+		decimal.NewFromInt(1000), decimal.NewFromInt(10), decimal.NewFromInt(1000), decimal.NewFromInt(1000), decimal.NewFromFloat(0.001))
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("got %v orders, wanted 4", len(got))
+	}
+	if !got[0].IsSellOrder || !got[0].Rate.Equal(decimal.NewFromInt(101)) || !got[0].Amount.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("got first order %v, wanted sell 1 at 101", got[0])
+	}
+}
+
+func TestBuy_KilledThenFilled_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t, stateCanceled)
+	defer server.Close()
+
+	got, err := poloniexSpotRequests(t, server).Buy(context.Background(), "BTC_USDC", decimal.NewFromInt(100), decimal.NewFromInt(101), decimal.NewFromFloat(0.5), "BTC,USDC", "")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if got.Status != entity.ExecutionStatusFilled || got.OrderNumber != "2" || len(got.Trades) != 2 {
+		t.Errorf("got report %v/%v with %v trades, wanted filled order 2 with 2 trades", got.Status, got.OrderNumber, len(got.Trades))
+	}
+	if !got.FilledAmount.Equal(decimal.NewFromInt(1)) || !got.AveragePrice.Equal(decimal.NewFromFloat(100.6)) {
+		t.Errorf("got filled %v at %v, wanted 1 at 100.6", got.FilledAmount, got.AveragePrice)
+	}
+	if !got.Fee.Equal(decimal.NewFromFloat(0.0025)) || got.FeeCurrency != "BTC" {
+		t.Errorf("got fee %v %v, wanted 0.0025 BTC", got.Fee, got.FeeCurrency)
+	}
+}
+
+func TestBuy_LostResponse_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t, stateLost)
+	defer server.Close()
+
+	// the order is found by the client order id, it isn't placed again
+	got, err := poloniexSpotRequests(t, server).Buy(context.Background(), "BTC_USDC", decimal.NewFromInt(100), decimal.NewFromInt(101), decimal.NewFromFloat(0.5), "BTC,USDC", "h1")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if got.Status != entity.ExecutionStatusFilled || got.OrderNumber != "1" {
+		t.Errorf("got report %v/%v, wanted filled order 1", got.Status, got.OrderNumber)
+	}
+}
+
+func TestSell_MaxPriceReached_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t, stateCanceled, stateCanceled, stateCanceled)
+	defer server.Close()
+
+	_, err := poloniexSpotRequests(t, server).Sell(context.Background(), "BTC_USDC", decimal.NewFromInt(100), decimal.NewFromFloat(99.85), decimal.NewFromFloat(0.05), "BTC,USDC", "")
+
+	if !errors.Is(err, common.ErrOrderNotFilled) {
+		t.Errorf("got error %v, wanted %v", err, common.ErrOrderNotFilled)
+	}
+}
+
+func TestGetMarketInfo_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t)
+	defer server.Close()
+
+	got, err := poloniexSpotRequests(t, server).GetMarketInfo(context.Background(), "BTC_USDC")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if !got.PriceStep.Equal(decimal.NewFromFloat(0.01)) || !got.AmountStep.Equal(decimal.NewFromFloat(0.000001)) {
+		t.Errorf("got tick %v and lot %v, wanted 0.01 and 0.000001", got.PriceStep, got.AmountStep)
+	}
+	if !got.MinAmount.Equal(decimal.NewFromFloat(0.000001)) || !got.MinNotional.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("got min amount %v and min notional %v, wanted 0.000001 and 1", got.MinAmount, got.MinNotional)
+	}
+}
+
+func TestGetFees_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t)
+	var requests = poloniexSpotRequests(t, server)
+
+	got, err := requests.GetFees(context.Background(), "BTC_USDC")
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if !got.MakerFee.Equal(decimal.NewFromFloat(0.0014)) || !got.TakerFee.Equal(decimal.NewFromFloat(0.0024)) {
+		t.Errorf("got maker %v, taker %v, wanted 0.0014, 0.0024", got.MakerFee, got.TakerFee)
+	}
+
+	// fees are cached, the trading system is not requested again
+	server.Close()
+	cached, err := requests.GetFees(context.Background(), "BTC_USDC")
+	if err != nil || cached != got {
+		t.Errorf("got %v (error %v), wanted cached %v", cached, err, got)
+	}
+}
+
+func TestGetOpenOrders_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t)
+	defer server.Close()
+
+	got, err := poloniexSpotRequests(t, server).GetOpenOrders(context.Background(), "BTC_USDC")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 1 || got[0].OrderNumber != "21934611" || got[0].ClientOrderId != "h1-0" || !got[0].IsSellOrder {
+		t.Fatalf("got %v, wanted sell order 21934611 h1-0", got)
+	}
+	if !got[0].StartingAmount.Equal(decimal.NewFromFloat(0.5)) || !got[0].Amount.Equal(decimal.NewFromFloat(0.3)) {
+		t.Errorf("got amount %v of %v, wanted 0.3 of 0.5", got[0].Amount, got[0].StartingAmount)
+	}
+}
+
+func TestGetTradeHistory_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t)
+	defer server.Close()
+
+	got, err := poloniexSpotRequests(t, server).GetTradeHistory(context.Background(), "BTC_USDC", time.Now().Add(-time.Hour), time.Now())
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 2 || got[0].OrderNumber != "21934612" || got[0].ClientOrderId != "h2-1" || got[0].IsSellOrder || !got[1].IsSellOrder {
+		t.Fatalf("got %v, wanted buy of order 21934612 h2-1 and sell", got)
+	}
+	// fee is taken from the received currency
+	if !got[0].FeeRate.Equal(decimal.NewFromFloat(0.0025)) || !got[1].FeeRate.Equal(decimal.NewFromFloat(0.0025)) {
+		t.Errorf("got fee rates %v and %v, wanted 0.0025", got[0].FeeRate, got[1].FeeRate)
+	}
+}
+
+func TestGetOrderTrades_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t)
+	defer server.Close()
+
+	got, err := poloniexSpotRequests(t, server).GetOrderTrades(context.Background(), "7")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 2 || got[0].OrderNumber != "7" || !got[0].Amount.Equal(decimal.NewFromFloat(0.4)) || !got[1].Amount.Equal(decimal.NewFromFloat(0.6)) {
+		t.Errorf("got %v, wanted trades 0.4 and 0.6 of order 7", got)
+	}
+}
+
+func TestWithdraw_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t)
+	defer server.Close()
+
+	got, err := poloniexSpotRequests(t, server).Withdraw(context.Background(), "address", decimal.NewFromFloat(0.1), "USDT", "USDTTRON")
+
+	if err != nil || got != "33485231" {
+		t.Errorf("got %v %v, wanted 33485231 nil", got, err)
+	}
+}
+
+func TestGetTransfers_Success(t *testing.T) {
+	t.Parallel()
+
+	var pr = newTestRequests(t, "wallet_activity")
+
+	got, err := pr.GetTransfers(context.Background(), "BTC", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC))
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %v transfers, wanted BTC deposit and 2 withdrawals without BTCST", len(got))
+	}
+	var deposit, pending, failed = got[0], got[1], got[2]
+	if !deposit.IsDeposit || deposit.Id != "7397520" || deposit.Status != entity.TransferStatusComplete || deposit.TxId != "9f2b1c" || deposit.Address != "bc1qtrading" {
+		t.Errorf("got deposit %+v, wanted complete 7397520 to bc1qtrading with tx 9f2b1c", deposit)
+	}
+	if pending.IsDeposit || pending.Id != "33485231" || pending.Status != entity.TransferStatusPending || !pending.Amount.Equal(decimal.NewFromFloat(0.5)) {
+		t.Errorf("got withdrawal %+v, wanted pending 33485231 of 0.5", pending)
+	}
+	if failed.Id != "33485232" || failed.Status != entity.TransferStatusFailed {
+		t.Errorf("got withdrawal %+v, wanted failed 33485232", failed)
+	}
+}
+
+func TestGetTransfers_WithdrawalNetwork_Success(t *testing.T) {
+	t.Parallel()
+
+	var pr = newTestRequests(t, "wallet_activity")
+	pr.withdrawalNetwork = "USDTTRON"
+
+	got, err := pr.GetTransfers(context.Background(), "USDT", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC))
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 1 || got[0].Id != "7397523" || !got[0].Amount.Equal(decimal.NewFromInt(250)) {
+		t.Errorf("got transfers %+v, wanted USDTTRON deposit 7397523", got)
+	}
+}
+
+func TestGetCryptoAddress_Success(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t)
+	defer server.Close()
+
+	got, err := poloniexSpotRequests(t, server).GetCryptoAddress(context.Background(), "USDT", "USDTTRON")
+	want := "USDTTRON-address"
+
+	if err != nil || got != want {
+		t.Errorf("got %v (error %v), wanted %v", got, err, want)
+	}
+}
+
+func TestGetCryptoAddress_InvalidResponse_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `["USDTTRON-address"]`)
+	}))
+	defer server.Close()
+
+	got, err := poloniexSpotRequests(t, server).GetCryptoAddress(context.Background(), "USDT", "USDTTRON")
+
+	if !errors.Is(err, common.ErrInvalidResponse) || got != "" {
+		t.Errorf("got %v (error %v), wanted empty address and %v", got, err, common.ErrInvalidResponse)
+	}
+}
+
+func TestGetTradingBalances_InvalidSignature_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var server = poloniexStandIn(t)
+	defer server.Close()
+
+	var pr = poloniexSpotRequests(t, server)
+	pr.signer = signer.Resolve("", signer.TypeHmacSha256Header, "wrong-secret")
+
+	_, err := pr.GetTradingBalances(context.Background())
+
+	if !errors.Is(err, common.ErrAuthFailure) {
+		t.Errorf("got error %v, wanted %v", err, common.ErrAuthFailure)
+	}
+}
@@ -0,0 +1,98 @@
+package tradingsystem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"trading_bot/config"
+	"trading_bot/internal/common"
+	"trading_bot/internal/common/requests/binance"
+	"trading_bot/internal/common/requests/poloniex"
+	"trading_bot/internal/common/requests/poloniexspot"
+	"trading_bot/internal/common/signer"
+	"trading_bot/pkg/logger"
+)
+
+// supported trading system types, poloniex_legacy is the alias of poloniex
+const (
+	TypePoloniex       = "poloniex"
+	TypePoloniexLegacy = "poloniex_legacy"
+	TypePoloniexSpot   = "poloniex_spot"
+	TypeBinance        = "binance"
+)
+
+// Constructor creates trading system requests for the given settings
+type Constructor func(l logger.ILogger, hm common.IHelperMethods, cs config.TradingSettings) common.ITradingSystemRequest
+
+var (
+	mu           sync.RWMutex
+	constructors = map[string]Constructor{
+		TypePoloniex: func(l logger.ILogger, hm common.IHelperMethods, cs config.TradingSettings) common.ITradingSystemRequest {
+			return poloniex.New(l, hm, cs)
+		},
+		TypePoloniexLegacy: func(l logger.ILogger, hm common.IHelperMethods, cs config.TradingSettings) common.ITradingSystemRequest {
+			return poloniex.New(l, hm, cs)
+		},
+		TypePoloniexSpot: func(l logger.ILogger, hm common.IHelperMethods, cs config.TradingSettings) common.ITradingSystemRequest {
+			return poloniexspot.New(l, hm, cs)
+		},
+		TypeBinance: func(l logger.ILogger, hm common.IHelperMethods, cs config.TradingSettings) common.ITradingSystemRequest {
+			return binance.New(l, hm, cs)
+		},
+	}
+)
+
+// Register adds (or replaces) a trading system constructor for the given type
+func Register(tradingSystemType string, constructor Constructor) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	constructors[normalizeType(tradingSystemType)] = constructor
+}
+
+// Types returns all registered trading system types
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var res = make([]string, 0, len(constructors))
+	for key := range constructors {
+		res = append(res, key)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// New resolves trading system requests by the Type of the given settings.
+// Empty type stays legacy Poloniex API for configs created before the Type field existed.
+func New(l logger.ILogger, hm common.IHelperMethods, cs config.TradingSettings) (common.ITradingSystemRequest, error) {
+	var tradingSystemType = normalizeType(cs.Type)
+	if len(tradingSystemType) == 0 {
+		tradingSystemType = TypePoloniex
+	}
+
+	mu.RLock()
+	constructor, found := constructors[tradingSystemType]
+	mu.RUnlock()
+
+	if !found {
+		return nil, fmt.Errorf("unknown trading system type %q, supported types: %v", cs.Type, strings.Join(Types(), ", "))
+	}
+
+	// streaming order book is the book_lv2 channel of the Poloniex spot WebSocket
+	if len(cs.WebSocketUrl) > 0 && tradingSystemType != TypePoloniexSpot {
+		return nil, fmt.Errorf("trading system %q : WebSocketUrl is supported by %q only", tradingSystemType, TypePoloniexSpot)
+	}
+
+	if err := signer.Validate(cs.Signer, cs.Secret.Reveal()); err != nil {
+		return nil, fmt.Errorf("trading system %q : %w", tradingSystemType, err)
+	}
+
+	return constructor(l, hm, cs), nil
+}
+
+func normalizeType(tradingSystemType string) string {
+	return strings.ToLower(strings.TrimSpace(tradingSystemType))
+}
@@ -0,0 +1,92 @@
+package tradingsystem
+
+import (
+	"testing"
+	"trading_bot/config"
+	"trading_bot/internal/common/requests/binance"
+	"trading_bot/internal/common/requests/poloniex"
+	"trading_bot/internal/common/requests/poloniexspot"
+	"trading_bot/internal/common/signer"
+	"trading_bot/mocks"
+)
+
+func TestNew_EmptyType_PoloniexLegacy(t *testing.T) {
+	t.Parallel()
+
+	got, err := New(&mocks.ILogger{}, &mocks.IHelperMethods{}, config.TradingSettings{})
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	if _, ok := got.(*poloniex.PoloniexRequests); !ok {
+		t.Errorf("got %T, wanted *poloniex.PoloniexRequests", got)
+	}
+}
+
+func TestNew_PoloniexType_PoloniexLegacy(t *testing.T) {
+	t.Parallel()
+
+	for _, tradingSystemType := range []string{TypePoloniex, TypePoloniexLegacy} {
+		got, err := New(&mocks.ILogger{}, &mocks.IHelperMethods{}, config.TradingSettings{Type: tradingSystemType})
+		if err != nil {
+			t.Fatalf("got error %v, wanted nil", err)
+		}
+
+		if _, ok := got.(*poloniex.PoloniexRequests); !ok {
+			t.Errorf("got %T for %v, wanted *poloniex.PoloniexRequests", got, tradingSystemType)
+		}
+	}
+}
+
+func TestNew_PoloniexSpotType_PoloniexSpot(t *testing.T) {
+	t.Parallel()
+
+	got, err := New(&mocks.ILogger{}, &mocks.IHelperMethods{}, config.TradingSettings{Type: TypePoloniexSpot})
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	if _, ok := got.(*poloniexspot.PoloniexSpotRequests); !ok {
+		t.Errorf("got %T, wanted *poloniexspot.PoloniexSpotRequests", got)
+	}
+}
+
+func TestNew_BinanceType_Binance(t *testing.T) {
+	t.Parallel()
+
+	got, err := New(&mocks.ILogger{}, &mocks.IHelperMethods{}, config.TradingSettings{Type: " Binance "})
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	if _, ok := got.(*binance.BinanceRequests); !ok {
+		t.Errorf("got %T, wanted *binance.BinanceRequests", got)
+	}
+}
+
+func TestNew_UnknownType_Error(t *testing.T) {
+	t.Parallel()
+
+	got, err := New(&mocks.ILogger{}, &mocks.IHelperMethods{}, config.TradingSettings{Type: "kraken"})
+	if err == nil {
+		t.Errorf("got %T, wanted error", got)
+	}
+}
+
+func TestNew_InvalidSigner_Error(t *testing.T) {
+	t.Parallel()
+
+	got, err := New(&mocks.ILogger{}, &mocks.IHelperMethods{}, config.TradingSettings{Type: TypeBinance, Signer: signer.TypeEd25519, Secret: "not a key"})
+	if err == nil {
+		t.Errorf("got %T, wanted error", got)
+	}
+}
+
+func TestNew_WebSocketUrlNotPoloniexSpot_Error(t *testing.T) {
+	t.Parallel()
+
+	got, err := New(&mocks.ILogger{}, &mocks.IHelperMethods{}, config.TradingSettings{Type: TypeBinance, WebSocketUrl: "wss://ws.poloniex.com/ws/public"})
+	if err == nil {
+		t.Errorf("got %T, wanted error", got)
+	}
+}
@@ -0,0 +1,160 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+	"trading_bot/config"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrExhausted is returned when attempts or elapsed time of the policy are over
+var ErrExhausted = errors.New("retry attempts exhausted")
+
+const (
+	defaultMaxAttempts       = 20
+	defaultMaxElapsed        = 2 * time.Minute
+	defaultInitialBackoff    = 500 * time.Millisecond
+	defaultMaxBackoff        = 10 * time.Second
+	defaultBackoffMultiplier = 2.0
+	defaultJitter            = 0.2
+)
+
+// default price step of the fill-or-kill orders: 0.1%
+var defaultPriceStep = decimal.NewFromFloat(0.001)
+
+// Policy limits retries of the trading system orders
+type Policy struct {
+	MaxAttempts       int
+	MaxElapsed        time.Duration
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	// Jitter is a part of the backoff randomly added or removed, 0.2 means +-20%
+	Jitter    float64
+	PriceStep decimal.Decimal
+}
+
+// NewPolicy creates policy from settings, zero values are replaced by defaults
+func NewPolicy(rs config.RetrySettings) Policy {
+	var p = Policy{
+		MaxAttempts:       rs.MaxAttempts,
+		MaxElapsed:        time.Duration(rs.MaxElapsedSeconds) * time.Second,
+		InitialBackoff:    time.Duration(rs.InitialBackoffMilliseconds) * time.Millisecond,
+		MaxBackoff:        time.Duration(rs.MaxBackoffMilliseconds) * time.Millisecond,
+		BackoffMultiplier: rs.BackoffMultiplier,
+		Jitter:            rs.Jitter,
+		PriceStep:         rs.PriceStep,
+	}
+
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultMaxAttempts
+	}
+	if p.MaxElapsed <= 0 {
+		p.MaxElapsed = defaultMaxElapsed
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = defaultInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaultMaxBackoff
+	}
+	if p.BackoffMultiplier < 1 {
+		p.BackoffMultiplier = defaultBackoffMultiplier
+	}
+	if p.Jitter <= 0 || p.Jitter >= 1 {
+		p.Jitter = defaultJitter
+	}
+	if !p.PriceStep.IsPositive() {
+		p.PriceStep = defaultPriceStep
+	}
+
+	return p
+}
+
+// StepUp rises price of the buy order by the price step
+func (p Policy) StepUp(price decimal.Decimal) decimal.Decimal {
+	return price.Mul(decimal.NewFromInt(1).Add(p.PriceStep)).RoundDown(8)
+}
+
+// StepDown lowers price of the sell order by the price step
+func (p Policy) StepDown(price decimal.Decimal) decimal.Decimal {
+	return price.Mul(decimal.NewFromInt(1).Sub(p.PriceStep)).RoundDown(8)
+}
+
+// Backoff returns delay before the attempt (1 based) with jitter applied
+func (p Policy) Backoff(attempt int) time.Duration {
+	var delay = float64(p.InitialBackoff)
+	for i := 1; i < attempt && delay < float64(p.MaxBackoff); i++ {
+		delay *= p.BackoffMultiplier
+	}
+	if delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	delay += delay * p.Jitter * (2*rand.Float64() - 1)
+
+	return time.Duration(delay)
+}
+
+// Start begins counting of attempts for the single operation
+func (p Policy) Start(ctx context.Context) *Attempts {
+	return &Attempts{
+		policy:  p,
+		ctx:     ctx,
+		started: time.Now(),
+	}
+}
+
+// Attempts tracks attempts of the single operation
+type Attempts struct {
+	policy  Policy
+	ctx     context.Context
+	started time.Time
+	count   int
+}
+
+// Next counts failed attempt and checks that the next one is allowed
+func (a *Attempts) Next(lastErr error) error {
+	a.count++
+
+	if err := a.ctx.Err(); err != nil {
+		return fmt.Errorf("%w : %w", err, lastErr)
+	}
+	if a.count >= a.policy.MaxAttempts || time.Since(a.started) >= a.policy.MaxElapsed {
+		return fmt.Errorf("%w after %v attempts in %v : %w", ErrExhausted, a.count, time.Since(a.started).Round(time.Millisecond), lastErr)
+	}
+
+	return nil
+}
+
+// Wait counts failed attempt and sleeps the backoff delay, it returns earlier when context is cancelled
+func (a *Attempts) Wait(lastErr error) error {
+	if err := a.Next(lastErr); err != nil {
+		return err
+	}
+
+	var delay = a.policy.Backoff(a.count)
+	// don't sleep beyond the elapsed time limit
+	if left := a.policy.MaxElapsed - time.Since(a.started); delay > left {
+		delay = left
+	}
+
+	var timer = time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-a.ctx.Done():
+		return fmt.Errorf("%w : %w", a.ctx.Err(), lastErr)
+	}
+}
+
+// Count returns number of failed attempts
+func (a *Attempts) Count() int {
+	return a.count
+}
@@ -0,0 +1,123 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"trading_bot/config"
+
+	"github.com/shopspring/decimal"
+)
+
+var errTest = errors.New("test error")
+
+func TestNewPolicy_Defaults_Success(t *testing.T) {
+	t.Parallel()
+
+	got := NewPolicy(config.RetrySettings{})
+
+	if got.MaxAttempts != defaultMaxAttempts || got.MaxElapsed != defaultMaxElapsed || !got.PriceStep.Equal(defaultPriceStep) {
+		t.Errorf("got %v, wanted defaults", got)
+	}
+}
+
+func TestBackoff_ExponentialWithCap_Success(t *testing.T) {
+	t.Parallel()
+
+	var p = NewPolicy(config.RetrySettings{
+		InitialBackoffMilliseconds: 100,
+		MaxBackoffMilliseconds:     1000,
+		BackoffMultiplier:          2,
+		Jitter:                     0.1,
+	})
+
+	var tests = []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{10, 1000 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		got := p.Backoff(tt.attempt)
+		if got < tt.want*9/10 || got > tt.want*11/10 {
+			t.Errorf("attempt %v : got %v, wanted %v +-10%%", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestStep_Success(t *testing.T) {
+	t.Parallel()
+
+	var p = NewPolicy(config.RetrySettings{PriceStep: decimal.NewFromFloat(0.01)})
+
+	if got := p.StepUp(decimal.NewFromInt(100)); !got.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("got %v, wanted 101", got)
+	}
+	if got := p.StepDown(decimal.NewFromInt(100)); !got.Equal(decimal.NewFromInt(99)) {
+		t.Errorf("got %v, wanted 99", got)
+	}
+}
+
+func TestAttempts_MaxAttempts_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var attempts = NewPolicy(config.RetrySettings{MaxAttempts: 3}).Start(context.Background())
+
+	for i := 0; i < 2; i++ {
+		if err := attempts.Next(errTest); err != nil {
+			t.Fatalf("attempt %v : got error %v, wanted nil", i+1, err)
+		}
+	}
+
+	err := attempts.Next(errTest)
+	if !errors.Is(err, ErrExhausted) || !errors.Is(err, errTest) {
+		t.Errorf("got error %v, wanted %v wrapping %v", err, ErrExhausted, errTest)
+	}
+}
+
+func TestAttempts_MaxElapsed_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var attempts = NewPolicy(config.RetrySettings{
+		MaxElapsedSeconds:          1,
+		InitialBackoffMilliseconds: 400,
+		BackoffMultiplier:          1,
+	}).Start(context.Background())
+
+	var started = time.Now()
+	var err error
+	for err == nil {
+		err = attempts.Wait(errTest)
+	}
+
+	if !errors.Is(err, ErrExhausted) {
+		t.Errorf("got error %v, wanted %v", err, ErrExhausted)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("got elapsed %v, wanted about 1s", elapsed)
+	}
+}
+
+func TestAttempts_WaitCancelled_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var attempts = NewPolicy(config.RetrySettings{InitialBackoffMilliseconds: 60000, MaxBackoffMilliseconds: 60000}).Start(ctx)
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	var started = time.Now()
+	err := attempts.Wait(errTest)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, wanted %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("got elapsed %v, wanted return on cancel", elapsed)
+	}
+}
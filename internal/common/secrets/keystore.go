@@ -0,0 +1,250 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	keystoreVersion = 1
+	keystoreKdf     = "pbkdf2-sha256"
+	// OWASP recommendation for PBKDF2-HMAC-SHA256
+	defaultIterations = 600000
+	saltSize          = 16
+	keySize           = 32
+)
+
+// ErrWrongPassphrase is returned when the keystore can't be decrypted with the passphrase
+var ErrWrongPassphrase = errors.New("wrong keystore passphrase or damaged keystore")
+
+// keystoreFile is the encrypted file, the secrets map is sealed by AES-256-GCM with the key derived from the passphrase
+type keystoreFile struct {
+	Version    int    `json:"version"`
+	Kdf        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// Keystore is the encrypted local file of the secrets. The secrets are decrypted on open and kept in memory,
+// every Save encrypts them with the new salt and nonce.
+type Keystore struct {
+	mu         sync.RWMutex
+	path       string
+	passphrase string
+	iterations int
+	secrets    map[string]Secret
+}
+
+// CreateKeystore creates the empty keystore, the file is written by Save
+func CreateKeystore(path string, passphrase string) (*Keystore, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("keystore : empty passphrase")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("keystore %v : already exists", path)
+	}
+
+	return &Keystore{
+		path:       path,
+		passphrase: passphrase,
+		iterations: defaultIterations,
+		secrets:    make(map[string]Secret),
+	}, nil
+}
+
+// OpenKeystore reads and decrypts the keystore file
+func OpenKeystore(path string, passphrase string) (*Keystore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("keystore : %w", err)
+	}
+
+	var file keystoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("keystore %v : %w", path, err)
+	}
+	if file.Version != keystoreVersion || file.Kdf != keystoreKdf {
+		return nil, fmt.Errorf("keystore %v : unsupported version %v %v", path, file.Version, file.Kdf)
+	}
+
+	gcm, err := newGcm(passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, []byte(keystoreKdf))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	var ks = &Keystore{
+		path:       path,
+		passphrase: passphrase,
+		iterations: file.Iterations,
+		secrets:    make(map[string]Secret),
+	}
+	if err := json.Unmarshal(plain, &ks.secrets); err != nil {
+		return nil, fmt.Errorf("keystore %v : %w", path, err)
+	}
+
+	return ks, nil
+}
+
+func (ks *Keystore) Get(name string) (Secret, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	var secret, found = ks.secrets[name]
+	if !found {
+		return "", fmt.Errorf("secret %q : %w", name, ErrNotFound)
+	}
+
+	return secret, nil
+}
+
+// Set adds or replaces the secret, it's written by Save
+func (ks *Keystore) Set(name string, secret Secret) error {
+	if !validName(name) {
+		return fmt.Errorf("secret %q : invalid name", name)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.secrets[name] = secret
+	return nil
+}
+
+// Remove deletes the secret, it's written by Save
+func (ks *Keystore) Remove(name string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, found := ks.secrets[name]; !found {
+		return fmt.Errorf("secret %q : %w", name, ErrNotFound)
+	}
+	delete(ks.secrets, name)
+
+	return nil
+}
+
+// Has reports whether the secret exists
+func (ks *Keystore) Has(name string) bool {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	_, found := ks.secrets[name]
+	return found
+}
+
+// Names returns sorted names of the secrets
+func (ks *Keystore) Names() []string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	var res = make([]string, 0, len(ks.secrets))
+	for name := range ks.secrets {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// SetPassphrase changes the passphrase, the keystore is encrypted with it on Save
+func (ks *Keystore) SetPassphrase(passphrase string) error {
+	if len(passphrase) == 0 {
+		return errors.New("keystore : empty passphrase")
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.passphrase = passphrase
+	return nil
+}
+
+// Save encrypts the secrets and replaces the file, the file is readable only by the owner
+func (ks *Keystore) Save() error {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	var plain = make(map[string]string, len(ks.secrets))
+	for name, secret := range ks.secrets {
+		plain[name] = secret.Reveal()
+	}
+	data, err := json.Marshal(plain)
+	if err != nil {
+		return fmt.Errorf("keystore : %w", err)
+	}
+
+	var file = keystoreFile{
+		Version:    keystoreVersion,
+		Kdf:        keystoreKdf,
+		Iterations: ks.iterations,
+		Salt:       make([]byte, saltSize),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return fmt.Errorf("keystore : %w", err)
+	}
+
+	gcm, err := newGcm(ks.passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return fmt.Errorf("keystore : %w", err)
+	}
+	file.Data = gcm.Seal(nil, file.Nonce, data, []byte(keystoreKdf))
+
+	encoded, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("keystore : %w", err)
+	}
+
+	return writeFile(ks.path, append(encoded, '\n'))
+}
+
+func newGcm(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("keystore : %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("keystore : %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// writeFile writes the temporary file and renames it, so the keystore is never left half written
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("keystore : %w", err)
+	}
+
+	var tmp = path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("keystore : %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("keystore : %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// PassphraseEnv is the environment variable of the keystore passphrase
+const PassphraseEnv = "SECRETS_PASSPHRASE"
+
+// Input reads passphrases and secret values line by line, the terminal doesn't echo them
+type Input struct {
+	reader   *bufio.Reader
+	terminal *os.File
+}
+
+// NewInput creates input of stdin, the lines are read without echo when stdin is a terminal
+func NewInput(stdin io.Reader) *Input {
+	var in = &Input{reader: bufio.NewReader(stdin)}
+	if file, ok := stdin.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		in.terminal = file
+	}
+	return in
+}
+
+// ReadLine reads the line without the line break, the prompt line is ended on out
+func (in *Input) ReadLine(out io.Writer) (string, error) {
+	var line string
+	var err error
+	if in.terminal != nil {
+		var value []byte
+		value, err = term.ReadPassword(int(in.terminal.Fd()))
+		line = string(value)
+	} else {
+		line, err = ReadLine(in.reader)
+	}
+
+	// the line is not echoed, neither its line break
+	if out != nil {
+		fmt.Fprintln(out)
+	}
+	if err != nil {
+		return "", err
+	}
+	return line, nil
+}
+
+// ReadPassphrase returns the passphrase from SECRETS_PASSPHRASE, otherwise the line read from in.
+// The prompt is written to out when the passphrase is read from in.
+func ReadPassphrase(in *Input, out io.Writer, prompt string) (string, error) {
+	if passphrase, found := os.LookupEnv(PassphraseEnv); found {
+		return passphrase, nil
+	}
+	if in == nil {
+		return "", fmt.Errorf("keystore passphrase is not set, set %v", PassphraseEnv)
+	}
+
+	if out != nil {
+		fmt.Fprint(out, prompt)
+	}
+	line, err := in.ReadLine(out)
+	if err != nil {
+		return "", fmt.Errorf("keystore passphrase : %w", err)
+	}
+	if len(line) == 0 {
+		return "", errors.New("keystore passphrase is empty")
+	}
+
+	return line, nil
+}
+
+// ReadLine reads the line without the line break, the last line may have no line break
+func ReadLine(in *bufio.Reader) (string, error) {
+	line, err := in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// NewProvider creates provider of the configured sources, the file mounts are asked before the keystore.
+// Nil provider is returned when no source is configured.
+func NewProvider(keystorePath string, dir string, passphrase func() (string, error)) (Provider, error) {
+	var chain Chain
+	if len(dir) > 0 {
+		chain = append(chain, NewDir(dir))
+	}
+	if len(keystorePath) > 0 {
+		phrase, err := passphrase()
+		if err != nil {
+			return nil, err
+		}
+		ks, err := OpenKeystore(keystorePath, phrase)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, ks)
+	}
+
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrNotFound is returned when no provider has the secret
+var ErrNotFound = errors.New("secret not found")
+
+// Provider returns secrets by name
+type Provider interface {
+	Get(name string) (Secret, error)
+}
+
+// Dir reads file-per-secret mounts (Docker and Kubernetes secrets), the file name is the secret name
+type Dir struct {
+	path string
+}
+
+func NewDir(path string) *Dir {
+	return &Dir{path: path}
+}
+
+func (d *Dir) Get(name string) (Secret, error) {
+	if !validName(name) {
+		return "", fmt.Errorf("secret %q : invalid name", name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(d.path, name))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("secret %q : %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("secret %q : %w", name, err)
+	}
+
+	// mounted files usually end with the new line
+	return Secret(strings.TrimRight(string(data), "\r\n")), nil
+}
+
+// Chain asks the providers in order, the first found secret is returned
+type Chain []Provider
+
+func (c Chain) Get(name string) (Secret, error) {
+	for _, provider := range c {
+		var secret, err = provider.Get(name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return secret, err
+	}
+
+	return "", fmt.Errorf("secret %q : %w", name, ErrNotFound)
+}
+
+var (
+	referencePattern = regexp.MustCompile(`\$\{secret:([^}]*)\}`)
+	namePattern      = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// Resolve replaces ${secret:name} references of the value with the secrets of the provider,
+// the value without references is returned as is
+func Resolve(value Secret, p Provider) (Secret, error) {
+	var raw = value.Reveal()
+	if !strings.Contains(raw, "${secret:") {
+		return value, nil
+	}
+
+	var resolveErr error
+	var res = referencePattern.ReplaceAllStringFunc(raw, func(reference string) string {
+		var name = referencePattern.FindStringSubmatch(reference)[1]
+		if p == nil {
+			resolveErr = errors.Join(resolveErr, fmt.Errorf("secret %q : %w, secrets provider is not configured", name, ErrNotFound))
+			return ""
+		}
+		var secret, err = p.Get(name)
+		if err != nil {
+			resolveErr = errors.Join(resolveErr, err)
+			return ""
+		}
+		return secret.Reveal()
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+
+	return Secret(res), nil
+}
+
+func validName(name string) bool {
+	return namePattern.MatchString(name) && name != "." && name != ".."
+}
@@ -0,0 +1,48 @@
+// Package secrets keeps API keys out of the config file and the logs.
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Redacted is printed instead of the secret value
+const Redacted = "REDACTED"
+
+// Secret is the value which is never printed: fmt, logs, panics and JSON get Redacted instead.
+// The value is taken only by Reveal.
+type Secret string
+
+// Reveal returns the secret value, it must be passed only to the signing code
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// IsEmpty reports whether the secret is not set
+func (s Secret) IsEmpty() bool {
+	return len(s) == 0
+}
+
+func (s Secret) String() string {
+	if s.IsEmpty() {
+		return ""
+	}
+	return Redacted
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("secrets.Secret(%q)", s.String())
+}
+
+// Format prints Redacted for every verb, so %x or %q don't leak the value
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		fmt.Fprint(f, s.GoString())
+		return
+	}
+	fmt.Fprint(f, s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
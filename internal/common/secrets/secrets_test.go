@@ -0,0 +1,139 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testValue = "poloniex-secret-value"
+
+// fastKeystore creates keystore with few iterations, the default count makes tests slow
+func fastKeystore(t *testing.T, path string, passphrase string) *Keystore {
+	t.Helper()
+
+	ks, err := CreateKeystore(path, passphrase)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	ks.iterations = 1000
+
+	return ks
+}
+
+func TestKeystore_SaveOpen_Success(t *testing.T) {
+	t.Parallel()
+
+	var path = filepath.Join(t.TempDir(), "config", "secrets.keystore")
+	var ks = fastKeystore(t, path, "passphrase")
+	ks.Set("poloniex_btc", testValue)
+	if err := ks.Save(); err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), testValue) || strings.Contains(string(data), "poloniex_btc") {
+		t.Errorf("got plain secret in the keystore file %s", data)
+	}
+
+	opened, err := OpenKeystore(path, "passphrase")
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	got, err := opened.Get("poloniex_btc")
+
+	if err != nil || got.Reveal() != testValue {
+		t.Errorf("got %v %v, wanted the saved value", got.Reveal(), err)
+	}
+}
+
+func TestKeystore_WrongPassphrase_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var path = filepath.Join(t.TempDir(), "secrets.keystore")
+	var ks = fastKeystore(t, path, "passphrase")
+	ks.Set("poloniex_btc", testValue)
+	ks.Save()
+
+	_, err := OpenKeystore(path, "wrong")
+
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("got error %v, wanted %v", err, ErrWrongPassphrase)
+	}
+}
+
+func TestResolve_Success(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	os.WriteFile(filepath.Join(dir, "jetcrypto_key"), []byte("mounted-key\n"), 0600)
+
+	var ks = fastKeystore(t, filepath.Join(t.TempDir(), "secrets.keystore"), "passphrase")
+	ks.Set("poloniex_btc", testValue)
+	ks.Set("jetcrypto_key", "keystore-key")
+
+	// mounts are asked first
+	var provider = Chain{NewDir(dir), ks}
+
+	var tests = []struct {
+		value Secret
+		want  string
+	}{
+		{value: "${secret:poloniex_btc}", want: testValue},
+		{value: "${secret:jetcrypto_key}", want: "mounted-key"},
+		{value: "prefix-${secret:poloniex_btc}", want: "prefix-" + testValue},
+		{value: "plain-value", want: "plain-value"},
+	}
+
+	for _, tt := range tests {
+		got, err := Resolve(tt.value, provider)
+
+		if err != nil || got.Reveal() != tt.want {
+			t.Errorf("got %v %v, wanted %v", got.Reveal(), err, tt.want)
+		}
+	}
+}
+
+func TestResolve_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var provider = Chain{NewDir(t.TempDir())}
+
+	for _, p := range []Provider{provider, nil} {
+		_, err := Resolve("${secret:missing}", p)
+
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("got error %v, wanted %v", err, ErrNotFound)
+		}
+	}
+	if _, err := NewDir(t.TempDir()).Get("../passwd"); err == nil {
+		t.Errorf("got nil error, wanted invalid name error")
+	}
+}
+
+func TestSecret_Redacted_Success(t *testing.T) {
+	t.Parallel()
+
+	var value = struct {
+		Key    Secret
+		Secret Secret
+	}{Key: "api-key", Secret: testValue}
+
+	data, _ := json.Marshal(value)
+	var printed = []string{
+		fmt.Sprint(value.Secret),
+		fmt.Sprintf("%v %+v %#v %s %q %x", value, value, value, value.Secret, value.Secret, value.Secret),
+		fmt.Errorf("request failed : %v", value.Secret).Error(),
+		string(data),
+	}
+
+	for _, got := range printed {
+		if strings.Contains(got, testValue) || strings.Contains(got, "api-key") {
+			t.Errorf("got %v, wanted redacted secrets", got)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Package signer signs private requests of the venues.
+package signer
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signer types selected by the Signer setting of the venue
+const (
+	// TypeHmacSha512Query signs the form body (the query when the body is empty), hex signature in the Sign header
+	TypeHmacSha512Query = "hmac-sha512-query"
+	// TypeHmacSha512Url signs the full url, hex signature in the Sign header
+	TypeHmacSha512Url = "hmac-sha512-url"
+	// TypeHmacSha256Header signs "METHOD\n/path\nparams" with signTimestamp, base64 signature in the signature header
+	TypeHmacSha256Header = "hmac-sha256-header"
+	// TypeHmacSha256Query appends hex signature of the query as the last signature parameter
+	TypeHmacSha256Query = "hmac-sha256-query"
+	// TypeEd25519 appends base64 Ed25519 signature of the query as the last signature parameter
+	TypeEd25519 = "ed25519"
+)
+
+// ErrUnknownType is returned for the signer type which is not supported
+var ErrUnknownType = errors.New("unknown signer type")
+
+// Request is the private request being signed. Url has the final query, signers add headers or the signature parameter.
+type Request struct {
+	Method  string
+	Url     *url.URL
+	Body    []byte
+	Headers map[string]string
+}
+
+// Signer signs the request with the secret of the venue key
+type Signer interface {
+	Sign(req *Request) error
+}
+
+// New creates signer of the type, the secret is the API secret (PEM or base64/hex seed of the private key for Ed25519)
+func New(signerType string, secret string) (Signer, error) {
+	switch normalizeType(signerType) {
+	case TypeHmacSha512Query:
+		return &hmacSha512{secret: []byte(secret)}, nil
+	case TypeHmacSha512Url:
+		return &hmacSha512{secret: []byte(secret), signUrl: true}, nil
+	case TypeHmacSha256Header:
+		return &hmacSha256Header{secret: []byte(secret), now: time.Now}, nil
+	case TypeHmacSha256Query:
+		return &hmacSha256Query{secret: []byte(secret)}, nil
+	case TypeEd25519:
+		var key, err = parseEd25519Key(secret)
+		if err != nil {
+			return nil, err
+		}
+		return &ed25519Query{key: key}, nil
+	}
+
+	return nil, fmt.Errorf("%w %q, supported types: %v", ErrUnknownType, signerType, strings.Join(Types(), ", "))
+}
+
+// Resolve creates signer of the configured type, defaultType is used when it's empty.
+// Invalid settings are reported by every Sign call, they are expected to be checked with Validate on start.
+func Resolve(signerType string, defaultType string, secret string) Signer {
+	if len(strings.TrimSpace(signerType)) == 0 {
+		signerType = defaultType
+	}
+
+	var s, err = New(signerType, secret)
+	if err != nil {
+		return failed{err: err}
+	}
+
+	return s
+}
+
+// Validate checks the configured signer type and secret, empty type means the venue default
+func Validate(signerType string, secret string) error {
+	if len(strings.TrimSpace(signerType)) == 0 {
+		return nil
+	}
+
+	var _, err = New(signerType, secret)
+	return err
+}
+
+// Types returns all supported signer types
+func Types() []string {
+	return []string{TypeEd25519, TypeHmacSha256Header, TypeHmacSha256Query, TypeHmacSha512Query, TypeHmacSha512Url}
+}
+
+func normalizeType(signerType string) string {
+	return strings.ToLower(strings.TrimSpace(signerType))
+}
+
+type hmacSha512 struct {
+	secret  []byte
+	signUrl bool
+}
+
+func (s *hmacSha512) Sign(req *Request) error {
+	var payload = req.Body
+	switch {
+	case s.signUrl:
+		payload = []byte(req.Url.String())
+	case len(payload) == 0:
+		payload = []byte(req.Url.RawQuery)
+	}
+
+	var h = hmac.New(sha512.New, s.secret)
+	h.Write(payload)
+	setHeader(req, "Sign", hex.EncodeToString(h.Sum(nil)))
+
+	return nil
+}
+
+type hmacSha256Header struct {
+	secret []byte
+	now    func() time.Time
+}
+
+func (s *hmacSha256Header) Sign(req *Request) error {
+	var timestamp = strconv.FormatInt(s.now().UnixMilli(), 10)
+
+	// params are sorted by key and contain signTimestamp, the body is signed as requestBody
+	var params string
+	if len(req.Body) > 0 {
+		params = "requestBody=" + string(req.Body) + "&signTimestamp=" + timestamp
+	} else {
+		var query = req.Url.Query()
+		query.Set("signTimestamp", timestamp)
+		params = query.Encode()
+	}
+
+	var payload = strings.ToUpper(req.Method) + "\n" + req.Url.EscapedPath() + "\n" + params
+	var h = hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+
+	setHeader(req, "signTimestamp", timestamp)
+	setHeader(req, "signature", base64.StdEncoding.EncodeToString(h.Sum(nil)))
+
+	return nil
+}
+
+type hmacSha256Query struct {
+	secret []byte
+}
+
+func (s *hmacSha256Query) Sign(req *Request) error {
+	var h = hmac.New(sha256.New, s.secret)
+	h.Write([]byte(req.Url.RawQuery))
+
+	// signature must be the last parameter of the signed query
+	req.Url.RawQuery += "&signature=" + hex.EncodeToString(h.Sum(nil))
+
+	return nil
+}
+
+type ed25519Query struct {
+	key ed25519.PrivateKey
+}
+
+func (s *ed25519Query) Sign(req *Request) error {
+	var signature = ed25519.Sign(s.key, []byte(req.Url.RawQuery))
+
+	// signature must be the last parameter of the signed query
+	req.Url.RawQuery += "&signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(signature))
+
+	return nil
+}
+
+// parseEd25519Key reads PKCS#8 PEM private key, or hex or base64 of the 32 bytes seed or the 64 bytes private key
+func parseEd25519Key(secret string) (ed25519.PrivateKey, error) {
+	secret = strings.TrimSpace(secret)
+
+	if block, _ := pem.Decode([]byte(secret)); block != nil {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("ed25519 key : %w", err)
+		}
+		key, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("ed25519 key : %T is not Ed25519 key", parsed)
+		}
+		return key, nil
+	}
+
+	var raw, err = hex.DecodeString(secret)
+	if err != nil {
+		raw, err = base64.StdEncoding.DecodeString(secret)
+	}
+	if err != nil {
+		return nil, errors.New("ed25519 key : secret is not PEM, hex or base64")
+	}
+
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+
+	return nil, fmt.Errorf("ed25519 key : got %v bytes, wanted %v or %v", len(raw), ed25519.SeedSize, ed25519.PrivateKeySize)
+}
+
+type failed struct {
+	err error
+}
+
+func (f failed) Sign(*Request) error {
+	return f.err
+}
+
+func setHeader(req *Request, name string, value string) {
+	if req.Headers == nil {
+		req.Headers = make(map[string]string)
+	}
+	req.Headers[name] = value
+}
@@ -0,0 +1,97 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Ledger appends the states of the transfers to the JSON lines file, the last line of the transfer is its current state.
+// The ledger is shared by the balance workers, the transfers are told apart by the currency.
+type Ledger struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewLedger(path string) (*Ledger, error) {
+	if len(path) == 0 {
+		return nil, errors.New("transfer ledger path is empty")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	// check that file is writable on start instead of the first transfer
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Ledger{path: path}, file.Close()
+}
+
+// Append writes the state of the transfer as a single line
+func (l *Ledger) Append(transfer *Transfer) error {
+	data, err := json.Marshal(transfer)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Transfers reads the last states of the transfers of the currency in the order they were recorded
+func (l *Ledger) Transfers(currency string) ([]*Transfer, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var transfers = make([]*Transfer, 0)
+	var index = make(map[string]int)
+	var scanner = bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var transfer = &Transfer{}
+		if err := json.Unmarshal(scanner.Bytes(), transfer); err != nil {
+			return nil, err
+		}
+		if transfer.Currency != currency {
+			continue
+		}
+
+		if i, found := index[transfer.Id.String()]; found {
+			transfers[i] = transfer
+			continue
+		}
+		index[transfer.Id.String()] = len(transfers)
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers, scanner.Err()
+}
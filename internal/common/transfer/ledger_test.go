@@ -0,0 +1,47 @@
+package transfer
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestLedger_AppendTransfers_Success(t *testing.T) {
+	t.Parallel()
+
+	l, err := NewLedger(filepath.Join(t.TempDir(), "data", "transfers.jsonl"))
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	var first = &Transfer{Id: uuid.Must(uuid.NewV4()), Currency: "BTC", Direction: InternalToTradingSystem, Amount: decimal.NewFromFloat(0.3), State: StateSubmitted, Created: time.Now()}
+	var second = &Transfer{Id: uuid.Must(uuid.NewV4()), Currency: "BTC", Direction: TradingSystemToInternal, Amount: decimal.NewFromFloat(0.5), State: StateSubmitted, Created: time.Now()}
+	var other = &Transfer{Id: uuid.Must(uuid.NewV4()), Currency: "ETH", Direction: InternalToTradingSystem, Amount: decimal.NewFromInt(2), State: StateSubmitted, Created: time.Now()}
+	for _, transfer := range []*Transfer{first, second, other} {
+		if err := l.Append(transfer); err != nil {
+			t.Fatalf("got error %v, wanted nil", err)
+		}
+	}
+	first.State = StateCredited
+	l.Append(first)
+
+	got, err := l.Transfers("BTC")
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 2 || got[0].Id != first.Id || got[0].State != StateCredited || got[1].Id != second.Id || !got[1].Amount.Equal(second.Amount) {
+		t.Errorf("got %+v, wanted the last states of the BTC transfers in order", got)
+	}
+}
+
+func TestNewLedger_EmptyPath_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewLedger(""); err == nil {
+		t.Errorf("got nil, wanted error")
+	}
+}
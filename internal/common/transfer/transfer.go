@@ -0,0 +1,441 @@
+// Package transfer tracks the withdrawals of the balance worker between the internal system and the trading system:
+// the status of the withdrawal on the sending side and the matching deposit on the receiving side.
+package transfer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"trading_bot/internal/common"
+	"trading_bot/internal/entity"
+	"trading_bot/pkg/logger"
+
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Direction of the transfer
+const (
+	InternalToTradingSystem = "internal_to_trading_system"
+	TradingSystemToInternal = "trading_system_to_internal"
+)
+
+// State of the transfer
+const (
+	// StateSubmitted withdrawal is accepted by the sending side
+	StateSubmitted = "submitted"
+	// StateBroadcast withdrawal is completed by the sending side
+	StateBroadcast = "broadcast"
+	// StateConfirmed deposit is seen on the receiving side and is not credited yet
+	StateConfirmed = "confirmed"
+	// StateCredited deposit is credited on the receiving side
+	StateCredited = "credited"
+	// StateFailed withdrawal or deposit is rejected
+	StateFailed = "failed"
+	// StateStuck transfer is not settled in the timeout, it's still polled and counted in flight
+	StateStuck = "stuck"
+)
+
+const (
+	// transfers are stuck after the hour when the timeout is not set
+	defaultTimeout = 60 * time.Minute
+	// deposit may be lower than the withdrawal by the network fee
+	feeTolerance = 0.05
+	// clocks of the systems may differ
+	clockSkew = 10 * time.Minute
+)
+
+// Transfer is the withdrawal from one system to the address of the other one
+type Transfer struct {
+	Id        uuid.UUID       `json:"id"`
+	Currency  string          `json:"currency"`
+	Direction string          `json:"direction"`
+	Amount    decimal.Decimal `json:"amount"`
+	Address   string          `json:"address"`
+	// WithdrawalId is the id of the withdrawal on the sending side, DepositId is the id of the matched deposit
+	WithdrawalId string    `json:"withdrawalId"`
+	DepositId    string    `json:"depositId"`
+	TxId         string    `json:"txId"`
+	State        string    `json:"state"`
+	Created      time.Time `json:"created"`
+	Broadcast    time.Time `json:"broadcast"`
+	Updated      time.Time `json:"updated"`
+}
+
+// Pending reports whether the transfer is on the way and is not timed out
+func (t *Transfer) Pending() bool {
+	return t.State == StateSubmitted || t.State == StateBroadcast || t.State == StateConfirmed
+}
+
+// Settled reports whether the transfer is credited or failed
+func (t *Transfer) Settled() bool {
+	return t.State == StateCredited || t.State == StateFailed
+}
+
+// Tracker records the withdrawals and polls both systems until the withdrawals are settled
+type Tracker struct {
+	mu                    sync.Mutex
+	logger                logger.ILogger
+	internal              common.IInternalRequest
+	tradingSystem         common.ITradingSystemRequest
+	internalCurrency      string
+	tradingSystemCurrency string
+	timeout               time.Duration
+	ledger                *Ledger
+	transfers             []*Transfer
+	// deposits and withdrawals matched to the transfers by the key of the system item
+	claimed map[string]uuid.UUID
+}
+
+// New creates the tracker, defaultTimeout is used when timeout is not positive.
+// The ledger is optional, the transfers of the currency which are not settled are restored from it.
+func New(l logger.ILogger, internal common.IInternalRequest, tradingSystem common.ITradingSystemRequest, internalCurrency string, tradingSystemCurrency string, timeout time.Duration, ledger *Ledger) (*Tracker, error) {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	var t = &Tracker{
+		logger:                l,
+		internal:              internal,
+		tradingSystem:         tradingSystem,
+		internalCurrency:      internalCurrency,
+		tradingSystemCurrency: tradingSystemCurrency,
+		timeout:               timeout,
+		ledger:                ledger,
+		claimed:               make(map[string]uuid.UUID),
+	}
+	if ledger == nil {
+		return t, nil
+	}
+
+	var transfers, err = ledger.Transfers(internalCurrency)
+	if err != nil {
+		return nil, err
+	}
+	for _, transfer := range transfers {
+		if t.expired(transfer) {
+			continue
+		}
+		t.transfers = append(t.transfers, transfer)
+		t.claim(transfer)
+		if !transfer.Settled() {
+			l.Info("Transfer %v : %v %v to %v is restored as %v", transfer.Id, transfer.Direction, transfer.Amount, transfer.Address, transfer.State)
+		}
+	}
+
+	return t, nil
+}
+
+// Record adds the submitted withdrawal. Id of the internal withdrawal is the payment id checked by IsPaymentCompleted,
+// withdrawalId is the id of the trading system withdrawal, it may be empty.
+func (t *Tracker) Record(id uuid.UUID, direction string, amount decimal.Decimal, address string, withdrawalId string) Transfer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var now = time.Now()
+	var transfer = &Transfer{
+		Id:           id,
+		Currency:     t.internalCurrency,
+		Direction:    direction,
+		Amount:       amount,
+		Address:      address,
+		WithdrawalId: withdrawalId,
+		State:        StateSubmitted,
+		Created:      now,
+		Updated:      now,
+	}
+	t.transfers = append(t.transfers, transfer)
+	t.logger.Info("Transfer %v : %v %v to %v is %v, withdrawal id %v", transfer.Id, transfer.Direction, transfer.Amount, transfer.Address, transfer.State, withdrawalId)
+	t.save(transfer)
+
+	return *transfer
+}
+
+// Transfers returns copies of the tracked transfers in the order they were recorded
+func (t *Tracker) Transfers() []Transfer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var res = make([]Transfer, 0, len(t.transfers))
+	for _, transfer := range t.transfers {
+		res = append(res, *transfer)
+	}
+	return res
+}
+
+// InFlight returns the amounts of the transfers to the trading system and to the internal system which are not settled.
+// Stuck transfers are counted, the funds are still moving until the transfer is settled or failed by the operator.
+func (t *Tracker) InFlight() (decimal.Decimal, decimal.Decimal) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var toTradingSystem, toInternal = decimal.Zero, decimal.Zero
+	for _, transfer := range t.transfers {
+		if transfer.Settled() {
+			continue
+		}
+		if transfer.Direction == InternalToTradingSystem {
+			toTradingSystem = toTradingSystem.Add(transfer.Amount)
+		} else {
+			toInternal = toInternal.Add(transfer.Amount)
+		}
+	}
+	return toTradingSystem, toInternal
+}
+
+// HasPending reports whether the transfer in the direction is pending or stuck
+func (t *Tracker) HasPending(direction string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, transfer := range t.transfers {
+		if transfer.Direction == direction && !transfer.Settled() {
+			return true
+		}
+	}
+	return false
+}
+
+// Update polls the sending and the receiving side of the transfers which are not settled and reports whether
+// any transfer was settled, the balances requested before the update may not include it.
+// Settled transfers are forgotten after the timeout, the rest is polled until it's settled.
+func (t *Tracker) Update(ctx context.Context) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var settled = false
+
+	var active = make([]*Transfer, 0, len(t.transfers))
+	var start = time.Now()
+	var inbound = false
+	for _, transfer := range t.transfers {
+		if transfer.Settled() {
+			continue
+		}
+		active = append(active, transfer)
+		if transfer.Created.Before(start) {
+			start = transfer.Created
+		}
+		inbound = inbound || transfer.Direction == TradingSystemToInternal
+	}
+	if len(active) > 0 {
+		// both directions need the trading system history, the internal deposits are requested only for inbound transfers
+		var end = time.Now().Add(clockSkew)
+		var tradingSystemHistory, tradingSystemOk = t.history(ctx, t.tradingSystem, t.tradingSystemCurrency, start.Add(-clockSkew), end)
+		var internalHistory []*entity.VenueTransfer
+		var internalOk = false
+		if inbound {
+			internalHistory, internalOk = t.history(ctx, t.internal, t.internalCurrency, start.Add(-clockSkew), end)
+		}
+
+		for _, transfer := range active {
+			var state = transfer.State
+			if transfer.Direction == InternalToTradingSystem {
+				t.updateOutbound(ctx, transfer, tradingSystemHistory, tradingSystemOk)
+			} else {
+				t.updateInbound(transfer, tradingSystemHistory, tradingSystemOk, internalHistory, internalOk)
+			}
+			t.setState(transfer, state)
+			settled = settled || transfer.Settled()
+		}
+	}
+
+	t.forget()
+	return settled
+}
+
+// updateOutbound checks the internal payment and the trading system deposit of the transfer
+func (t *Tracker) updateOutbound(ctx context.Context, transfer *Transfer, deposits []*entity.VenueTransfer, depositsOk bool) {
+	if transfer.Broadcast.IsZero() {
+		var completed, err = t.internal.IsPaymentCompleted(ctx, transfer.Id)
+		if err != nil {
+			t.logger.Warn("Transfer %v : can't get internal payment status : %v", transfer.Id, err)
+		}
+		if completed {
+			transfer.Broadcast = time.Now()
+		}
+	}
+
+	if depositsOk {
+		t.matchDeposit(transfer, deposits, "trading system")
+	}
+}
+
+// updateInbound checks the trading system withdrawal and the internal deposit of the transfer
+func (t *Tracker) updateInbound(transfer *Transfer, withdrawals []*entity.VenueTransfer, withdrawalsOk bool, deposits []*entity.VenueTransfer, depositsOk bool) {
+	if withdrawalsOk && transfer.Broadcast.IsZero() {
+		var withdrawal = t.match(transfer, withdrawals, false, "trading system")
+		switch {
+		case withdrawal == nil:
+		case withdrawal.Status == entity.TransferStatusFailed:
+			transfer.State = StateFailed
+			return
+		case withdrawal.Status == entity.TransferStatusComplete:
+			transfer.Broadcast = time.Now()
+			transfer.TxId = withdrawal.TxId
+		}
+	}
+
+	if depositsOk {
+		t.matchDeposit(transfer, deposits, "internal")
+	}
+}
+
+// matchDeposit finds the deposit of the transfer on the receiving side and updates the transfer by its status
+func (t *Tracker) matchDeposit(transfer *Transfer, deposits []*entity.VenueTransfer, system string) {
+	var deposit = t.match(transfer, deposits, true, system)
+	if deposit == nil {
+		return
+	}
+
+	transfer.DepositId = deposit.Id
+	if len(deposit.TxId) > 0 {
+		transfer.TxId = deposit.TxId
+	}
+	// the deposit can be seen before the sending side reports the withdrawal as completed
+	if transfer.Broadcast.IsZero() {
+		transfer.Broadcast = time.Now()
+	}
+
+	switch deposit.Status {
+	case entity.TransferStatusComplete:
+		transfer.State = StateCredited
+	case entity.TransferStatusFailed:
+		transfer.State = StateFailed
+	}
+}
+
+// match returns the item of the transfer: the claimed one, the one with the withdrawal id or the tx id,
+// or the first unclaimed item to the address with the amount lower than the transfer amount by the fee tolerance
+func (t *Tracker) match(transfer *Transfer, items []*entity.VenueTransfer, isDeposit bool, system string) *entity.VenueTransfer {
+	var res *entity.VenueTransfer
+	for _, item := range items {
+		if item.IsDeposit != isDeposit {
+			continue
+		}
+
+		var key = claimKey(system, item)
+		if owner, found := t.claimed[key]; found {
+			if owner == transfer.Id {
+				return item
+			}
+			continue
+		}
+
+		if res != nil {
+			continue
+		}
+		switch {
+		case !isDeposit && len(transfer.WithdrawalId) > 0:
+			if item.Id == transfer.WithdrawalId {
+				res = item
+			}
+		case len(transfer.TxId) > 0 && item.TxId == transfer.TxId:
+			res = item
+		case item.Address == transfer.Address && !item.Date.Before(transfer.Created.Add(-clockSkew)) && matchAmount(transfer.Amount, item.Amount):
+			res = item
+		}
+	}
+
+	if res != nil {
+		t.claimed[claimKey(system, res)] = transfer.Id
+	}
+	return res
+}
+
+// setState moves the pending transfer to the state of its progress and logs the change of the state
+func (t *Tracker) setState(transfer *Transfer, previous string) {
+	if !transfer.Settled() {
+		// the seen deposit is confirmed after the timeout too
+		switch {
+		case len(transfer.DepositId) > 0:
+			transfer.State = StateConfirmed
+		case time.Since(transfer.Created) > t.timeout:
+			transfer.State = StateStuck
+		case !transfer.Broadcast.IsZero():
+			transfer.State = StateBroadcast
+		}
+	}
+
+	if transfer.State == previous {
+		return
+	}
+	transfer.Updated = time.Now()
+	t.save(transfer)
+
+	if transfer.State == StateStuck || transfer.State == StateFailed {
+		t.logger.Warn("Transfer %v : %v %v to %v is %v after %v, tx id %v", transfer.Id, transfer.Direction, transfer.Amount, transfer.Address, transfer.State, time.Since(transfer.Created).Round(time.Second), transfer.TxId)
+		return
+	}
+	t.logger.Info("Transfer %v : %v %v to %v is %v, tx id %v", transfer.Id, transfer.Direction, transfer.Amount, transfer.Address, transfer.State, transfer.TxId)
+}
+
+// forget removes the transfers settled before the timeout
+func (t *Tracker) forget() {
+	var kept = t.transfers[:0]
+	for _, transfer := range t.transfers {
+		if !t.expired(transfer) {
+			kept = append(kept, transfer)
+		}
+	}
+	t.transfers = kept
+}
+
+// expired reports whether the transfer is settled before the timeout, transfers which are not settled never expire
+func (t *Tracker) expired(transfer *Transfer) bool {
+	return transfer.Settled() && time.Since(transfer.Updated) > t.timeout
+}
+
+// claim marks the deposit and the withdrawal of the restored transfer as matched
+func (t *Tracker) claim(transfer *Transfer) {
+	var receiver, sender = "trading system", "internal"
+	if transfer.Direction == TradingSystemToInternal {
+		receiver, sender = "internal", "trading system"
+	}
+
+	if len(transfer.DepositId) > 0 {
+		t.claimed[claimKey(receiver, &entity.VenueTransfer{Id: transfer.DepositId, IsDeposit: true})] = transfer.Id
+	}
+	if len(transfer.WithdrawalId) > 0 && !transfer.Broadcast.IsZero() {
+		t.claimed[claimKey(sender, &entity.VenueTransfer{Id: transfer.WithdrawalId})] = transfer.Id
+	}
+}
+
+// save appends the state of the transfer to the ledger, the transfer is tracked in memory when it can't be written
+func (t *Tracker) save(transfer *Transfer) {
+	if t.ledger == nil {
+		return
+	}
+	if err := t.ledger.Append(transfer); err != nil {
+		t.logger.Error("Transfer %v : can't write transfer ledger : %v", transfer.Id, err)
+	}
+}
+
+// history returns the deposits and withdrawals of the system, false is returned when the system doesn't report them
+func (t *Tracker) history(ctx context.Context, system interface{}, currency string, start time.Time, end time.Time) ([]*entity.VenueTransfer, bool) {
+	var requests, ok = system.(common.ITransferHistoryRequest)
+	if !ok {
+		return nil, false
+	}
+
+	var res, err = requests.GetTransfers(ctx, currency, start, end)
+	if err != nil {
+		if !errors.Is(err, common.ErrNotSupported) {
+			t.logger.Warn("Transfer : can't get %v deposits and withdrawals : %v", currency, err)
+		}
+		return nil, false
+	}
+	return res, true
+}
+
+func claimKey(system string, item *entity.VenueTransfer) string {
+	if item.IsDeposit {
+		return system + " deposit " + item.Id
+	}
+	return system + " withdrawal " + item.Id
+}
+
+func matchAmount(amount decimal.Decimal, received decimal.Decimal) bool {
+	return received.LessThanOrEqual(amount) && received.GreaterThanOrEqual(amount.Mul(decimal.NewFromFloat(1-feeTolerance)))
+}
@@ -0,0 +1,247 @@
+package transfer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+	"trading_bot/internal/entity"
+	"trading_bot/mocks"
+
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+)
+
+// tradingSystem reports deposits and withdrawals as the legacy Poloniex API does
+type tradingSystem struct {
+	*mocks.ITradingSystemRequest
+	*mocks.ITransferHistoryRequest
+}
+
+// internal reports deposits as JetCrypto does
+type internal struct {
+	*mocks.IInternalRequest
+	*mocks.ITransferHistoryRequest
+}
+
+func testLogger(t *testing.T) *mocks.ILogger {
+	t.Helper()
+
+	var l = &mocks.ILogger{}
+	var args = []interface{}{mock.Anything}
+	for i := 0; i < 8; i++ {
+		l.On("Info", args...).Return()
+		l.On("Warn", args...).Return()
+		l.On("Error", args...).Return()
+		args = append(args, mock.Anything)
+	}
+
+	return l
+}
+
+func TestTracker_InternalToTradingSystem_Success(t *testing.T) {
+	t.Parallel()
+
+	var id = uuid.Must(uuid.NewV4())
+	var internalRequests = &mocks.IInternalRequest{}
+	internalRequests.On("IsPaymentCompleted", mock.Anything, id).Return(false, nil).Once()
+	internalRequests.On("IsPaymentCompleted", mock.Anything, id).Return(true, nil).Once()
+	var history = &mocks.ITransferHistoryRequest{}
+	history.On("GetTransfers", mock.Anything, "BTC", mock.Anything, mock.Anything).Return([]*entity.VenueTransfer{}, nil).Twice()
+	var deposit = &entity.VenueTransfer{Id: "7001", Address: "bc1qtrading", Amount: decimal.NewFromFloat(0.2999), TxId: "9f2b1c", Status: entity.TransferStatusPending, IsDeposit: true, Date: time.Now()}
+	history.On("GetTransfers", mock.Anything, "BTC", mock.Anything, mock.Anything).Return([]*entity.VenueTransfer{deposit}, nil).Once()
+	var credited = *deposit
+	credited.Status = entity.TransferStatusComplete
+	history.On("GetTransfers", mock.Anything, "BTC", mock.Anything, mock.Anything).Return([]*entity.VenueTransfer{&credited}, nil).Once()
+
+	var tracker, _ = New(testLogger(t), internalRequests, tradingSystem{&mocks.ITradingSystemRequest{}, history}, "BTC", "BTC", time.Hour, nil)
+	tracker.Record(id, InternalToTradingSystem, decimal.NewFromFloat(0.3), "bc1qtrading", "4521")
+
+	var states []string
+	for i := 0; i < 4; i++ {
+		tracker.Update(context.Background())
+		states = append(states, tracker.Transfers()[0].State)
+	}
+
+	var want = []string{StateSubmitted, StateBroadcast, StateConfirmed, StateCredited}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("got states %v, wanted %v", states, want)
+			break
+		}
+	}
+	if got := tracker.Transfers()[0]; got.TxId != "9f2b1c" || got.DepositId != "7001" {
+		t.Errorf("got tx %v and deposit %v, wanted 9f2b1c and 7001", got.TxId, got.DepositId)
+	}
+	internalRequests.AssertExpectations(t)
+}
+
+func TestTracker_TradingSystemToInternal_Success(t *testing.T) {
+	t.Parallel()
+
+	var withdrawal = &entity.VenueTransfer{Id: "134933", Address: "bc1qinternal", Amount: decimal.NewFromFloat(0.5), TxId: "36e483ef", Status: entity.TransferStatusComplete, Date: time.Now()}
+	var tradingSystemHistory = &mocks.ITransferHistoryRequest{}
+	tradingSystemHistory.On("GetTransfers", mock.Anything, "BTC", mock.Anything, mock.Anything).Return([]*entity.VenueTransfer{withdrawal}, nil)
+	var internalHistory = &mocks.ITransferHistoryRequest{}
+	internalHistory.On("GetTransfers", mock.Anything, "BTC", mock.Anything, mock.Anything).Return([]*entity.VenueTransfer{}, nil).Once()
+	// the deposit is matched by the tx id, the address of the deposit is not reported
+	internalHistory.On("GetTransfers", mock.Anything, "BTC", mock.Anything, mock.Anything).Return([]*entity.VenueTransfer{
+		{Id: "12", Amount: decimal.NewFromFloat(0.5), TxId: "36e483ef", Status: entity.TransferStatusComplete, IsDeposit: true, Date: time.Now()},
+	}, nil).Once()
+
+	var tracker, _ = New(testLogger(t), internal{&mocks.IInternalRequest{}, internalHistory}, tradingSystem{&mocks.ITradingSystemRequest{}, tradingSystemHistory}, "BTC", "BTC", time.Hour, nil)
+	tracker.Record(uuid.Must(uuid.NewV4()), TradingSystemToInternal, decimal.NewFromFloat(0.5), "bc1qinternal", "134933")
+
+	tracker.Update(context.Background())
+	if got := tracker.Transfers()[0]; got.State != StateBroadcast || got.TxId != "36e483ef" {
+		t.Errorf("got %v with tx %v, wanted %v with tx 36e483ef", got.State, got.TxId, StateBroadcast)
+	}
+	tracker.Update(context.Background())
+	if got := tracker.Transfers()[0]; got.State != StateCredited {
+		t.Errorf("got %v, wanted %v", got.State, StateCredited)
+	}
+}
+
+func TestTracker_WithdrawalFailed_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var tradingSystemHistory = &mocks.ITransferHistoryRequest{}
+	tradingSystemHistory.On("GetTransfers", mock.Anything, "BTC", mock.Anything, mock.Anything).Return([]*entity.VenueTransfer{
+		{Id: "134933", Address: "bc1qinternal", Amount: decimal.NewFromFloat(0.5), Status: entity.TransferStatusFailed, Date: time.Now()},
+	}, nil).Once()
+
+	var tracker, _ = New(testLogger(t), &mocks.IInternalRequest{}, tradingSystem{&mocks.ITradingSystemRequest{}, tradingSystemHistory}, "BTC", "BTC", time.Hour, nil)
+	tracker.Record(uuid.Must(uuid.NewV4()), TradingSystemToInternal, decimal.NewFromFloat(0.5), "bc1qinternal", "134933")
+
+	tracker.Update(context.Background())
+	// the settled transfer is not polled again
+	tracker.Update(context.Background())
+
+	if got := tracker.Transfers()[0]; got.State != StateFailed {
+		t.Errorf("got %v, wanted %v", got.State, StateFailed)
+	}
+	tradingSystemHistory.AssertExpectations(t)
+}
+
+func TestTracker_Stuck_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var id = uuid.Must(uuid.NewV4())
+	var internalRequests = &mocks.IInternalRequest{}
+	internalRequests.On("IsPaymentCompleted", mock.Anything, id).Return(false, nil)
+
+	// the trading system doesn't report deposits, the timeout is passed on the first update
+	var tracker, _ = New(testLogger(t), internalRequests, &mocks.ITradingSystemRequest{}, "BTC", "BTC", time.Nanosecond, nil)
+	tracker.Record(id, InternalToTradingSystem, decimal.NewFromFloat(0.3), "bc1qtrading", "4521")
+	tracker.Update(context.Background())
+
+	var got = tracker.Transfers()[0]
+	if got.State != StateStuck || got.Pending() || got.Settled() {
+		t.Errorf("got %v, wanted %v which is neither pending nor settled", got.State, StateStuck)
+	}
+	// the stuck transfer is in flight until it's settled
+	if toTradingSystem, _ := tracker.InFlight(); !toTradingSystem.Equal(decimal.NewFromFloat(0.3)) {
+		t.Errorf("got %v to trading system, wanted 0.3", toTradingSystem)
+	}
+	if !tracker.HasPending(InternalToTradingSystem) {
+		t.Errorf("got no pending stuck transfer, wanted one")
+	}
+}
+
+func TestTracker_ConfirmedAfterTimeout_Success(t *testing.T) {
+	t.Parallel()
+
+	var internalRequests = &mocks.IInternalRequest{}
+	internalRequests.On("IsPaymentCompleted", mock.Anything, mock.Anything).Return(true, nil)
+	var history = &mocks.ITransferHistoryRequest{}
+	history.On("GetTransfers", mock.Anything, "BTC", mock.Anything, mock.Anything).Return([]*entity.VenueTransfer{
+		{Id: "7001", Address: "bc1qtrading", Amount: decimal.NewFromFloat(0.3), Status: entity.TransferStatusPending, IsDeposit: true, Date: time.Now()},
+	}, nil)
+
+	// the deposit is seen after the timeout, the transfer is confirmed and not stuck
+	var tracker, _ = New(testLogger(t), internalRequests, tradingSystem{&mocks.ITradingSystemRequest{}, history}, "BTC", "BTC", time.Nanosecond, nil)
+	tracker.Record(uuid.Must(uuid.NewV4()), InternalToTradingSystem, decimal.NewFromFloat(0.3), "bc1qtrading", "4521")
+	tracker.Update(context.Background())
+	tracker.Update(context.Background())
+
+	if got := tracker.Transfers()[0]; got.State != StateConfirmed || got.DepositId != "7001" {
+		t.Errorf("got %v with deposit %v, wanted %v with deposit 7001", got.State, got.DepositId, StateConfirmed)
+	}
+}
+
+func TestTracker_DepositClaimedOnce_Success(t *testing.T) {
+	t.Parallel()
+
+	var first, second = uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	var internalRequests = &mocks.IInternalRequest{}
+	internalRequests.On("IsPaymentCompleted", mock.Anything, mock.Anything).Return(true, nil)
+	var history = &mocks.ITransferHistoryRequest{}
+	history.On("GetTransfers", mock.Anything, "BTC", mock.Anything, mock.Anything).Return([]*entity.VenueTransfer{
+		{Id: "7001", Address: "bc1qtrading", Amount: decimal.NewFromFloat(0.3), Status: entity.TransferStatusPending, IsDeposit: true, Date: time.Now()},
+	}, nil)
+
+	var tracker, _ = New(testLogger(t), internalRequests, tradingSystem{&mocks.ITradingSystemRequest{}, history}, "BTC", "BTC", time.Hour, nil)
+	tracker.Record(first, InternalToTradingSystem, decimal.NewFromFloat(0.3), "bc1qtrading", "4521")
+	tracker.Record(second, InternalToTradingSystem, decimal.NewFromFloat(0.3), "bc1qtrading", "4522")
+	tracker.Update(context.Background())
+	tracker.Update(context.Background())
+
+	var got = tracker.Transfers()
+	if got[0].State != StateConfirmed || got[1].State != StateBroadcast {
+		t.Errorf("got %v and %v, wanted %v and %v", got[0].State, got[1].State, StateConfirmed, StateBroadcast)
+	}
+}
+
+func TestTracker_InFlight_Success(t *testing.T) {
+	t.Parallel()
+
+	var internalRequests = &mocks.IInternalRequest{}
+	internalRequests.On("IsPaymentCompleted", mock.Anything, mock.Anything).Return(false, nil)
+	var ledger, err = NewLedger(filepath.Join(t.TempDir(), "data", "transfers.jsonl"))
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	var tracker, _ = New(testLogger(t), internalRequests, &mocks.ITradingSystemRequest{}, "BTC", "BTC", time.Hour, ledger)
+	tracker.Record(uuid.Must(uuid.NewV4()), InternalToTradingSystem, decimal.NewFromFloat(0.3), "bc1qtrading", "4521")
+	tracker.Record(uuid.Must(uuid.NewV4()), InternalToTradingSystem, decimal.NewFromFloat(0.1), "bc1qtrading", "4522")
+	tracker.Update(context.Background())
+
+	// pending transfers are restored from the ledger after the restart
+	restored, err := New(testLogger(t), internalRequests, &mocks.ITradingSystemRequest{}, "BTC", "BTC", time.Hour, ledger)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	var toTradingSystem, toInternal = restored.InFlight()
+	if !toTradingSystem.Equal(decimal.NewFromFloat(0.4)) || !toInternal.IsZero() {
+		t.Errorf("got %v to trading system and %v to internal, wanted 0.4 and 0", toTradingSystem, toInternal)
+	}
+	if !restored.HasPending(InternalToTradingSystem) || restored.HasPending(TradingSystemToInternal) {
+		t.Errorf("got pending %t and %t, wanted only transfer to trading system", restored.HasPending(InternalToTradingSystem), restored.HasPending(TradingSystemToInternal))
+	}
+}
+
+func TestTracker_Update_Settled_Success(t *testing.T) {
+	t.Parallel()
+
+	var history = &mocks.ITransferHistoryRequest{}
+	history.On("GetTransfers", mock.Anything, "BTC", mock.Anything, mock.Anything).Return([]*entity.VenueTransfer{
+		{Id: "134933", Address: "bc1qinternal", Amount: decimal.NewFromFloat(0.5), Status: entity.TransferStatusFailed, Date: time.Now()},
+	}, nil)
+
+	var tracker, _ = New(testLogger(t), &mocks.IInternalRequest{}, tradingSystem{&mocks.ITradingSystemRequest{}, history}, "BTC", "BTC", time.Hour, nil)
+	tracker.Record(uuid.Must(uuid.NewV4()), TradingSystemToInternal, decimal.NewFromFloat(0.5), "bc1qinternal", "134933")
+
+	// the balances requested before the update don't include the settled transfer
+	if got := tracker.Update(context.Background()); !got {
+		t.Errorf("got %t, wanted true", got)
+	}
+	if got := tracker.Update(context.Background()); got {
+		t.Errorf("got %t, wanted false", got)
+	}
+	if tracker.HasPending(TradingSystemToInternal) {
+		t.Errorf("got pending failed transfer, wanted none")
+	}
+}
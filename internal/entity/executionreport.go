@@ -0,0 +1,76 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Final status of the trading system order
+const (
+	ExecutionStatusFilled          = "filled"
+	ExecutionStatusPartiallyFilled = "partially_filled"
+	ExecutionStatusNotFilled       = "not_filled"
+)
+
+type ExecutionTrade struct {
+	TradeId     string          `json:"tradeId"`
+	Price       decimal.Decimal `json:"price"`
+	Amount      decimal.Decimal `json:"amount"`
+	Total       decimal.Decimal `json:"total"`
+	Fee         decimal.Decimal `json:"fee"`
+	FeeCurrency string          `json:"feeCurrency"`
+}
+
+// ExecutionReport describes result of the hedge order in the trading system
+type ExecutionReport struct {
+	Venue           string            `json:"venue"`
+	Pair            string            `json:"pair"`
+	IsSellOrder     bool              `json:"isSellOrder"`
+	OrderNumber     string            `json:"orderNumber"`
+	ClientOrderId   string            `json:"clientOrderId"`
+	RequestedAmount decimal.Decimal   `json:"requestedAmount"`
+	Trades          []*ExecutionTrade `json:"trades"`
+	AveragePrice    decimal.Decimal   `json:"averagePrice"`
+	FilledAmount    decimal.Decimal   `json:"filledAmount"`
+	Fee             decimal.Decimal   `json:"fee"`
+	FeeCurrency     string            `json:"feeCurrency"`
+	Status          string            `json:"status"`
+	Created         time.Time         `json:"created"`
+}
+
+func NewExecutionReport(venue string, pair string, isSellOrder bool, requestedAmount decimal.Decimal) *ExecutionReport {
+	return &ExecutionReport{
+		Venue:           venue,
+		Pair:            pair,
+		IsSellOrder:     isSellOrder,
+		RequestedAmount: requestedAmount,
+		Trades:          make([]*ExecutionTrade, 0),
+		Status:          ExecutionStatusNotFilled,
+		Created:         time.Now().UTC(),
+	}
+}
+
+// AddTrade appends the trade and recalculates filled amount, average price and fee
+func (r *ExecutionReport) AddTrade(trade *ExecutionTrade) {
+	r.Trades = append(r.Trades, trade)
+
+	var total decimal.Decimal
+	r.FilledAmount = decimal.Decimal{}
+	r.Fee = decimal.Decimal{}
+	for _, item := range r.Trades {
+		r.FilledAmount = r.FilledAmount.Add(item.Amount)
+		total = total.Add(item.Total)
+		// fees in the other currency (e.g. BNB on Binance) are kept in trades only
+		if len(r.FeeCurrency) == 0 {
+			r.FeeCurrency = item.FeeCurrency
+		}
+		if item.FeeCurrency == r.FeeCurrency {
+			r.Fee = r.Fee.Add(item.Fee)
+		}
+	}
+
+	if r.FilledAmount.IsPositive() {
+		r.AveragePrice = total.Div(r.FilledAmount).RoundDown(8)
+	}
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FeeSchedule of the trading system account, fees are rates (0.0025 is 0.25%) taken from the received currency
+type FeeSchedule struct {
+	MakerFee decimal.Decimal `json:"makerFee"`
+	TakerFee decimal.Decimal `json:"takerFee"`
+	Updated  time.Time       `json:"updated"`
+}
+
+// GrossAmount returns the amount to buy by the taker order to receive the amount after the fee
+func (f *FeeSchedule) GrossAmount(amount decimal.Decimal) decimal.Decimal {
+	return amount.Div(decimal.NewFromInt(1).Sub(f.TakerFee)).RoundUp(8)
+}
+
+// BuyCost returns the price of the received base currency bought by the taker order at the price
+func (f *FeeSchedule) BuyCost(price decimal.Decimal) decimal.Decimal {
+	return price.Div(decimal.NewFromInt(1).Sub(f.TakerFee))
+}
+
+// SellProceeds returns the received quote currency per unit sold by the taker order at the price
+func (f *FeeSchedule) SellProceeds(price decimal.Decimal) decimal.Decimal {
+	return price.Mul(decimal.NewFromInt(1).Sub(f.TakerFee))
+}
@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// prices and amounts are rounded to 8 decimal places when the market has no step
+const defaultPrecision = 8
+
+// MarketInfo is the trading rules of the pair, zero limits are not checked.
+// PriceStep is the tick size, AmountStep is the lot size, MinNotional is the minimal total (price * amount) of the order.
+type MarketInfo struct {
+	Pair        string          `json:"pair"`
+	PriceStep   decimal.Decimal `json:"priceStep"`
+	AmountStep  decimal.Decimal `json:"amountStep"`
+	MinAmount   decimal.Decimal `json:"minAmount"`
+	MaxAmount   decimal.Decimal `json:"maxAmount"`
+	MinNotional decimal.Decimal `json:"minNotional"`
+	Updated     time.Time       `json:"updated"`
+}
+
+// StepFromPrecision returns the step of the number of decimal places, 2 places is 0.01
+func StepFromPrecision(places int32) decimal.Decimal {
+	return decimal.New(1, -places)
+}
+
+// RoundPrice rounds the price to the tick size
+func (m *MarketInfo) RoundPrice(price decimal.Decimal, up bool) decimal.Decimal {
+	return roundToStep(price, m.PriceStep, up)
+}
+
+// RoundAmount rounds the amount to the lot size
+func (m *MarketInfo) RoundAmount(amount decimal.Decimal, up bool) decimal.Decimal {
+	return roundToStep(amount, m.AmountStep, up)
+}
+
+// Validate returns the error when the rounded order breaks the limits of the market
+func (m *MarketInfo) Validate(price decimal.Decimal, amount decimal.Decimal) error {
+	switch {
+	case amount.LessThan(m.MinAmount):
+		return fmt.Errorf("amount %v is lower than min amount %v of %v", amount, m.MinAmount, m.Pair)
+	case m.MaxAmount.IsPositive() && amount.GreaterThan(m.MaxAmount):
+		return fmt.Errorf("amount %v is greater than max amount %v of %v", amount, m.MaxAmount, m.Pair)
+	case price.Mul(amount).LessThan(m.MinNotional):
+		return fmt.Errorf("total %v is lower than min notional %v of %v", price.Mul(amount), m.MinNotional, m.Pair)
+	}
+	return nil
+}
+
+// Merge returns the rules satisfying both markets: the coarser steps, the higher minimums and the lower max amount.
+// Steps are expected to be powers of ten, so the coarser step is a multiple of the finer one.
+func (m *MarketInfo) Merge(other *MarketInfo) *MarketInfo {
+	var res = *m
+	res.PriceStep = decimal.Max(m.PriceStep, other.PriceStep)
+	res.AmountStep = decimal.Max(m.AmountStep, other.AmountStep)
+	res.MinAmount = decimal.Max(m.MinAmount, other.MinAmount)
+	res.MinNotional = decimal.Max(m.MinNotional, other.MinNotional)
+	if !m.MaxAmount.IsPositive() || (other.MaxAmount.IsPositive() && other.MaxAmount.LessThan(m.MaxAmount)) {
+		res.MaxAmount = other.MaxAmount
+	}
+	return &res
+}
+
+func roundToStep(value decimal.Decimal, step decimal.Decimal, up bool) decimal.Decimal {
+	if !step.IsPositive() {
+		if up {
+			return value.RoundUp(defaultPrecision)
+		}
+		return value.RoundDown(defaultPrecision)
+	}
+
+	var steps, rest = value.QuoRem(step, 0)
+	if up && rest.IsPositive() {
+		steps = steps.Add(decimal.NewFromInt(1))
+	}
+	return steps.Mul(step)
+}
@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// NewInternalOrder is the order of the batch placement
+type NewInternalOrder struct {
+	CurrencyFrom string          `json:"currencyFrom"`
+	CurrencyTo   string          `json:"currencyTo"`
+	Amount       decimal.Decimal `json:"amount"`
+	Price        decimal.Decimal `json:"price"`
+	IsSellOrder  bool            `json:"isSellOrder"`
+}
+
+// OrderResult is the result of the batch item, Err is nil when the item succeeded
+type OrderResult struct {
+	Id  uuid.UUID
+	Err error
+}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// VenueTrade is the trade of the account in the trading system
+type VenueTrade struct {
+	TradeId       string
+	OrderNumber   string
+	ClientOrderId string
+	Pair          string
+	IsSellOrder   bool
+	Price         decimal.Decimal
+	Amount        decimal.Decimal
+	Total         decimal.Decimal
+	// fee rate of the trade, the fee is taken from the received currency
+	FeeRate decimal.Decimal
+	Date    time.Time
+}
+
+// VenueOrder is the open order of the account in the trading system
+type VenueOrder struct {
+	OrderNumber    string
+	ClientOrderId  string
+	Pair           string
+	IsSellOrder    bool
+	Price          decimal.Decimal
+	StartingAmount decimal.Decimal
+	Amount         decimal.Decimal
+	Date           time.Time
+}
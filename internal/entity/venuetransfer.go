@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Status of the deposit or the withdrawal reported by the system
+const (
+	TransferStatusPending  = "pending"
+	TransferStatusComplete = "complete"
+	TransferStatusFailed   = "failed"
+)
+
+// VenueTransfer is the deposit or the withdrawal of the account, TxId is empty until the transfer is broadcast
+type VenueTransfer struct {
+	Id            string
+	Currency      string
+	Address       string
+	Amount        decimal.Decimal
+	Fee           decimal.Decimal
+	TxId          string
+	Confirmations int
+	Status        string
+	IsDeposit     bool
+	Date          time.Time
+}
@@ -16,6 +16,29 @@ type IHelperMethods struct {
 	mock.Mock
 }
 
+// HmacSha256 provides a mock function with given fields: keyBytes, messageBytes
+func (_m *IHelperMethods) HmacSha256(keyBytes []byte, messageBytes []byte) ([]byte, error) {
+	ret := _m.Called(keyBytes, messageBytes)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func([]byte, []byte) []byte); ok {
+		r0 = rf(keyBytes, messageBytes)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]byte, []byte) error); ok {
+		r1 = rf(keyBytes, messageBytes)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // HmacSha512 provides a mock function with given fields: keyBytes, messageBytes
 func (_m *IHelperMethods) HmacSha512(keyBytes []byte, messageBytes []byte) ([]byte, error) {
 	ret := _m.Called(keyBytes, messageBytes)
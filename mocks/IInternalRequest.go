@@ -23,30 +23,76 @@ type IInternalRequest struct {
 }
 
 // AddOrder provides a mock function with given fields: ctx, currencyFrom, currencyTo, amount, price, isSellOrder
-func (_m *IInternalRequest) AddOrder(ctx context.Context, currencyFrom string, currencyTo string, amount decimal.Decimal, price decimal.Decimal, isSellOrder bool) (bool, uuid.UUID) {
+func (_m *IInternalRequest) AddOrder(ctx context.Context, currencyFrom string, currencyTo string, amount decimal.Decimal, price decimal.Decimal, isSellOrder bool) (uuid.UUID, error) {
 	ret := _m.Called(ctx, currencyFrom, currencyTo, amount, price, isSellOrder)
 
-	var r0 bool
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, decimal.Decimal, decimal.Decimal, bool) bool); ok {
+	var r0 uuid.UUID
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, decimal.Decimal, decimal.Decimal, bool) uuid.UUID); ok {
 		r0 = rf(ctx, currencyFrom, currencyTo, amount, price, isSellOrder)
 	} else {
-		r0 = ret.Get(0).(bool)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(uuid.UUID)
+		}
 	}
 
-	var r1 uuid.UUID
-	if rf, ok := ret.Get(1).(func(context.Context, string, string, decimal.Decimal, decimal.Decimal, bool) uuid.UUID); ok {
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, decimal.Decimal, decimal.Decimal, bool) error); ok {
 		r1 = rf(ctx, currencyFrom, currencyTo, amount, price, isSellOrder)
 	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).(uuid.UUID)
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddOrders provides a mock function with given fields: ctx, orders
+func (_m *IInternalRequest) AddOrders(ctx context.Context, orders []*entity.NewInternalOrder) ([]*entity.OrderResult, error) {
+	ret := _m.Called(ctx, orders)
+
+	var r0 []*entity.OrderResult
+	if rf, ok := ret.Get(0).(func(context.Context, []*entity.NewInternalOrder) []*entity.OrderResult); ok {
+		r0 = rf(ctx, orders)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.OrderResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []*entity.NewInternalOrder) error); ok {
+		r1 = rf(ctx, orders)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBalance provides a mock function with given fields: ctx, currency
+func (_m *IInternalRequest) GetBalance(ctx context.Context, currency string) (*entity.BalanceObject, error) {
+	ret := _m.Called(ctx, currency)
+
+	var r0 *entity.BalanceObject
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.BalanceObject); ok {
+		r0 = rf(ctx, currency)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.BalanceObject)
 		}
 	}
 
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, currency)
+	} else {
+		r1 = ret.Error(1)
+	}
+
 	return r0, r1
 }
 
 // GetBalances provides a mock function with given fields: ctx
-func (_m *IInternalRequest) GetBalances(ctx context.Context) map[string]*entity.BalanceObject {
+func (_m *IInternalRequest) GetBalances(ctx context.Context) (map[string]*entity.BalanceObject, error) {
 	ret := _m.Called(ctx)
 
 	var r0 map[string]*entity.BalanceObject
@@ -58,11 +104,18 @@ func (_m *IInternalRequest) GetBalances(ctx context.Context) map[string]*entity.
 		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // GetCompleteOrder provides a mock function with given fields: ctx, orderId, jetCryptoPair
-func (_m *IInternalRequest) GetCompleteOrder(ctx context.Context, orderId uuid.UUID, jetCryptoPair string) []*entity.InternalOrder {
+func (_m *IInternalRequest) GetCompleteOrder(ctx context.Context, orderId uuid.UUID, jetCryptoPair string) ([]*entity.InternalOrder, error) {
 	ret := _m.Called(ctx, orderId, jetCryptoPair)
 
 	var r0 []*entity.InternalOrder
@@ -74,11 +127,18 @@ func (_m *IInternalRequest) GetCompleteOrder(ctx context.Context, orderId uuid.U
 		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string) error); ok {
+		r1 = rf(ctx, orderId, jetCryptoPair)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // GetCryptoAddress provides a mock function with given fields: ctx, currency
-func (_m *IInternalRequest) GetCryptoAddress(ctx context.Context, currency string) string {
+func (_m *IInternalRequest) GetCryptoAddress(ctx context.Context, currency string) (string, error) {
 	ret := _m.Called(ctx, currency)
 
 	var r0 string
@@ -88,11 +148,18 @@ func (_m *IInternalRequest) GetCryptoAddress(ctx context.Context, currency strin
 		r0 = ret.Get(0).(string)
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, currency)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // GetOrder provides a mock function with given fields: ctx, orderId, jetCryptoPair
-func (_m *IInternalRequest) GetOrder(ctx context.Context, orderId uuid.UUID, jetCryptoPair string) *entity.InternalOrder {
+func (_m *IInternalRequest) GetOrder(ctx context.Context, orderId uuid.UUID, jetCryptoPair string) (*entity.InternalOrder, error) {
 	ret := _m.Called(ctx, orderId, jetCryptoPair)
 
 	var r0 *entity.InternalOrder
@@ -104,11 +171,18 @@ func (_m *IInternalRequest) GetOrder(ctx context.Context, orderId uuid.UUID, jet
 		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string) error); ok {
+		r1 = rf(ctx, orderId, jetCryptoPair)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // GetOrders provides a mock function with given fields: ctx, jetCryptoPair
-func (_m *IInternalRequest) GetOrders(ctx context.Context, jetCryptoPair string) map[uuid.UUID]*entity.InternalOrder {
+func (_m *IInternalRequest) GetOrders(ctx context.Context, jetCryptoPair string) (map[uuid.UUID]*entity.InternalOrder, error) {
 	ret := _m.Called(ctx, jetCryptoPair)
 
 	var r0 map[uuid.UUID]*entity.InternalOrder
@@ -120,25 +194,41 @@ func (_m *IInternalRequest) GetOrders(ctx context.Context, jetCryptoPair string)
 		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, jetCryptoPair)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // GetTradingPairInfo provides a mock function with given fields: ctx, jetCryptoPair
-func (_m *IInternalRequest) GetTradingPairInfo(ctx context.Context, jetCryptoPair string) decimal.Decimal {
+func (_m *IInternalRequest) GetTradingPairInfo(ctx context.Context, jetCryptoPair string) (*entity.MarketInfo, error) {
 	ret := _m.Called(ctx, jetCryptoPair)
 
-	var r0 decimal.Decimal
-	if rf, ok := ret.Get(0).(func(context.Context, string) decimal.Decimal); ok {
+	var r0 *entity.MarketInfo
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.MarketInfo); ok {
 		r0 = rf(ctx, jetCryptoPair)
 	} else {
-		r0 = ret.Get(0).(decimal.Decimal)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.MarketInfo)
+		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, jetCryptoPair)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // IsPaymentCompleted provides a mock function with given fields: ctx, orderId
-func (_m *IInternalRequest) IsPaymentCompleted(ctx context.Context, orderId uuid.UUID) bool {
+func (_m *IInternalRequest) IsPaymentCompleted(ctx context.Context, orderId uuid.UUID) (bool, error) {
 	ret := _m.Called(ctx, orderId)
 
 	var r0 bool
@@ -148,35 +238,109 @@ func (_m *IInternalRequest) IsPaymentCompleted(ctx context.Context, orderId uuid
 		r0 = ret.Get(0).(bool)
 	}
 
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, orderId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ModifyOrder provides a mock function with given fields: ctx, orderId, currencyFrom, currencyTo, amount, price
+func (_m *IInternalRequest) ModifyOrder(ctx context.Context, orderId uuid.UUID, currencyFrom string, currencyTo string, amount decimal.Decimal, price decimal.Decimal) error {
+	ret := _m.Called(ctx, orderId, currencyFrom, currencyTo, amount, price)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, string, decimal.Decimal, decimal.Decimal) error); ok {
+		r0 = rf(ctx, orderId, currencyFrom, currencyTo, amount, price)
+	} else {
+		r0 = ret.Error(0)
+	}
+
 	return r0
 }
 
+// RemoveAllOrders provides a mock function with given fields: ctx, jetCryptoPair
+func (_m *IInternalRequest) RemoveAllOrders(ctx context.Context, jetCryptoPair string) ([]*entity.OrderResult, error) {
+	ret := _m.Called(ctx, jetCryptoPair)
+
+	var r0 []*entity.OrderResult
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*entity.OrderResult); ok {
+		r0 = rf(ctx, jetCryptoPair)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.OrderResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, jetCryptoPair)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RemoveOrder provides a mock function with given fields: ctx, orderId, currencyFrom, currencyTo
-func (_m *IInternalRequest) RemoveOrder(ctx context.Context, orderId uuid.UUID, currencyFrom string, currencyTo string) bool {
+func (_m *IInternalRequest) RemoveOrder(ctx context.Context, orderId uuid.UUID, currencyFrom string, currencyTo string) error {
 	ret := _m.Called(ctx, orderId, currencyFrom, currencyTo)
 
-	var r0 bool
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, string) bool); ok {
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, string) error); ok {
 		r0 = rf(ctx, orderId, currencyFrom, currencyTo)
 	} else {
-		r0 = ret.Get(0).(bool)
+		r0 = ret.Error(0)
 	}
 
 	return r0
 }
 
-// Withdraw provides a mock function with given fields: ctx, addr, destinationTag, withdrawalAmount, currentCurrencyId
-func (_m *IInternalRequest) Withdraw(ctx context.Context, addr string, destinationTag string, withdrawalAmount decimal.Decimal, currentCurrencyId string) null.Int {
-	ret := _m.Called(ctx, addr, destinationTag, withdrawalAmount, currentCurrencyId)
+// RemoveOrders provides a mock function with given fields: ctx, orderIds, currencyFrom, currencyTo
+func (_m *IInternalRequest) RemoveOrders(ctx context.Context, orderIds []uuid.UUID, currencyFrom string, currencyTo string) ([]*entity.OrderResult, error) {
+	ret := _m.Called(ctx, orderIds, currencyFrom, currencyTo)
+
+	var r0 []*entity.OrderResult
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID, string, string) []*entity.OrderResult); ok {
+		r0 = rf(ctx, orderIds, currencyFrom, currencyTo)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.OrderResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []uuid.UUID, string, string) error); ok {
+		r1 = rf(ctx, orderIds, currencyFrom, currencyTo)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Withdraw provides a mock function with given fields: ctx, addr, destinationTag, withdrawalAmount, currentCurrencyId, orderId
+func (_m *IInternalRequest) Withdraw(ctx context.Context, addr string, destinationTag string, withdrawalAmount decimal.Decimal, currentCurrencyId string, orderId uuid.UUID) (null.Int, error) {
+	ret := _m.Called(ctx, addr, destinationTag, withdrawalAmount, currentCurrencyId, orderId)
 
 	var r0 null.Int
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, decimal.Decimal, string) null.Int); ok {
-		r0 = rf(ctx, addr, destinationTag, withdrawalAmount, currentCurrencyId)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, decimal.Decimal, string, uuid.UUID) null.Int); ok {
+		r0 = rf(ctx, addr, destinationTag, withdrawalAmount, currentCurrencyId, orderId)
 	} else {
 		r0 = ret.Get(0).(null.Int)
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, decimal.Decimal, string, uuid.UUID) error); ok {
+		r1 = rf(ctx, addr, destinationTag, withdrawalAmount, currentCurrencyId, orderId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // NewIInternalRequest creates a new instance of IInternalRequest. It also registers the testing.TB interface on the mock and a cleanup function to assert the mocks expectations.
@@ -18,22 +18,31 @@ type ITradingSystemRequest struct {
 	mock.Mock
 }
 
-// Buy provides a mock function with given fields: ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair
-func (_m *ITradingSystemRequest) Buy(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string) bool {
-	ret := _m.Called(ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair)
+// Buy provides a mock function with given fields: ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair, clientOrderId
+func (_m *ITradingSystemRequest) Buy(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string, clientOrderId string) (*entity.ExecutionReport, error) {
+	ret := _m.Called(ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair, clientOrderId)
 
-	var r0 bool
-	if rf, ok := ret.Get(0).(func(context.Context, string, decimal.Decimal, decimal.Decimal, decimal.Decimal, string) bool); ok {
-		r0 = rf(ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair)
+	var r0 *entity.ExecutionReport
+	if rf, ok := ret.Get(0).(func(context.Context, string, decimal.Decimal, decimal.Decimal, decimal.Decimal, string, string) *entity.ExecutionReport); ok {
+		r0 = rf(ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair, clientOrderId)
 	} else {
-		r0 = ret.Get(0).(bool)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.ExecutionReport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, decimal.Decimal, decimal.Decimal, decimal.Decimal, string, string) error); ok {
+		r1 = rf(ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair, clientOrderId)
+	} else {
+		r1 = ret.Error(1)
 	}
 
-	return r0
+	return r0, r1
 }
 
 // GetCryptoAddress provides a mock function with given fields: ctx, currency, tradingSystemWithdrawalNetwork
-func (_m *ITradingSystemRequest) GetCryptoAddress(ctx context.Context, currency string, tradingSystemWithdrawalNetwork string) string {
+func (_m *ITradingSystemRequest) GetCryptoAddress(ctx context.Context, currency string, tradingSystemWithdrawalNetwork string) (string, error) {
 	ret := _m.Called(ctx, currency, tradingSystemWithdrawalNetwork)
 
 	var r0 string
@@ -43,11 +52,64 @@ func (_m *ITradingSystemRequest) GetCryptoAddress(ctx context.Context, currency
 		r0 = ret.Get(0).(string)
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, currency, tradingSystemWithdrawalNetwork)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFees provides a mock function with given fields: ctx, tradingSystemPair
+func (_m *ITradingSystemRequest) GetFees(ctx context.Context, tradingSystemPair string) (*entity.FeeSchedule, error) {
+	ret := _m.Called(ctx, tradingSystemPair)
+
+	var r0 *entity.FeeSchedule
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.FeeSchedule); ok {
+		r0 = rf(ctx, tradingSystemPair)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.FeeSchedule)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tradingSystemPair)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMarketInfo provides a mock function with given fields: ctx, tradingSystemPair
+func (_m *ITradingSystemRequest) GetMarketInfo(ctx context.Context, tradingSystemPair string) (*entity.MarketInfo, error) {
+	ret := _m.Called(ctx, tradingSystemPair)
+
+	var r0 *entity.MarketInfo
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.MarketInfo); ok {
+		r0 = rf(ctx, tradingSystemPair)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.MarketInfo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tradingSystemPair)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // GetPublicTradingOrders provides a mock function with given fields: ctx, tradingSystemPair, usdcTradingLimit, cryptoTradingLimit, internalCryptoBalance, internalUsdcBalance, pairMinAmount
-func (_m *ITradingSystemRequest) GetPublicTradingOrders(ctx context.Context, tradingSystemPair string, usdcTradingLimit decimal.Decimal, cryptoTradingLimit decimal.Decimal, internalCryptoBalance decimal.Decimal, internalUsdcBalance decimal.Decimal, pairMinAmount decimal.Decimal) []*entity.TradingOrder {
+func (_m *ITradingSystemRequest) GetPublicTradingOrders(ctx context.Context, tradingSystemPair string, usdcTradingLimit decimal.Decimal, cryptoTradingLimit decimal.Decimal, internalCryptoBalance decimal.Decimal, internalUsdcBalance decimal.Decimal, pairMinAmount decimal.Decimal) ([]*entity.TradingOrder, error) {
 	ret := _m.Called(ctx, tradingSystemPair, usdcTradingLimit, cryptoTradingLimit, internalCryptoBalance, internalUsdcBalance, pairMinAmount)
 
 	var r0 []*entity.TradingOrder
@@ -59,11 +121,18 @@ func (_m *ITradingSystemRequest) GetPublicTradingOrders(ctx context.Context, tra
 		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, decimal.Decimal, decimal.Decimal, decimal.Decimal, decimal.Decimal, decimal.Decimal) error); ok {
+		r1 = rf(ctx, tradingSystemPair, usdcTradingLimit, cryptoTradingLimit, internalCryptoBalance, internalUsdcBalance, pairMinAmount)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // GetTradingBalances provides a mock function with given fields: ctx
-func (_m *ITradingSystemRequest) GetTradingBalances(ctx context.Context) map[string]*entity.BalanceObject {
+func (_m *ITradingSystemRequest) GetTradingBalances(ctx context.Context) (map[string]*entity.BalanceObject, error) {
 	ret := _m.Called(ctx)
 
 	var r0 map[string]*entity.BalanceObject
@@ -75,35 +144,58 @@ func (_m *ITradingSystemRequest) GetTradingBalances(ctx context.Context) map[str
 		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// Sell provides a mock function with given fields: ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair
-func (_m *ITradingSystemRequest) Sell(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string) bool {
-	ret := _m.Called(ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair)
+// Sell provides a mock function with given fields: ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair, clientOrderId
+func (_m *ITradingSystemRequest) Sell(ctx context.Context, tradingSystemPair string, tradingSystemPrice decimal.Decimal, internalPrice decimal.Decimal, amount decimal.Decimal, internalPair string, clientOrderId string) (*entity.ExecutionReport, error) {
+	ret := _m.Called(ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair, clientOrderId)
+
+	var r0 *entity.ExecutionReport
+	if rf, ok := ret.Get(0).(func(context.Context, string, decimal.Decimal, decimal.Decimal, decimal.Decimal, string, string) *entity.ExecutionReport); ok {
+		r0 = rf(ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair, clientOrderId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.ExecutionReport)
+		}
+	}
 
-	var r0 bool
-	if rf, ok := ret.Get(0).(func(context.Context, string, decimal.Decimal, decimal.Decimal, decimal.Decimal, string) bool); ok {
-		r0 = rf(ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair)
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, decimal.Decimal, decimal.Decimal, decimal.Decimal, string, string) error); ok {
+		r1 = rf(ctx, tradingSystemPair, tradingSystemPrice, internalPrice, amount, internalPair, clientOrderId)
 	} else {
-		r0 = ret.Get(0).(bool)
+		r1 = ret.Error(1)
 	}
 
-	return r0
+	return r0, r1
 }
 
 // Withdraw provides a mock function with given fields: ctx, addr, withdrawalAmount, currency, tradingSystemWithdrawalNetwork
-func (_m *ITradingSystemRequest) Withdraw(ctx context.Context, addr string, withdrawalAmount decimal.Decimal, currency string, tradingSystemWithdrawalNetwork string) bool {
+func (_m *ITradingSystemRequest) Withdraw(ctx context.Context, addr string, withdrawalAmount decimal.Decimal, currency string, tradingSystemWithdrawalNetwork string) (string, error) {
 	ret := _m.Called(ctx, addr, withdrawalAmount, currency, tradingSystemWithdrawalNetwork)
 
-	var r0 bool
-	if rf, ok := ret.Get(0).(func(context.Context, string, decimal.Decimal, string, string) bool); ok {
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string, decimal.Decimal, string, string) string); ok {
 		r0 = rf(ctx, addr, withdrawalAmount, currency, tradingSystemWithdrawalNetwork)
 	} else {
-		r0 = ret.Get(0).(bool)
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, decimal.Decimal, string, string) error); ok {
+		r1 = rf(ctx, addr, withdrawalAmount, currency, tradingSystemWithdrawalNetwork)
+	} else {
+		r1 = ret.Error(1)
 	}
 
-	return r0
+	return r0, r1
 }
 
 // NewITradingSystemRequest creates a new instance of ITradingSystemRequest. It also registers the testing.TB interface on the mock and a cleanup function to assert the mocks expectations.
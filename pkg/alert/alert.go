@@ -0,0 +1,66 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"trading_bot/pkg/logger"
+)
+
+const sendTimeout = 10 * time.Second
+
+// IAlerter -.
+type IAlerter interface {
+	Alert(message string, args ...interface{})
+}
+
+// Alerter logs alerts and posts them to the webhook (Slack compatible {"text": "..."} payload)
+type Alerter struct {
+	logger     logger.ILogger
+	webhookUrl string
+	appName    string
+	client     *http.Client
+}
+
+var _ IAlerter = (*Alerter)(nil)
+
+// New -.
+func New(l logger.ILogger, webhookUrl string, appName string) *Alerter {
+	return &Alerter{
+		logger:     l,
+		webhookUrl: webhookUrl,
+		appName:    appName,
+		client:     &http.Client{Timeout: sendTimeout},
+	}
+}
+
+// Alert sends the message in background, it never blocks the caller
+func (a *Alerter) Alert(message string, args ...interface{}) {
+	var text = fmt.Sprintf(message, args...)
+	a.logger.Error("Alert : %v", text)
+
+	if len(a.webhookUrl) == 0 {
+		return
+	}
+
+	go a.send(text)
+}
+
+func (a *Alerter) send(text string) {
+	data, _ := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("[%v] %v", a.appName, text),
+	})
+
+	resp, err := a.client.Post(a.webhookUrl, "application/json", bytes.NewReader(data))
+	if err != nil {
+		a.logger.Error("Alert : can't send alert : %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		a.logger.Error("Alert : can't send alert, response status : %v", resp.StatusCode)
+	}
+}
@@ -0,0 +1,39 @@
+package alert
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"trading_bot/mocks"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAlert_Webhook_Success(t *testing.T) {
+	t.Parallel()
+
+	var received = make(chan string, 1)
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload["text"]
+	}))
+	defer server.Close()
+
+	var l = &mocks.ILogger{}
+	l.On("Error", mock.Anything, mock.Anything).Return()
+
+	New(l, server.URL, "trading_bot").Alert("JetCrypto circuit breaker is %v", "open")
+
+	select {
+	case got := <-received:
+		want := "[trading_bot] JetCrypto circuit breaker is open"
+		if got != want {
+			t.Errorf("got %v, wanted %v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("got no alert, wanted webhook call")
+	}
+}
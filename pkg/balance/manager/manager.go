@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"sync"
 	"trading_bot/config"
+	"trading_bot/internal/common/breaker"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/transfer"
 	balance "trading_bot/pkg/balance/worker"
 	"trading_bot/pkg/logger"
 )
@@ -15,7 +18,7 @@ type BalanceManager struct {
 	notify  chan error
 }
 
-func New(ctx context.Context, wg *sync.WaitGroup, cryptoCurrencies []config.CryptoCurrency, l logger.ILogger) (*BalanceManager, error) {
+func New(ctx context.Context, wg *sync.WaitGroup, cryptoCurrencies []config.CryptoCurrency, l logger.ILogger, transferLedger *transfer.Ledger, breakers *breaker.Registry, httpClient *helpermethods.Client) (*BalanceManager, error) {
 	if len(cryptoCurrencies) == 0 {
 		return nil, errors.New("balancemanager no currencies provided")
 	}
@@ -30,7 +33,7 @@ func New(ctx context.Context, wg *sync.WaitGroup, cryptoCurrencies []config.Cryp
 		if len(item.InternalSettings.Currency) == 0 {
 			continue
 		}
-		wk, err := balance.New(ctx, wg, item, l, s.notify)
+		wk, err := balance.New(ctx, wg, item, l, s.notify, transferLedger, breakers, httpClient)
 		if err != nil {
 			return nil, fmt.Errorf("BalanceWorker.New: %w", err)
 		}
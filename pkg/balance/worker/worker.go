@@ -6,16 +6,27 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 	"trading_bot/config"
 	"trading_bot/internal/common"
+	"trading_bot/internal/common/breaker"
 	"trading_bot/internal/common/helpermethods"
 	jetcryptoReq "trading_bot/internal/common/requests/jetcrypto"
-	tradingsystemReq "trading_bot/internal/common/requests/poloniex"
+	tradingsystemReq "trading_bot/internal/common/requests/tradingsystem"
+	"trading_bot/internal/common/signer"
+	"trading_bot/internal/common/transfer"
 	"trading_bot/pkg/logger"
 
+	"github.com/gofrs/uuid"
 	"github.com/shopspring/decimal"
-	"gopkg.in/guregu/null.v4"
+)
+
+const (
+	// pause between cycles when WorkIntervalMilliseconds is not set
+	defaultWorkInterval = 10 * time.Second
+	// check of the started worker
+	startPollInterval = 10 * time.Millisecond
 )
 
 type BalanceWorker struct {
@@ -23,20 +34,48 @@ type BalanceWorker struct {
 	settings              config.CryptoCurrency
 	tradingSystemRequests common.ITradingSystemRequest
 	internalRequests      common.IInternalRequest
+	internalBreaker       *breaker.Breaker
+	transfers             *transfer.Tracker
 	waitGroup             *sync.WaitGroup
+	workInterval          time.Duration
 	notify                chan error
-	running               bool
+	running               atomic.Bool
 }
 
-func New(ctx context.Context, wg *sync.WaitGroup, currencySettings config.CryptoCurrency, l logger.ILogger, err chan error) (*BalanceWorker, error) {
+// New creates and runs the worker, transfers of the currency which are not settled are restored from the transferLedger (optional)
+func New(ctx context.Context, wg *sync.WaitGroup, currencySettings config.CryptoCurrency, l logger.ILogger, err chan error, transferLedger *transfer.Ledger, breakers *breaker.Registry, httpClient *helpermethods.Client) (*BalanceWorker, error) {
+	// requests of the worker share keep-alive connections of the application client
+	var hm = helpermethods.New(l, httpClient)
+
+	tradingSystemRequests, tsErr := tradingsystemReq.New(l, hm, currencySettings.TradingSettings)
+	if tsErr != nil {
+		return nil, fmt.Errorf("BalanceWorker %v : %w", currencySettings.TradingSettings.Currency, tsErr)
+	}
+
+	if err := signer.Validate(currencySettings.InternalSettings.Signer, currencySettings.InternalSettings.Secret.Reveal()); err != nil {
+		return nil, fmt.Errorf("BalanceWorker %v : JetCrypto : %w", currencySettings.InternalSettings.Currency, err)
+	}
+
+	// internal backend breaker is shared with the other workers
+	var internalBreaker = breakers.Get("JetCrypto "+currencySettings.InternalSettings.Url, currencySettings.InternalSettings.Breaker)
+
+	var internalRequests = jetcryptoReq.New(l, hm, currencySettings.InternalSettings, internalBreaker)
+
+	transfers, trErr := transfer.New(l, internalRequests, tradingSystemRequests, currencySettings.InternalSettings.Currency, currencySettings.TradingSettings.Currency, time.Duration(currencySettings.TimeoutMinutes)*time.Minute, transferLedger)
+	if trErr != nil {
+		return nil, fmt.Errorf("BalanceWorker %v : transfer ledger : %w", currencySettings.InternalSettings.Currency, trErr)
+	}
+
 	s := &BalanceWorker{
 		notify:                err,
-		running:               false,
 		logger:                l,
 		settings:              currencySettings,
-		tradingSystemRequests: tradingsystemReq.New(l, helpermethods.New(l), currencySettings.TradingSettings),
-		internalRequests:      jetcryptoReq.New(l, helpermethods.New(l), currencySettings.InternalSettings),
+		tradingSystemRequests: tradingSystemRequests,
+		internalRequests:      internalRequests,
+		internalBreaker:       internalBreaker,
+		transfers:             transfers,
 		waitGroup:             wg,
+		workInterval:          workInterval(currencySettings),
 	}
 
 	go func(bw *BalanceWorker) {
@@ -49,71 +88,94 @@ func New(ctx context.Context, wg *sync.WaitGroup, currencySettings config.Crypto
 // Start worker
 func (s *BalanceWorker) Start() {
 	s.waitGroup.Add(1)
-	s.running = true
+	s.running.Store(true)
 	s.logger.Debug("Start BalanceWorker called")
 }
 
 // Shutdown -.
 func (s *BalanceWorker) Stop() {
-	s.running = false
+	s.running.Store(false)
 	s.waitGroup.Done()
 	s.logger.Debug("Stop BalanceWorker called")
 }
 
 func (s *BalanceWorker) DoWork(ctx context.Context) {
-	defer s.Stop()
-	for {
-		if !s.running {
-			continue
+	// waiting for Start, the worker is not stopped when it's cancelled before
+	for !s.running.Load() {
+		select {
+		case <-time.After(startPollInterval):
+		case <-ctx.Done():
+			return
 		}
+	}
 
+	defer s.Stop()
+	for {
 		select {
-		case <-time.After(10 * time.Second):
+		case <-time.After(s.workInterval):
 
 		case <-ctx.Done():
 			s.logger.Debug("Context cancelled")
 			return
 		}
 
+		// don't move funds while the internal system is in unknown state
+		if s.internalBreaker != nil && s.internalBreaker.State() == breaker.StateOpen {
+			s.logger.Warn("Balancer %v : %v circuit breaker is open, balancing is paused", s.settings.InternalSettings.Currency, s.internalBreaker.Name())
+			continue
+		}
+
 		if len(s.settings.TradingSettings.CryptoAddress) == 0 {
 			// try to get trading system crypto address
-			s.settings.TradingSettings.CryptoAddress = s.tradingSystemRequests.GetCryptoAddress(ctx, s.settings.TradingSettings.Currency, s.settings.TradingSettings.WithdrawalNetwork)
-			if len(s.settings.TradingSettings.CryptoAddress) == 0 {
-				var errStr = fmt.Sprintf("Balancer %v : Can't get Trading system CryptoAddress!", s.settings.TradingSettings.Currency)
+			var cryptoAddress, err = s.tradingSystemRequests.GetCryptoAddress(ctx, s.settings.TradingSettings.Currency, s.settings.TradingSettings.WithdrawalNetwork)
+			if err != nil {
+				if common.IsRetryable(err) {
+					s.handleRequestError("Can't get Trading system CryptoAddress", err)
+					continue
+				}
+				var errStr = fmt.Sprintf("Balancer %v : Can't get Trading system CryptoAddress : %v", s.settings.TradingSettings.Currency, err)
 				s.logger.Error(errStr)
-				s.notify <- errors.New(errStr)
+				s.notify <- fmt.Errorf("Balancer %v : Can't get Trading system CryptoAddress : %w", s.settings.TradingSettings.Currency, err)
 				break
 			}
+			s.settings.TradingSettings.CryptoAddress = cryptoAddress
 		}
 
 		if len(s.settings.InternalSettings.CryptoAddress) == 0 {
 			// try to get internal crypto address
-			s.settings.InternalSettings.CryptoAddress = s.internalRequests.GetCryptoAddress(ctx, s.settings.InternalSettings.Currency)
-			if len(s.settings.InternalSettings.CryptoAddress) == 0 {
-				var errStr = fmt.Sprintf("Balancer %v : Can't get Internal CryptoAddress!", s.settings.InternalSettings.Currency)
+			var cryptoAddress, err = s.internalRequests.GetCryptoAddress(ctx, s.settings.InternalSettings.Currency)
+			if err != nil {
+				if common.IsRetryable(err) {
+					s.handleRequestError("Can't get Internal CryptoAddress", err)
+					continue
+				}
+				var errStr = fmt.Sprintf("Balancer %v : Can't get Internal CryptoAddress : %v", s.settings.InternalSettings.Currency, err)
 				s.logger.Error(errStr)
-				s.notify <- errors.New(errStr)
+				s.notify <- fmt.Errorf("Balancer %v : Can't get Internal CryptoAddress : %w", s.settings.InternalSettings.Currency, err)
 				break
 			}
+			s.settings.InternalSettings.CryptoAddress = cryptoAddress
 		}
 
-		var internalBalanceCache = s.internalRequests.GetBalances(ctx)
-		if len(internalBalanceCache) == 0 {
-			s.logger.Error("Balancer Error : Can't get own internalBalances!!!")
+		var intBalance, err = s.internalRequests.GetBalance(ctx, s.settings.InternalSettings.Currency)
+		if errors.Is(err, common.ErrNotFound) {
+			s.logger.Error("Balancer Error : Can't get own internalBalance!!!")
 			continue
 		}
-
-		var intBalance, found = internalBalanceCache[s.settings.InternalSettings.Currency]
-		if !found {
-			s.logger.Error("Balancer Error : Can't get own internalBalance!!!")
+		if err != nil {
+			if s.handleRequestError("Can't get own internalBalance", err) {
+				return
+			}
 			continue
 		}
 
 		var internalBalance = intBalance.Balance.Add(intBalance.Reserved)
 
-		var tradingBalanceCache = s.tradingSystemRequests.GetTradingBalances(ctx)
-		if len(tradingBalanceCache) == 0 {
-			s.logger.Error("Balancer Error : Can't get tradingSystemBalances!!!")
+		tradingBalanceCache, err := s.tradingSystemRequests.GetTradingBalances(ctx)
+		if err != nil {
+			if s.handleRequestError("Can't get tradingSystemBalances", err) {
+				return
+			}
 			continue
 		}
 
@@ -123,8 +185,19 @@ func (s *BalanceWorker) DoWork(ctx context.Context) {
 			continue
 		}
 
-		var tradingBalance = tsBalance.Balance
-		s.logger.Debug("Balancer %v tradingBalance is : %v, internalBalance is : %v", s.settings.TradingSettings.Currency, tradingBalance, internalBalance)
+		// withdrawals sent by the previous cycles, the balances may not include the transfer settled meanwhile
+		if s.transfers.Update(ctx) {
+			// the cached trading system balances may be requested before the transfer was credited
+			s.invalidateTradingBalances()
+			s.logger.Debug("Balancer %v : transfer is settled, balances are requested again on next cycle", s.settings.InternalSettings.Currency)
+			continue
+		}
+
+		// transfers on the way are counted on the receiving side
+		var toTradingSystem, toInternal = s.transfers.InFlight()
+		var tradingBalance = tsBalance.Balance.Add(toTradingSystem)
+		internalBalance = internalBalance.Add(toInternal)
+		s.logger.Debug("Balancer %v tradingBalance is : %v, internalBalance is : %v, in flight to trading system : %v, to internal : %v", s.settings.TradingSettings.Currency, tradingBalance, internalBalance, toTradingSystem, toInternal)
 
 		var totalBalance = tradingBalance.Add(internalBalance).RoundDown(8)
 		var diffABS = tradingBalance.Sub(totalBalance.Mul((decimal.NewFromInt(1).Sub(s.settings.BalancePercent)))).Abs().RoundDown(8)
@@ -146,6 +219,16 @@ func (s *BalanceWorker) transferLogic(diffABS decimal.Decimal, thresholdAbs deci
 			internalToTradingSystem = true
 		}
 
+		// the next transfer in the same direction waits until the previous one is settled
+		var direction = transfer.TradingSystemToInternal
+		if internalToTradingSystem {
+			direction = transfer.InternalToTradingSystem
+		}
+		if s.transfers.HasPending(direction) {
+			s.logger.Info("Balancer %v : transfer %v is pending, waiting for it to settle", s.settings.InternalSettings.Currency, direction)
+			return false
+		}
+
 		if internalToTradingSystem {
 			s.logger.Info("Balancer %v diffABS is : %v > thresholdAbs : %v AND internalBalance : %v > totalBalanceUpper %v starting Balancer!", s.settings.InternalSettings.Currency, diffABS, thresholdAbs, internalBalance, totalBalanceUpper)
 
@@ -153,9 +236,22 @@ func (s *BalanceWorker) transferLogic(diffABS decimal.Decimal, thresholdAbs deci
 			var amountToWithdraw = diffABS
 			s.logger.Info("Balancer %v : Creating withdraw order Internal -> Trading system, amountToWithdraw %v", s.settings.InternalSettings.Currency, amountToWithdraw)
 
-			var paymentId = s.internalRequests.Withdraw(ctx, s.settings.TradingSettings.CryptoAddress, s.settings.TradingSettings.DestinationTag, amountToWithdraw, strconv.Itoa(s.settings.CurrencyId))
+			// the payment is tracked by the order id
+			var orderId, _ = uuid.NewV4()
+			var paymentId, err = s.internalRequests.Withdraw(ctx, s.settings.TradingSettings.CryptoAddress, s.settings.TradingSettings.DestinationTag, amountToWithdraw, strconv.Itoa(s.settings.CurrencyId), orderId)
 			s.logger.Info("Balancer %v : Withdraw order Internal -> Trading system, amountToWithdraw %v result PaymentId is : %v", s.settings.InternalSettings.Currency, amountToWithdraw, paymentId)
-			success = paymentId != null.Int{}
+			if err != nil {
+				s.handleRequestError("Withdraw order Internal -> Trading system failed", err)
+			}
+			success = err == nil && paymentId.Valid
+			switch {
+			case success:
+				s.transfers.Record(orderId, transfer.InternalToTradingSystem, amountToWithdraw, s.settings.TradingSettings.CryptoAddress, strconv.FormatInt(paymentId.Int64, 10))
+			case err == nil || common.IsAmbiguous(err):
+				// the payment may be made, it's tracked by the order id until it's completed
+				s.logger.Warn("Balancer %v : Withdraw order Internal -> Trading system %v outcome is unknown, it's tracked : %v", s.settings.InternalSettings.Currency, orderId, err)
+				s.transfers.Record(orderId, transfer.InternalToTradingSystem, amountToWithdraw, s.settings.TradingSettings.CryptoAddress, "")
+			}
 		} else {
 			s.logger.Info("Balancer %v diffABS is : %v > thresholdAbs : %v AND tradingBalance : %v > totalBalanceLower %v starting Balancer!", s.settings.TradingSettings.Currency, diffABS, thresholdAbs, tradingBalance, totalBalanceLower)
 
@@ -163,11 +259,59 @@ func (s *BalanceWorker) transferLogic(diffABS decimal.Decimal, thresholdAbs deci
 			var amountToWithdraw = diffABS
 			s.logger.Info("Balancer %v : Creating withdraw order Trading system -> Internal, amountToWithdraw %v", s.settings.TradingSettings.Currency, amountToWithdraw)
 
-			success = s.tradingSystemRequests.Withdraw(ctx, s.settings.InternalSettings.CryptoAddress, amountToWithdraw, s.settings.TradingSettings.Currency, s.settings.TradingSettings.WithdrawalNetwork)
+			var withdrawalId, err = s.tradingSystemRequests.Withdraw(ctx, s.settings.InternalSettings.CryptoAddress, amountToWithdraw, s.settings.TradingSettings.Currency, s.settings.TradingSettings.WithdrawalNetwork)
+			// the cached balance includes the withdrawn amount, it's counted in flight to internal
+			s.invalidateTradingBalances()
+			success = err == nil
 			s.logger.Info("Balancer %v : Withdraw order Trading system -> Internal, amountToWithdraw %v result is : %t", s.settings.InternalSettings.Currency, amountToWithdraw, success)
+			if err != nil {
+				s.handleRequestError("Withdraw order Trading system -> Internal failed", err)
+			}
+			var transferId, _ = uuid.NewV4()
+			switch {
+			case success:
+				s.transfers.Record(transferId, transfer.TradingSystemToInternal, amountToWithdraw, s.settings.InternalSettings.CryptoAddress, withdrawalId)
+			case common.IsAmbiguous(err):
+				// the withdrawal may be made, it's matched in the trading system history by the address and the amount
+				s.logger.Warn("Balancer %v : Withdraw order Trading system -> Internal %v outcome is unknown, it's tracked : %v", s.settings.InternalSettings.Currency, transferId, err)
+				s.transfers.Record(transferId, transfer.TradingSystemToInternal, amountToWithdraw, s.settings.InternalSettings.CryptoAddress, "")
+			}
 		}
 
 	}
 
 	return success
 }
+
+// invalidateTradingBalances drops the cached trading system balances, the next cycle requests them
+func (s *BalanceWorker) invalidateTradingBalances() {
+	if cache, ok := s.tradingSystemRequests.(common.IBalanceCacheRequest); ok {
+		cache.InvalidateBalances()
+	}
+}
+
+// handleRequestError logs the failed request and reports whether the worker has to stop
+func (s *BalanceWorker) handleRequestError(message string, err error) bool {
+	switch {
+	case common.IsFatal(err):
+		s.logger.Error("Balancer %v : %v : %v, stopping worker", s.settings.InternalSettings.Currency, message, err)
+		select {
+		case s.notify <- fmt.Errorf("Balancer %v : %w", s.settings.InternalSettings.Currency, err):
+		default:
+		}
+		return true
+	case common.IsRetryable(err):
+		s.logger.Warn("Balancer %v : %v : %v, retrying on next cycle", s.settings.InternalSettings.Currency, message, err)
+	default:
+		s.logger.Error("Balancer %v : %v : %v", s.settings.InternalSettings.Currency, message, err)
+	}
+	return false
+}
+
+// workInterval returns pause between the cycles, 10 seconds by default
+func workInterval(currencySettings config.CryptoCurrency) time.Duration {
+	if currencySettings.WorkIntervalMilliseconds <= 0 {
+		return defaultWorkInterval
+	}
+	return time.Duration(currencySettings.WorkIntervalMilliseconds) * time.Millisecond
+}
@@ -2,11 +2,20 @@ package worker
 
 import (
 	"context"
+	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 	"trading_bot/config"
+	"trading_bot/internal/common"
+	"trading_bot/internal/common/breaker"
+	"trading_bot/internal/common/requests/jetcrypto/jetcryptotest"
+	"trading_bot/internal/common/requests/poloniex/poloniextest"
+	"trading_bot/internal/common/secrets"
+	"trading_bot/internal/common/transfer"
 	"trading_bot/mocks"
 
+	"github.com/gofrs/uuid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
 	"gopkg.in/guregu/null.v4"
@@ -53,20 +62,26 @@ func balanceWorker(t *testing.T) *BalanceWorker {
 	l.On("Info", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
 	l.On("Info", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
 	l.On("Info", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	l.On("Info", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
 
 	var tradingSystemRequests = &mocks.ITradingSystemRequest{}
-	tradingSystemRequests.On("Withdraw", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(true)
+	tradingSystemRequests.On("Withdraw", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("134933", nil)
 
 	var internalRequests = &mocks.IInternalRequest{}
-	internalRequests.On("Withdraw", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(null.NewInt(10, true))
+	internalRequests.On("Withdraw", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(null.NewInt(10, true), nil)
+
+	var tracker, trErr = transfer.New(l, internalRequests, tradingSystemRequests, "BTC", "BTC", time.Hour, nil)
+	if trErr != nil {
+		t.Fatalf("got error %v, wanted nil", trErr)
+	}
 
 	return &BalanceWorker{
 		notify:                err,
-		running:               false,
 		logger:                l,
 		settings:              currencySettings,
 		tradingSystemRequests: tradingSystemRequests,
 		internalRequests:      internalRequests,
+		transfers:             tracker,
 		waitGroup:             wg,
 	}
 }
@@ -89,6 +104,9 @@ func TestTransferLogic_TradingSystemWithdraw_Success(t *testing.T) {
 	if got != want {
 		t.Errorf("got %t, wanted %t", got, want)
 	}
+	if transfers := bw.transfers.Transfers(); len(transfers) != 1 || transfers[0].WithdrawalId != "134933" || transfers[0].State != transfer.StateSubmitted {
+		t.Errorf("got transfers %+v, wanted submitted withdrawal 134933", transfers)
+	}
 }
 
 func TestTransferLogic_InternalWithdraw_Success(t *testing.T) {
@@ -111,6 +129,28 @@ func TestTransferLogic_InternalWithdraw_Success(t *testing.T) {
 	}
 }
 
+func TestTransferLogic_PendingTransfer_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var bw = balanceWorker(t)
+	bw.transfers.Record(uuid.Must(uuid.NewV4()), transfer.InternalToTradingSystem, decimal.NewFromFloat32(10), "bc1qtrading", "9")
+
+	var diffABS = decimal.NewFromFloat32(100)
+	var thresholdAbs = decimal.NewFromFloat32(1)
+	var tradingBalance = decimal.NewFromFloat32(100)
+	var totalBalanceLower = decimal.NewFromFloat32(80)
+	var internalBalance = decimal.NewFromFloat32(100)
+	var totalBalanceUpper = decimal.NewFromFloat32(90)
+
+	got := bw.transferLogic(diffABS, thresholdAbs, tradingBalance, totalBalanceLower, internalBalance, totalBalanceUpper, context.Background())
+	want := false
+
+	if got != want {
+		t.Errorf("got %t, wanted %t", got, want)
+	}
+	bw.internalRequests.(*mocks.IInternalRequest).AssertNotCalled(t, "Withdraw", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestTransferLogic_Withdraw_NotSuccess(t *testing.T) {
 	t.Parallel()
 
@@ -130,3 +170,303 @@ func TestTransferLogic_Withdraw_NotSuccess(t *testing.T) {
 		t.Errorf("got %t, wanted %t", got, want)
 	}
 }
+
+func TestTransferLogic_AmbiguousWithdraw_Success(t *testing.T) {
+	t.Parallel()
+
+	var bw = balanceWorker(t)
+	var l = bw.logger.(*mocks.ILogger)
+	l.On("Warn", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	l.On("Error", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	var tradingSystemRequests = &mocks.ITradingSystemRequest{}
+	tradingSystemRequests.On("Withdraw", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("", common.NewRequestError(common.ErrServiceUnavailable, "Poloniex", "withdraw", 502, "")).Once()
+	bw.tradingSystemRequests = tradingSystemRequests
+
+	var diffABS = decimal.NewFromFloat32(100)
+	var thresholdAbs = decimal.NewFromFloat32(1)
+	var tradingBalance = decimal.NewFromFloat32(100)
+	var totalBalanceLower = decimal.NewFromFloat32(90)
+	var internalBalance = decimal.NewFromFloat32(100)
+	var totalBalanceUpper = decimal.NewFromFloat32(110)
+
+	if got := bw.transferLogic(diffABS, thresholdAbs, tradingBalance, totalBalanceLower, internalBalance, totalBalanceUpper, context.Background()); got {
+		t.Errorf("got %t, wanted false", got)
+	}
+	// the withdrawal may be made, the next one waits for it
+	if got := bw.transferLogic(diffABS, thresholdAbs, tradingBalance, totalBalanceLower, internalBalance, totalBalanceUpper, context.Background()); got {
+		t.Errorf("got %t, wanted false", got)
+	}
+
+	if transfers := bw.transfers.Transfers(); len(transfers) != 1 || len(transfers[0].WithdrawalId) != 0 || transfers[0].State != transfer.StateSubmitted {
+		t.Errorf("got transfers %+v, wanted submitted withdrawal without id", transfers)
+	}
+	tradingSystemRequests.AssertNumberOfCalls(t, "Withdraw", 1)
+}
+
+func TestTransferLogic_RejectedWithdraw_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var bw = balanceWorker(t)
+	var l = bw.logger.(*mocks.ILogger)
+	l.On("Error", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	var internalRequests = &mocks.IInternalRequest{}
+	internalRequests.On("Withdraw", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(null.Int{}, common.NewRequestError(common.ErrInsufficientFunds, "JetCrypto", "withdraw", 400, "")).Once()
+	bw.internalRequests = internalRequests
+
+	var diffABS = decimal.NewFromFloat32(100)
+	var thresholdAbs = decimal.NewFromFloat32(1)
+	var tradingBalance = decimal.NewFromFloat32(100)
+	var totalBalanceLower = decimal.NewFromFloat32(80)
+	var internalBalance = decimal.NewFromFloat32(100)
+	var totalBalanceUpper = decimal.NewFromFloat32(90)
+
+	got := bw.transferLogic(diffABS, thresholdAbs, tradingBalance, totalBalanceLower, internalBalance, totalBalanceUpper, context.Background())
+
+	if got {
+		t.Errorf("got %t, wanted false", got)
+	}
+	if transfers := bw.transfers.Transfers(); len(transfers) != 0 {
+		t.Errorf("got transfers %+v, wanted none", transfers)
+	}
+}
+
+// standInSettings balances BTC between the stand-ins, 20% of the total is kept in the trading system
+func standInSettings(trading *poloniextest.Server, internal *jetcryptotest.Server, key secrets.Secret, secret secrets.Secret) config.CryptoCurrency {
+	var unlimited = config.RateLimit{RequestsPerSecond: 1000}
+	return config.CryptoCurrency{
+		CurrencyId:               2001,
+		BalancePercent:           decimal.NewFromFloat(0.8),
+		ThresholdPercent:         decimal.NewFromFloat(0.1),
+		ThresholdAbs:             decimal.NewFromFloat(0.2),
+		TimeoutMinutes:           60,
+		WorkIntervalMilliseconds: 20,
+		InternalSettings: config.InternalSettings{
+			Url:       internal.URL(),
+			Key:       key,
+			Secret:    secret,
+			Pair:      "BTC,USDC",
+			Currency:  "BTC",
+			RateLimit: config.RateLimitSettings{Public: unlimited, Private: unlimited, Order: unlimited},
+		},
+		TradingSettings: config.TradingSettings{
+			Type:      "poloniex_legacy",
+			Url:       trading.URL(),
+			Key:       key,
+			Secret:    secret,
+			Pair:      "USDC_BTC",
+			Currency:  "BTC",
+			RateLimit: config.RateLimitSettings{Public: unlimited, Private: unlimited, Order: unlimited},
+		},
+	}
+}
+
+func standInLogger() *mocks.ILogger {
+	var l = &mocks.ILogger{}
+	var args = []interface{}{mock.Anything}
+	for i := 0; i < 8; i++ {
+		l.On("Debug", args...).Return()
+		l.On("Info", args...).Return()
+		l.On("Warn", args...).Return()
+		l.On("Error", args...).Return()
+		args = append(args, mock.Anything)
+	}
+	return l
+}
+
+func TestBalanceWorker_InternalToTradingSystem_Success(t *testing.T) {
+	t.Parallel()
+
+	const key, secret = "balance-test-key", "balance-test-secret"
+
+	var trading = poloniextest.NewServer(key, secret)
+	defer trading.Close()
+	trading.SetBalance("BTC", decimal.NewFromFloat(0.1))
+	trading.SetDepositAddress("BTC", "bc1qtrading")
+
+	var internal = jetcryptotest.NewServer(key, secret)
+	defer internal.Close()
+	internal.SetBalance("BTC", decimal.NewFromFloat(1.9))
+	internal.SetCryptoAddress("BTC", "bc1qinternal")
+	internal.SetCurrencyId(2001, "BTC")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg = &sync.WaitGroup{}
+	var errChan = make(chan error, 1)
+
+	bw, err := New(ctx, wg, standInSettings(trading, internal, key, secret), standInLogger(), errChan, nil, breaker.NewRegistry(nil), nil)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	bw.Start()
+
+	var deadline = time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && len(internal.Payments()) == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	// 20% of the total 2 BTC is kept in the trading system
+	var payments = internal.Payments()
+	if len(payments) == 0 {
+		t.Fatalf("got no payments, wanted withdrawal to the trading system")
+	}
+	if payments[0].Address != "bc1qtrading" || !payments[0].Amount.Equal(decimal.NewFromFloat(0.3)) {
+		t.Errorf("got payment %+v, wanted 0.3 to bc1qtrading", payments[0])
+	}
+	if len(trading.Withdrawals()) != 0 {
+		t.Errorf("got trading system withdrawals %+v, wanted none", trading.Withdrawals())
+	}
+	// the payment is tracked by its order id
+	var transfers = bw.transfers.Transfers()
+	if len(transfers) == 0 || transfers[0].Direction != transfer.InternalToTradingSystem || transfers[0].Id.String() != payments[0].UuId {
+		t.Errorf("got transfers %+v, wanted transfer of payment %v", transfers, payments[0].UuId)
+	}
+}
+
+func TestBalanceWorker_TransferInFlight_Success(t *testing.T) {
+	t.Parallel()
+
+	const key, secret = "balance-test-key", "balance-test-secret"
+
+	var trading = poloniextest.NewServer(key, secret)
+	defer trading.Close()
+	trading.SetBalance("BTC", decimal.NewFromFloat(0.1))
+	trading.SetDepositAddress("BTC", "bc1qtrading")
+
+	var internal = jetcryptotest.NewServer(key, secret)
+	defer internal.Close()
+	internal.SetBalance("BTC", decimal.NewFromFloat(1.9))
+	internal.SetCryptoAddress("BTC", "bc1qinternal")
+	internal.SetCurrencyId(2001, "BTC")
+
+	ledger, err := transfer.NewLedger(filepath.Join(t.TempDir(), "transfers.jsonl"))
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	var settings = standInSettings(trading, internal, key, secret)
+
+	// run runs the worker for the cycles, the worker is stopped when the condition is met
+	var run = func(until func() bool) *BalanceWorker {
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg = &sync.WaitGroup{}
+
+		bw, err := New(ctx, wg, settings, standInLogger(), make(chan error, 1), ledger, breaker.NewRegistry(nil), nil)
+		if err != nil {
+			t.Fatalf("got error %v, wanted nil", err)
+		}
+		bw.Start()
+
+		var deadline = time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) && !until() {
+			time.Sleep(5 * time.Millisecond)
+		}
+		cancel()
+		wg.Wait()
+		return bw
+	}
+
+	// the payment on the way is counted in the trading system balance, no more payments are sent in the next cycles
+	var sent time.Time
+	run(func() bool {
+		if sent.IsZero() && len(internal.Payments()) > 0 {
+			sent = time.Now()
+		}
+		return !sent.IsZero() && time.Since(sent) > 10*workInterval(settings)
+	})
+	if got := len(internal.Payments()); got != 1 {
+		t.Fatalf("got %v payments, wanted 1", got)
+	}
+
+	// the transfer is restored after the restart and is credited by the trading system deposit
+	internal.CompletePayment(internal.Payments()[0].Id)
+	trading.CompleteDeposit(trading.AddDeposit("BTC", "bc1qtrading", decimal.NewFromFloat(0.3), "9f2b1c"))
+	var bw = run(func() bool {
+		for _, item := range ledgerTransfers(t, ledger) {
+			if item.State == transfer.StateCredited {
+				return true
+			}
+		}
+		return false
+	})
+
+	var transfers = bw.transfers.Transfers()
+	if len(transfers) != 1 || transfers[0].State != transfer.StateCredited || transfers[0].TxId != "9f2b1c" {
+		t.Errorf("got transfers %+v, wanted credited transfer with tx 9f2b1c", transfers)
+	}
+	if got := len(internal.Payments()); got != 1 || len(trading.Withdrawals()) != 0 {
+		t.Errorf("got %v payments and %v withdrawals, wanted single payment", got, len(trading.Withdrawals()))
+	}
+}
+
+func TestBalanceWorker_SettledWithCachedBalance_Success(t *testing.T) {
+	t.Parallel()
+
+	const key, secret = "balance-test-key", "balance-test-secret"
+
+	var trading = poloniextest.NewServer(key, secret)
+	defer trading.Close()
+	trading.SetBalance("BTC", decimal.NewFromFloat(0.1))
+	trading.SetDepositAddress("BTC", "bc1qtrading")
+
+	var internal = jetcryptotest.NewServer(key, secret)
+	defer internal.Close()
+	internal.SetBalance("BTC", decimal.NewFromFloat(1.9))
+	internal.SetCryptoAddress("BTC", "bc1qinternal")
+	internal.SetCurrencyId(2001, "BTC")
+
+	var settings = standInSettings(trading, internal, key, secret)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg = &sync.WaitGroup{}
+
+	bw, err := New(ctx, wg, settings, standInLogger(), make(chan error, 1), nil, breaker.NewRegistry(nil), nil)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	bw.Start()
+
+	var waitFor = func(until func() bool) {
+		var deadline = time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) && !until() {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// the trading system balance 0.1 is cached when the payment is sent
+	waitFor(func() bool { return len(internal.Payments()) > 0 })
+	if got := len(internal.Payments()); got != 1 {
+		t.Fatalf("got %v payments, wanted 1", got)
+	}
+
+	// the deposit is credited within the minute of the cached balance
+	internal.CompletePayment(internal.Payments()[0].Id)
+	trading.CompleteDeposit(trading.AddDeposit("BTC", "bc1qtrading", decimal.NewFromFloat(0.3), "9f2b1c"))
+	waitFor(func() bool {
+		var transfers = bw.transfers.Transfers()
+		return len(transfers) > 0 && transfers[0].State == transfer.StateCredited
+	})
+	var credited = time.Now()
+	waitFor(func() bool { return time.Since(credited) > 10*workInterval(settings) })
+	cancel()
+	wg.Wait()
+
+	// the credited transfer is not counted anymore, the balances are requested again instead of the cached ones
+	if got := len(internal.Payments()); got != 1 {
+		t.Errorf("got %v payments, wanted 1", got)
+	}
+	if transfers := bw.transfers.Transfers(); len(transfers) != 1 || transfers[0].State != transfer.StateCredited {
+		t.Errorf("got transfers %+v, wanted single credited transfer", transfers)
+	}
+}
+
+func ledgerTransfers(t *testing.T, ledger *transfer.Ledger) []*transfer.Transfer {
+	t.Helper()
+
+	var res, err = ledger.Transfers("BTC")
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	return res
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"sync"
 	"trading_bot/config"
+	"trading_bot/internal/common/breaker"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/journal"
 	"trading_bot/pkg/logger"
 
 	trading "trading_bot/pkg/trading/worker"
@@ -16,7 +19,7 @@ type TradingManager struct {
 	notify  chan error
 }
 
-func New(ctx context.Context, wg *sync.WaitGroup, cryptoCurrencies []config.CryptoCurrency, l logger.ILogger) (*TradingManager, error) {
+func New(ctx context.Context, wg *sync.WaitGroup, cryptoCurrencies []config.CryptoCurrency, l logger.ILogger, hedgeJournal *journal.Journal, breakers *breaker.Registry, httpClient *helpermethods.Client) (*TradingManager, error) {
 	if len(cryptoCurrencies) == 0 {
 		return nil, errors.New("no currencies provided for Tradingmanager")
 	}
@@ -31,7 +34,7 @@ func New(ctx context.Context, wg *sync.WaitGroup, cryptoCurrencies []config.Cryp
 		if len(item.InternalSettings.Currency) == 0 {
 			continue
 		}
-		wk, err := trading.New(ctx, wg, item, l, s.notify)
+		wk, err := trading.New(ctx, wg, item, l, s.notify, hedgeJournal, breakers, httpClient)
 		if err != nil {
 			return nil, fmt.Errorf("TradingWorker.New: %w", err)
 		}
@@ -0,0 +1,199 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+	"trading_bot/config"
+	"trading_bot/internal/common"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/journal"
+	"trading_bot/internal/common/reconcile"
+	"trading_bot/internal/common/retry"
+	"trading_bot/internal/entity"
+	"trading_bot/pkg/alert"
+	"trading_bot/pkg/logger"
+
+	tradingsystemReq "trading_bot/internal/common/requests/tradingsystem"
+)
+
+const (
+	// pause between reconciliations when IntervalSeconds is not set
+	defaultInterval = 5 * time.Minute
+	// hedges journaled within LookbackMinutes are reconciled, 60 minutes when it's not set
+	defaultLookback = 60 * time.Minute
+	// hedge orders are placed before the hedge is journaled, retries of the hedge take up to MaxElapsedSeconds
+	historyMargin = 10 * time.Minute
+	// check of the started reconciler
+	startPollInterval = 10 * time.Millisecond
+)
+
+// HedgeReconciler periodically matches the journaled hedges of the currency with the open orders and the trade history
+// of the trading system and alerts missing, duplicated, over-filled and open hedges
+type HedgeReconciler struct {
+	logger     logger.ILogger
+	settings   config.CryptoCurrency
+	requests   common.ITradeHistoryRequest
+	journal    *journal.Journal
+	alerter    alert.IAlerter
+	waitGroup  *sync.WaitGroup
+	interval   time.Duration
+	lookback   time.Duration
+	priceSteps int
+	// keys of the alerted discrepancies, every discrepancy is alerted once
+	reported map[string]time.Time
+	running  atomic.Bool
+}
+
+// New creates the reconciler, common.ErrNotSupported is returned when the trading system has no trade history requests
+func New(ctx context.Context, wg *sync.WaitGroup, currencySettings config.CryptoCurrency, reconcileSettings config.Reconcile, l logger.ILogger, hedgeJournal *journal.Journal, alerter alert.IAlerter, httpClient *helpermethods.Client) (*HedgeReconciler, error) {
+	tradingSystemRequests, err := tradingsystemReq.New(l, helpermethods.New(l, httpClient), currencySettings.TradingSettings)
+	if err != nil {
+		return nil, fmt.Errorf("HedgeReconciler %v : %w", currencySettings.TradingSettings.Currency, err)
+	}
+
+	historyRequests, ok := tradingSystemRequests.(common.ITradeHistoryRequest)
+	if !ok {
+		return nil, fmt.Errorf("HedgeReconciler %v : trade history : %w", currencySettings.TradingSettings.Currency, common.ErrNotSupported)
+	}
+
+	var interval = time.Duration(reconcileSettings.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	var lookback = time.Duration(reconcileSettings.LookbackMinutes) * time.Minute
+	if lookback <= 0 {
+		lookback = defaultLookback
+	}
+
+	s := &HedgeReconciler{
+		logger:     l,
+		settings:   currencySettings,
+		requests:   historyRequests,
+		journal:    hedgeJournal,
+		alerter:    alerter,
+		waitGroup:  wg,
+		interval:   interval,
+		lookback:   lookback,
+		priceSteps: retry.NewPolicy(currencySettings.TradingSettings.Retry).MaxAttempts,
+		reported:   make(map[string]time.Time),
+	}
+
+	go func(hr *HedgeReconciler) {
+		hr.DoWork(ctx)
+	}(s)
+
+	return s, nil
+}
+
+// Start reconciler
+func (s *HedgeReconciler) Start() {
+	s.waitGroup.Add(1)
+	s.running.Store(true)
+	s.logger.Debug("Start HedgeReconciler called")
+}
+
+// Shutdown -.
+func (s *HedgeReconciler) Stop() {
+	s.waitGroup.Done()
+	s.running.Store(false)
+	s.logger.Debug("Stop HedgeReconciler called")
+}
+
+func (s *HedgeReconciler) DoWork(ctx context.Context) {
+	// waiting for Start, the reconciler is not stopped when it's cancelled before
+	for !s.running.Load() {
+		select {
+		case <-time.After(startPollInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	defer s.Stop()
+	for {
+		select {
+		case <-time.After(s.interval):
+		case <-ctx.Done():
+			s.logger.Debug("Context cancelled")
+			return
+		}
+
+		var discrepancies, err = s.Reconcile(ctx)
+		if err != nil {
+			s.logger.Error("HedgeReconciler %v : Can't reconcile hedges : %v", s.settings.InternalSettings.Pair, err)
+			continue
+		}
+
+		for _, discrepancy := range discrepancies {
+			if _, found := s.reported[discrepancy.Key()]; found {
+				continue
+			}
+			s.reported[discrepancy.Key()] = discrepancy.Hedge.Time
+			s.alerter.Alert("HedgeReconciler %v : %v", s.settings.InternalSettings.Pair, discrepancy)
+		}
+
+		// hedges out of the lookback are not reconciled anymore
+		for key, hedgeTime := range s.reported {
+			if time.Since(hedgeTime) > s.lookback {
+				delete(s.reported, key)
+			}
+		}
+	}
+}
+
+// Reconcile matches the hedges journaled within the lookback with the trading system once
+func (s *HedgeReconciler) Reconcile(ctx context.Context) ([]*reconcile.Discrepancy, error) {
+	var records, err = s.journal.Records()
+	if err != nil {
+		return nil, fmt.Errorf("journal : %w", err)
+	}
+
+	var now = time.Now()
+	var hedges []*journal.HedgeRecord
+	for _, record := range records {
+		if record.InternalPair == s.settings.InternalSettings.Pair && record.Currency == s.settings.InternalSettings.Currency && now.Sub(record.Time) <= s.lookback {
+			hedges = append(hedges, record)
+		}
+	}
+	if len(hedges) == 0 {
+		return nil, nil
+	}
+
+	var pair = s.settings.TradingSettings.Pair
+	trades, err := s.requests.GetTradeHistory(ctx, pair, now.Add(-s.lookback-historyMargin), now.Add(time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	openOrders, err := s.requests.GetOpenOrders(ctx, pair)
+	if err != nil {
+		return nil, err
+	}
+
+	// orders of the reports missing in the history are requested one by one
+	var orders = make(map[string]bool)
+	for _, trade := range trades {
+		orders[trade.OrderNumber] = true
+	}
+	for _, hedge := range hedges {
+		if hedge.Report == nil || len(hedge.Report.OrderNumber) == 0 || orders[hedge.Report.OrderNumber] {
+			continue
+		}
+
+		var orderTrades []*entity.VenueTrade
+		orderTrades, err = s.requests.GetOrderTrades(ctx, hedge.Report.OrderNumber)
+		if err != nil && !errors.Is(err, common.ErrNotFound) {
+			return nil, err
+		}
+		trades = append(trades, orderTrades...)
+		orders[hedge.Report.OrderNumber] = true
+	}
+
+	var discrepancies = reconcile.Reconcile(hedges, trades, openOrders, s.priceSteps)
+	s.logger.Info("HedgeReconciler %v : %v hedges reconciled with %v trades and %v open orders, discrepancies : %v", s.settings.InternalSettings.Pair, len(hedges), len(trades), len(openOrders), len(discrepancies))
+
+	return discrepancies, nil
+}
@@ -0,0 +1,127 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"trading_bot/config"
+	"trading_bot/internal/common"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/journal"
+	"trading_bot/internal/common/reconcile"
+	"trading_bot/internal/common/requests/poloniex"
+	"trading_bot/internal/common/requests/poloniex/poloniextest"
+	"trading_bot/internal/entity"
+	"trading_bot/mocks"
+
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	testKey    = "reconciler-test-key"
+	testSecret = "reconciler-test-secret"
+)
+
+func testLogger(t *testing.T) *mocks.ILogger {
+	t.Helper()
+
+	var l = &mocks.ILogger{}
+	var args = []interface{}{mock.Anything}
+	for i := 0; i < 8; i++ {
+		l.On("Debug", args...).Return()
+		l.On("Info", args...).Return()
+		l.On("Warn", args...).Return()
+		l.On("Error", args...).Return()
+		args = append(args, mock.Anything)
+	}
+
+	return l
+}
+
+func TestHedgeReconciler_Reconcile_Success(t *testing.T) {
+	t.Parallel()
+
+	var sim = poloniextest.NewServer(testKey, testSecret)
+	t.Cleanup(sim.Close)
+	sim.SetTakerFee(decimal.Zero)
+	sim.SetBalance("USDC", decimal.NewFromInt(100000))
+	sim.SetBook("USDC_BTC", []poloniextest.Level{{Price: decimal.NewFromInt(20000), Volume: decimal.NewFromInt(1)}}, nil)
+
+	var settings = config.CryptoCurrency{
+		InternalSettings: config.InternalSettings{Pair: "BTC,USDC", Currency: "BTC"},
+		TradingSettings: config.TradingSettings{
+			Type:      "poloniex_legacy",
+			Url:       sim.URL(),
+			Key:       testKey,
+			Secret:    testSecret,
+			Pair:      "USDC_BTC",
+			Currency:  "BTC",
+			RateLimit: config.RateLimitSettings{Order: config.RateLimit{RequestsPerSecond: 100}},
+		},
+	}
+	hedgeJournal, err := journal.New(filepath.Join(t.TempDir(), "hedges.jsonl"))
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	var l = testLogger(t)
+	var requests = poloniex.New(l, helpermethods.New(l, nil), settings.TradingSettings)
+	var record = func(clientOrderId string, report *entity.ExecutionReport) *journal.HedgeRecord {
+		var hedge = journal.NewHedgeRecord("BTC", "BTC,USDC", uuid.Must(uuid.NewV4()), decimal.NewFromInt(20100), decimal.NewFromFloat(0.1), true, report, nil)
+		hedge.ClientOrderId = clientOrderId
+		if err := hedgeJournal.Append(hedge); err != nil {
+			t.Fatalf("got error %v, wanted nil", err)
+		}
+		return hedge
+	}
+
+	// the hedge is journaled as executed
+	var report, _ = requests.Buy(context.Background(), "USDC_BTC", decimal.NewFromInt(20000), decimal.NewFromInt(20100), decimal.NewFromFloat(0.1), "BTC,USDC", "h1")
+	record("h1", report)
+	// the hedge is executed, but the journal has no report of it
+	requests.Buy(context.Background(), "USDC_BTC", decimal.NewFromInt(20000), decimal.NewFromInt(20100), decimal.NewFromFloat(0.1), "BTC,USDC", "h2")
+	var overFilled = record("h2", nil)
+	// the journaled order has no trades
+	var lost = entity.NewExecutionReport("Poloniex", "USDC_BTC", false, decimal.NewFromFloat(0.1))
+	lost.OrderNumber = "999"
+	lost.FilledAmount = decimal.NewFromFloat(0.1)
+	var missing = record("h3", lost)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hr, err := New(ctx, &sync.WaitGroup{}, settings, config.Reconcile{}, l, hedgeJournal, nil, nil)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	got, err := hr.Reconcile(context.Background())
+
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %v discrepancies, wanted 2", got)
+	}
+	if got[0].Kind != reconcile.KindOverFilled || got[0].Hedge.InternalOrderId != overFilled.InternalOrderId {
+		t.Errorf("got %v, wanted %v hedge %v", got[0], reconcile.KindOverFilled, overFilled.InternalOrderId)
+	}
+	if got[1].Kind != reconcile.KindMissing || got[1].Hedge.InternalOrderId != missing.InternalOrderId {
+		t.Errorf("got %v, wanted %v hedge %v", got[1], reconcile.KindMissing, missing.InternalOrderId)
+	}
+}
+
+func TestHedgeReconciler_New_NotSuccess(t *testing.T) {
+	t.Parallel()
+
+	var settings = config.CryptoCurrency{TradingSettings: config.TradingSettings{Type: "binance", Key: testKey, Secret: testSecret}}
+
+	_, err := New(context.Background(), &sync.WaitGroup{}, settings, config.Reconcile{}, testLogger(t), nil, nil, nil)
+
+	if !errors.Is(err, common.ErrNotSupported) {
+		t.Errorf("got error %v, wanted %v", err, common.ErrNotSupported)
+	}
+}
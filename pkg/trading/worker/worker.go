@@ -2,32 +2,60 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"trading_bot/config"
 	"trading_bot/internal/common"
+	"trading_bot/internal/common/breaker"
+	"trading_bot/internal/common/clientid"
 	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/journal"
+	"trading_bot/internal/common/marketdata"
+	"trading_bot/internal/common/marketinfo"
+	"trading_bot/internal/common/orderbook"
+	"trading_bot/internal/common/orderdiff"
 	"trading_bot/internal/entity"
 	"trading_bot/pkg/logger"
 
 	jetcryptoReq "trading_bot/internal/common/requests/jetcrypto"
-	tradingsystemReq "trading_bot/internal/common/requests/poloniex"
+	tradingsystemReq "trading_bot/internal/common/requests/tradingsystem"
+	"trading_bot/internal/common/signer"
 
 	"github.com/gofrs/uuid"
 	"github.com/shopspring/decimal"
 )
 
+const (
+	// order book depth mirrored to the internal system
+	bookDepth = 20
+	// minimal pause between cycles triggered by order book changes
+	minBookReaction = 1 * time.Second
+	// streaming order book without changes for this time is not used, it's polled instead
+	maxBookAge = 1 * time.Minute
+	// pause between cycles when WorkIntervalMilliseconds is not set
+	defaultWorkInterval = 10 * time.Second
+	// check of the started worker
+	startPollInterval = 10 * time.Millisecond
+)
+
 type TradingWorker struct {
 	logger                logger.ILogger
 	settings              config.CryptoCurrency
 	tradingSystemRequests common.ITradingSystemRequest
 	internalRequests      common.IInternalRequest
+	internalBreaker       *breaker.Breaker
+	marketData            *marketdata.Stream
+	hedgeJournal          *journal.Journal
 	waitGroup             *sync.WaitGroup
+	workInterval          time.Duration
 	internalOrdersCache   map[uuid.UUID]*tradingOrderPair
-	pairMinAmount         decimal.Decimal
+	markets               *marketinfo.Service
 	notify                chan error
-	running               bool
+	running               atomic.Bool
 }
 
 type tradingOrderPair struct {
@@ -37,20 +65,51 @@ type tradingOrderPair struct {
 	TradingSystemAmount decimal.Decimal
 	TradingSystemPrice  decimal.Decimal
 	IsSellOrder         bool
+	// filled amount of the internal order hedged in the trading system
+	HedgedAmount decimal.Decimal
+	// fills of the order have to be checked on the next cycle
+	unsettled bool
 }
 
-func New(ctx context.Context, wg *sync.WaitGroup, currencySettings config.CryptoCurrency, l logger.ILogger, err chan error) (*TradingWorker, error) {
+func New(ctx context.Context, wg *sync.WaitGroup, currencySettings config.CryptoCurrency, l logger.ILogger, err chan error, hedgeJournal *journal.Journal, breakers *breaker.Registry, httpClient *helpermethods.Client) (*TradingWorker, error) {
+	// requests of the worker share keep-alive connections of the application client
+	var hm = helpermethods.New(l, httpClient)
+
+	tradingSystemRequests, tsErr := tradingsystemReq.New(l, hm, currencySettings.TradingSettings)
+	if tsErr != nil {
+		return nil, fmt.Errorf("TradingWorker %v : %w", currencySettings.TradingSettings.Currency, tsErr)
+	}
+
+	if err := signer.Validate(currencySettings.InternalSettings.Signer, currencySettings.InternalSettings.Secret.Reveal()); err != nil {
+		return nil, fmt.Errorf("TradingWorker %v : JetCrypto : %w", currencySettings.InternalSettings.Currency, err)
+	}
+
+	// internal backend breaker is shared with the other workers
+	var internalBreaker = breakers.Get("JetCrypto "+currencySettings.InternalSettings.Url, currencySettings.InternalSettings.Breaker)
+
+	var internalRequests = jetcryptoReq.New(l, hm, currencySettings.InternalSettings, internalBreaker)
+	var marketRefresh = time.Duration(currencySettings.MarketInfoRefreshMinutes) * time.Minute
+
 	s := &TradingWorker{
 		notify:                err,
-		running:               false,
 		logger:                l,
 		settings:              currencySettings,
-		tradingSystemRequests: tradingsystemReq.New(l, helpermethods.New(l), currencySettings.TradingSettings),
-		internalRequests:      jetcryptoReq.New(l, helpermethods.New(l), currencySettings.InternalSettings),
+		tradingSystemRequests: tradingSystemRequests,
+		internalRequests:      internalRequests,
+		markets:               marketinfo.New(l, internalRequests, tradingSystemRequests, currencySettings.InternalSettings.Pair, currencySettings.TradingSettings.Pair, marketRefresh),
+		internalBreaker:       internalBreaker,
+		hedgeJournal:          hedgeJournal,
 		waitGroup:             wg,
+		workInterval:          workInterval(currencySettings),
 		internalOrdersCache:   make(map[uuid.UUID]*tradingOrderPair),
 	}
 
+	// streaming order book, polling is used when it's not configured or not synced
+	if len(currencySettings.TradingSettings.WebSocketUrl) > 0 {
+		s.marketData = marketdata.New(l, currencySettings.TradingSettings.WebSocketUrl, []string{currencySettings.TradingSettings.Pair})
+		go s.marketData.Run(ctx)
+	}
+
 	go func(bw *TradingWorker) {
 		bw.DoWork(ctx)
 	}(s)
@@ -60,60 +119,100 @@ func New(ctx context.Context, wg *sync.WaitGroup, currencySettings config.Crypto
 
 // Start worker
 func (s *TradingWorker) Start() {
-	s.pairMinAmount = s.internalRequests.GetTradingPairInfo(context.Background(), s.settings.InternalSettings.Pair)
+	// the rules are requested again by the cycle when they can't be requested now
+	if _, err := s.markets.Get(context.Background()); err != nil {
+		s.logger.Error("TradingWorker %v : Can't get market info : %v", s.settings.InternalSettings.Pair, err)
+	}
 	s.waitGroup.Add(1)
-	s.running = true
+	s.running.Store(true)
 	s.logger.Debug("Start TradingWorker called")
 }
 
 // Shutdown -.
 func (s *TradingWorker) Stop() {
 	s.waitGroup.Done()
-	s.running = false
+	s.running.Store(false)
 	s.logger.Debug("Stop TradingWorker called")
 }
 
 func (s *TradingWorker) DoWork(ctx context.Context) {
-	defer s.Stop()
-	for {
-		if !s.running {
-			continue
+	// waiting for Start, the worker is not stopped when it's cancelled before
+	for !s.running.Load() {
+		select {
+		case <-time.After(startPollInterval):
+		case <-ctx.Done():
+			return
 		}
+	}
 
+	defer s.Stop()
+	var lastCycle time.Time
+	for {
 		select {
-		case <-time.After(10 * time.Second):
+		case <-time.After(s.workInterval):
+
+		case <-s.bookUpdates():
+			// react to book changes immediately, but not more often than minBookReaction
+			select {
+			case <-time.After(time.Until(lastCycle.Add(minBookReaction))):
+			case <-ctx.Done():
+				s.logger.Debug("Context cancelled")
+				return
+			}
 
 		case <-ctx.Done():
 			s.logger.Debug("Context cancelled")
 			return
 		}
+		lastCycle = time.Now()
 
-		var tradingBalanceCache = s.tradingSystemRequests.GetTradingBalances(ctx)
-		if len(tradingBalanceCache) == 0 {
-			s.logger.Error("TradingWorker Error : Can't get tradingSystemBalances!!!")
+		// internal system is in unknown state, don't quote and hedge
+		if !s.internalAvailable() {
 			continue
 		}
 
-		var internalBalanceCache = s.internalRequests.GetBalances(ctx)
-		if len(internalBalanceCache) == 0 {
-			s.logger.Error("TradingWorker Error : Can't get own internalBalances!!!")
+		var tradingBalanceCache, err = s.tradingSystemRequests.GetTradingBalances(ctx)
+		if err != nil {
+			if s.handleRequestError("Can't get tradingSystemBalances", err) {
+				return
+			}
+			continue
+		}
+
+		internalBalanceCache, err := s.internalRequests.GetBalances(ctx)
+		if err != nil {
+			if s.handleRequestError("Can't get own internalBalances", err) {
+				return
+			}
+			continue
+		}
+
+		internalOrders, err := s.internalRequests.GetOrders(ctx, s.settings.InternalSettings.Pair)
+		if err != nil {
+			if s.handleRequestError("Can't get own internalOrders", err) {
+				return
+			}
 			continue
 		}
 
 		// first time or empty cache
-		if len(s.internalOrdersCache) == 0 {
-			var internalOrders = s.internalRequests.GetOrders(ctx, s.settings.InternalSettings.Pair)
-			if len(internalOrders) == 0 {
-				s.logger.Error("TradingWorker Error : Can't get own internalOrders!!!")
+		if len(s.internalOrdersCache) == 0 && len(internalOrders) > 0 {
+			// fills hedged before the restart are not hedged again
+			var hedged, err = s.hedgedAmounts(internalOrders)
+			if err != nil {
+				s.logger.Error("TradingWorker %v : Can't read hedge journal, own internalOrders are not adopted : %v", s.settings.InternalSettings.Pair, err)
 				continue
 			}
+
 			for key, item := range internalOrders {
+				// fills of the adopted order are checked against the initial amount
 				var newPair = &tradingOrderPair{
 					InternalId:          item.Id,
 					InternalAmount:      item.Amount,
 					InternalPrice:       item.Price,
 					TradingSystemAmount: item.Amount,
 					IsSellOrder:         item.IsSellOrder,
+					HedgedAmount:        hedged[key],
 				}
 				// remove 1% from price
 				if newPair.IsSellOrder {
@@ -126,27 +225,30 @@ func (s *TradingWorker) DoWork(ctx context.Context) {
 			}
 		}
 
-		var errorState = s.removeOldOrders(ctx)
-		if errorState {
+		err = s.hedgeFills(ctx, internalOrders)
+		if err != nil {
+			if s.handleRequestError("Can't hedge filled orders", err) {
+				return
+			}
 			continue
 		}
 
-		// clear orders cache
-		s.internalOrdersCache = make(map[uuid.UUID]*tradingOrderPair)
+		// funds reserved by own quotes can be quoted again, the quotes are changed in place
+		var reservedCrypto, reservedUSDC = s.reservedFunds()
 
 		var intBalance, found = internalBalanceCache[s.settings.InternalSettings.Currency]
 		if !found {
 			s.logger.Error("TradingWorker Error : Can't get own internalBalance!!!")
 			continue
 		}
-		var internalBalance = intBalance.Balance
+		var internalBalance = intBalance.Balance.Add(reservedCrypto)
 
 		var intUSDCBalance, found1 = internalBalanceCache["USDC"]
 		if !found1 {
 			s.logger.Error("TradingWorker Error : Can't get own USDC balance!!!")
 			continue
 		}
-		var internalUSDCBalance = intUSDCBalance.Balance.Mul(s.settings.InternalSettings.UsdcUsageLimit).RoundDown(8)
+		var internalUSDCBalance = intUSDCBalance.Balance.Add(reservedUSDC).Mul(s.settings.InternalSettings.UsdcUsageLimit).RoundDown(8)
 
 		var tsBalance, found2 = tradingBalanceCache[s.settings.TradingSettings.Currency]
 		if !found2 {
@@ -162,97 +264,497 @@ func (s *TradingWorker) DoWork(ctx context.Context) {
 		}
 		var usdcTradingLimit = tsUSDCBalance.Balance.Mul(s.settings.TradingSettings.UsdcUsageLimit).RoundDown(8)
 
+		// quotes are normalised by the rules of both systems
+		rules, err := s.markets.Get(ctx)
+		if err != nil {
+			if s.handleRequestError("Can't get market info", err) {
+				return
+			}
+			continue
+		}
+		var quoteRules = rules.Quote()
+
 		// get trading system orders
-		var allTradingOrders = s.tradingSystemRequests.GetPublicTradingOrders(ctx, s.settings.TradingSettings.Pair, usdcTradingLimit, cryptoTradingLimit, internalBalance, internalUSDCBalance, s.pairMinAmount)
-		if len(allTradingOrders) == 0 {
-			s.logger.Error("TradingWorker %v : getAllTradingOrders empty response!", s.settings.InternalSettings.Currency)
+		allTradingOrders, err := s.getTradingOrders(ctx, usdcTradingLimit, cryptoTradingLimit, internalBalance, internalUSDCBalance, quoteRules.MinAmount)
+		if err != nil {
+			if s.handleRequestError("Can't get trading system orders", err) {
+				return
+			}
+			// quotes without the trading system book are not hedgeable, all of them are removed
 			allTradingOrders = make([]*entity.TradingOrder, 0)
 		}
 
-		// 2) Add orders from trading system to internal system
+		// quotes cover the taker fee of the hedge
+		fees, err := s.tradingSystemRequests.GetFees(ctx, s.settings.TradingSettings.Pair)
+		if err != nil {
+			if s.handleRequestError("Can't get trading fees", err) {
+				return
+			}
+			continue
+		}
+
+		// 2) Change internal orders to the trading system orders
+		var desired = make([]*tradingOrderPair, 0, len(allTradingOrders))
 		for _, tradingOrder := range allTradingOrders {
-			// add new internal order
-			if !s.addNewOrderPair(ctx, tradingOrder) {
-				// if error just continue
-				s.logger.Error("TradingWorker %v :  Error on add order to Internal system : %v!", s.settings.InternalSettings.Currency, tradingOrder)
+			if newPair := s.newOrderPair(tradingOrder, fees, quoteRules); newPair != nil {
+				desired = append(desired, newPair)
 			}
 		}
+		if s.applyQuotes(ctx, desired) {
+			return
+		}
+	}
+}
+
+// internalAvailable reports whether the internal system circuit breaker lets requests through
+func (s *TradingWorker) internalAvailable() bool {
+	if s.internalBreaker == nil || s.internalBreaker.State() != breaker.StateOpen {
+		return true
+	}
+
+	s.logger.Warn("TradingWorker %v : %v circuit breaker is open, quoting and hedging are paused", s.settings.InternalSettings.Currency, s.internalBreaker.Name())
+	return false
+}
+
+// handleRequestError logs the failed request and reports whether the worker has to stop
+func (s *TradingWorker) handleRequestError(message string, err error) bool {
+	switch {
+	case common.IsFatal(err):
+		s.logger.Error("TradingWorker %v : %v : %v, stopping worker", s.settings.InternalSettings.Currency, message, err)
+		select {
+		case s.notify <- fmt.Errorf("TradingWorker %v : %w", s.settings.InternalSettings.Currency, err):
+		default:
+		}
+		return true
+	case common.IsRetryable(err):
+		s.logger.Warn("TradingWorker %v : %v : %v, retrying on next cycle", s.settings.InternalSettings.Currency, message, err)
+	default:
+		s.logger.Error("TradingWorker %v : %v : %v", s.settings.InternalSettings.Currency, message, err)
+	}
+	return false
+}
+
+// bookUpdates returns nil channel (never ready) when streaming is not configured
+func (s *TradingWorker) bookUpdates() <-chan string {
+	if s.marketData == nil {
+		return nil
 	}
+	return s.marketData.Updates()
 }
 
-func (s *TradingWorker) removeOldOrders(ctx context.Context) bool {
-	var errorState = false
-	// removing old orders
+func (s *TradingWorker) getTradingOrders(ctx context.Context, usdcTradingLimit decimal.Decimal, cryptoTradingLimit decimal.Decimal, internalBalance decimal.Decimal, internalUSDCBalance decimal.Decimal, pairMinAmount decimal.Decimal) ([]*entity.TradingOrder, error) {
+	if s.marketData != nil {
+		var book, found = s.marketData.Book(s.settings.TradingSettings.Pair)
+		switch {
+		case !found || !book.Synced():
+			s.logger.Debug("TradingWorker %v : streaming order book is not synced, polling", s.settings.InternalSettings.Currency)
+		case time.Since(book.Updated()) > maxBookAge:
+			s.logger.Warn("TradingWorker %v : streaming order book was updated at %v, polling", s.settings.InternalSettings.Currency, book.Updated())
+		default:
+			var asks, bids = book.Levels(bookDepth)
+			return orderbook.SelectTradingOrders(asks, bids, usdcTradingLimit, cryptoTradingLimit, internalBalance, internalUSDCBalance, pairMinAmount), nil
+		}
+	}
+
+	return s.tradingSystemRequests.GetPublicTradingOrders(ctx, s.settings.TradingSettings.Pair, usdcTradingLimit, cryptoTradingLimit, internalBalance, internalUSDCBalance, pairMinAmount)
+}
+
+// hedgeFills hedges new fills of the cached orders. Fills are requested only when amount left of the order changed,
+// the order is not active anymore or it was amended. Hedged amount is kept per order, so every fill is hedged once.
+// Inactive orders leave the cache, the rest is checked on the next cycle.
+func (s *TradingWorker) hedgeFills(ctx context.Context, internalOrders map[uuid.UUID]*entity.InternalOrder) error {
 	for key, currentOrder := range s.internalOrdersCache {
-		if !s.removeInternalOrder(ctx, key) {
-			// checking of order is totally spent
-			var completedOrderInfos = s.internalRequests.GetCompleteOrder(ctx, key, s.settings.InternalSettings.Pair)
-			if len(completedOrderInfos) == 0 {
-				s.logger.Error("TradingWorker Error : Can't cancel internal order : %v", key)
-				errorState = true
-				break
+		var liveOrder, active = internalOrders[key]
+		if active && !currentOrder.unsettled && amountLeft(liveOrder).Equal(currentOrder.InternalAmount) {
+			continue
+		}
+
+		if _, err := s.settleOrder(ctx, currentOrder); err != nil {
+			return err
+		}
+		if active {
+			currentOrder.InternalAmount = amountLeft(liveOrder)
+		} else {
+			delete(s.internalOrdersCache, key)
+		}
+	}
+	return nil
+}
+
+// settleOrder hedges fills of the order not hedged yet and returns the filled amount of the order
+func (s *TradingWorker) settleOrder(ctx context.Context, currentOrder *tradingOrderPair) (decimal.Decimal, error) {
+	var key = currentOrder.InternalId
+
+	// checking of order is totally or partially spent
+	var completedOrderInfos, err = s.internalRequests.GetCompleteOrder(ctx, key, s.settings.InternalSettings.Pair)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	var filledAmount = decimal.Zero
+	for _, item := range completedOrderInfos {
+		filledAmount = filledAmount.Add(item.Amount)
+	}
+	var completedAmount = filledAmount.Sub(currentOrder.HedgedAmount)
+	if !completedAmount.IsPositive() {
+		currentOrder.unsettled = false
+		return filledAmount, nil
+	}
+
+	// don't hedge while the internal system is in unknown state, the order is checked again on the next cycle
+	if !s.internalAvailable() {
+		currentOrder.unsettled = true
+		return filledAmount, common.NewRequestError(common.ErrServiceUnavailable, s.internalBreaker.Name(), "hedge", 0, "internal order "+key.String()+" is not hedged")
+	}
+
+	// create order in trading system, the client order id is the same for the same fills of the order,
+	// so the trading system adapter can find the order when its outcome is unknown
+	var clientOrderId = clientid.Hedge(key, len(completedOrderInfos))
+	s.logger.Info("TradingWorker %v : Creating new order %v in Trading API for params : amount : %v, price : %v", s.settings.InternalSettings.Pair, clientOrderId, completedAmount, currentOrder.TradingSystemPrice)
+	var report *entity.ExecutionReport
+	if currentOrder.IsSellOrder {
+		report, err = s.tradingSystemRequests.Buy(ctx, s.settings.TradingSettings.Pair, currentOrder.TradingSystemPrice, currentOrder.InternalPrice, completedAmount, s.settings.InternalSettings.Pair, clientOrderId)
+	} else {
+		report, err = s.tradingSystemRequests.Sell(ctx, s.settings.TradingSettings.Pair, currentOrder.TradingSystemPrice, currentOrder.InternalPrice, completedAmount, s.settings.InternalSettings.Pair, clientOrderId)
+	}
+	s.recordHedge(key, clientOrderId, currentOrder, completedAmount, report, err)
+	// failed hedges are not repeated, they are in the hedge journal
+	currentOrder.HedgedAmount = filledAmount
+	currentOrder.unsettled = false
+	if err != nil && common.IsFatal(err) {
+		return filledAmount, err
+	}
+
+	return filledAmount, nil
+}
+
+// applyQuotes changes the cached orders to the desired quotes and reports whether the worker has to stop.
+// Orders are removed first to release their funds, then changed orders are amended and missing quotes are added,
+// removals and additions are sent by batches.
+func (s *TradingWorker) applyQuotes(ctx context.Context, desired []*tradingOrderPair) bool {
+	var live = make([]*tradingOrderPair, 0, len(s.internalOrdersCache))
+	for _, order := range s.internalOrdersCache {
+		live = append(live, order)
+	}
+
+	// nothing to quote, all orders of the pair are removed by one request
+	if len(desired) == 0 {
+		if len(live) == 0 {
+			return false
+		}
+		if err := s.cancelAllOrders(ctx); err != nil {
+			return s.handleRequestError("Can't remove internal orders", err)
+		}
+		return false
+	}
+
+	var plan = orderdiff.Diff(quotes(live), quotes(desired))
+	if err := s.cancelOrders(ctx, pick(live, plan.Cancel)); err != nil {
+		// funds of the orders can be still reserved, quotes are changed on the next cycle
+		return s.handleRequestError("Can't remove internal orders", err)
+	}
+
+	var replaced []*tradingOrderPair
+	var added = pick(desired, plan.Add)
+	for _, match := range plan.Amend {
+		var err = s.amendOrder(ctx, live[match.Live], desired[match.Desired])
+		if errors.Is(err, common.ErrNotSupported) {
+			// the internal system can't modify orders, the order is replaced
+			replaced = append(replaced, live[match.Live])
+			added = append(added, desired[match.Desired])
+			continue
+		}
+		if err != nil {
+			s.invalidateRules(err)
+			if s.handleRequestError(fmt.Sprintf("Can't amend internal order %v", live[match.Live].InternalId), err) {
+				return true
 			}
+			if errors.Is(err, breaker.ErrOpen) {
+				return false
+			}
+		}
+	}
 
-			// checking of order is partially spent
-			completedOrderInfos = s.internalRequests.GetCompleteOrder(ctx, key, s.settings.InternalSettings.Pair)
-			if len(completedOrderInfos) > 0 {
-				var completedAmount = decimal.Decimal{}
+	if err := s.cancelOrders(ctx, replaced); err != nil {
+		return s.handleRequestError("Can't replace internal orders", err)
+	}
 
-				for _, item := range completedOrderInfos {
-					completedAmount = completedAmount.Add(item.Amount)
-				}
+	return s.addOrders(ctx, added)
+}
 
-				// create order in trading system
-				var operationResult = false
-				s.logger.Info("TradingWorker %v : Creating new order in Trading API for params : amount : %v, price : %v", s.settings.InternalSettings.Pair, completedAmount, currentOrder.TradingSystemPrice)
-				if currentOrder.IsSellOrder {
-					operationResult = s.tradingSystemRequests.Buy(ctx, s.settings.TradingSettings.Pair, currentOrder.TradingSystemPrice, currentOrder.InternalPrice, completedAmount, s.settings.InternalSettings.Pair)
-				} else {
-					operationResult = s.tradingSystemRequests.Sell(ctx, s.settings.TradingSettings.Pair, currentOrder.TradingSystemPrice, currentOrder.InternalPrice, completedAmount, s.settings.InternalSettings.Pair)
-				}
-				s.logger.Info("TradingWorker %v : New order in Trading API for params : amount : %v, price : %v result is : %t", s.settings.InternalSettings.Pair, completedAmount, currentOrder.TradingSystemPrice, operationResult)
+// cancelOrders removes the orders by batch and hedges their fills. Orders whose removal failed stay in the cache,
+// the first error is returned when all orders are handled.
+func (s *TradingWorker) cancelOrders(ctx context.Context, orders []*tradingOrderPair) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	var currFrom, currTo = s.currencies()
+	var ids = make([]uuid.UUID, 0, len(orders))
+	for _, order := range orders {
+		ids = append(ids, order.InternalId)
+	}
+	var results, batchErr = s.internalRequests.RemoveOrders(ctx, ids, currFrom, currTo)
+
+	var firstErr error
+	for i, order := range orders {
+		// orders of the batches not sent are in the unknown state
+		var removeErr = batchErr
+		if i < len(results) {
+			removeErr = results[i].Err
+		}
+
+		if err := s.settleRemoved(ctx, order, removeErr); err != nil {
+			if common.IsFatal(err) {
+				return err
+			}
+			if firstErr == nil {
+				firstErr = err
 			}
 		}
 	}
-	return errorState
+	return firstErr
 }
 
-func (s *TradingWorker) removeInternalOrder(ctx context.Context, orderId uuid.UUID) bool {
+// cancelAllOrders removes all orders of the pair by one request and hedges fills of the cached orders.
+// Cached orders missing in the results were not active anymore.
+func (s *TradingWorker) cancelAllOrders(ctx context.Context) error {
+	var results, err = s.internalRequests.RemoveAllOrders(ctx, s.settings.InternalSettings.Pair)
+	if err != nil {
+		return err
+	}
+	var removeErrs = make(map[uuid.UUID]error, len(results))
+	for _, result := range results {
+		removeErrs[result.Id] = result.Err
+	}
 
-	var currencies = strings.Split(s.settings.InternalSettings.Pair, ",")
-	var currFrom = currencies[0]
-	var currTo = currencies[1]
+	var firstErr error
+	for key, order := range s.internalOrdersCache {
+		if err := s.settleRemoved(ctx, order, removeErrs[key]); err != nil {
+			if common.IsFatal(err) {
+				return err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// settleRemoved hedges fills of the removed order and drops it from the cache. Removed order can be partially filled,
+// so fills are checked even when the removal failed because the order is not active anymore.
+func (s *TradingWorker) settleRemoved(ctx context.Context, currentOrder *tradingOrderPair, removeErr error) error {
+	var key = currentOrder.InternalId
+	if common.IsRetryable(removeErr) {
+		// the order can be still active, it's removed on the next cycle
+		return removeErr
+	}
+
+	var filledAmount, err = s.settleOrder(ctx, currentOrder)
+	if err != nil {
+		return err
+	}
+	if removeErr != nil && filledAmount.IsZero() {
+		return fmt.Errorf("can't cancel internal order %v : %w", key, removeErr)
+	}
+	delete(s.internalOrdersCache, key)
+
+	return nil
+}
+
+// amendOrder changes price and amount of the order in place, ErrNotSupported is returned when the internal system
+// can't modify orders
+func (s *TradingWorker) amendOrder(ctx context.Context, currentOrder *tradingOrderPair, quote *tradingOrderPair) error {
+	var currFrom, currTo = s.currencies()
+
+	var err = s.internalRequests.ModifyOrder(ctx, currentOrder.InternalId, currFrom, currTo, quote.InternalAmount, quote.InternalPrice)
+	if err != nil {
+		return err
+	}
+
+	// fills made before the change are not seen in the amount left, they are hedged with the previous prices
+	currentOrder.unsettled = true
+	if _, err := s.settleOrder(ctx, currentOrder); err != nil {
+		return err
+	}
 
-	var deleteRes = s.internalRequests.RemoveOrder(ctx, orderId, currFrom, currTo)
+	currentOrder.InternalAmount = quote.InternalAmount
+	currentOrder.InternalPrice = quote.InternalPrice
+	currentOrder.TradingSystemAmount = quote.TradingSystemAmount
+	currentOrder.TradingSystemPrice = quote.TradingSystemPrice
 
-	return deleteRes
+	return nil
 }
 
-func (s *TradingWorker) addNewOrderPair(ctx context.Context, order *entity.TradingOrder) bool {
+// addOrders places the quotes by batch and reports whether the worker has to stop, placed orders are saved to the cache
+func (s *TradingWorker) addOrders(ctx context.Context, orders []*tradingOrderPair) bool {
+	if len(orders) == 0 {
+		return false
+	}
+
+	var currFrom, currTo = s.currencies()
+	var newOrders = make([]*entity.NewInternalOrder, 0, len(orders))
+	for _, order := range orders {
+		newOrders = append(newOrders, &entity.NewInternalOrder{
+			CurrencyFrom: currFrom,
+			CurrencyTo:   currTo,
+			Amount:       order.InternalAmount,
+			Price:        order.InternalPrice,
+			IsSellOrder:  order.IsSellOrder,
+		})
+	}
+
+	var results, err = s.internalRequests.AddOrders(ctx, newOrders)
+	for i, result := range results {
+		if result.Err != nil {
+			s.invalidateRules(result.Err)
+			// if error just continue
+			if s.handleRequestError(fmt.Sprintf("Error on add order to Internal system : %v", orders[i]), result.Err) {
+				return true
+			}
+			continue
+		}
+
+		// save order to cache
+		orders[i].InternalId = result.Id
+		s.internalOrdersCache[result.Id] = orders[i]
+	}
+	if err != nil {
+		return s.handleRequestError("Error on add orders to Internal system", err)
+	}
+
+	return false
+}
+
+// invalidateRules makes the next cycle request the market rules when the internal system rejected the order,
+// the rules of the pair may have changed
+func (s *TradingWorker) invalidateRules(err error) {
+	if errors.Is(err, common.ErrRejected) {
+		s.markets.Invalidate()
+	}
+}
+
+// reservedFunds returns the crypto reserved by own sell quotes and USDC reserved by own buy quotes
+func (s *TradingWorker) reservedFunds() (decimal.Decimal, decimal.Decimal) {
+	var crypto, usdc = decimal.Zero, decimal.Zero
+	for _, order := range s.internalOrdersCache {
+		if order.IsSellOrder {
+			crypto = crypto.Add(order.InternalAmount)
+		} else {
+			usdc = usdc.Add(order.InternalAmount.Mul(order.InternalPrice).RoundDown(8))
+		}
+	}
+	return crypto, usdc
+}
+
+// hedgedAmounts returns the hedged fills of the adopted orders by the hedge journal, every hedge attempt of the order
+// is journaled with the fills it covers. Without the journal the fills of the adopted orders are hedged.
+func (s *TradingWorker) hedgedAmounts(internalOrders map[uuid.UUID]*entity.InternalOrder) (map[uuid.UUID]decimal.Decimal, error) {
+	var hedged = make(map[uuid.UUID]decimal.Decimal, len(internalOrders))
+	if s.hedgeJournal == nil {
+		s.logger.Warn("TradingWorker %v : hedge journal is not set, fills of the adopted orders made before the start are hedged", s.settings.InternalSettings.Pair)
+		return hedged, nil
+	}
+
+	records, err := s.hedgeJournal.Records()
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if _, found := internalOrders[record.InternalOrderId]; found {
+			hedged[record.InternalOrderId] = hedged[record.InternalOrderId].Add(record.InternalAmount)
+		}
+	}
+
+	return hedged, nil
+}
+
+// recordHedge logs the hedge result and appends it to the hedge journal
+func (s *TradingWorker) recordHedge(internalOrderId uuid.UUID, clientOrderId string, order *tradingOrderPair, completedAmount decimal.Decimal, report *entity.ExecutionReport, hedgeErr error) {
+	var record = journal.NewHedgeRecord(s.settings.InternalSettings.Currency, s.settings.InternalSettings.Pair, internalOrderId, order.InternalPrice, completedAmount, order.IsSellOrder, report, hedgeErr)
+	record.ClientOrderId = clientOrderId
+
+	if report != nil {
+		s.logger.Info("TradingWorker %v : Hedge order %v in %v : status : %v, filled : %v, average price : %v, fee : %v %v, realized spread : %v, error : %v", s.settings.InternalSettings.Pair, report.OrderNumber, report.Venue, report.Status, report.FilledAmount, report.AveragePrice, report.Fee, report.FeeCurrency, record.RealizedSpread, hedgeErr)
+	} else {
+		s.logger.Info("TradingWorker %v : New order in Trading API for params : amount : %v, price : %v result is : %v", s.settings.InternalSettings.Pair, completedAmount, order.TradingSystemPrice, hedgeErr)
+	}
+
+	if s.hedgeJournal == nil {
+		return
+	}
+	if err := s.hedgeJournal.Append(record); err != nil {
+		s.logger.Error("TradingWorker %v : Can't write hedge journal : %v", s.settings.InternalSettings.Pair, err)
+	}
+}
+
+// currencies returns currencies of the internal pair ("BTC,USDC")
+func (s *TradingWorker) currencies() (string, string) {
+	var currencies = strings.Split(s.settings.InternalSettings.Pair, ",")
+	return currencies[0], currencies[1]
+}
+
+// newOrderPair makes the internal quote of the trading system order, the price includes the taker fee of the hedge.
+// The price is rounded away from the trading system price and the amount is rounded down to the rules of the quotes,
+// nil is returned when the quote breaks the limits of the rules.
+func (s *TradingWorker) newOrderPair(order *entity.TradingOrder, fees *entity.FeeSchedule, rules *entity.MarketInfo) *tradingOrderPair {
+	var amount = rules.RoundAmount(order.Amount, false)
+	if rules.MaxAmount.IsPositive() && amount.GreaterThan(rules.MaxAmount) {
+		amount = rules.RoundAmount(rules.MaxAmount, false)
+	}
 
 	var newOrder = &tradingOrderPair{
-		TradingSystemAmount: order.Amount,
-		InternalAmount:      order.Amount,
+		TradingSystemAmount: amount,
+		InternalAmount:      amount,
 		TradingSystemPrice:  order.Rate,
 		IsSellOrder:         order.IsSellOrder,
 	}
 	// Add 1% to price
 	if newOrder.IsSellOrder {
-		newOrder.InternalPrice = newOrder.TradingSystemPrice.Mul(s.settings.SellMultiplier).RoundDown(8)
+		newOrder.InternalPrice = rules.RoundPrice(fees.BuyCost(newOrder.TradingSystemPrice).Mul(s.settings.SellMultiplier), true)
 	} else {
-		newOrder.InternalPrice = newOrder.TradingSystemPrice.Mul(s.settings.BuyMultiplier).RoundDown(8)
+		newOrder.InternalPrice = rules.RoundPrice(fees.SellProceeds(newOrder.TradingSystemPrice).Mul(s.settings.BuyMultiplier), false)
 	}
 
-	var currencies = strings.Split(s.settings.InternalSettings.Pair, ",")
-	var currFrom = currencies[0]
-	var currTo = currencies[1]
+	if err := rules.Validate(newOrder.InternalPrice, newOrder.InternalAmount); err != nil {
+		s.logger.Debug("TradingWorker %v : quote of %v at %v is skipped : %v", s.settings.InternalSettings.Currency, order.Amount, order.Rate, err)
+		return nil
+	}
+
+	return newOrder
+}
 
-	var success, id = s.internalRequests.AddOrder(ctx, currFrom, currTo, newOrder.InternalAmount, newOrder.InternalPrice, newOrder.IsSellOrder)
-	if success {
-		newOrder.InternalId = id
+// quotes returns price and amount of the orders for the diff
+func quotes(orders []*tradingOrderPair) []orderdiff.Quote {
+	var res = make([]orderdiff.Quote, 0, len(orders))
+	for _, order := range orders {
+		res = append(res, orderdiff.Quote{IsSellOrder: order.IsSellOrder, Price: order.InternalPrice, Amount: order.InternalAmount})
 	}
-	// save order to cache
-	s.internalOrdersCache[newOrder.InternalId] = newOrder
+	return res
+}
 
-	return success
+// pick returns the orders of the indexes
+func pick(orders []*tradingOrderPair, indexes []int) []*tradingOrderPair {
+	var res = make([]*tradingOrderPair, 0, len(indexes))
+	for _, i := range indexes {
+		res = append(res, orders[i])
+	}
+	return res
+}
+
+// amountLeft of the active order, the initial amount is used when the internal system doesn't send amount left
+func amountLeft(order *entity.InternalOrder) decimal.Decimal {
+	if order.AmountLeft.IsZero() {
+		return order.Amount
+	}
+	return order.AmountLeft
+}
+
+// workInterval returns pause between the cycles, 10 seconds by default
+func workInterval(currencySettings config.CryptoCurrency) time.Duration {
+	if currencySettings.WorkIntervalMilliseconds <= 0 {
+		return defaultWorkInterval
+	}
+	return time.Duration(currencySettings.WorkIntervalMilliseconds) * time.Millisecond
 }
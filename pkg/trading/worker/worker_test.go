@@ -0,0 +1,413 @@
+package worker
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	"trading_bot/config"
+	"trading_bot/internal/common/breaker"
+	"trading_bot/internal/common/helpermethods"
+	"trading_bot/internal/common/journal"
+	"trading_bot/internal/common/requests/jetcrypto"
+	"trading_bot/internal/common/requests/jetcrypto/jetcryptotest"
+	"trading_bot/internal/common/requests/poloniex/poloniextest"
+	"trading_bot/internal/entity"
+	"trading_bot/mocks"
+
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	testKey    = "worker-test-key"
+	testSecret = "worker-test-secret"
+)
+
+func testLogger(t *testing.T) *mocks.ILogger {
+	t.Helper()
+
+	var l = &mocks.ILogger{}
+	var args = []interface{}{mock.Anything}
+	for i := 0; i < 12; i++ {
+		l.On("Debug", args...).Return()
+		l.On("Info", args...).Return()
+		l.On("Warn", args...).Return()
+		l.On("Error", args...).Return()
+		args = append(args, mock.Anything)
+	}
+
+	return l
+}
+
+// testEnvironment runs the worker against the Poloniex and JetCrypto stand-ins
+type testEnvironment struct {
+	trading  *poloniextest.Server
+	internal *jetcryptotest.Server
+	settings config.CryptoCurrency
+	journal  *journal.Journal
+}
+
+func newTestEnvironment(t *testing.T) *testEnvironment {
+	t.Helper()
+
+	var trading = poloniextest.NewServer(testKey, testSecret)
+	t.Cleanup(trading.Close)
+	trading.SetBalance("BTC", decimal.NewFromInt(2))
+	trading.SetBalance("USDC", decimal.NewFromInt(100000))
+	trading.SetBook("USDC_BTC", []poloniextest.Level{
+		{Price: decimal.NewFromInt(20000), Volume: decimal.NewFromFloat(0.1)},
+		{Price: decimal.NewFromInt(20050), Volume: decimal.NewFromInt(1)},
+	}, []poloniextest.Level{
+		{Price: decimal.NewFromInt(19950), Volume: decimal.NewFromFloat(0.5)},
+	})
+
+	var internal = jetcryptotest.NewServer(testKey, testSecret)
+	t.Cleanup(internal.Close)
+	internal.SetMinAmount("BTC,USDC", decimal.NewFromFloat(0.001))
+	internal.SetBalance("BTC", decimal.NewFromInt(1))
+	internal.SetBalance("USDC", decimal.NewFromInt(50000))
+
+	var unlimited = config.RateLimit{RequestsPerSecond: 1000}
+
+	return &testEnvironment{
+		trading:  trading,
+		internal: internal,
+		settings: config.CryptoCurrency{
+			CurrencyId:               2001,
+			SellMultiplier:           decimal.NewFromFloat(1.005),
+			BuyMultiplier:            decimal.NewFromFloat(0.995),
+			WorkIntervalMilliseconds: 20,
+			InternalSettings: config.InternalSettings{
+				Url:            internal.URL(),
+				Key:            testKey,
+				Secret:         testSecret,
+				Pair:           "BTC,USDC",
+				Currency:       "BTC",
+				UsdcUsageLimit: decimal.NewFromFloat(0.4),
+				RateLimit:      config.RateLimitSettings{Public: unlimited, Private: unlimited, Order: unlimited},
+			},
+			TradingSettings: config.TradingSettings{
+				Type:           "poloniex_legacy",
+				Url:            trading.URL(),
+				Key:            testKey,
+				Secret:         testSecret,
+				Pair:           "USDC_BTC",
+				Currency:       "BTC",
+				UsdcUsageLimit: decimal.NewFromFloat(0.8),
+				Retry:          config.RetrySettings{InitialBackoffMilliseconds: 10},
+				RateLimit:      config.RateLimitSettings{Public: unlimited, Private: unlimited, Order: unlimited},
+			},
+		},
+	}
+}
+
+// run starts the worker and stops it when done returns true or after 5 seconds
+func (e *testEnvironment) run(t *testing.T, done func() bool) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg = &sync.WaitGroup{}
+	var errChan = make(chan error, 1)
+
+	wk, err := New(ctx, wg, e.settings, testLogger(t), errChan, e.journal, breaker.NewRegistry(nil), nil)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	wk.Start()
+
+	var deadline = time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && !done() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	select {
+	case err := <-errChan:
+		t.Errorf("got worker error %v, wanted nil", err)
+	default:
+	}
+}
+
+// order returns the posted internal order
+func (e *testEnvironment) order(id uuid.UUID) (jetcryptotest.Order, bool) {
+	for _, order := range e.internal.Orders() {
+		if order.Id == id {
+			return order, true
+		}
+	}
+	return jetcryptotest.Order{}, false
+}
+
+// sellPrice returns price of the active sell quote, zero without the quote
+func (e *testEnvironment) sellPrice() decimal.Decimal {
+	for _, order := range e.internal.Orders() {
+		if order.Active && order.IsSellOrder {
+			return order.Price
+		}
+	}
+	return decimal.Zero
+}
+
+// quotePrice returns the internal sell price of the trading system ask with the taker fee of the stand-in
+func (e *testEnvironment) quotePrice(ask decimal.Decimal) decimal.Decimal {
+	var fees = entity.FeeSchedule{TakerFee: decimal.NewFromFloat(0.0025)}
+	return fees.BuyCost(ask).Mul(e.settings.SellMultiplier).RoundUp(8)
+}
+
+func (e *testEnvironment) interval() time.Duration {
+	return time.Duration(e.settings.WorkIntervalMilliseconds) * time.Millisecond
+}
+
+// hedgedAmount returns the amount bought in the trading system
+func (e *testEnvironment) hedgedAmount() decimal.Decimal {
+	var res = decimal.Zero
+	for _, trade := range e.trading.Trades() {
+		if trade.Type == "buy" {
+			res = res.Add(trade.Amount)
+		}
+	}
+	return res
+}
+
+func TestTradingWorker_PartialFillHedged_Success(t *testing.T) {
+	t.Parallel()
+
+	var env = newTestEnvironment(t)
+	var filled = decimal.Zero
+
+	env.run(t, func() bool {
+		// the customer takes part of the quote, the rest of the order keeps quoting
+		if filled.IsZero() {
+			filled = env.internal.CustomerBuy("BTC,USDC", decimal.NewFromInt(20200), decimal.NewFromFloat(0.05))
+			return false
+		}
+		return len(env.trading.Trades()) > 0
+	})
+
+	if !filled.Equal(decimal.NewFromFloat(0.05)) {
+		t.Fatalf("got filled %v, wanted 0.05", filled)
+	}
+	// the fill is bought with the trading system fee
+	if got := env.hedgedAmount(); !got.Equal(decimal.RequireFromString("0.05012532")) {
+		t.Errorf("got hedged %v, wanted 0.05012532", got)
+	}
+	if trades := env.trading.Trades(); len(trades[0].ClientOrderId) == 0 {
+		t.Errorf("got hedge without client order id, wanted id")
+	}
+	for _, order := range env.internal.Orders() {
+		if len(order.Fills) > 0 && !order.Active {
+			t.Errorf("got removed partially filled order %v, wanted quoting", order.Id)
+		}
+	}
+}
+
+func TestTradingWorker_RestartHedgesPartialFill_Success(t *testing.T) {
+	t.Parallel()
+
+	var env = newTestEnvironment(t)
+
+	// the order is posted and partially filled before the worker starts
+	var l = testLogger(t)
+	var requests = jetcrypto.New(l, helpermethods.New(l, nil), env.settings.InternalSettings, nil)
+	var id, err = requests.AddOrder(context.Background(), "BTC", "USDC", decimal.NewFromFloat(0.3), decimal.NewFromInt(20100), true)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	env.internal.CustomerBuy("BTC,USDC", decimal.NewFromInt(20100), decimal.NewFromFloat(0.2))
+
+	// the adopted order is amended to the desired amount after the hedge
+	env.run(t, func() bool {
+		var order, found = env.order(id)
+		return len(env.trading.Trades()) > 0 && found && !order.AmountLeft.Equal(decimal.NewFromFloat(0.1))
+	})
+
+	if got := env.hedgedAmount(); !got.Equal(decimal.RequireFromString("0.20050126")) {
+		t.Errorf("got hedged %v, wanted 0.20050126", got)
+	}
+	if order, _ := env.order(id); !order.Active {
+		t.Errorf("got removed order %v, wanted amended", id)
+	}
+}
+
+func TestTradingWorker_RestartWithJournal_HedgedFillNotHedgedAgain(t *testing.T) {
+	t.Parallel()
+
+	var env = newTestEnvironment(t)
+	var err error
+	env.journal, err = journal.New(filepath.Join(t.TempDir(), "hedges.jsonl"))
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+
+	// the order was partially filled and hedged before the restart, the next fill came while the worker was stopped
+	var l = testLogger(t)
+	var requests = jetcrypto.New(l, helpermethods.New(l, nil), env.settings.InternalSettings, nil)
+	id, err := requests.AddOrder(context.Background(), "BTC", "USDC", decimal.NewFromFloat(0.3), decimal.NewFromInt(20100), true)
+	if err != nil {
+		t.Fatalf("got error %v, wanted nil", err)
+	}
+	env.internal.CustomerBuy("BTC,USDC", decimal.NewFromInt(20100), decimal.NewFromFloat(0.2))
+	env.journal.Append(journal.NewHedgeRecord("BTC", "BTC,USDC", id, decimal.NewFromInt(20100), decimal.NewFromFloat(0.2), true, nil, nil))
+	env.internal.CustomerBuy("BTC,USDC", decimal.NewFromInt(20100), decimal.NewFromFloat(0.05))
+
+	env.run(t, func() bool {
+		return len(env.trading.Trades()) > 0
+	})
+
+	// only the fill after the journaled hedge is bought with the trading system fee
+	if got := env.hedgedAmount(); !got.Equal(decimal.RequireFromString("0.05012532")) {
+		t.Errorf("got hedged %v, wanted 0.05012532", got)
+	}
+	records, _ := env.journal.Records()
+	if len(records) != 2 || !records[1].InternalAmount.Equal(decimal.NewFromFloat(0.05)) {
+		t.Errorf("got %v journaled hedges, wanted the second hedge of 0.05", len(records))
+	}
+}
+
+func TestTradingWorker_UnchangedBook_Success(t *testing.T) {
+	t.Parallel()
+
+	var env = newTestEnvironment(t)
+	var quoted time.Time
+
+	// the book doesn't change, the quotes of the first cycle are kept
+	env.run(t, func() bool {
+		if quoted.IsZero() && len(env.internal.Orders()) > 0 {
+			quoted = time.Now()
+		}
+		return !quoted.IsZero() && time.Since(quoted) > 10*env.interval()
+	})
+
+	var orders = env.internal.Orders()
+	if len(orders) != 2 {
+		t.Errorf("got %v posted orders, wanted 2", len(orders))
+	}
+	for _, order := range orders {
+		if !order.Active {
+			t.Errorf("got removed order %v, wanted kept", order.Id)
+		}
+	}
+	// the quotes are placed by one batch
+	if got, single := env.internal.Requests("api/Trading/TradeBatch"), env.internal.Requests("api/Trading/Trade"); got != 1 || single != 0 {
+		t.Errorf("got %v batch and %v single requests, wanted 1 and 0", got, single)
+	}
+}
+
+func TestTradingWorker_QuotesNormalised_Success(t *testing.T) {
+	t.Parallel()
+
+	var env = newTestEnvironment(t)
+	// orders with more decimal places are rejected by the internal system
+	env.internal.SetPrecision("BTC,USDC", 2, 4)
+
+	env.run(t, func() bool {
+		return !env.sellPrice().IsZero()
+	})
+
+	// 20000 / (1 - 0.0025) * 1.005 = 20150.3759..., the sell quote is rounded up to the tick
+	if got := env.sellPrice(); !got.Equal(decimal.RequireFromString("20150.38")) {
+		t.Errorf("got sell price %v, wanted 20150.38", got)
+	}
+	for _, order := range env.internal.Orders() {
+		if !order.Active || !order.Amount.Equal(order.Amount.Truncate(4)) {
+			t.Errorf("got order %v of %v (active %v), wanted active order with 4 decimal places", order.Id, order.Amount, order.Active)
+		}
+	}
+}
+
+func TestTradingWorker_BookChangeAmended_Success(t *testing.T) {
+	t.Parallel()
+
+	var env = newTestEnvironment(t)
+	var changed = false
+
+	// the best ask moves up, the sell quote is amended in place
+	env.run(t, func() bool {
+		if !changed && len(env.internal.Orders()) > 0 {
+			env.trading.SetBook("USDC_BTC", []poloniextest.Level{
+				{Price: decimal.NewFromInt(20020), Volume: decimal.NewFromFloat(0.1)},
+			}, []poloniextest.Level{
+				{Price: decimal.NewFromInt(19950), Volume: decimal.NewFromFloat(0.5)},
+			})
+			changed = true
+		}
+		return changed && env.sellPrice().Equal(env.quotePrice(decimal.NewFromInt(20020)))
+	})
+
+	var orders = env.internal.Orders()
+	if len(orders) != 2 {
+		t.Errorf("got %v posted orders, wanted 2", len(orders))
+	}
+	for _, order := range orders {
+		if !order.Active {
+			t.Errorf("got removed order %v, wanted amended", order.Id)
+		}
+	}
+}
+
+func TestTradingWorker_ModifyNotSupported_Success(t *testing.T) {
+	t.Parallel()
+
+	var env = newTestEnvironment(t)
+	env.internal.SetModifyEndpoint(false)
+	env.internal.SetBatchEndpoints(false)
+	var changed = false
+
+	// the backend can't modify orders and has no batches, the sell quote is replaced by the single requests
+	env.run(t, func() bool {
+		if !changed && len(env.internal.Orders()) > 0 {
+			env.trading.SetBook("USDC_BTC", []poloniextest.Level{
+				{Price: decimal.NewFromInt(20020), Volume: decimal.NewFromFloat(0.1)},
+			}, []poloniextest.Level{
+				{Price: decimal.NewFromInt(19950), Volume: decimal.NewFromFloat(0.5)},
+			})
+			changed = true
+		}
+		return changed && env.sellPrice().Equal(env.quotePrice(decimal.NewFromInt(20020)))
+	})
+
+	var active = 0
+	for _, order := range env.internal.Orders() {
+		if order.Active {
+			active++
+		}
+	}
+	if got := len(env.internal.Orders()); got != 3 || active != 2 {
+		t.Errorf("got %v posted and %v active orders, wanted 3 and 2", got, active)
+	}
+}
+
+func TestTradingWorker_EmptyBookRemovesAll_Success(t *testing.T) {
+	t.Parallel()
+
+	var env = newTestEnvironment(t)
+	var emptied = false
+
+	// nothing is left to mirror, all quotes are removed by one request
+	env.run(t, func() bool {
+		if !emptied && len(env.internal.Orders()) > 0 {
+			env.trading.SetBook("USDC_BTC", nil, nil)
+			emptied = true
+		}
+		if !emptied {
+			return false
+		}
+		for _, order := range env.internal.Orders() {
+			if order.Active {
+				return false
+			}
+		}
+		return true
+	})
+
+	if got, single := env.internal.Requests("api/Trading/RemoveAllOrders"), env.internal.Requests("api/Trading/RemoveOrder"); got != 1 || single != 0 {
+		t.Errorf("got %v remove all and %v single requests, wanted 1 and 0", got, single)
+	}
+	if _, reserved := env.internal.Balance("BTC"); !reserved.IsZero() {
+		t.Errorf("got reserved %v, wanted 0", reserved)
+	}
+}